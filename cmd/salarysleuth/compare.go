@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/internal/ui"
+	"github.com/fr4nk3nst1ner/salarysleuth/internal/utils"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/offers"
+	"gopkg.in/yaml.v3"
+)
+
+// offersFile is the YAML file read by the compare subcommand
+type offersFile struct {
+	Offers []offers.Offer `yaml:"offers"`
+}
+
+// runCompare implements `salarysleuth compare offers.yaml`: it prints the
+// offers in the file side by side and where each one falls against its
+// posted range and the company's Levels.fyi median
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	noLevels := fs.Bool("no-levels", false, "Don't look up Levels.fyi medians missing from the file")
+	debug := fs.Bool("debug", false, "Enable debug mode")
+	silence := fs.Bool("silence", false, "Silence the banner")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: salarysleuth compare [options] offers.yaml")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ui.PrintBanner(*silence)
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Error reading offers: %v", err)
+	}
+	var file offersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		log.Fatalf("Error parsing %s: %v", fs.Arg(0), err)
+	}
+	if len(file.Offers) == 0 {
+		log.Fatalf("No offers in %s", fs.Arg(0))
+	}
+
+	if !*noLevels {
+		for i, o := range file.Offers {
+			if o.LevelsMedian != "" || o.Company == "" {
+				continue
+			}
+			median, err := utils.GetSalaryFromLevelsFyi(o.Company, *debug)
+			if err != nil {
+				if *debug {
+					log.Printf("No Levels.fyi median for %s: %v", o.Company, err)
+				}
+				continue
+			}
+			file.Offers[i].LevelsMedian = median
+		}
+	}
+
+	comparisons, err := offers.Compare(file.Offers)
+	if err != nil {
+		log.Fatalf("Invalid offer: %v", err)
+	}
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		return comparisons[i].Rank < comparisons[j].Rank
+	})
+
+	// Print table header
+	fmt.Printf("\n\033[1m%-4s %-32s %-10s %-10s %-11s %-11s %-11s %-11s\033[0m\n",
+		"#", "Offer", "Base", "Bonus", "Equity/yr", "Sign-on/yr", "Year 1", "Annual TC")
+	fmt.Println(strings.Repeat("-", 106))
+	for _, c := range comparisons {
+		fmt.Printf("%-4d \033[35m%-32s\033[0m %-10s %-10s %-11s %-11s %-11s \033[32m%-11s\033[0m\n",
+			c.Rank,
+			truncateString(c.Name, 31),
+			dollars(c.Annual.Base),
+			dollars(c.Annual.Bonus),
+			dollars(c.Annual.Equity),
+			dollars(c.Annual.SignOn),
+			dollars(c.FirstYear.Total),
+			dollars(c.Annual.Total))
+	}
+	fmt.Println(strings.Repeat("-", 106))
+	fmt.Println("Annual figures average the years until the equity grant has fully vested.")
+
+	fmt.Printf("\n\033[1mPlacement\033[0m\n")
+	for _, c := range comparisons {
+		fmt.Printf("\n\033[35m%s\033[0m\n", c.Name)
+		switch {
+		case c.PostedPercentile != nil:
+			fmt.Printf("  Posted range:  %s - %s, base %s\n",
+				dollars(c.PostedMin), dollars(c.PostedMax), rangePlacement(*c.PostedPercentile))
+		case c.PostedMax > 0:
+			fmt.Printf("  Posted salary: %s\n", dollars(c.PostedMax))
+		default:
+			fmt.Println("  Posted range:  not given")
+		}
+		if c.VsLevels != nil {
+			direction := "above"
+			if *c.VsLevels < 0 {
+				direction = "below"
+			}
+			fmt.Printf("  Levels.fyi:    median %s, annual TC %.0f%% %s\n",
+				ui.ColorizeSalary(dollars(c.LevelsMedian)), math.Abs(*c.VsLevels), direction)
+		} else {
+			fmt.Println("  Levels.fyi:    no data")
+		}
+	}
+}
+
+// rangePlacement describes where a percentile of the posted range falls
+func rangePlacement(pct float64) string {
+	switch {
+	case pct < 0:
+		return "below the range"
+	case pct > 100:
+		return "above the range"
+	}
+	return fmt.Sprintf("at the %s percentile", ordinal(int(math.Round(pct))))
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// dollars formats a whole-dollar amount, or "-" for none
+func dollars(v float64) string {
+	if v < 0.5 {
+		return "-"
+	}
+	return utils.FormatSalary(strconv.Itoa(int(math.Round(v))))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -12,8 +13,16 @@ import (
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/scraper"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/ui"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/utils"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 )
 
+// writeProgress writes a -progress line to stderr, where it stays out of the
+// results
+func writeProgress(event map[string]interface{}) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(os.Stderr, "PROGRESS %s\n", data)
+}
+
 // printExamples displays usage examples for the program
 func printExamples() {
 	fmt.Println("\n📋 SalarySleuth Usage Examples 📋")
@@ -41,15 +50,32 @@ func printExamples() {
 	fmt.Println("\n8. Search for jobs and display URLs as clickable hyperlinks (requires terminal support):")
 	fmt.Println("   salarysleuth -description \"Security Engineer\" -hyperlink")
 	
+	fmt.Println("\n9. Search Greenhouse and Lever postings whose description mentions OSCP and red teaming but not management:")
+	fmt.Println("   salarysleuth -description 'oscp AND \"red team\" NOT manager' -source greenhouse -match description")
+	
+	fmt.Println("\n10. Search all sources and keep senior, well-paid red team roles outside of management:")
+	fmt.Println("   salarysleuth -description \"Red Team\" -query 'title:(senior OR staff) salary>=200k -title:manager'")
+	
+	fmt.Println("\n11. Compare job offers side by side, with percentile placement against posted ranges and Levels.fyi:")
+	fmt.Println("   salarysleuth compare offers.yaml")
+	
 	fmt.Println("\nFor more information, visit: https://github.com/fr4nk3nst1ner/salarysleuth")
 	os.Exit(0)
 }
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompare(os.Args[2:])
+		return
+	}
+
 	// Command line flags
-	description := flag.String("description", "", "Job description to search for")
+	description := flag.String("description", "", "Job description to search for (Greenhouse and Lever accept AND, OR, NOT, -term, \"phrases\" and parentheses)")
+	matchMode := flag.String("match", scraper.MatchBoth, "Where Greenhouse and Lever match -description: title, description, or both")
 	city := flag.String("city", "", "City to search in")
-	titleKeyword := flag.String("title", "", "Title keyword to filter by")
+	titleKeyword := flag.String("title", "", "Title keyword to filter by (query syntax, matched against the title)")
+	resultQuery := flag.String("query", "", "Filter results with a query, e.g. 'title:\"red team\" company:stripe salary>=200k -title:manager'")
 	pages := flag.Int("pages", 10, "Number of pages to scrape")
 	source := flag.String("source", "", "Source to scrape (linkedin, greenhouse, lever, monster, indeed). If not specified, searches all sources.")
 	remoteOnly := flag.Bool("remote", false, "Only show remote positions")
@@ -69,6 +95,9 @@ func main() {
 	// URL display flag
 	hyperlink := flag.Bool("hyperlink", false, "Display job URLs as clickable terminal hyperlinks (requires terminal support)")
 
+	// Progress reporting for programs running the scraper
+	reportProgress := flag.Bool("progress", false, "Write progress to stderr as PROGRESS {json} lines, one per page fetched and company looked up on Levels.fyi")
+
 	flag.Parse()
 
 	// Display banner (skip if either -silence or -nobanner is set)
@@ -93,6 +122,26 @@ func main() {
 		log.Fatal("Description is required")
 	}
 
+	// Validate the match mode
+	*matchMode = strings.ToLower(*matchMode)
+	if !scraper.IsValidMatchMode(*matchMode) {
+		log.Fatal("Invalid match mode. Must be one of: title, description, both")
+	}
+
+	var err error
+	var titleQuery *query.Query
+	if *titleKeyword != "" {
+		if titleQuery, err = query.Parse(*titleKeyword); err != nil {
+			log.Fatalf("Invalid title query: %v", err)
+		}
+	}
+	var filterQuery *query.Query
+	if *resultQuery != "" {
+		if filterQuery, err = query.Parse(*resultQuery); err != nil {
+			log.Fatalf("Invalid -query: %v", err)
+		}
+	}
+
 	// Validate table mode with no-levels
 	if *table && *noLevels {
 		log.Fatal("Cannot use -table with -no-levels as table mode requires Levels.fyi data")
@@ -119,6 +168,18 @@ func main() {
 		fmt.Println("Searching all sources: LinkedIn, Greenhouse, Lever")
 	}
 
+	// Greenhouse and Lever match the description locally as a boolean query;
+	// the other sources pass it to the site as it is
+	var descQuery *query.Query
+	for _, src := range sourcesToSearch {
+		if src := strings.ToLower(src); src == "greenhouse" || src == "lever" {
+			if descQuery, err = query.Parse(*description); err != nil {
+				log.Fatalf("Invalid description query: %v", err)
+			}
+			break
+		}
+	}
+
 	// Search each source
 	for _, src := range sourcesToSearch {
 		var results []models.SalaryInfo
@@ -127,29 +188,36 @@ func main() {
 		if *debug {
 			fmt.Printf("\nSearching source: %s\n", src)
 		}
+		if *reportProgress {
+			source := src
+			progress.Pages = 0
+			progress.OnPage = func(pages, foundJobs int) {
+				writeProgress(map[string]interface{}{"event": "page", "source": source, "pages": pages, "found": foundJobs})
+			}
+		}
 
 		switch strings.ToLower(src) {
 		case "linkedin":
-			results, err = scraper.ScrapeLinkedIn(*description, *city, *titleKeyword, *remoteOnly, *internshipsOnly, *topPayOnly, *pages, *debug, *proxyURL, progress)
+			results, err = scraper.ScrapeLinkedIn(*description, *city, titleQuery, *remoteOnly, *internshipsOnly, *topPayOnly, *pages, *debug, *proxyURL, progress)
 		case "greenhouse":
-			results, err = scraper.ScrapeGreenhouse(*description, *pages, *debug, *proxyURL, progress, *topPayOnly)
+			results, err = scraper.ScrapeGreenhouse(*description, *matchMode, *pages, *debug, *proxyURL, progress, *topPayOnly)
 			// Filter for remote jobs in post-processing
 			if *remoteOnly && err == nil {
 				var filteredResults []models.SalaryInfo
 				for _, job := range results {
-					if utils.IsValidJob(job.Title, job.Location, *titleKeyword, true, *internshipsOnly, *topPayOnly, job.Company) {
+					if utils.IsValidJob(job.Title, job.Location, titleQuery, true, *internshipsOnly, *topPayOnly, job.Company) {
 						filteredResults = append(filteredResults, job)
 					}
 				}
 				results = filteredResults
 			}
 		case "lever":
-			results, err = scraper.ScrapeLever(*description, *pages, *debug, *proxyURL, progress, *topPayOnly)
+			results, err = scraper.ScrapeLever(*description, *matchMode, *pages, *debug, *proxyURL, progress, *topPayOnly)
 			// Filter for remote jobs in post-processing
 			if *remoteOnly && err == nil {
 				var filteredResults []models.SalaryInfo
 				for _, job := range results {
-					if utils.IsValidJob(job.Title, job.Location, *titleKeyword, true, *internshipsOnly, *topPayOnly, job.Company) {
+					if utils.IsValidJob(job.Title, job.Location, titleQuery, true, *internshipsOnly, *topPayOnly, job.Company) {
 						filteredResults = append(filteredResults, job)
 					}
 				}
@@ -161,7 +229,7 @@ func main() {
 			if *remoteOnly && err == nil {
 				var filteredResults []models.SalaryInfo
 				for _, job := range results {
-					if utils.IsValidJob(job.Title, job.Location, *titleKeyword, true, *internshipsOnly, *topPayOnly, job.Company) {
+					if utils.IsValidJob(job.Title, job.Location, titleQuery, true, *internshipsOnly, *topPayOnly, job.Company) {
 						filteredResults = append(filteredResults, job)
 					}
 				}
@@ -173,7 +241,7 @@ func main() {
 			if *remoteOnly && err == nil {
 				var filteredResults []models.SalaryInfo
 				for _, job := range results {
-					if utils.IsValidJob(job.Title, job.Location, *titleKeyword, true, *internshipsOnly, *topPayOnly, job.Company) {
+					if utils.IsValidJob(job.Title, job.Location, titleQuery, true, *internshipsOnly, *topPayOnly, job.Company) {
 						filteredResults = append(filteredResults, job)
 					}
 				}
@@ -200,7 +268,21 @@ func main() {
 	// Process results with Levels.fyi data if not disabled
 	if len(allResults) > 0 && !*noLevels {
 		fmt.Printf("\nFetching salary data from Levels.fyi...\n")
-		utils.ProcessWithLevelsFyi(allResults, *debug)
+		var onEnrich func(done, total int)
+		if *reportProgress {
+			onEnrich = func(done, total int) {
+				writeProgress(map[string]interface{}{"event": "enrich", "done": done, "total": total})
+			}
+		}
+		utils.ProcessWithLevelsFyi(allResults, *debug, onEnrich)
+	}
+
+	// Apply the -query filter once Levels.fyi data is available for salary comparisons
+	if filterQuery != nil {
+		allResults = filterByQuery(allResults, filterQuery)
+		if *debug {
+			fmt.Printf("After -query filter: %d jobs\n", len(allResults))
+		}
 	}
 
 	// Print results
@@ -251,11 +333,40 @@ func main() {
 			}
 			fmt.Printf("URL: %s\n", ui.FormatURL(job.URL, *hyperlink))
 			fmt.Printf("Source: %s\n", job.Source)
+			if job.MatchedIn != "" {
+				fmt.Printf("Matched In: %s\n", job.MatchedIn)
+			}
+			if job.MatchSnippet != "" && descQuery != nil {
+				fmt.Printf("Match: %s\n", ui.HighlightMatches(job.MatchSnippet, descQuery.Spans(job.MatchSnippet)))
+			}
 			fmt.Println(strings.Repeat("-", 80))
 		}
 	}
 }
 
+// filterByQuery keeps the jobs whose title, company, location and salary match
+// q. The posted salary range is used for salary comparisons, falling back to
+// the Levels.fyi figure.
+func filterByQuery(jobs []models.SalaryInfo, q *query.Query) []models.SalaryInfo {
+	var filtered []models.SalaryInfo
+	for _, job := range jobs {
+		doc := query.Document{
+			Title:    job.Title,
+			Company:  job.Company,
+			Location: job.Location,
+		}
+		if salary, ok := query.ParseAmount(job.SalaryRange); ok {
+			doc.Salary = salary
+		} else if salary, ok := query.ParseAmount(job.LevelSalary); ok {
+			doc.Salary = salary
+		}
+		if q.MatchDocument(doc) {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered
+}
+
 // truncateString truncates a string to the specified length and adds "..." if necessary
 func truncateString(s string, length int) string {
 	if len(s) <= length {
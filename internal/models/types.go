@@ -9,6 +9,10 @@ type SalaryInfo struct {
 	SalaryRange string `json:"salary_range"`
 	LevelSalary string `json:"level_salary,omitempty"`
 	Source      string `json:"source"`
+	// MatchedIn is "title" or "description" for sources matched locally
+	// against the -description query (Greenhouse, Lever)
+	MatchedIn    string `json:"matched_in,omitempty"`
+	MatchSnippet string `json:"match_snippet,omitempty"`
 }
 
 // Salary represents the salary structure from job postings
@@ -24,4 +28,17 @@ type Salary struct {
 // ScrapeProgress represents the progress of a scraping operation
 type ScrapeProgress struct {
 	FoundJobs int `json:"found_jobs"`
+	Pages     int `json:"pages"` // result pages, or company job boards, fetched
+
+	// OnPage, when set, is called after each page with the totals so far
+	OnPage func(pages, foundJobs int) `json:"-"`
+}
+
+// PageDone records a fetched page and the number of jobs found so far
+func (p *ScrapeProgress) PageDone(foundJobs int) {
+	p.Pages++
+	p.FoundJobs = foundJobs
+	if p.OnPage != nil {
+		p.OnPage(p.Pages, foundJobs)
+	}
 } 
\ No newline at end of file
@@ -32,7 +32,7 @@ type GreenhouseJob struct {
 	Location    struct {
 		Name string `json:"name"`
 	} `json:"location"`
-	Content  string `json:"content"` // HTML content (only in single job response)
+	Content  string `json:"content"` // HTML content (single job response, or list with ?content=true)
 	Metadata []struct {
 		ID        int64  `json:"id"`
 		Name      string `json:"name"`
@@ -107,13 +107,21 @@ var greenhouseCompanies = []string{
 	"framer",
 }
 
-// ScrapeGreenhouse scrapes job listings from Greenhouse job boards
-func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress, topPayOnly bool) ([]models.SalaryInfo, error) {
+// ScrapeGreenhouse scrapes job listings from Greenhouse job boards. The
+// description is a boolean query (see pkg/query) evaluated against each
+// posting's title, description text, or both according to matchMode.
+func ScrapeGreenhouse(description, matchMode string, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress, topPayOnly bool) ([]models.SalaryInfo, error) {
+	q, err := parseDescriptionQuery(description)
+	if err != nil {
+		return nil, err
+	}
+
 	httpClient := client.CreateProxyHTTPClient(proxyURL)
 	var results []models.SalaryInfo
+	withContent := needsDescription(matchMode)
 
 	if debug {
-		fmt.Printf("Searching Greenhouse for jobs with description: %s\n", description)
+		fmt.Printf("Searching Greenhouse for jobs with description: %s (match: %s)\n", description, matchMode)
 	}
 
 	for _, company := range greenhouseCompanies {
@@ -129,7 +137,7 @@ func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string
 			fmt.Printf("Fetching jobs from Greenhouse for %s\n", company)
 		}
 
-		jobs, err := fetchGreenhouseJobs(httpClient, company, debug)
+		jobs, err := fetchGreenhouseJobs(httpClient, company, withContent, debug)
 		if err != nil {
 			if debug {
 				fmt.Printf("Error fetching jobs for %s: %v\n", company, err)
@@ -144,43 +152,40 @@ func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string
 		// Filter jobs by description and process them
 		matchingJobs := 0
 		for _, job := range jobs {
-			titleLower := strings.ToLower(job.Title)
-			descLower := strings.ToLower(description)
-			matched := strings.Contains(titleLower, descLower)
-			if !matched {
-				for _, word := range strings.Fields(descLower) {
-					if len(word) >= 4 && strings.Contains(titleLower, word) {
-						matched = true
-						break
-					}
-				}
-			}
-			if !matched {
+			body := utils.HTMLToText(job.Content)
+			match, ok := matchPosting(q, matchMode, job.Title, body)
+			if !ok {
 				continue
 			}
 
 			matchingJobs++
 
-			// Get detailed job info to extract salary
+			// The board listing only includes content when requested, so
+			// title-only searches still fetch the detail to look for salary
 			salary := "Not Available"
-			jobDetail, err := fetchGreenhouseJobDetail(httpClient, company, job.ID, debug)
-			if err == nil && jobDetail.Content != "" {
-				// Try to find salary in the job content
-				if salaryMatch := utils.FindSalaryInText(jobDetail.Content); salaryMatch != "" {
-					salary = salaryMatch
-					if debug {
-						fmt.Printf("Found salary %s for job %s\n", salary, job.Title)
-					}
+			if body == "" {
+				jobDetail, err := fetchGreenhouseJobDetail(httpClient, company, job.ID, debug)
+				if err == nil {
+					body = utils.HTMLToText(jobDetail.Content)
+					job.Metadata = jobDetail.Metadata
 				}
-				
-				// Also check metadata for salary info
-				for _, meta := range jobDetail.Metadata {
-					metaName := strings.ToLower(meta.Name)
-					if strings.Contains(metaName, "salary") || strings.Contains(metaName, "compensation") {
-						if strVal, ok := meta.Value.(string); ok && strVal != "" {
-							if salary == "Not Available" {
-								salary = strVal
-							}
+			}
+
+			// Try to find salary in the job content
+			if salaryMatch := utils.FindSalaryInText(body); salaryMatch != "" {
+				salary = salaryMatch
+				if debug {
+					fmt.Printf("Found salary %s for job %s\n", salary, job.Title)
+				}
+			}
+
+			// Also check metadata for salary info
+			for _, meta := range job.Metadata {
+				metaName := strings.ToLower(meta.Name)
+				if strings.Contains(metaName, "salary") || strings.Contains(metaName, "compensation") {
+					if strVal, ok := meta.Value.(string); ok && strVal != "" {
+						if salary == "Not Available" {
+							salary = strVal
 						}
 					}
 				}
@@ -190,12 +195,14 @@ func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string
 			formattedCompany := formatCompanyName(company)
 
 			jobInfo := models.SalaryInfo{
-				Company:     formattedCompany,
-				Title:       job.Title,
-				Location:    job.Location.Name,
-				URL:         job.AbsoluteURL,
-				SalaryRange: salary,
-				Source:      "greenhouse",
+				Company:      formattedCompany,
+				Title:        job.Title,
+				Location:     job.Location.Name,
+				URL:          job.AbsoluteURL,
+				SalaryRange:  salary,
+				Source:       "greenhouse",
+				MatchedIn:    match.field,
+				MatchSnippet: match.snippet,
 			}
 
 			results = append(results, jobInfo)
@@ -208,7 +215,7 @@ func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string
 			fmt.Printf("Found %d matching jobs for %s\n", matchingJobs, company)
 		}
 
-		progress.FoundJobs = len(results)
+		progress.PageDone(len(results))
 
 		// Add delay between companies to be respectful
 		delay := time.Duration(rand.Int63n(int64(maxGreenhouseDelay-minGreenhouseDelay))) + minGreenhouseDelay
@@ -221,9 +228,14 @@ func ScrapeGreenhouse(description string, pages int, debug bool, proxyURL string
 	return results, nil
 }
 
-// fetchGreenhouseJobs fetches all job listings from a company's Greenhouse board
-func fetchGreenhouseJobs(httpClient *http.Client, company string, debug bool) ([]GreenhouseJob, error) {
+// fetchGreenhouseJobs fetches all job listings from a company's Greenhouse board.
+// With withContent set, the board API includes each posting's HTML description
+// in the same response, avoiding one detail request per job.
+func fetchGreenhouseJobs(httpClient *http.Client, company string, withContent bool, debug bool) ([]GreenhouseJob, error) {
 	url := fmt.Sprintf("%s/%s/jobs", greenhouseAPIURL, company)
+	if withContent {
+		url += "?content=true"
+	}
 
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
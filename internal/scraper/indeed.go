@@ -83,7 +83,7 @@ func ScrapeIndeed(description string, pages int, debug bool, proxyURL string, pr
 		}
 
 		results = append(results, pageResults...)
-		progress.FoundJobs = len(results)
+		progress.PageDone(len(results))
 
 		if debug {
 			fmt.Printf("Found %d jobs on page %d (total: %d)\n", len(pageResults), page+1, len(results))
@@ -81,13 +81,20 @@ var leverCompanies = []string{
 	"kabam",
 }
 
-// ScrapeLever scrapes job listings from Lever's public API
-func ScrapeLever(description string, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress, topPayOnly bool) ([]models.SalaryInfo, error) {
+// ScrapeLever scrapes job listings from Lever's public API. The description
+// is a boolean query (see pkg/query) evaluated against each posting's title,
+// description text, or both according to matchMode.
+func ScrapeLever(description, matchMode string, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress, topPayOnly bool) ([]models.SalaryInfo, error) {
+	q, err := parseDescriptionQuery(description)
+	if err != nil {
+		return nil, err
+	}
+
 	httpClient := client.CreateProxyHTTPClient(proxyURL)
 	var results []models.SalaryInfo
 
 	if debug {
-		fmt.Printf("Searching Lever for jobs with description: %s\n", description)
+		fmt.Printf("Searching Lever for jobs with description: %s (match: %s)\n", description, matchMode)
 	}
 
 	for _, company := range leverCompanies {
@@ -118,20 +125,8 @@ func ScrapeLever(description string, pages int, debug bool, proxyURL string, pro
 		// Filter and process jobs
 		matchingJobs := 0
 		for _, job := range jobs {
-			titleLower := strings.ToLower(job.Text)
-			descLower := strings.ToLower(description)
-			fullTextLower := strings.ToLower(job.DescriptionPlain + " " + job.AdditionalPlain)
-
-			matched := strings.Contains(titleLower, descLower) || strings.Contains(fullTextLower, descLower)
-			if !matched {
-				for _, word := range strings.Fields(descLower) {
-					if len(word) >= 4 && strings.Contains(titleLower, word) {
-						matched = true
-						break
-					}
-				}
-			}
-			if !matched {
+			match, ok := matchPosting(q, matchMode, job.Text, leverPostingText(job))
+			if !ok {
 				continue
 			}
 
@@ -144,12 +139,14 @@ func ScrapeLever(description string, pages int, debug bool, proxyURL string, pro
 			formattedCompany := formatLeverCompanyName(company)
 
 			jobInfo := models.SalaryInfo{
-				Company:     formattedCompany,
-				Title:       job.Text,
-				Location:    job.Categories.Location,
-				URL:         job.HostedURL,
-				SalaryRange: salary,
-				Source:      "lever",
+				Company:      formattedCompany,
+				Title:        job.Text,
+				Location:     job.Categories.Location,
+				URL:          job.HostedURL,
+				SalaryRange:  salary,
+				Source:       "lever",
+				MatchedIn:    match.field,
+				MatchSnippet: match.snippet,
 			}
 
 			results = append(results, jobInfo)
@@ -162,7 +159,7 @@ func ScrapeLever(description string, pages int, debug bool, proxyURL string, pro
 			fmt.Printf("Found %d matching jobs for %s\n", matchingJobs, company)
 		}
 
-		progress.FoundJobs = len(results)
+		progress.PageDone(len(results))
 
 		// Add delay between companies
 		delay := time.Duration(rand.Int63n(int64(maxLeverDelay-minLeverDelay))) + minLeverDelay
@@ -229,6 +226,17 @@ func fetchLeverJobs(httpClient *http.Client, company string, debug bool) ([]Leve
 	return jobs, nil
 }
 
+// leverPostingText returns the plain-text body of a posting, including the
+// requirement lists which Lever only provides as HTML
+func leverPostingText(job LeverJob) string {
+	parts := []string{job.DescriptionPlain}
+	for _, list := range job.Lists {
+		parts = append(parts, list.Text, utils.HTMLToText(list.Content))
+	}
+	parts = append(parts, job.AdditionalPlain)
+	return strings.Join(parts, "\n")
+}
+
 // extractLeverSalary extracts salary information from a Lever job posting
 func extractLeverSalary(job LeverJob, debug bool) string {
 	// First, check the structured salary range field
@@ -15,6 +15,7 @@ import (
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/models"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/client"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/utils"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 )
 
 const (
@@ -57,7 +58,7 @@ func getLinkedInHeaders(referer string) http.Header {
 }
 
 // ScrapeLinkedIn scrapes job listings from LinkedIn
-func ScrapeLinkedIn(description, city, titleKeyword string, remoteOnly, internshipsOnly, topPayOnly bool, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress) ([]models.SalaryInfo, error) {
+func ScrapeLinkedIn(description, city string, titleQuery *query.Query, remoteOnly, internshipsOnly, topPayOnly bool, pages int, debug bool, proxyURL string, progress *models.ScrapeProgress) ([]models.SalaryInfo, error) {
 	// Create HTTP client with cookie support
 	jar, err := cookiejar.New(nil)
 	if err != nil {
@@ -170,7 +171,7 @@ func ScrapeLinkedIn(description, city, titleKeyword string, remoteOnly, internsh
 				location := strings.TrimSpace(s.Find("span.job-search-card__location").Text())
 				jobURL, _ := s.Find("a.base-card__full-link").Attr("href")
 
-				if !utils.IsValidJob(title, location, titleKeyword, remoteOnly, internshipsOnly, topPayOnly, company) {
+				if !utils.IsValidJob(title, location, titleQuery, remoteOnly, internshipsOnly, topPayOnly, company) {
 					return
 				}
 
@@ -230,7 +231,7 @@ func ScrapeLinkedIn(description, city, titleKeyword string, remoteOnly, internsh
 	// Collect results and errors
 	for pageResults := range resultsChan {
 		results = append(results, pageResults...)
-		progress.FoundJobs = len(results)
+		progress.PageDone(len(results))
 	}
 
 	// Check for errors
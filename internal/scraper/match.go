@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+)
+
+// Match modes for sources that return full postings (Greenhouse, Lever), where
+// the -description query is evaluated locally instead of by a search engine
+const (
+	MatchTitle       = "title"
+	MatchDescription = "description"
+	MatchBoth        = "both"
+)
+
+// snippetRadius is how much context to show on each side of a description match
+const snippetRadius = 60
+
+// IsValidMatchMode checks if the -match flag value is supported
+func IsValidMatchMode(mode string) bool {
+	switch strings.ToLower(mode) {
+	case MatchTitle, MatchDescription, MatchBoth:
+		return true
+	}
+	return false
+}
+
+// needsDescription reports whether the match mode requires the full posting body
+func needsDescription(mode string) bool {
+	return mode != MatchTitle
+}
+
+// parseDescriptionQuery parses the -description value for local matching
+func parseDescriptionQuery(description string) (*query.Query, error) {
+	q, err := query.Parse(description)
+	if err != nil {
+		return nil, fmt.Errorf("invalid description query %q: %v", description, err)
+	}
+	return q, nil
+}
+
+// jobMatch describes where a query matched a posting
+type jobMatch struct {
+	field   string // "title" or "description"
+	snippet string
+}
+
+// matchPosting evaluates the query against a posting's title and plain-text
+// body according to the match mode. In "both" mode the title and body are
+// searched together, so `oscp "red team"` matches a posting with "Red Team"
+// in the title and OSCP in the requirements.
+func matchPosting(q *query.Query, mode, title, body string) (jobMatch, bool) {
+	doc := query.Document{Title: title, Description: body}
+	switch mode {
+	case MatchTitle:
+		doc.Description = ""
+	case MatchDescription:
+		doc.Title = ""
+	}
+	if !q.MatchDocument(doc) {
+		return jobMatch{}, false
+	}
+	if mode == MatchTitle {
+		return jobMatch{field: MatchTitle}, true
+	}
+
+	m := jobMatch{field: MatchDescription}
+	if mode == MatchBoth && q.MatchDocument(query.Document{Title: title}) {
+		m.field = MatchTitle
+	}
+	m.snippet, _ = q.Snippet(body, snippetRadius)
+	return m, true
+}
@@ -81,7 +81,7 @@ func ScrapeMonster(description string, pages int, debug bool, proxyURL string, p
 		}
 
 		results = append(results, pageResults...)
-		progress.FoundJobs = len(results)
+		progress.PageDone(len(results))
 
 		if debug {
 			fmt.Printf("Found %d jobs on page %d (total: %d)\n", len(pageResults), page, len(results))
@@ -9,6 +9,7 @@ import (
 
 	"github.com/pterm/pterm"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/utils"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 )
 
 const bannerText = `
@@ -81,4 +82,21 @@ func ColorizeSalary(salary string) string {
 	default:
 		return pterm.Red(formattedSalary) // Red for <$100K
 	}
-} 
\ No newline at end of file
+} 
+
+// HighlightMatches colors the parts of text that matched the search query
+func HighlightMatches(text string, spans []query.Span) string {
+	if len(spans) == 0 {
+		return text
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		sb.WriteString(text[last:span.Start])
+		sb.WriteString(pterm.Bold.Sprint(pterm.LightCyan(text[span.Start:span.End])))
+		last = span.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/fr4nk3nst1ner/salarysleuth/internal/models"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 )
 
 const (
@@ -268,35 +269,34 @@ func NormalizeGreenhouseURL(jobURL string) string {
 	return jobURL
 }
 
-// IsValidJob checks if a job matches the search criteria
-func IsValidJob(title, location, titleKeyword string, remoteOnly, internshipsOnly, topPayOnly bool, company string) bool {
-	title = strings.ToLower(title)
-	location = strings.ToLower(location)
-	titleKeyword = strings.ToLower(titleKeyword)
+// Word-boundary queries for the -remote and -internships filters, so that
+// "intern" no longer matches "International" and "wfh" must be a whole word
+var (
+	remoteQuery     = query.MustParse(`location:(remote OR anywhere OR "work from home" OR wfh) OR title:(remote OR "work from home" OR wfh)`)
+	internshipQuery = query.MustParse(`title:(intern OR interns OR internship OR internships)`)
+	// LinkedIn often lists nationwide remote jobs as "United States"
+	nationwideQuery = query.MustParse(`location:"united states"`)
+)
 
-	if titleKeyword != "" && !strings.Contains(title, titleKeyword) {
+// IsValidJob checks if a job matches the search criteria. titleQuery, if
+// not nil, is evaluated against the job title.
+func IsValidJob(title, location string, titleQuery *query.Query, remoteOnly, internshipsOnly, topPayOnly bool, company string) bool {
+	doc := query.Document{Title: title, Location: location}
+
+	if titleQuery != nil && !titleQuery.MatchDocument(query.Document{Title: title}) {
 		return false
 	}
 
 	if remoteOnly {
-		// Check for various remote indicators in the location
-		isRemote := strings.Contains(location, "remote") ||
-			strings.Contains(location, "anywhere") ||
-			strings.Contains(location, "work from home") ||
-			strings.Contains(location, "wfh") ||
-			strings.Contains(title, "remote") ||
-			// LinkedIn often lists nationwide remote jobs as "United States"
-			(strings.Contains(location, "united states") && !strings.Contains(location, ",")) ||
-			// Check for remote indicators in the title
-			strings.Contains(title, "work from home") ||
-			strings.Contains(title, "wfh")
+		isRemote := remoteQuery.MatchDocument(doc) ||
+			(nationwideQuery.MatchDocument(doc) && !strings.Contains(location, ","))
 
 		if !isRemote {
 			return false
 		}
 	}
 
-	if internshipsOnly && !strings.Contains(title, "intern") && !strings.Contains(title, "internship") {
+	if internshipsOnly && !internshipQuery.MatchDocument(doc) {
 		return false
 	}
 
@@ -498,8 +498,10 @@ func GetSalaryFromLevelsFyi(companyName string, debug bool) (string, error) {
 	return salaryElem, nil
 }
 
-// ProcessWithLevelsFyi enriches job listings with Levels.fyi salary data
-func ProcessWithLevelsFyi(jobs []models.SalaryInfo, debug bool) {
+// ProcessWithLevelsFyi enriches job listings with Levels.fyi salary data.
+// onProgress, when set, is called as each company is looked up with the
+// number done and the total.
+func ProcessWithLevelsFyi(jobs []models.SalaryInfo, debug bool, onProgress func(done, total int)) {
 	// Create a map to track unique companies to avoid duplicate requests
 	uniqueCompanies := make(map[string]struct{})
 	for _, job := range jobs {
@@ -514,6 +516,8 @@ func ProcessWithLevelsFyi(jobs []models.SalaryInfo, debug bool) {
 	resultsChan := make(chan salaryResult, len(uniqueCompanies))
 	semaphore := make(chan struct{}, maxWorkers)
 	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
 
 	// Process unique companies concurrently
 	for company := range uniqueCompanies {
@@ -528,6 +532,12 @@ func ProcessWithLevelsFyi(jobs []models.SalaryInfo, debug bool) {
 			if err == nil {
 				resultsChan <- salaryResult{company: companyName, salary: salary}
 			}
+			if onProgress != nil {
+				progressMu.Lock()
+				done++
+				onProgress(done, len(uniqueCompanies))
+				progressMu.Unlock()
+			}
 
 			// Add small random delay to avoid rate limiting
 			time.Sleep(time.Duration(rand.Int63n(500)) * time.Millisecond)
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"html"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+)
+
+// blockElements are tags that start a new line when converting HTML to text,
+// so that words in adjacent paragraphs or list items don't run together
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"tr": true, "table": true, "section": true, "blockquote": true, "hr": true,
+}
+
+// HTMLToText converts an HTML job description to plain text. Greenhouse
+// returns the description with its markup entity-escaped (&lt;p&gt;...), so
+// escaped input is unescaped before the tags are stripped.
+func HTMLToText(content string) string {
+	if strings.Contains(content, "&lt;") {
+		content = html.UnescapeString(content)
+	}
+
+	var sb strings.Builder
+	tokenizer := nethtml.NewTokenizer(strings.NewReader(content))
+	skipDepth := 0
+
+	for {
+		switch tokenizer.Next() {
+		case nethtml.ErrorToken:
+			// io.EOF or malformed input; either way return what we have
+			return collapseWhitespace(sb.String())
+		case nethtml.TextToken:
+			if skipDepth == 0 {
+				sb.Write(tokenizer.Text())
+			}
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "script" || tag == "style" {
+				skipDepth++
+			}
+			if blockElements[tag] {
+				sb.WriteString("\n")
+			}
+		case nethtml.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if (tag == "script" || tag == "style") && skipDepth > 0 {
+				skipDepth--
+			}
+			if blockElements[tag] {
+				sb.WriteString("\n")
+			}
+		}
+	}
+}
+
+// collapseWhitespace trims each line and drops empty ones
+func collapseWhitespace(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
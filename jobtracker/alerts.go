@@ -1,13 +1,7 @@
 package main
 
 import (
-	"encoding/json"
-	"fmt"
 	"log"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
 )
 
 type TelegramAlertConfig struct {
@@ -18,169 +12,109 @@ type TelegramAlertConfig struct {
 }
 
 type ScheduledScan struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Type        string `json:"type"` // "default" or "custom"
-	Query       string `json:"query,omitempty"`
-	Schedule    string `json:"schedule"` // "daily", "weekdays", "weekly", "custom"
-	Days        []int  `json:"days,omitempty"`
-	Hour        int    `json:"hour"`
-	Minute      int    `json:"minute"`
-	Enabled     bool   `json:"enabled"`
-	NotifyEmpty bool   `json:"notify_empty"`
-	LastRun     string `json:"last_run,omitempty"`
-	LastResult  string `json:"last_result,omitempty"`
-	CreatedAt   string `json:"created_at"`
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Type               string `json:"type"` // "default" or "custom"
+	Query              string `json:"query,omitempty"`
+	Cron               string `json:"cron"`               // 5-field cron expression
+	Timezone           string `json:"timezone,omitempty"` // IANA zone the expression runs in; the server's when empty
+	NextRun            string `json:"next_run,omitempty"`
+	Schedule           string `json:"schedule,omitempty"` // before cron: "daily", "weekdays", "weekly", "custom"
+	Days               []int  `json:"days,omitempty"`
+	Hour               int    `json:"hour,omitempty"`
+	Minute             int    `json:"minute,omitempty"`
+	Enabled            bool   `json:"enabled"`
+	NotifyEmpty        bool   `json:"notify_empty"`
+	NotifySeen         bool   `json:"notify_seen"`          // send every job found, not only unseen ones
+	NotifySalaryChange bool   `json:"notify_salary_change"` // also send seen jobs whose salary changed
+	LastRun            string `json:"last_run,omitempty"`
+	LastResult         string `json:"last_result,omitempty"`
+	CreatedAt          string `json:"created_at"`
 }
 
 type UserAlertConfig struct {
 	Telegram  TelegramAlertConfig `json:"telegram"`
+	Email     EmailAlertConfig    `json:"email"`
+	Webhooks  []WebhookChannel    `json:"webhooks,omitempty"`
 	Schedules []ScheduledScan     `json:"schedules"`
+	Delivery  DeliveryPrefs       `json:"delivery"`
 }
 
-var alertsMu sync.RWMutex
-
-func alertsDir() string {
-	return filepath.Join(config.DataDir, "alerts")
-}
-
-func ensureAlertsDir() {
-	if err := os.MkdirAll(alertsDir(), 0755); err != nil {
-		log.Printf("Failed to create alerts directory: %v", err)
-	}
+// telegramActive reports whether alerts should be sent to Telegram
+func (cfg UserAlertConfig) telegramActive() bool {
+	return cfg.Telegram.Enabled && cfg.Telegram.Verified && cfg.Telegram.BotToken != "" && cfg.Telegram.ChatID != ""
 }
 
-func userAlertFile(username string) string {
-	return filepath.Join(alertsDir(), username+".json")
+// hasAlertChannel reports whether the user has a verified channel for alerts
+func (cfg UserAlertConfig) hasAlertChannel() bool {
+	return len(userNotifiers(cfg)) > 0
 }
 
 func loadUserAlerts(username string) UserAlertConfig {
-	alertsMu.RLock()
-	defer alertsMu.RUnlock()
-
-	data, err := os.ReadFile(userAlertFile(username))
-	if err != nil {
-		return UserAlertConfig{}
-	}
-
 	var cfg UserAlertConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		log.Printf("Failed to parse alerts for %s: %v", username, err)
+	err := dataStore.View(func(tx Tx) error {
+		_, err := tx.Get(collAlerts, username, &cfg)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to load alerts for %s: %v", username, err)
 		return UserAlertConfig{}
 	}
 	return cfg
 }
 
 func saveUserAlerts(username string, cfg UserAlertConfig) error {
-	alertsMu.Lock()
-	defer alertsMu.Unlock()
-
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal alerts: %v", err)
-	}
-	return os.WriteFile(userAlertFile(username), data, 0644)
+	return dataStore.Update(func(tx Tx) error {
+		return tx.Put(collAlerts, username, cfg)
+	})
 }
 
-func loadAllUserAlerts() map[string]UserAlertConfig {
-	alertsMu.RLock()
-	defer alertsMu.RUnlock()
-
-	result := make(map[string]UserAlertConfig)
-	entries, err := os.ReadDir(alertsDir())
-	if err != nil {
-		return result
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		if len(name) < 6 || name[len(name)-5:] != ".json" {
-			continue
-		}
-		username := name[:len(name)-5]
-		data, err := os.ReadFile(filepath.Join(alertsDir(), name))
-		if err != nil {
-			continue
-		}
+// updateUserAlerts applies fn to the user's stored alert settings in a
+// single transaction
+func updateUserAlerts(username string, fn func(cfg *UserAlertConfig) error) error {
+	return dataStore.Update(func(tx Tx) error {
 		var cfg UserAlertConfig
-		if err := json.Unmarshal(data, &cfg); err != nil {
-			continue
+		if _, err := tx.Get(collAlerts, username, &cfg); err != nil {
+			return err
 		}
-		result[username] = cfg
-	}
-	return result
+		if err := fn(&cfg); err != nil {
+			return err
+		}
+		return tx.Put(collAlerts, username, cfg)
+	})
 }
 
-func sendUserTelegramNotification(tg TelegramAlertConfig, text string) error {
-	if !tg.Enabled || tg.BotToken == "" || tg.ChatID == "" {
-		return fmt.Errorf("telegram not configured")
+func loadAllUserAlerts() map[string]UserAlertConfig {
+	var result map[string]UserAlertConfig
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		result, err = loadCollection[UserAlertConfig](tx, collAlerts)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to load alerts: %v", err)
+		return make(map[string]UserAlertConfig)
 	}
-	return sendTelegramMessageWithCreds(tg.BotToken, tg.ChatID, text)
+	return result
 }
 
+// notifySubscribedUsers sends each user with an alert channel the new jobs
+// that pass their filter profile
 func notifySubscribedUsers(scanName string, jobs []Job) {
-	allAlerts := loadAllUserAlerts()
-	for username, cfg := range allAlerts {
-		if cfg.Telegram.Enabled && cfg.Telegram.Verified && cfg.Telegram.BotToken != "" && cfg.Telegram.ChatID != "" {
-			if err := sendUserTelegramJobAlert(cfg.Telegram, scanName, jobs); err != nil {
-				log.Printf("Failed to notify %s via Telegram: %v", username, err)
-			} else {
-				log.Printf("Sent Telegram alert to %s (%d jobs)", username, len(jobs))
-			}
-		}
-	}
-}
-
-func sendUserTelegramJobAlert(tg TelegramAlertConfig, scanName string, jobs []Job) error {
 	if len(jobs) == 0 {
-		return nil
+		return
 	}
-
-	batchSize := 10
-	for i := 0; i < len(jobs); i += batchSize {
-		end := i + batchSize
-		if end > len(jobs) {
-			end = len(jobs)
-		}
-		batch := jobs[i:end]
-
-		var msg string
-		if i == 0 {
-			msg = fmt.Sprintf("🎯 *SalarySleuth Alert*\n\n")
-			msg += fmt.Sprintf("📋 Schedule: *%s*\n", escapeMarkdown(scanName))
-			msg += fmt.Sprintf("Found *%d* new job\\(s\\)\\!\n\n", len(jobs))
-			msg += "━━━━━━━━━━━━━━━━━━━━\n\n"
-		}
-
-		for j, job := range batch {
-			msg += fmt.Sprintf("*%d\\.* %s\n", i+j+1, escapeMarkdown(job.Title))
-			msg += fmt.Sprintf("   🏢 %s\n", escapeMarkdown(job.Company))
-			if job.Location != "" {
-				msg += fmt.Sprintf("   📍 %s\n", escapeMarkdown(job.Location))
-			}
-			if job.LevelSalary != "" {
-				msg += fmt.Sprintf("   💰 %s\n", escapeMarkdown(job.LevelSalary))
-			} else if job.SalaryRange != "" && job.SalaryRange != "Not Available" {
-				msg += fmt.Sprintf("   💰 %s\n", escapeMarkdown(job.SalaryRange))
-			}
-			msg += fmt.Sprintf("   🔗 [Apply](%s)\n\n", job.URL)
-		}
-
-		if end == len(jobs) {
-			msg += "━━━━━━━━━━━━━━━━━━━━\n"
-			msg += fmt.Sprintf("📅 %s", escapeMarkdown(time.Now().Format("Jan 2, 2006 3:04 PM")))
-		}
-
-		if err := sendUserTelegramNotification(tg, msg); err != nil {
-			return err
+	allAlerts := loadAllUserAlerts()
+	for username, cfg := range allAlerts {
+		notifiers := userNotifiers(cfg)
+		if len(notifiers) == 0 {
+			continue
 		}
-
-		if end < len(jobs) {
-			time.Sleep(2 * time.Second)
+		// Each user is alerted according to their own filter profile
+		userJobs := withoutHidden(username, alertableJobs(jobs, configForUser(username)))
+		if len(userJobs) == 0 {
+			continue
 		}
+		deliverDigest(username, cfg, newDigest(scanName, userJobs))
 	}
-	return nil
 }
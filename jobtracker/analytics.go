@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/jobtracker/analytics"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+)
+
+// recordScan stores a summary of a scrape's effect on the job store for the
+// new-vs-closed chart. It runs in the transaction that writes the new
+// version; an update that didn't process a scrape is not a scan.
+func recordScan(tx Tx, before, after JobStore) error {
+	if after.LastUpdated.Equal(before.LastUpdated) {
+		return nil
+	}
+	previous := make(map[string]Job, len(before.Jobs))
+	for _, job := range before.Jobs {
+		previous[job.ID] = job
+	}
+
+	scan := analytics.Scan{At: after.LastUpdated}
+	for _, job := range after.Jobs {
+		prev, existed := previous[job.ID]
+		status := jobStatus(job)
+		if job.LastSeen.Equal(after.LastUpdated) {
+			scan.Scraped++
+		}
+		switch {
+		case !existed:
+			scan.New++
+		case status == JobClosed && jobStatus(prev) != JobClosed:
+			scan.Closed++
+		case status != JobClosed && jobStatus(prev) == JobClosed:
+			scan.Reopened++
+		}
+		if status != JobClosed {
+			scan.Open++
+		}
+	}
+	return tx.Put(collScans, after.LastUpdated.UTC().Format(time.RFC3339Nano), scan)
+}
+
+// analyticsJobs converts the stored jobs for the analytics package, tagging
+// them with cfg. Excluded jobs are left out so they don't skew salaries. A
+// posted range counts as its lower bound, as in salary comparisons.
+func analyticsJobs(jobs []Job, cfg *AppConfig) []analytics.Job {
+	result := make([]analytics.Job, 0, len(jobs))
+	for _, job := range jobs {
+		tagged := TagJob(job, cfg)
+		if tagged.Tags.IsExcluded {
+			continue
+		}
+		aj := analytics.Job{
+			ID:         job.ID,
+			Company:    job.Company,
+			Categories: tagged.Tags.Categories,
+			Level:      tagged.Tags.Level,
+			FirstSeen:  job.FirstSeen,
+			LastSeen:   job.LastSeen,
+			ClosedAt:   job.ClosedAt,
+		}
+		if salary, ok := query.ParseAmount(job.SalaryRange); ok {
+			aj.Salary = salary
+		}
+		if jobStatus(job) == JobClosed && aj.ClosedAt == nil {
+			closedAt := job.LastSeen
+			aj.ClosedAt = &closedAt
+		}
+		result = append(result, aj)
+	}
+	return result
+}
+
+// intParam reads a positive integer query parameter, falling back to def
+// when it is missing and capping it at max
+func intParam(r *http.Request, name string, def, max int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number", name)
+	}
+	if n > max {
+		n = max
+	}
+	return n, nil
+}
+
+// handleAnalyticsSalaries returns the median posted salary per category
+// (?by=category, the default) or level (?by=level) for each month or week
+// (?interval=)
+func handleAnalyticsSalaries(w http.ResponseWriter, r *http.Request) {
+	interval, err := analytics.ParseInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cfg := configForUser(r.Header.Get("X-Auth-User"))
+
+	by := r.URL.Query().Get("by")
+	var groups func(analytics.Job) []string
+	var rules map[string]CategoryConfig
+	switch by {
+	case "", "category":
+		by = "category"
+		groups = func(j analytics.Job) []string { return j.Categories }
+		rules = cfg.Filters.Categories
+	case "level":
+		groups = func(j analytics.Job) []string {
+			if j.Level == "" {
+				return nil
+			}
+			return []string{j.Level}
+		}
+		rules = cfg.Filters.Levels
+	default:
+		jsonError(w, "by must be category or level", http.StatusBadRequest)
+		return
+	}
+
+	series := analytics.MedianSalary(analyticsJobs(loadJobStore().Jobs, cfg), interval, groups)
+	for i := range series {
+		if series[i].Key == "all" {
+			series[i].Label = "All jobs"
+		} else {
+			series[i].Label = displayName(rules[series[i].Key], series[i].Key)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"by":       by,
+		"interval": interval,
+		"series":   series,
+	})
+}
+
+// handleAnalyticsOpenRoles returns the number of open roles over the last
+// ?days= (default 180) at the companies with the most open roles now
+// (?limit=, default 5)
+func handleAnalyticsOpenRoles(w http.ResponseWriter, r *http.Request) {
+	interval, err := analytics.ParseInterval(r.URL.Query().Get("interval"))
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	days, err := intParam(r, "days", 180, 3650)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := intParam(r, "limit", 5, 50)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, -days)
+	jobs := analyticsJobs(loadJobStore().Jobs, configForUser(r.Header.Get("X-Auth-User")))
+	var companies []string
+	for _, c := range analytics.TopCompanies(jobs, from, now, limit) {
+		companies = append(companies, c.Company)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"interval": interval,
+		"from":     from,
+		"series":   analytics.OpenRoles(jobs, interval, from, now, companies),
+	})
+}
+
+// handleAnalyticsScans returns the new, closed and reopened counts of the
+// last ?limit= scans (default 30)
+func handleAnalyticsScans(w http.ResponseWriter, r *http.Request) {
+	limit, err := intParam(r, "limit", 30, 1000)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var scans map[string]analytics.Scan
+	err = dataStore.View(func(tx Tx) error {
+		var err error
+		scans, err = loadCollection[analytics.Scan](tx, collScans)
+		return err
+	})
+	if err != nil {
+		jsonError(w, "Failed to load scans", http.StatusInternalServerError)
+		return
+	}
+	list := make([]analytics.Scan, 0, len(scans))
+	for _, scan := range scans {
+		list = append(list, scan)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scans": analytics.RecentScans(list, limit),
+	})
+}
+
+// handleAnalyticsTopCompanies ranks companies by open roles, with the roles
+// they opened and closed over the last ?days= (default 90)
+func handleAnalyticsTopCompanies(w http.ResponseWriter, r *http.Request) {
+	days, err := intParam(r, "days", 90, 3650)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit, err := intParam(r, "limit", 10, 100)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+	jobs := analyticsJobs(loadJobStore().Jobs, configForUser(r.Header.Get("X-Auth-User")))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"since":     since,
+		"companies": analytics.TopCompanies(jobs, since, now, limit),
+	})
+}
@@ -0,0 +1,313 @@
+// Package analytics computes salary and hiring trends from the jobs the
+// tracker has seen: median posted salaries per group and period, open roles
+// per company over time, new and closed counts per scan, and the companies
+// hiring most. It works on plain values so it doesn't depend on how jobs are
+// stored or tagged.
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Job is a tracked job as seen by the analytics
+type Job struct {
+	ID         string
+	Company    string
+	Categories []string
+	Level      string
+	// Salary is the posted salary, or 0 when the posting has none
+	Salary    float64
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// ClosedAt is nil while the job is open
+	ClosedAt *time.Time
+}
+
+// openAt reports whether the job was listed at t
+func (j Job) openAt(t time.Time) bool {
+	return !j.FirstSeen.After(t) && (j.ClosedAt == nil || j.ClosedAt.After(t))
+}
+
+// Scan summarises one scrape's effect on the job store
+type Scan struct {
+	At       time.Time `json:"at"`
+	Scraped  int       `json:"scraped"`  // jobs in the scrape
+	New      int       `json:"new"`      // jobs seen for the first time
+	Closed   int       `json:"closed"`   // jobs marked closed
+	Reopened int       `json:"reopened"` // closed jobs seen again
+	Open     int       `json:"open"`     // jobs open after the scan
+}
+
+// Point is one period of a time series. Count is the number of jobs behind
+// the value.
+type Point struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	Value  float64   `json:"value"`
+	Count  int       `json:"count"`
+}
+
+// Series is a named time series
+type Series struct {
+	Key    string  `json:"key"`
+	Label  string  `json:"label,omitempty"`
+	Points []Point `json:"points"`
+}
+
+// Interval is the length of a period in a time series
+type Interval string
+
+const (
+	Week  Interval = "week"
+	Month Interval = "month"
+)
+
+// ParseInterval accepts "week" or "month"; empty means month
+func ParseInterval(s string) (Interval, error) {
+	switch Interval(s) {
+	case "", Month:
+		return Month, nil
+	case Week:
+		return Week, nil
+	}
+	return "", fmt.Errorf("unknown interval %q (expected week or month)", s)
+}
+
+// start returns the beginning of the period containing t, in UTC. Weeks
+// start on Monday.
+func (i Interval) start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if i == Week {
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (i Interval) next(start time.Time) time.Time {
+	if i == Week {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+func (i Interval) label(start time.Time) string {
+	if i == Week {
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	}
+	return start.Format("2006-01")
+}
+
+// MedianSalary returns the median posted salary of the jobs first seen in
+// each period, with one series per group key and an "all" series first.
+// groups returns the keys a job belongs to, such as its categories or its
+// level. Jobs without a posted salary are left out.
+func MedianSalary(jobs []Job, interval Interval, groups func(Job) []string) []Series {
+	salaries := make(map[string]map[time.Time][]float64)
+	add := func(key string, start time.Time, salary float64) {
+		if salaries[key] == nil {
+			salaries[key] = make(map[time.Time][]float64)
+		}
+		salaries[key][start] = append(salaries[key][start], salary)
+	}
+	for _, job := range jobs {
+		if job.Salary <= 0 || job.FirstSeen.IsZero() {
+			continue
+		}
+		start := interval.start(job.FirstSeen)
+		add("all", start, job.Salary)
+		for _, key := range groups(job) {
+			add(key, start, job.Salary)
+		}
+	}
+
+	keys := make([]string, 0, len(salaries))
+	for key := range salaries {
+		if key != "all" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := salaries["all"]; ok {
+		keys = append([]string{"all"}, keys...)
+	}
+
+	series := make([]Series, 0, len(keys))
+	for _, key := range keys {
+		starts := make([]time.Time, 0, len(salaries[key]))
+		for start := range salaries[key] {
+			starts = append(starts, start)
+		}
+		sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+		s := Series{Key: key, Points: make([]Point, 0, len(starts))}
+		for _, start := range starts {
+			values := salaries[key][start]
+			s.Points = append(s.Points, Point{
+				Period: interval.label(start),
+				Start:  start,
+				Value:  median(values),
+				Count:  len(values),
+			})
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// OpenRoles returns, for each of the given companies, how many of its jobs
+// were open at the end of each period from `from` to `to`. The last period
+// is counted at `to`.
+func OpenRoles(jobs []Job, interval Interval, from, to time.Time, companies []string) []Series {
+	byCompany := make(map[string][]Job)
+	for _, job := range jobs {
+		id := CompanyID(job.Company)
+		byCompany[id] = append(byCompany[id], job)
+	}
+
+	var starts []time.Time
+	for start := interval.start(from); !start.After(to); start = interval.next(start) {
+		starts = append(starts, start)
+	}
+
+	series := make([]Series, 0, len(companies))
+	for _, company := range companies {
+		id := CompanyID(company)
+		s := Series{Key: id, Label: company, Points: make([]Point, 0, len(starts))}
+		for _, start := range starts {
+			end := interval.next(start).Add(-time.Nanosecond)
+			if end.After(to) {
+				end = to
+			}
+			n := 0
+			for _, job := range byCompany[id] {
+				if job.openAt(end) {
+					n++
+				}
+			}
+			s.Points = append(s.Points, Point{
+				Period: interval.label(start),
+				Start:  start,
+				Value:  float64(n),
+				Count:  n,
+			})
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// CompanyStats summarises a company's hiring
+type CompanyStats struct {
+	ID      string `json:"id"`
+	Company string `json:"company"`
+	Open    int    `json:"open"`
+	// Opened and Closed count the roles first seen and closed since the
+	// start of the window
+	Opened       int     `json:"opened"`
+	Closed       int     `json:"closed"`
+	MedianSalary float64 `json:"median_salary,omitempty"`
+}
+
+// TopCompanies ranks companies by open roles, then by roles opened since
+// `since`, and returns at most limit of them (all when limit <= 0)
+func TopCompanies(jobs []Job, since, now time.Time, limit int) []CompanyStats {
+	stats := make(map[string]*CompanyStats)
+	salaries := make(map[string][]float64)
+	for _, job := range jobs {
+		id := CompanyID(job.Company)
+		if id == "" {
+			continue
+		}
+		s := stats[id]
+		if s == nil {
+			s = &CompanyStats{ID: id, Company: job.Company}
+			stats[id] = s
+		}
+		if job.openAt(now) {
+			s.Open++
+			if job.Salary > 0 {
+				salaries[id] = append(salaries[id], job.Salary)
+			}
+		}
+		if !job.FirstSeen.Before(since) {
+			s.Opened++
+		}
+		if job.ClosedAt != nil && !job.ClosedAt.Before(since) {
+			s.Closed++
+		}
+	}
+
+	result := make([]CompanyStats, 0, len(stats))
+	for id, s := range stats {
+		if s.Open == 0 && s.Opened == 0 && s.Closed == 0 {
+			continue
+		}
+		if values := salaries[id]; len(values) > 0 {
+			s.MedianSalary = median(values)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Open != b.Open {
+			return a.Open > b.Open
+		}
+		if a.Opened != b.Opened {
+			return a.Opened > b.Opened
+		}
+		return a.ID < b.ID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// RecentScans returns the last limit scans in time order (all when
+// limit <= 0)
+func RecentScans(scans []Scan, limit int) []Scan {
+	sorted := append([]Scan(nil), scans...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].At.Before(sorted[j].At) })
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[len(sorted)-limit:]
+	}
+	return sorted
+}
+
+// CompanyID turns a company name into a stable, URL-safe identifier, so the
+// same company scraped with different capitalisation or punctuation is
+// counted once
+func CompanyID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// APIToken is a personal access token for scripting against the API,
+// stored in the api_tokens collection under the SHA-256 of the token. The
+// token itself is only shown when it is created.
+type APIToken struct {
+	ID        string     `json:"id"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil for never
+	LastUsed  *time.Time `json:"last_used,omitempty"`
+}
+
+// Token scopes
+const (
+	ScopeReadJobs = "jobs:read"   // jobs, analytics and company pages
+	ScopeSearch   = "search"      // run custom searches and read search history
+	ScopeManage   = "saved:write" // saved and hidden jobs, applications and alerts
+	ScopeAdmin    = "admin"       // everything, including admin endpoints, for admin users
+)
+
+var apiTokenScopes = []string{ScopeReadJobs, ScopeSearch, ScopeManage, ScopeAdmin}
+
+// apiTokenPrefix starts every token, so leaked ones are easy to search for
+const apiTokenPrefix = "sst_"
+
+// maxAPITokens is how many tokens a user can have
+const maxAPITokens = 20
+
+// apiTokenTouchInterval is how stale a token's LastUsed gets before a
+// request updates it, so every request doesn't write to the store
+const apiTokenTouchInterval = time.Minute
+
+// apiTokenRoutes are the paths a token can use and the scope each needs. A
+// path ending in / covers everything under it; one without covers itself
+// and its subpaths, but not paths it is merely the start of, such as
+// /api/saved-searches for /api/saved. Anything else, such as managing
+// tokens, needs a logged-in session.
+var apiTokenRoutes = []struct{ prefix, scope string }{
+	{"/api/jobs", ScopeReadJobs},
+	{"/api/analytics/", ScopeReadJobs},
+	{"/api/companies/", ScopeReadJobs},
+	{"/api/search", ScopeSearch},
+	{"/api/history", ScopeSearch},
+	{"/api/saved", ScopeManage},
+	{"/api/hidden", ScopeManage},
+	{"/api/applications", ScopeManage},
+	{"/api/offers/", ScopeManage},
+	{"/api/alerts/", ScopeManage},
+	{"/api/refresh", ScopeAdmin},
+	{"/api/admin/", ScopeAdmin},
+}
+
+var errTokenScope = errors.New("token lacks the scope for this endpoint")
+
+// hashToken is the key an API or session token is stored under. Tokens
+// are random, so a plain hash is enough to keep them unusable if the store
+// leaks.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenScope is the scope a token needs for path, if tokens can use it
+func tokenScope(path string) (string, bool) {
+	for _, route := range apiTokenRoutes {
+		rest, ok := strings.CutPrefix(path, route.prefix)
+		if ok && (rest == "" || strings.HasSuffix(route.prefix, "/") || strings.HasPrefix(rest, "/")) {
+			return route.scope, true
+		}
+	}
+	return "", false
+}
+
+// hasScope reports whether the token grants scope. The admin scope grants
+// every other.
+func (t APIToken) hasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken returns the request's Authorization: Bearer token
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// authenticateToken returns the user whose token the request carries, or
+// errTokenScope if the token is valid but can't be used for the request
+func authenticateToken(r *http.Request) (*User, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	key := hashToken(token)
+	var info APIToken
+	var user User
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		if found, err = tx.Get(collAPITokens, key, &info); err != nil || !found {
+			return err
+		}
+		found, err = tx.Get(collUsers, info.Username, &user)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to look up API token: %v", err)
+		return nil, nil
+	}
+	now := time.Now()
+	if !found || info.ExpiresAt != nil && now.After(*info.ExpiresAt) {
+		return nil, nil
+	}
+	if scope, ok := tokenScope(r.URL.Path); !ok || !info.hasScope(scope) {
+		return nil, errTokenScope
+	}
+
+	if info.LastUsed == nil || now.Sub(*info.LastUsed) > apiTokenTouchInterval {
+		err := dataStore.Update(func(tx Tx) error {
+			var current APIToken
+			if found, err := tx.Get(collAPITokens, key, &current); err != nil || !found {
+				return err
+			}
+			current.LastUsed = &now
+			return tx.Put(collAPITokens, key, current)
+		})
+		if err != nil {
+			log.Printf("Failed to record API token use: %v", err)
+		}
+	}
+	return &user, nil
+}
+
+// userAPITokens returns the user's tokens by key, newest first
+func userAPITokens(tx Tx, username string) ([]string, []APIToken, error) {
+	tokens, err := loadCollection[APIToken](tx, collAPITokens)
+	if err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	for key, t := range tokens {
+		if t.Username == username {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return tokens[keys[i]].CreatedAt.After(tokens[keys[j]].CreatedAt) })
+	list := make([]APIToken, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, tokens[key])
+	}
+	return keys, list, nil
+}
+
+// revokeAPITokens deletes the user's tokens
+func revokeAPITokens(tx Tx, username string) error {
+	keys, _, err := userAPITokens(tx, username)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := tx.Delete(collAPITokens, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleAPITokens manages the user's personal access tokens: GET lists
+// them, POST creates one for {"name", "scopes", "expires_in_days"} and
+// returns the token once, and DELETE revokes {"id"}
+func handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+
+	switch r.Method {
+	case http.MethodGet:
+		var list []APIToken
+		err := dataStore.View(func(tx Tx) error {
+			var err error
+			_, list, err = userAPITokens(tx, username)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to load API tokens for %s: %v", username, err)
+			jsonError(w, "Failed to load tokens", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"tokens": list, "scopes": apiTokenScopes})
+
+	case http.MethodPost:
+		var req struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" || len(req.Name) > 60 {
+			jsonError(w, "Token name must be 1-60 characters", http.StatusBadRequest)
+			return
+		}
+		if len(req.Scopes) == 0 {
+			jsonError(w, "Choose at least one scope", http.StatusBadRequest)
+			return
+		}
+		for _, scope := range req.Scopes {
+			valid := false
+			for _, s := range apiTokenScopes {
+				valid = valid || s == scope
+			}
+			if !valid {
+				jsonError(w, "Unknown scope: "+scope, http.StatusBadRequest)
+				return
+			}
+			if scope == ScopeAdmin && r.Header.Get("X-Auth-Role") != "admin" {
+				jsonError(w, "Only admins can create admin tokens", http.StatusForbidden)
+				return
+			}
+		}
+		if req.ExpiresInDays < 0 {
+			jsonError(w, "Expiry must be a number of days, or 0 for never", http.StatusBadRequest)
+			return
+		}
+
+		b := make([]byte, 32)
+		rand.Read(b)
+		token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+		info := APIToken{
+			ID:        generateEntryID(),
+			Username:  username,
+			Name:      req.Name,
+			Scopes:    req.Scopes,
+			CreatedAt: time.Now(),
+		}
+		if req.ExpiresInDays > 0 {
+			expires := info.CreatedAt.AddDate(0, 0, req.ExpiresInDays)
+			info.ExpiresAt = &expires
+		}
+		tooMany := false
+		err := dataStore.Update(func(tx Tx) error {
+			keys, _, err := userAPITokens(tx, username)
+			if err != nil {
+				return err
+			}
+			if tooMany = len(keys) >= maxAPITokens; tooMany {
+				return nil
+			}
+			return tx.Put(collAPITokens, hashToken(token), info)
+		})
+		if err != nil {
+			log.Printf("Failed to save API token for %s: %v", username, err)
+			jsonError(w, "Failed to create token", http.StatusInternalServerError)
+			return
+		}
+		if tooMany {
+			jsonError(w, fmt.Sprintf("You can have up to %d tokens. Revoke one first.", maxAPITokens), http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("API token %q created by %s (scopes: %s)", info.Name, username, strings.Join(info.Scopes, ", "))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"token":   token,
+			"info":    info,
+		})
+
+	case http.MethodDelete:
+		var req struct {
+			ID string `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		found := false
+		err := dataStore.Update(func(tx Tx) error {
+			keys, list, err := userAPITokens(tx, username)
+			if err != nil {
+				return err
+			}
+			for i, t := range list {
+				if t.ID == req.ID {
+					found = true
+					return tx.Delete(collAPITokens, keys[i])
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to revoke API token for %s: %v", username, err)
+			jsonError(w, "Failed to revoke token", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			jsonError(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("API token %s revoked by %s", req.ID, username)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// putAPIToken stores a token for a user with the role
+func putAPIToken(t *testing.T, token, username, role string, expires *time.Time, scopes ...string) {
+	t.Helper()
+	err := dataStore.Update(func(tx Tx) error {
+		if err := tx.Put(collUsers, username, User{Username: username, Role: role, CreatedAt: time.Now()}); err != nil {
+			return err
+		}
+		return tx.Put(collAPITokens, hashToken(token), APIToken{
+			ID: generateEntryID(), Username: username, Name: token, Scopes: scopes,
+			CreatedAt: time.Now(), ExpiresAt: expires,
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tokenRequest is the status a route guarded by guard answers a request
+// for path with the bearer token
+func tokenRequest(guard func(http.HandlerFunc) http.HandlerFunc, path, token string) int {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	guard(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+	return w.Code
+}
+
+func TestTokenScope(t *testing.T) {
+	tests := []struct {
+		path  string
+		scope string // empty when tokens can't use the path
+	}{
+		{"/api/jobs", ScopeReadJobs},
+		{"/api/jobs/match", ScopeReadJobs},
+		{"/api/jobs/history/abc", ScopeReadJobs},
+		{"/api/companies/acme", ScopeReadJobs},
+		{"/api/search", ScopeSearch},
+		{"/api/search/abc/events", ScopeSearch},
+		{"/api/searches", ""},
+		{"/api/saved", ScopeManage},
+		{"/api/saved/ids", ScopeManage},
+		{"/api/saved-searches", ""},
+		{"/api/history/results/abc", ScopeSearch},
+		{"/api/historyx", ""},
+		{"/api/refresh/status", ScopeAdmin},
+		{"/api/admin/users", ScopeAdmin},
+		{"/api/tokens", ""},
+		{"/api/sessions", ""},
+		{"/api/auth/password", ""},
+	}
+	for _, tt := range tests {
+		scope, ok := tokenScope(tt.path)
+		if ok != (tt.scope != "") || scope != tt.scope {
+			t.Errorf("tokenScope(%q) = %q, %v, want %q", tt.path, scope, ok, tt.scope)
+		}
+	}
+}
+
+func TestAPITokenAuth(t *testing.T) {
+	newTestStore(t)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	putAPIToken(t, "sst_reader", "alice", "user", &future, ScopeReadJobs)
+	putAPIToken(t, "sst_manager", "alice", "user", nil, ScopeManage)
+	putAPIToken(t, "sst_admin", "root", "admin", nil, ScopeAdmin)
+	putAPIToken(t, "sst_user_admin", "alice", "user", nil, ScopeAdmin)
+	putAPIToken(t, "sst_expired", "alice", "user", &past, ScopeReadJobs)
+
+	tests := []struct {
+		name  string
+		guard func(http.HandlerFunc) http.HandlerFunc
+		path  string
+		token string
+		want  int
+	}{
+		{"read token on jobs", requireAuth, "/api/jobs", "sst_reader", http.StatusOK},
+		{"read token on saved jobs", requireAuth, "/api/saved", "sst_reader", http.StatusForbidden},
+		{"read token on applications", requireAuth, "/api/applications/abc", "sst_reader", http.StatusForbidden},
+		{"read token on refresh", requireAuth, "/api/refresh", "sst_reader", http.StatusForbidden},
+		{"read token on admin", requireAdmin, "/api/admin/users", "sst_reader", http.StatusForbidden},
+		{"read token on token management", requireAuth, "/api/tokens", "sst_reader", http.StatusForbidden},
+		{"manage token on saved jobs", requireAuth, "/api/saved/ids", "sst_manager", http.StatusOK},
+		{"manage token on a path sharing a prefix", requireAuth, "/api/saved-searches", "sst_manager", http.StatusForbidden},
+		{"manage token on jobs", requireAuth, "/api/jobs", "sst_manager", http.StatusForbidden},
+		{"admin token on admin", requireAdmin, "/api/admin/users", "sst_admin", http.StatusOK},
+		{"admin token on saved jobs", requireAuth, "/api/saved", "sst_admin", http.StatusOK},
+		{"admin token of a non-admin", requireAdmin, "/api/admin/users", "sst_user_admin", http.StatusForbidden},
+		{"expired token", requireAuth, "/api/jobs", "sst_expired", http.StatusUnauthorized},
+		{"unknown token", requireAuth, "/api/jobs", "sst_unknown", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := tokenRequest(tt.guard, tt.path, tt.token); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRevokedAPIToken(t *testing.T) {
+	newTestStore(t)
+	putAPIToken(t, "sst_reader", "alice", "user", nil, ScopeReadJobs)
+	if got := tokenRequest(requireAuth, "/api/jobs", "sst_reader"); got != http.StatusOK {
+		t.Fatalf("token before revoking: status %d", got)
+	}
+
+	var list []APIToken
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		_, list, err = userAPITokens(tx, "alice")
+		return err
+	})
+	if err != nil || len(list) != 1 {
+		t.Fatalf("alice's tokens = %+v (err %v)", list, err)
+	}
+	id := list[0].ID
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/tokens", strings.NewReader(`{"id":"`+id+`"}`))
+	req.Header.Set("X-Auth-User", "alice")
+	handleAPITokens(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoking: status %d: %s", rec.Code, rec.Body)
+	}
+
+	if got := tokenRequest(requireAuth, "/api/jobs", "sst_reader"); got != http.StatusUnauthorized {
+		t.Errorf("revoked token: status %d, want %d", got, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/offers"
+)
+
+// Application stages, in pipeline order. Rejected and withdrawn end an
+// application.
+const (
+	StageInterested = "interested"
+	StageApplied    = "applied"
+	StageScreen     = "recruiter_screen"
+	StageOnsite     = "onsite"
+	StageOffer      = "offer"
+	StageRejected   = "rejected"
+	StageWithdrawn  = "withdrawn"
+)
+
+var applicationStages = []string{
+	StageInterested, StageApplied, StageScreen, StageOnsite, StageOffer, StageRejected, StageWithdrawn,
+}
+
+// followUpHour is the local hour on its date a follow-up becomes due
+const followUpHour = 9
+
+var errApplicationNotFound = errors.New("application not found")
+
+type StageChange struct {
+	At   time.Time `json:"at"`
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+	Note string    `json:"note,omitempty"`
+}
+
+type Contact struct {
+	Name  string `json:"name"`
+	Role  string `json:"role,omitempty"` // recruiter, hiring manager, referral...
+	Email string `json:"email,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+// Interview is a scheduled interview. At is a local date and time, as a
+// datetime-local input sends it.
+type Interview struct {
+	At       string `json:"at"`                 // YYYY-MM-DDTHH:MM
+	Minutes  int    `json:"minutes,omitempty"`  // defaults to an hour
+	Kind     string `json:"kind,omitempty"`     // phone screen, technical, onsite...
+	Location string `json:"location,omitempty"` // address or video link
+}
+
+// Offer holds the terms of an offer; offers.Terms describes how equity
+// vesting and sign-on bonuses are annualised
+type Offer struct {
+	offers.Terms
+	Deadline string `json:"deadline,omitempty"` // YYYY-MM-DD
+	Notes    string `json:"notes,omitempty"`
+}
+
+// ApplicationUpdate is a change to a saved job's application. Fields left
+// out of the request are not changed.
+type ApplicationUpdate struct {
+	Stage *string `json:"stage,omitempty"`
+	// StageNote is recorded with the stage change
+	StageNote    string       `json:"stage_note,omitempty"`
+	Note         *string      `json:"note,omitempty"`
+	Contacts     *[]Contact   `json:"contacts,omitempty"`
+	Interviews   *[]Interview `json:"interviews,omitempty"`
+	Offer        *Offer       `json:"offer,omitempty"`
+	ClearOffer   bool         `json:"clear_offer,omitempty"`
+	FollowUp     *string      `json:"follow_up,omitempty"` // "" clears it
+	FollowUpNote *string      `json:"follow_up_note,omitempty"`
+}
+
+func applicationStage(job SavedJob) string {
+	if job.Stage == "" {
+		return StageInterested
+	}
+	return job.Stage
+}
+
+func isApplicationStage(stage string) bool {
+	for _, s := range applicationStages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// isApplicationClosed reports whether the application has ended, so no
+// follow-ups are sent for it
+func isApplicationClosed(job SavedJob) bool {
+	stage := applicationStage(job)
+	return stage == StageRejected || stage == StageWithdrawn
+}
+
+func parseInterviewTime(at string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02T15:04", at, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid interview time %q (expected YYYY-MM-DDTHH:MM)", at)
+	}
+	return t, nil
+}
+
+func parseFollowUpDate(date string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q (expected YYYY-MM-DD)", date)
+	}
+	return t, nil
+}
+
+// apply validates the update and applies it to job
+func (u ApplicationUpdate) apply(job *SavedJob, now time.Time) error {
+	if u.Stage != nil && *u.Stage != applicationStage(*job) {
+		if !isApplicationStage(*u.Stage) {
+			return fmt.Errorf("unknown stage %q (expected one of %s)", *u.Stage, strings.Join(applicationStages, ", "))
+		}
+		job.StageHistory = append(job.StageHistory, StageChange{
+			At: now, From: applicationStage(*job), To: *u.Stage, Note: strings.TrimSpace(u.StageNote),
+		})
+		job.Stage = *u.Stage
+	}
+	if u.Note != nil {
+		job.Note = strings.TrimSpace(*u.Note)
+	}
+	if u.Contacts != nil {
+		contacts := make([]Contact, 0, len(*u.Contacts))
+		for _, c := range *u.Contacts {
+			c.Name = strings.TrimSpace(c.Name)
+			c.Email = strings.TrimSpace(c.Email)
+			if c.Name == "" && c.Email == "" {
+				return fmt.Errorf("contacts need a name or an email")
+			}
+			if c.Email != "" && !strings.Contains(c.Email, "@") {
+				return fmt.Errorf("invalid email %q", c.Email)
+			}
+			contacts = append(contacts, c)
+		}
+		job.Contacts = contacts
+	}
+	if u.Interviews != nil {
+		interviews := make([]Interview, 0, len(*u.Interviews))
+		for _, iv := range *u.Interviews {
+			if _, err := parseInterviewTime(iv.At); err != nil {
+				return err
+			}
+			if iv.Minutes < 0 {
+				return fmt.Errorf("interview length can't be negative")
+			}
+			iv.Kind = strings.TrimSpace(iv.Kind)
+			iv.Location = strings.TrimSpace(iv.Location)
+			interviews = append(interviews, iv)
+		}
+		sort.Slice(interviews, func(i, j int) bool { return interviews[i].At < interviews[j].At })
+		job.Interviews = interviews
+	}
+	if u.ClearOffer {
+		job.Offer = nil
+	} else if u.Offer != nil {
+		if err := u.Offer.Validate(); err != nil {
+			return fmt.Errorf("offer: %v", err)
+		}
+		if u.Offer.Deadline != "" {
+			if _, err := parseFollowUpDate(u.Offer.Deadline); err != nil {
+				return fmt.Errorf("offer deadline: %v", err)
+			}
+		}
+		offer := *u.Offer
+		job.Offer = &offer
+	}
+	if u.FollowUp != nil {
+		if *u.FollowUp != "" {
+			if _, err := parseFollowUpDate(*u.FollowUp); err != nil {
+				return fmt.Errorf("follow_up: %v", err)
+			}
+		}
+		job.FollowUp = *u.FollowUp
+	}
+	if u.FollowUpNote != nil {
+		job.FollowUpNote = strings.TrimSpace(*u.FollowUpNote)
+	}
+	return nil
+}
+
+// updateApplication applies fn to one of the user's saved jobs in a single
+// transaction and returns the result
+func updateApplication(username, jobID string, fn func(job *SavedJob) error) (SavedJob, error) {
+	var updated SavedJob
+	err := dataStore.Update(func(tx Tx) error {
+		store, err := readUserSavedJobs(tx, username)
+		if err != nil {
+			return err
+		}
+		for i := range store.Jobs {
+			if store.Jobs[i].JobID != jobID {
+				continue
+			}
+			if err := fn(&store.Jobs[i]); err != nil {
+				return err
+			}
+			updated = store.Jobs[i]
+			return tx.Put(collSavedJobs, username, store)
+		}
+		return errApplicationNotFound
+	})
+	return updated, err
+}
+
+func findApplication(username, jobID string) (SavedJob, error) {
+	for _, job := range loadUserSavedJobs(username).Jobs {
+		if job.JobID == jobID {
+			return job, nil
+		}
+	}
+	return SavedJob{}, errApplicationNotFound
+}
+
+// followUpDue reports whether a reminder should be sent for the job's
+// follow-up date
+func followUpDue(job SavedJob, now time.Time) bool {
+	if job.FollowUp == "" || job.FollowUpSent == job.FollowUp || isApplicationClosed(job) {
+		return false
+	}
+	date, err := parseFollowUpDate(job.FollowUp)
+	if err != nil {
+		return false
+	}
+	return !now.Before(date.Add(followUpHour * time.Hour))
+}
+
+// checkFollowUps sends each user with verified Telegram alerts a reminder
+// listing their applications with a follow-up due, once per follow-up date
+func checkFollowUps(now time.Time) {
+	for username, cfg := range loadAllUserAlerts() {
+		if !cfg.telegramActive() {
+			continue
+		}
+		var due []SavedJob
+		for _, job := range loadUserSavedJobs(username).Jobs {
+			if followUpDue(job, now) {
+				due = append(due, job)
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		msg := telegramOutgoing{Text: formatFollowUpMessage(due)}
+		if err := (telegramNotifier{cfg.Telegram}).send(msg); err != nil {
+			log.Printf("Failed to send follow-up reminder to %s: %v", username, err)
+			continue
+		}
+		log.Printf("Sent follow-up reminder to %s (%d applications)", username, len(due))
+
+		for _, job := range due {
+			sent := job.FollowUp
+			_, err := updateApplication(username, job.JobID, func(stored *SavedJob) error {
+				// Leave it due if the date was changed while sending
+				if stored.FollowUp == sent {
+					stored.FollowUpSent = sent
+				}
+				return nil
+			})
+			if err != nil {
+				log.Printf("Failed to record follow-up reminder for %s: %v", username, err)
+			}
+		}
+	}
+}
+
+func formatFollowUpMessage(jobs []SavedJob) string {
+	msg := "⏰ *SalarySleuth Follow\\-ups*\n\n"
+	msg += fmt.Sprintf("*%d* application\\(s\\) to follow up on:\n\n", len(jobs))
+	for i, job := range jobs {
+		msg += fmt.Sprintf("*%d\\.* %s\n", i+1, escapeMarkdown(job.Title))
+		msg += fmt.Sprintf("   🏢 %s\n", escapeMarkdown(job.Company))
+		msg += fmt.Sprintf("   📌 %s, follow up %s\n", escapeMarkdown(stageLabel(applicationStage(job))), escapeMarkdown(job.FollowUp))
+		if job.FollowUpNote != "" {
+			msg += fmt.Sprintf("   📝 %s\n", escapeMarkdown(job.FollowUpNote))
+		}
+		for _, c := range job.Contacts {
+			contact := c.Name
+			if c.Email != "" {
+				contact = strings.TrimSpace(contact + " " + c.Email)
+			}
+			msg += fmt.Sprintf("   👤 %s\n", escapeMarkdown(contact))
+		}
+		msg += fmt.Sprintf("   🔗 [Listing](%s)\n\n", escapeMarkdownURL(job.URL))
+	}
+	return msg
+}
+
+func stageLabel(stage string) string {
+	switch stage {
+	case StageScreen:
+		return "Recruiter screen"
+	case StageOnsite:
+		return "Onsite"
+	}
+	return strings.ToUpper(stage[:1]) + stage[1:]
+}
+
+// handleApplications lists the user's applications, newest saved first
+func handleApplications(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobs := getUserSavedJobsList(r.Header.Get("X-Auth-User"))
+	for i := range jobs {
+		jobs[i].Stage = applicationStage(jobs[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"stages":       applicationStages,
+		"applications": jobs,
+		"count":        len(jobs),
+	})
+}
+
+// handleApplication serves /api/applications/{job_id}: GET returns the
+// application, PATCH applies an ApplicationUpdate and DELETE removes the
+// saved job
+func handleApplication(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	jobID := strings.TrimPrefix(r.URL.Path, "/api/applications/")
+	if jobID == "" {
+		jsonError(w, "Missing job ID", http.StatusBadRequest)
+		return
+	}
+
+	var job SavedJob
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		job, err = findApplication(username, jobID)
+
+	case http.MethodPatch:
+		var req ApplicationUpdate
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		var invalid error
+		job, err = updateApplication(username, jobID, func(job *SavedJob) error {
+			invalid = req.apply(job, time.Now())
+			return invalid
+		})
+		if invalid != nil {
+			jsonError(w, invalid.Error(), http.StatusBadRequest)
+			return
+		}
+
+	case http.MethodDelete:
+		if !removeSavedJob(username, jobID) {
+			jsonError(w, "Application not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		return
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, errApplicationNotFound) {
+		jsonError(w, "Application not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		jsonError(w, "Failed to update application", http.StatusInternalServerError)
+		return
+	}
+	job.Stage = applicationStage(job)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
-const passwordHashIterations = 100000
+// Password hashes are stored as $pbkdf2-sha256$i=<iterations>$<salt>$<key>,
+// so the iterations can be raised and older hashes upgraded when their user
+// next logs in. Hashes from before the format are hex(salt)$hex(key), from
+// legacyHashIterations rounds of plain SHA-256.
+const (
+	passwordHashScheme     = "pbkdf2-sha256"
+	passwordHashIterations = 600000
+	legacyHashIterations   = 100000
+)
 
 type User struct {
 	Username     string    `json:"username"`
@@ -45,35 +54,60 @@ type TokenStoreData struct {
 	Tokens map[string]RegistrationToken `json:"tokens"`
 }
 
-type Session struct {
-	Username  string
-	Role      string
-	CreatedAt time.Time
-	ExpiresAt time.Time
-}
-
+// Errors that abort a user or token update and map to a response
 var (
-	userStoreMu  sync.RWMutex
-	tokenStoreMu sync.RWMutex
-	sessionMu    sync.RWMutex
-	sessions     = make(map[string]*Session)
+	errInvalidRegistrationToken = errors.New("invalid or expired registration token")
+	errUsernameTaken            = errors.New("username already taken")
+	errUserNotFound             = errors.New("user not found")
+	errLastAdmin                = errors.New("cannot delete the last admin user")
 )
 
-const sessionMaxAge = 24 * time.Hour
 const sessionCookieName = "session"
 
-// --- Password hashing (iterated SHA-256 with salt) ---
+// --- Password hashing (PBKDF2-HMAC-SHA256) ---
 
 func hashPassword(password string) (string, error) {
-	salt := make([]byte, 32)
+	salt := make([]byte, 16)
 	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
-	dk := deriveKey(password, salt)
-	return hex.EncodeToString(salt) + "$" + hex.EncodeToString(dk), nil
+	dk := pbkdf2SHA256([]byte(password), salt, passwordHashIterations, sha256.Size)
+	return fmt.Sprintf("$%s$i=%d$%s$%s", passwordHashScheme, passwordHashIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(dk)), nil
+}
+
+// verifyPassword checks password against a stored hash in either format.
+// rehash reports that the hash is a legacy one or uses fewer iterations than
+// passwordHashIterations, so it should be replaced now the password is known.
+func verifyPassword(password, stored string) (ok, rehash bool) {
+	if !strings.HasPrefix(stored, "$") {
+		ok := verifyLegacyPassword(password, stored)
+		return ok, ok
+	}
+	parts := strings.Split(stored, "$")
+	if len(parts) != 5 || parts[1] != passwordHashScheme || !strings.HasPrefix(parts[2], "i=") {
+		return false, false
+	}
+	iterations, err := strconv.Atoi(strings.TrimPrefix(parts[2], "i="))
+	if err != nil || iterations < 1 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(expected) == 0 {
+		return false, false
+	}
+	actual := pbkdf2SHA256([]byte(password), salt, iterations, len(expected))
+	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+		return false, false
+	}
+	return true, iterations < passwordHashIterations
 }
 
-func verifyPassword(password, stored string) bool {
+func verifyLegacyPassword(password, stored string) bool {
 	parts := strings.SplitN(stored, "$", 2)
 	if len(parts) != 2 {
 		return false
@@ -86,130 +120,84 @@ func verifyPassword(password, stored string) bool {
 	if err != nil {
 		return false
 	}
-	actual := deriveKey(password, salt)
+	actual := legacyDeriveKey(password, salt)
 	return subtle.ConstantTimeCompare(expected, actual) == 1
 }
 
-func deriveKey(password string, salt []byte) []byte {
+func legacyDeriveKey(password string, salt []byte) []byte {
 	input := make([]byte, len(salt)+len(password))
 	copy(input, salt)
 	copy(input[len(salt):], password)
 	h := sha256.Sum256(input)
-	for i := 1; i < passwordHashIterations; i++ {
+	for i := 1; i < legacyHashIterations; i++ {
 		h = sha256.Sum256(h[:])
 	}
 	return h[:]
 }
 
-// --- User store ---
+// pbkdf2SHA256 is PBKDF2 (RFC 8018) with HMAC-SHA256. crypto/pbkdf2 only
+// arrived in Go 1.24, which this module doesn't require yet.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	u := make([]byte, 0, sha256.Size)
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
 
-func userStoreFile() string { return filepath.Join(config.DataDir, "users.json") }
+// --- User store ---
 
 func loadUsers() *UserStoreData {
-	userStoreMu.RLock()
-	defer userStoreMu.RUnlock()
-	return loadUsersUnsafe()
-}
-
-func loadUsersUnsafe() *UserStoreData {
-	data, err := os.ReadFile(userStoreFile())
+	var store *UserStoreData
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		store, err = readUsers(tx)
+		return err
+	})
 	if err != nil {
+		log.Printf("Failed to load users: %v", err)
 		return &UserStoreData{Users: make(map[string]User)}
 	}
-	var store UserStoreData
-	if err := json.Unmarshal(data, &store); err != nil {
-		return &UserStoreData{Users: make(map[string]User)}
-	}
-	if store.Users == nil {
-		store.Users = make(map[string]User)
-	}
-	return &store
+	return store
 }
 
-func saveUsersUnsafe(store *UserStoreData) error {
-	data, err := json.MarshalIndent(store, "", "  ")
+func readUsers(tx Tx) (*UserStoreData, error) {
+	users, err := loadCollection[User](tx, collUsers)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(userStoreFile(), data, 0600)
+	return &UserStoreData{Users: users}, nil
 }
 
 // --- Token store ---
 
-func tokenStoreFile() string { return filepath.Join(config.DataDir, "tokens.json") }
-
 func loadTokens() *TokenStoreData {
-	tokenStoreMu.RLock()
-	defer tokenStoreMu.RUnlock()
-	return loadTokensUnsafe()
-}
-
-func loadTokensUnsafe() *TokenStoreData {
-	data, err := os.ReadFile(tokenStoreFile())
+	var store *TokenStoreData
+	err := dataStore.View(func(tx Tx) error {
+		tokens, err := loadCollection[RegistrationToken](tx, collTokens)
+		store = &TokenStoreData{Tokens: tokens}
+		return err
+	})
 	if err != nil {
+		log.Printf("Failed to load registration tokens: %v", err)
 		return &TokenStoreData{Tokens: make(map[string]RegistrationToken)}
 	}
-	var store TokenStoreData
-	if err := json.Unmarshal(data, &store); err != nil {
-		return &TokenStoreData{Tokens: make(map[string]RegistrationToken)}
-	}
-	if store.Tokens == nil {
-		store.Tokens = make(map[string]RegistrationToken)
-	}
-	return &store
-}
-
-func saveTokensUnsafe(store *TokenStoreData) error {
-	data, err := json.MarshalIndent(store, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(tokenStoreFile(), data, 0600)
-}
-
-// --- Session management ---
-
-func createSession(username, role string) string {
-	b := make([]byte, 32)
-	rand.Read(b)
-	token := base64.RawURLEncoding.EncodeToString(b)
-
-	sessionMu.Lock()
-	defer sessionMu.Unlock()
-	sessions[token] = &Session{
-		Username:  username,
-		Role:      role,
-		CreatedAt: time.Now(),
-		ExpiresAt: time.Now().Add(sessionMaxAge),
-	}
-	return token
-}
-
-func getSession(token string) *Session {
-	sessionMu.RLock()
-	defer sessionMu.RUnlock()
-	s, ok := sessions[token]
-	if !ok || time.Now().After(s.ExpiresAt) {
-		return nil
-	}
-	return s
-}
-
-func deleteSession(token string) {
-	sessionMu.Lock()
-	defer sessionMu.Unlock()
-	delete(sessions, token)
-}
-
-func cleanExpiredSessions() {
-	sessionMu.Lock()
-	defer sessionMu.Unlock()
-	now := time.Now()
-	for k, s := range sessions {
-		if now.After(s.ExpiresAt) {
-			delete(sessions, k)
-		}
-	}
+	return store
 }
 
 // --- Auth helpers ---
@@ -220,17 +208,28 @@ func isAuthEnabled() bool {
 }
 
 func authenticateRequest(r *http.Request) *User {
+	user, _ := authenticate(r)
+	return user
+}
+
+// authenticate returns the user logged in with the request's session
+// cookie or Authorization: Bearer API token. err is errTokenScope when the
+// token can't be used for the request.
+func authenticate(r *http.Request) (*User, error) {
+	if bearerToken(r) != "" {
+		return authenticateToken(r)
+	}
 	cookie, err := r.Cookie(sessionCookieName)
 	if err == nil && cookie.Value != "" {
 		sess := getSession(cookie.Value)
 		if sess != nil {
 			store := loadUsers()
 			if user, exists := store.Users[sess.Username]; exists {
-				return &user
+				return &user, nil
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
 func isValidUsername(s string) bool {
@@ -252,7 +251,11 @@ func requireAuth(next http.HandlerFunc) http.HandlerFunc {
 			next(w, r)
 			return
 		}
-		user := authenticateRequest(r)
+		user, err := authenticate(r)
+		if err == errTokenScope {
+			jsonError(w, "API token lacks the scope for this endpoint", http.StatusForbidden)
+			return
+		}
 		if user == nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -273,7 +276,11 @@ func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 			next(w, r)
 			return
 		}
-		user := authenticateRequest(r)
+		user, err := authenticate(r)
+		if err == errTokenScope {
+			jsonError(w, "API token lacks the scope for this endpoint", http.StatusForbidden)
+			return
+		}
 		if user == nil {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusUnauthorized)
@@ -295,34 +302,41 @@ func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
 // --- Seed initial admin from env vars ---
 
 func seedAdminUser() {
-	userStoreMu.Lock()
-	defer userStoreMu.Unlock()
-	store := loadUsersUnsafe()
-	if len(store.Users) > 0 {
-		return
-	}
 	username := os.Getenv("WEB_USERNAME")
 	password := os.Getenv("WEB_PASSWORD")
 	if username == "" || password == "" {
 		return
 	}
+	if isAuthEnabled() {
+		return
+	}
 	hash, err := hashPassword(password)
 	if err != nil {
 		log.Printf("Warning: failed to hash initial admin password: %v", err)
 		return
 	}
-	store.Users[username] = User{
-		Username:     username,
-		PasswordHash: hash,
-		Role:         "admin",
-		CreatedAt:    time.Now(),
-		CreatedBy:    "system",
-	}
-	if err := saveUsersUnsafe(store); err != nil {
+	created := false
+	err = dataStore.Update(func(tx Tx) error {
+		store, err := readUsers(tx)
+		if err != nil || len(store.Users) > 0 {
+			return err
+		}
+		created = true
+		return tx.Put(collUsers, username, User{
+			Username:     username,
+			PasswordHash: hash,
+			Role:         "admin",
+			CreatedAt:    time.Now(),
+			CreatedBy:    "system",
+		})
+	})
+	if err != nil {
 		log.Printf("Warning: failed to save initial admin user: %v", err)
 		return
 	}
-	log.Printf("Created initial admin user: %s", username)
+	if created {
+		log.Printf("Created initial admin user: %s", username)
+	}
 }
 
 func startSessionCleanup() {
@@ -392,12 +406,24 @@ func handleLoginAPI(w http.ResponseWriter, r *http.Request) {
 
 	store := loadUsers()
 	user, exists := store.Users[req.Username]
-	if !exists || !verifyPassword(req.Password, user.PasswordHash) {
+	ok, rehash := false, false
+	if exists {
+		ok, rehash = verifyPassword(req.Password, user.PasswordHash)
+	}
+	if !ok {
 		jsonError(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
+	if rehash {
+		upgradePasswordHash(user.Username, req.Password, user.PasswordHash)
+	}
 
-	token := createSession(user.Username, user.Role)
+	token, err := createSession(user.Username, r)
+	if err != nil {
+		log.Printf("Failed to create session for %s: %v", user.Username, err)
+		jsonError(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    token,
@@ -593,6 +619,28 @@ var loginPageHTML = `<!DOCTYPE html>
 			<div class="login-footer register-link">
 				Have a registration token? <a onclick="showRegSection()">Create an account</a>
 			</div>
+			<div class="login-footer">
+				Forgot your password? Ask an admin for a reset token, then <a onclick="showResetSection()">reset it</a>
+			</div>
+		</div>
+		<div id="reset-section" class="login-card" style="display:none;margin-top:1rem">
+			<h2>Reset Password</h2>
+			<div id="reset-error" class="form-error"></div>
+			<div id="reset-success" class="form-error" style="color:var(--accent-primary);background:rgba(0,255,136,0.1)"></div>
+			<form onsubmit="return doReset(event)">
+				<div class="form-group">
+					<label for="reset-token">Reset Token</label>
+					<input type="text" id="reset-token" placeholder="Paste token from admin">
+				</div>
+				<div class="form-group">
+					<label for="reset-password">New Password</label>
+					<input type="password" id="reset-password" placeholder="Choose a password (8+ chars)" autocomplete="new-password">
+				</div>
+				<button type="submit" class="btn-login">Reset Password</button>
+			</form>
+			<div class="login-footer register-link">
+				<a onclick="hideResetSection()">Back to sign in</a>
+			</div>
 		</div>
 		<div id="register-section" class="login-card" style="display:none;margin-top:1rem">
 			<h2>Register</h2>
@@ -659,6 +707,43 @@ var loginPageHTML = `<!DOCTYPE html>
 		function hideRegSection() {
 			document.getElementById('register-section').style.display = 'none';
 		}
+		function showResetSection() {
+			document.getElementById('reset-section').style.display = 'block';
+		}
+		function hideResetSection() {
+			document.getElementById('reset-section').style.display = 'none';
+		}
+		function doReset(e) {
+			e.preventDefault();
+			var errEl = document.getElementById('reset-error');
+			var succEl = document.getElementById('reset-success');
+			errEl.style.display = 'none';
+			succEl.style.display = 'none';
+			fetch('/api/auth/reset', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({
+					token: document.getElementById('reset-token').value.trim(),
+					new_password: document.getElementById('reset-password').value
+				})
+			})
+			.then(function(res) { return res.json().then(function(d) { return { ok: res.ok, data: d }; }); })
+			.then(function(r) {
+				if (r.ok) {
+					succEl.textContent = r.data.message + '. You can now sign in above.';
+					succEl.style.display = 'block';
+					document.getElementById('username').value = r.data.username;
+				} else {
+					errEl.textContent = r.data.error || 'Reset failed';
+					errEl.style.display = 'block';
+				}
+			})
+			.catch(function() {
+				errEl.textContent = 'Connection error.';
+				errEl.style.display = 'block';
+			});
+			return false;
+		}
 		function doRegister(e) {
 			e.preventDefault();
 			var errEl = document.getElementById('reg-error');
@@ -727,41 +812,52 @@ func handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokenStoreMu.Lock()
-	ts := loadTokensUnsafe()
-	regToken, exists := ts.Tokens[req.Token]
-	if !exists || regToken.Used || time.Now().After(regToken.ExpiresAt) {
-		tokenStoreMu.Unlock()
-		jsonError(w, "Invalid or expired registration token", http.StatusBadRequest)
-		return
-	}
-	regToken.Used = true
-	regToken.UsedBy = req.Username
-	ts.Tokens[req.Token] = regToken
-	saveTokensUnsafe(ts)
-	tokenStoreMu.Unlock()
-
-	userStoreMu.Lock()
-	defer userStoreMu.Unlock()
-	us := loadUsersUnsafe()
-	if _, exists := us.Users[req.Username]; exists {
-		jsonError(w, "Username already taken", http.StatusConflict)
-		return
-	}
-
 	hash, err := hashPassword(req.Password)
 	if err != nil {
 		jsonError(w, "Internal error", http.StatusInternalServerError)
 		return
 	}
-	us.Users[req.Username] = User{
-		Username:     req.Username,
-		PasswordHash: hash,
-		Role:         regToken.Role,
-		CreatedAt:    time.Now(),
-		CreatedBy:    "token:" + regToken.CreatedBy,
-	}
-	if err := saveUsersUnsafe(us); err != nil {
+
+	// Using the token and creating the account happen together, so a taken
+	// username leaves the token usable
+	var regToken RegistrationToken
+	err = dataStore.Update(func(tx Tx) error {
+		exists, err := tx.Get(collTokens, req.Token, &regToken)
+		if err != nil {
+			return err
+		}
+		if !exists || regToken.Used || time.Now().After(regToken.ExpiresAt) {
+			return errInvalidRegistrationToken
+		}
+		var existing User
+		if taken, err := tx.Get(collUsers, req.Username, &existing); err != nil {
+			return err
+		} else if taken {
+			return errUsernameTaken
+		}
+
+		regToken.Used = true
+		regToken.UsedBy = req.Username
+		if err := tx.Put(collTokens, req.Token, regToken); err != nil {
+			return err
+		}
+		return tx.Put(collUsers, req.Username, User{
+			Username:     req.Username,
+			PasswordHash: hash,
+			Role:         regToken.Role,
+			CreatedAt:    time.Now(),
+			CreatedBy:    "token:" + regToken.CreatedBy,
+		})
+	})
+	switch {
+	case err == errInvalidRegistrationToken:
+		jsonError(w, "Invalid or expired registration token", http.StatusBadRequest)
+		return
+	case err == errUsernameTaken:
+		jsonError(w, "Username already taken", http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Failed to register %s: %v", req.Username, err)
 		jsonError(w, "Failed to create user", http.StatusInternalServerError)
 		return
 	}
@@ -818,22 +914,26 @@ func handleAdminTokens(w http.ResponseWriter, r *http.Request) {
 		rand.Read(b)
 		tokenStr := base64.RawURLEncoding.EncodeToString(b)
 
-		tokenStoreMu.Lock()
-		defer tokenStoreMu.Unlock()
-		store := loadTokensUnsafe()
-		store.Tokens[tokenStr] = RegistrationToken{
+		token := RegistrationToken{
 			Token: tokenStr, Role: req.Role,
 			CreatedBy: r.Header.Get("X-Auth-User"),
 			CreatedAt: time.Now(),
 			ExpiresAt: time.Now().Add(time.Duration(req.ExpiresIn) * time.Hour),
 		}
-		saveTokensUnsafe(store)
+		err := dataStore.Update(func(tx Tx) error {
+			return tx.Put(collTokens, tokenStr, token)
+		})
+		if err != nil {
+			log.Printf("Failed to save registration token: %v", err)
+			jsonError(w, "Failed to create token", http.StatusInternalServerError)
+			return
+		}
 
 		log.Printf("Registration token created by %s (role: %s)", r.Header.Get("X-Auth-User"), req.Role)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"token": tokenStr, "role": req.Role,
-			"expires_at": store.Tokens[tokenStr].ExpiresAt,
+			"expires_at": token.ExpiresAt,
 		})
 
 	case http.MethodDelete:
@@ -841,15 +941,24 @@ func handleAdminTokens(w http.ResponseWriter, r *http.Request) {
 			Token string `json:"token"`
 		}
 		json.NewDecoder(r.Body).Decode(&req)
-		tokenStoreMu.Lock()
-		defer tokenStoreMu.Unlock()
-		store := loadTokensUnsafe()
-		if _, exists := store.Tokens[req.Token]; !exists {
+		found := false
+		err := dataStore.Update(func(tx Tx) error {
+			var token RegistrationToken
+			var err error
+			if found, err = tx.Get(collTokens, req.Token, &token); err != nil || !found {
+				return err
+			}
+			return tx.Delete(collTokens, req.Token)
+		})
+		if err != nil {
+			log.Printf("Failed to delete registration token: %v", err)
+			jsonError(w, "Failed to delete token", http.StatusInternalServerError)
+			return
+		}
+		if !found {
 			jsonError(w, "Token not found", http.StatusNotFound)
 			return
 		}
-		delete(store.Tokens, req.Token)
-		saveTokensUnsafe(store)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"success": "Token deleted"})
 
@@ -867,12 +976,27 @@ func handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 			Role      string    `json:"role"`
 			CreatedAt time.Time `json:"created_at"`
 			CreatedBy string    `json:"created_by"`
+			Sessions  int       `json:"sessions"`
+		}
+		active := make(map[string]int)
+		err := dataStore.View(func(tx Tx) error {
+			sessions, err := loadCollection[Session](tx, collSessions)
+			for _, sess := range sessions {
+				if time.Now().Before(sess.ExpiresAt) {
+					active[sess.Username]++
+				}
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to count sessions: %v", err)
 		}
 		users := make([]info, 0)
 		for _, u := range store.Users {
 			users = append(users, info{
 				Username: u.Username, Role: u.Role,
 				CreatedAt: u.CreatedAt, CreatedBy: u.CreatedBy,
+				Sessions: active[u.Username],
 			})
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -890,28 +1014,52 @@ func handleAdminUsers(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		userStoreMu.Lock()
-		defer userStoreMu.Unlock()
-		store := loadUsersUnsafe()
-		target, exists := store.Users[req.Username]
-		if !exists {
-			jsonError(w, "User not found", http.StatusNotFound)
-			return
-		}
-		if target.Role == "admin" {
-			adminCount := 0
-			for _, u := range store.Users {
-				if u.Role == "admin" {
-					adminCount++
+		err := dataStore.Update(func(tx Tx) error {
+			store, err := readUsers(tx)
+			if err != nil {
+				return err
+			}
+			target, exists := store.Users[req.Username]
+			if !exists {
+				return errUserNotFound
+			}
+			if target.Role == "admin" {
+				adminCount := 0
+				for _, u := range store.Users {
+					if u.Role == "admin" {
+						adminCount++
+					}
 				}
+				if adminCount <= 1 {
+					return errLastAdmin
+				}
+			}
+			if err := revokeCalendarTokens(tx, req.Username); err != nil {
+				return err
+			}
+			if err := revokeAPITokens(tx, req.Username); err != nil {
+				return err
 			}
-			if adminCount <= 1 {
-				jsonError(w, "Cannot delete the last admin user", http.StatusBadRequest)
-				return
+			if err := revokeUserSessions(tx, req.Username, ""); err != nil {
+				return err
 			}
+			if err := revokePasswordResets(tx, req.Username); err != nil {
+				return err
+			}
+			return tx.Delete(collUsers, req.Username)
+		})
+		switch {
+		case err == errUserNotFound:
+			jsonError(w, "User not found", http.StatusNotFound)
+			return
+		case err == errLastAdmin:
+			jsonError(w, "Cannot delete the last admin user", http.StatusBadRequest)
+			return
+		case err != nil:
+			log.Printf("Failed to delete user %s: %v", req.Username, err)
+			jsonError(w, "Failed to delete user", http.StatusInternalServerError)
+			return
 		}
-		delete(store.Users, req.Username)
-		saveUsersUnsafe(store)
 
 		log.Printf("User %s deleted by %s", req.Username, currentUser)
 		w.Header().Set("Content-Type", "application/json")
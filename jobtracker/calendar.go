@@ -0,0 +1,480 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CalendarToken is the secret in a user's calendar feed URL, stored in the
+// calendar_tokens collection under the SHA-256 of the token, so the feed URL
+// is only shown when it is created. Calendar apps can't log in, so the token
+// alone grants read access to the feed; a user has at most one and can reset
+// or revoke it.
+type CalendarToken struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// interviewMinutes is the length of an interview with no duration set
+const interviewMinutes = 60
+
+// scanEventMinutes is the length of the events marking scheduled scans
+const scanEventMinutes = 15
+
+// rruleDays are the iCalendar weekday codes, indexed by time.Weekday
+var rruleDays = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func calendarFeedPath(token string) string {
+	return "/api/calendar/" + token + ".ics"
+}
+
+// userCalendarToken returns the user's calendar token, if they have one
+func userCalendarToken(tx Tx, username string) (CalendarToken, bool, error) {
+	tokens, err := loadCollection[CalendarToken](tx, collCalendarTokens)
+	if err != nil {
+		return CalendarToken{}, false, err
+	}
+	for _, info := range tokens {
+		if info.Username == username {
+			return info, true, nil
+		}
+	}
+	return CalendarToken{}, false, nil
+}
+
+// revokeCalendarTokens deletes the user's calendar tokens
+func revokeCalendarTokens(tx Tx, username string) error {
+	tokens, err := loadCollection[CalendarToken](tx, collCalendarTokens)
+	if err != nil {
+		return err
+	}
+	for key, info := range tokens {
+		if info.Username == username {
+			if err := tx.Delete(collCalendarTokens, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleCalendarToken manages the user's calendar feed: GET reports whether
+// it is on, POST creates the feed or replaces its token (so the old URL
+// stops working) and returns the new URL, and DELETE turns it off
+func handleCalendarToken(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+
+	var token string
+	var info CalendarToken
+	var enabled bool
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		err = dataStore.View(func(tx Tx) error {
+			info, enabled, err = userCalendarToken(tx, username)
+			return err
+		})
+
+	case http.MethodPost:
+		b := make([]byte, 24)
+		rand.Read(b)
+		token = base64.RawURLEncoding.EncodeToString(b)
+		info = CalendarToken{Username: username, CreatedAt: time.Now()}
+		enabled = true
+		err = dataStore.Update(func(tx Tx) error {
+			if err := revokeCalendarTokens(tx, username); err != nil {
+				return err
+			}
+			return tx.Put(collCalendarTokens, hashToken(token), info)
+		})
+		if err == nil {
+			log.Printf("Calendar feed token created for %s", username)
+		}
+
+	case http.MethodDelete:
+		err = dataStore.Update(func(tx Tx) error {
+			return revokeCalendarTokens(tx, username)
+		})
+		if err == nil {
+			log.Printf("Calendar feed revoked for %s", username)
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update calendar feed for %s: %v", username, err)
+		jsonError(w, "Failed to update calendar feed", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{"enabled": enabled}
+	if enabled {
+		resp["created_at"] = info.CreatedAt
+	}
+	if token != "" {
+		resp["url"] = calendarFeedPath(token)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleCalendarFeed serves /api/calendar/{token}.ics. It is authenticated
+// by the token rather than the session, so calendar apps can poll it.
+func handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/calendar/")
+	token := strings.TrimSuffix(name, ".ics")
+	if token == "" || token == name || r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.NotFound(w, r)
+		return
+	}
+
+	var info CalendarToken
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		found, err = tx.Get(collCalendarTokens, hashToken(token), &info)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to look up calendar token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed := buildCalendar(loadUserSavedJobs(info.Username).Jobs, loadUserAlerts(info.Username).Schedules, time.Now())
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write([]byte(feed))
+}
+
+// buildCalendar renders the user's interviews, follow-ups, offer deadlines
+// and scheduled scans as an iCalendar feed
+func buildCalendar(jobs []SavedJob, schedules []ScheduledScan, now time.Time) string {
+	c := &icalWriter{}
+	stamp := now.UTC().Format("20060102T150405Z")
+	zone := calendarZone()
+
+	c.line("BEGIN", "VCALENDAR")
+	c.line("VERSION", "2.0")
+	c.line("PRODID", "-//SalarySleuth//Jobtracker//EN")
+	c.line("CALSCALE", "GREGORIAN")
+	c.line("METHOD", "PUBLISH")
+	c.text("X-WR-CALNAME", "SalarySleuth")
+	if zone != "" {
+		c.line("X-WR-TIMEZONE", zone)
+	}
+	// Describe the server's zone and every zone a schedule runs in
+	zones := map[string]bool{}
+	for _, name := range append([]string{zone}, scheduleZones(schedules)...) {
+		if name != "" && !zones[name] {
+			zones[name] = true
+			c.vtimezone(name, now)
+		}
+	}
+
+	for _, job := range jobs {
+		listing := job.Title + " at " + job.Company
+		details := []string{"Stage: " + stageLabel(applicationStage(job))}
+		for _, contact := range job.Contacts {
+			details = append(details, "Contact: "+strings.TrimSpace(contact.Name+" "+contact.Email))
+		}
+		link := icalURL(job.URL)
+		if link != "" {
+			details = append(details, link)
+		}
+
+		for _, iv := range job.Interviews {
+			start, err := parseInterviewTime(iv.At)
+			if err != nil {
+				continue
+			}
+			minutes := iv.Minutes
+			if minutes <= 0 {
+				minutes = interviewMinutes
+			}
+			summary := "Interview: " + listing
+			if iv.Kind != "" {
+				summary = iv.Kind + ": " + listing
+			}
+			c.line("BEGIN", "VEVENT")
+			c.line("UID", fmt.Sprintf("interview-%s-%s@salarysleuth", job.JobID, start.Format("200601021504")))
+			c.line("DTSTAMP", stamp)
+			c.line("DTSTART", start.UTC().Format("20060102T150405Z"))
+			c.line("DTEND", start.Add(time.Duration(minutes)*time.Minute).UTC().Format("20060102T150405Z"))
+			c.text("SUMMARY", summary)
+			if iv.Location != "" {
+				c.text("LOCATION", iv.Location)
+			}
+			c.text("DESCRIPTION", strings.Join(details, "\n"))
+			if link != "" {
+				c.line("URL", link)
+			}
+			c.line("END", "VEVENT")
+		}
+
+		if isApplicationClosed(job) {
+			continue
+		}
+		if date, err := parseFollowUpDate(job.FollowUp); job.FollowUp != "" && err == nil {
+			description := details
+			if job.FollowUpNote != "" {
+				description = append([]string{job.FollowUpNote}, details...)
+			}
+			c.allDayEvent("followup-"+job.JobID, stamp, date, "Follow up: "+listing, strings.Join(description, "\n"), link)
+		}
+		if job.Offer != nil && job.Offer.Deadline != "" {
+			if date, err := parseFollowUpDate(job.Offer.Deadline); err == nil {
+				c.allDayEvent("offer-"+job.JobID, stamp, date, "Offer deadline: "+listing, strings.Join(details, "\n"), link)
+			}
+		}
+	}
+
+	for _, sched := range schedules {
+		rule, ok := scheduleRRule(sched)
+		if !sched.Enabled || !ok {
+			continue
+		}
+		start := scheduleFirstRun(sched, now)
+		if start.IsZero() {
+			continue
+		}
+		summary := "SalarySleuth scan: " + sched.Name
+		description := "Default OffSec roles"
+		if sched.Type == "custom" {
+			description = "Search: " + sched.Query
+		}
+		c.line("BEGIN", "VEVENT")
+		c.line("UID", "scan-"+sched.ID+"@salarysleuth")
+		c.line("DTSTAMP", stamp)
+		if tz := scheduleZone(sched); tz != "" {
+			c.line("DTSTART;TZID="+tz, start.Format("20060102T150405"))
+		} else {
+			c.line("DTSTART", start.UTC().Format("20060102T150405Z"))
+		}
+		c.line("DURATION", fmt.Sprintf("PT%dM", scanEventMinutes))
+		c.line("RRULE", rule)
+		c.text("SUMMARY", summary)
+		c.text("DESCRIPTION", description)
+		c.line("TRANSP", "TRANSPARENT")
+		c.line("END", "VEVENT")
+	}
+
+	c.line("END", "VCALENDAR")
+	return c.String()
+}
+
+// scheduleRRule returns the recurrence rule matching the schedule's cron
+// expression. Expressions restricting both the day of the month and the
+// weekday have none and are left out of the feed.
+func scheduleRRule(sched ScheduledScan) (string, bool) {
+	spec, err := parseCron(sched.cronExpr())
+	if err != nil {
+		return "", false
+	}
+	return spec.rrule()
+}
+
+// scheduleFirstRun returns the first time the schedule ran, or would have,
+// since it was created: the start of its recurrence, in its zone
+func scheduleFirstRun(sched ScheduledScan, now time.Time) time.Time {
+	from := now
+	if created, err := time.Parse(time.RFC3339, sched.CreatedAt); err == nil {
+		from = created
+	}
+	return sched.nextRun(from.Add(-time.Minute))
+}
+
+// scheduleZone is the IANA name of the zone the schedule runs in, or ""
+// when that is the server's zone and it isn't known
+func scheduleZone(sched ScheduledScan) string {
+	if sched.Timezone != "" {
+		if _, err := time.LoadLocation(sched.Timezone); err == nil {
+			return sched.Timezone
+		}
+	}
+	return calendarZone()
+}
+
+// scheduleZones lists the zones enabled schedules run in
+func scheduleZones(schedules []ScheduledScan) []string {
+	var zones []string
+	for _, sched := range schedules {
+		if sched.Enabled {
+			zones = append(zones, scheduleZone(sched))
+		}
+	}
+	return zones
+}
+
+// calendarZone returns the IANA name of the server's time zone, which
+// schedules without a zone of their own run in, or "" when it isn't known
+// (scans are then placed in UTC)
+func calendarZone() string {
+	name := strings.TrimPrefix(os.Getenv("TZ"), ":")
+	if name == "" || name == "UTC" {
+		return ""
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return ""
+	}
+	return name
+}
+
+// icalWriter builds an iCalendar document, folding long lines as RFC 5545
+// requires
+type icalWriter struct {
+	strings.Builder
+}
+
+func (c *icalWriter) line(name, value string) {
+	line := name + ":" + value
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		c.WriteString(line[:cut] + "\r\n")
+		line = " " + line[cut:]
+	}
+	c.WriteString(line + "\r\n")
+}
+
+// text writes a TEXT property, escaping its value
+func (c *icalWriter) text(name, value string) {
+	value = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(value)
+	c.line(name, value)
+}
+
+// icalURL returns a saved job's URL if it is safe to write as a URI
+// property: an http or https URL with no control characters, which could
+// otherwise end the line and inject properties. It returns "" otherwise.
+func icalURL(raw string) string {
+	for _, r := range raw {
+		if unicode.IsControl(r) {
+			return ""
+		}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return ""
+	}
+	return raw
+}
+
+// allDayEvent writes an event on date with a reminder at followUpHour
+func (c *icalWriter) allDayEvent(uid, stamp string, date time.Time, summary, description, link string) {
+	c.line("BEGIN", "VEVENT")
+	c.line("UID", uid+"@salarysleuth")
+	c.line("DTSTAMP", stamp)
+	c.line("DTSTART;VALUE=DATE", date.Format("20060102"))
+	c.line("DTEND;VALUE=DATE", date.AddDate(0, 0, 1).Format("20060102"))
+	c.text("SUMMARY", summary)
+	c.text("DESCRIPTION", description)
+	if link != "" {
+		c.line("URL", link)
+	}
+	c.line("TRANSP", "TRANSPARENT")
+	c.line("BEGIN", "VALARM")
+	c.line("ACTION", "DISPLAY")
+	c.text("DESCRIPTION", summary)
+	c.line("TRIGGER;RELATED=START", fmt.Sprintf("PT%dH", followUpHour))
+	c.line("END", "VALARM")
+	c.line("END", "VEVENT")
+}
+
+// vtimezone describes the zone's current rules, found from its offset
+// changes this year, so clients place recurring scans the same way the
+// scheduler does across daylight saving changes
+func (c *icalWriter) vtimezone(name string, now time.Time) {
+	loc, _ := time.LoadLocation(name)
+	year := now.In(loc).Year()
+
+	type transition struct {
+		at       time.Time
+		from, to int
+		abbr     string
+	}
+	var transitions []transition
+	t := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, loc)
+	_, offset := t.Zone()
+	for t.Before(end) {
+		next := t.Add(24 * time.Hour)
+		if _, o := next.Zone(); o != offset {
+			// Narrow the change down to the minute
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Minute {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			// Zones change on the minute
+			hi = hi.Truncate(time.Minute)
+			abbr, to := hi.Zone()
+			transitions = append(transitions, transition{at: hi, from: offset, to: to, abbr: abbr})
+			offset = to
+		}
+		t = next
+	}
+
+	c.line("BEGIN", "VTIMEZONE")
+	c.line("TZID", name)
+	if len(transitions) == 0 {
+		abbr, offset := t.Zone()
+		c.line("BEGIN", "STANDARD")
+		c.line("DTSTART", "19700101T000000")
+		c.line("TZOFFSETFROM", icalOffset(offset))
+		c.line("TZOFFSETTO", icalOffset(offset))
+		c.line("TZNAME", abbr)
+		c.line("END", "STANDARD")
+	}
+	for _, tr := range transitions {
+		kind := "STANDARD"
+		if tr.to > tr.from {
+			kind = "DAYLIGHT"
+		}
+		// Transitions are given in the local time before them
+		local := tr.at.UTC().Add(time.Duration(tr.from) * time.Second)
+		week := (local.Day()-1)/7 + 1
+		if local.Day()+7 > time.Date(local.Year(), local.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			week = -1
+		}
+		c.line("BEGIN", kind)
+		c.line("DTSTART", local.Format("20060102T150405"))
+		c.line("RRULE", fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", int(local.Month()), week, rruleDays[local.Weekday()]))
+		c.line("TZOFFSETFROM", icalOffset(tr.from))
+		c.line("TZOFFSETTO", icalOffset(tr.to))
+		c.line("TZNAME", tr.abbr)
+		c.line("END", kind)
+	}
+	c.line("END", "VTIMEZONE")
+}
+
+// icalOffset formats a UTC offset in seconds as +HHMM
+func icalOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
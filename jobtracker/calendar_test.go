@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMigrateCalendarTokens(t *testing.T) {
+	newTestStore(t)
+	info := CalendarToken{Username: "alice", CreatedAt: time.Now().UTC()}
+	err := dataStore.Update(func(tx Tx) error {
+		if err := tx.Put(collCalendarTokens, "plain-token", info); err != nil {
+			return err
+		}
+		return migrateCalendarTokens(tx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dataStore.View(func(tx Tx) error {
+		var got CalendarToken
+		if found, err := tx.Get(collCalendarTokens, "plain-token", &got); err != nil || found {
+			t.Errorf("plain token still stored (err %v)", err)
+		}
+		if found, err := tx.Get(collCalendarTokens, hashToken("plain-token"), &got); err != nil || !found {
+			t.Errorf("hashed token not stored (err %v)", err)
+		} else if got.Username != "alice" {
+			t.Errorf("hashed token belongs to %q, want alice", got.Username)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
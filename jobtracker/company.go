@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/internal/utils"
+	"github.com/fr4nk3nst1ner/salarysleuth/jobtracker/analytics"
+)
+
+// companyWindowDays is the window for the opened and closed counts on
+// company pages
+const companyWindowDays = 90
+
+// CompanyInfo is what the scraper looked up about a company, kept in the
+// companies collection under its ID so pages don't wait on Levels.fyi
+type CompanyInfo struct {
+	Name      string    `json:"name"`
+	TopPaying bool      `json:"top_paying"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CompanyProfile is everything the company page shows
+type CompanyProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// TopPaying is whether Levels.fyi lists the company as top-paying, as of
+	// the last scrape
+	TopPaying    bool    `json:"top_paying"`
+	LevelsMedian string  `json:"levels_median,omitempty"`
+	MedianPosted float64 `json:"median_posted_salary,omitempty"`
+	// ATS names the applicant tracking systems the company's postings link
+	// to; Sources are the job boards they were scraped from
+	ATS     []string `json:"ats"`
+	Sources []string `json:"sources"`
+	Open    int      `json:"open"`
+	Opened  int      `json:"opened"`
+	Closed  int      `json:"closed"`
+	// WindowDays is the window Opened, Closed and OpenTrend cover
+	WindowDays int               `json:"window_days"`
+	OpenTrend  []analytics.Point `json:"open_trend"`
+	Roles      []TaggedJob       `json:"roles"`
+}
+
+// atsHosts maps the hosts of applicant tracking systems to their names
+var atsHosts = map[string]string{
+	"greenhouse.io":       "Greenhouse",
+	"lever.co":            "Lever",
+	"ashbyhq.com":         "Ashby",
+	"myworkdayjobs.com":   "Workday",
+	"smartrecruiters.com": "SmartRecruiters",
+	"workable.com":        "Workable",
+	"icims.com":           "iCIMS",
+	"bamboohr.com":        "BambooHR",
+	"jobvite.com":         "Jobvite",
+}
+
+// jobATS returns the applicant tracking system a job posting links to, or
+// "" when it can't tell (LinkedIn postings)
+func jobATS(job Job) string {
+	if u, err := url.Parse(job.URL); err == nil {
+		host := strings.ToLower(u.Hostname())
+		for domain, name := range atsHosts {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return name
+			}
+		}
+	}
+	switch job.Source {
+	case "greenhouse":
+		return "Greenhouse"
+	case "lever":
+		return "Lever"
+	}
+	return ""
+}
+
+// enrichCompanies checks each company with open jobs against the Levels.fyi
+// top-paying list and stores the result. It runs after a scrape, outside the
+// job store transaction, since the first check may fetch the list.
+func enrichCompanies(jobs []Job) {
+	infos := make(map[string]CompanyInfo)
+	now := time.Now()
+	for _, job := range jobs {
+		id := analytics.CompanyID(job.Company)
+		if id == "" || jobStatus(job) == JobClosed {
+			continue
+		}
+		if _, ok := infos[id]; ok {
+			continue
+		}
+		infos[id] = CompanyInfo{
+			Name:      job.Company,
+			TopPaying: utils.IsTopPayingCompany(job.Company, false),
+			CheckedAt: now,
+		}
+	}
+
+	err := dataStore.Update(func(tx Tx) error {
+		for id, info := range infos {
+			if err := tx.Put(collCompanies, id, info); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save company info: %v", err)
+	}
+}
+
+// buildCompanyProfile gathers the company's jobs from the store, reporting
+// false if none are stored. Roles are the open jobs cfg doesn't exclude,
+// newest first.
+func buildCompanyProfile(id string, cfg *AppConfig) (CompanyProfile, bool, error) {
+	var store JobStore
+	var info CompanyInfo
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		if store, err = readJobStore(tx); err != nil {
+			return err
+		}
+		_, err = tx.Get(collCompanies, id, &info)
+		return err
+	})
+	if err != nil {
+		return CompanyProfile{}, false, err
+	}
+
+	var jobs []Job
+	for _, job := range store.Jobs {
+		if analytics.CompanyID(job.Company) == id {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return CompanyProfile{}, false, nil
+	}
+
+	// Jobs are sorted newest first, so the first is the company's current name
+	profile := CompanyProfile{
+		ID:         id,
+		Name:       jobs[0].Company,
+		TopPaying:  info.TopPaying,
+		ATS:        []string{},
+		Sources:    []string{},
+		WindowDays: companyWindowDays,
+		OpenTrend:  []analytics.Point{},
+		Roles:      []TaggedJob{},
+	}
+	ats := make(map[string]bool)
+	sources := make(map[string]bool)
+	var latestLevels time.Time
+	for _, job := range jobs {
+		if name := jobATS(job); name != "" {
+			ats[name] = true
+		}
+		if job.Source != "" {
+			sources[job.Source] = true
+		}
+		if isValidSalary(job.LevelSalary) && job.LevelSalary != "No Data" && job.LastSeen.After(latestLevels) {
+			profile.LevelsMedian = job.LevelSalary
+			latestLevels = job.LastSeen
+		}
+		if jobStatus(job) == JobClosed {
+			continue
+		}
+		if tagged := TagJob(job, cfg); !tagged.Tags.IsExcluded {
+			profile.Roles = append(profile.Roles, tagged)
+		}
+	}
+	for name := range ats {
+		profile.ATS = append(profile.ATS, name)
+	}
+	for source := range sources {
+		profile.Sources = append(profile.Sources, source)
+	}
+	sort.Strings(profile.ATS)
+	sort.Strings(profile.Sources)
+
+	now := time.Now()
+	since := now.AddDate(0, 0, -companyWindowDays)
+	aj := analyticsJobs(jobs, cfg)
+	if stats := analytics.TopCompanies(aj, since, now, 1); len(stats) > 0 {
+		profile.Open = stats[0].Open
+		profile.Opened = stats[0].Opened
+		profile.Closed = stats[0].Closed
+		profile.MedianPosted = stats[0].MedianSalary
+	}
+	if series := analytics.OpenRoles(aj, analytics.Week, since, now, []string{profile.Name}); len(series) > 0 {
+		profile.OpenTrend = series[0].Points
+	}
+	return profile, true, nil
+}
+
+// handleAPICompany returns the profile of the company at
+// /api/companies/{id}
+func handleAPICompany(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/companies/")
+	if id == "" {
+		jsonError(w, "Missing company ID", http.StatusBadRequest)
+		return
+	}
+
+	profile, found, err := buildCompanyProfile(id, configForUser(r.Header.Get("X-Auth-User")))
+	if err != nil {
+		jsonError(w, "Failed to load company", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		jsonError(w, "Company not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
+}
+
+// handleCompanyPage serves /company/{id}. Like the dashboard it is public,
+// applying the profile of a logged-in user.
+func handleCompanyPage(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/company/")
+	if id == "" {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+
+	cfg := configForUser(dashboardUser(r))
+	profile, found, err := buildCompanyProfile(id, cfg)
+	if err != nil {
+		http.Error(w, "Failed to load company", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Company not found", http.StatusNotFound)
+		return
+	}
+
+	levels := make(map[string]string)
+	for id, level := range cfg.Filters.Levels {
+		levels[id] = displayName(level, id)
+	}
+	data, _ := json.Marshal(profile)
+	levelsJSON, _ := json.Marshal(levels)
+	page := strings.Replace(companyPageHTML, "{{COMPANY_NAME}}", html.EscapeString(profile.Name), 1)
+	page = strings.Replace(page, "{{COMPANY_DATA}}", string(data), 1)
+	page = strings.Replace(page, "{{LEVEL_NAMES}}", string(levelsJSON), 1)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+var companyPageHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>{{COMPANY_NAME}} | OffSec Jobs</title>
+	<style>
+		:root {
+			--bg-primary: #0a0a0f;
+			--bg-secondary: #12121a;
+			--bg-card: #16161f;
+			--accent-primary: #00ff88;
+			--accent-glow: rgba(0, 255, 136, 0.15);
+			--text-primary: #e8e8ed;
+			--text-secondary: #8888a0;
+			--text-muted: #5a5a70;
+			--border-color: #2a2a3a;
+			--warning: #ffa502;
+		}
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body {
+			font-family: 'Outfit', -apple-system, BlinkMacSystemFont, sans-serif;
+			background: var(--bg-primary);
+			color: var(--text-primary);
+			line-height: 1.6;
+		}
+		.container { max-width: 1100px; margin: 0 auto; padding: 2rem 1.5rem; }
+		a { color: var(--accent-primary); text-decoration: none; }
+		a:hover { text-decoration: underline; }
+		.back { font-size: 0.85rem; }
+		h1 { font-size: 2rem; margin: 0.75rem 0 0.25rem; display: flex; align-items: center; gap: 0.75rem; flex-wrap: wrap; }
+		.badge {
+			font-size: 0.75rem;
+			font-weight: 500;
+			padding: 0.2rem 0.6rem;
+			border-radius: 999px;
+			background: var(--accent-glow);
+			color: var(--accent-primary);
+			border: 1px solid var(--accent-primary);
+		}
+		.subtitle { color: var(--text-secondary); font-size: 0.9rem; margin-bottom: 1.5rem; }
+		.stats { display: grid; grid-template-columns: repeat(auto-fit, minmax(160px, 1fr)); gap: 1rem; margin-bottom: 1.5rem; }
+		.stat, .panel {
+			background: var(--bg-secondary);
+			border: 1px solid var(--border-color);
+			border-radius: 12px;
+			padding: 1rem 1.25rem;
+		}
+		.stat-value { font-family: 'JetBrains Mono', monospace; font-size: 1.4rem; font-weight: 600; color: var(--accent-primary); }
+		.stat-label { color: var(--text-secondary); font-size: 0.8rem; }
+		.panel { margin-bottom: 1.5rem; }
+		.panel h2 { font-size: 1rem; font-weight: 500; margin-bottom: 0.75rem; }
+		.chart { width: 100%; height: auto; display: block; }
+		.chart text { fill: var(--text-muted); font-size: 10px; font-family: 'JetBrains Mono', monospace; }
+		.chart .grid { stroke: var(--border-color); stroke-width: 1; }
+		table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+		th { text-align: left; color: var(--text-secondary); font-weight: 500; padding: 0.5rem; border-bottom: 1px solid var(--border-color); }
+		td { padding: 0.5rem; border-bottom: 1px solid rgba(42, 42, 58, 0.5); vertical-align: top; }
+		td.salary { font-family: 'JetBrains Mono', monospace; white-space: nowrap; }
+		.muted { color: var(--text-muted); }
+		.missing { color: var(--warning); font-size: 0.75rem; }
+	</style>
+</head>
+<body>
+	<div class="container">
+		<a class="back" href="/">&larr; All jobs</a>
+		<h1 id="company-name"></h1>
+		<div class="subtitle" id="company-subtitle"></div>
+		<div class="stats" id="company-stats"></div>
+		<div class="panel">
+			<h2 id="trend-title">Open Roles</h2>
+			<div id="company-trend"></div>
+		</div>
+		<div class="panel">
+			<h2>Open Roles</h2>
+			<div id="company-roles"></div>
+		</div>
+	</div>
+	<script>
+		const company = {{COMPANY_DATA}};
+		const levelNames = {{LEVEL_NAMES}};
+
+		function escapeHtml(text) {
+			if (!text) return '';
+			const div = document.createElement('div');
+			div.textContent = text;
+			return div.innerHTML;
+		}
+
+		function formatSalaryK(v) {
+			return '$' + Math.round(v / 1000) + 'k';
+		}
+
+		function stat(value, label) {
+			return '<div class="stat"><div class="stat-value">' + value + '</div><div class="stat-label">' + label + '</div></div>';
+		}
+
+		function renderHeader() {
+			document.getElementById('company-name').innerHTML = escapeHtml(company.name) +
+				(company.top_paying ? ' <span class="badge" title="Listed on the Levels.fyi top-paying companies leaderboard">&#9733; Top-paying</span>' : '');
+			const levelsUrl = 'https://www.levels.fyi/companies/' + encodeURIComponent(company.name.toLowerCase().replace(/\s+/g, '-')) + '/salaries/';
+			const ats = company.ats.length ? company.ats.join(', ') : 'Unknown';
+			document.getElementById('company-subtitle').innerHTML =
+				'ATS: ' + escapeHtml(ats) + ' &middot; Found on ' + escapeHtml(company.sources.join(', ')) +
+				' &middot; <a href="' + levelsUrl + '" target="_blank" rel="noopener">Levels.fyi</a>';
+
+			document.getElementById('company-stats').innerHTML =
+				stat(company.open, 'Open roles') +
+				stat('+' + company.opened, 'Opened (' + company.window_days + ' days)') +
+				stat('&minus;' + company.closed, 'Closed (' + company.window_days + ' days)') +
+				stat(company.median_posted_salary ? formatSalaryK(company.median_posted_salary) : '&mdash;', 'Median posted salary') +
+				stat(company.levels_median ? escapeHtml(company.levels_median) : '&mdash;', 'Levels.fyi median');
+		}
+
+		function renderTrend() {
+			const points = company.open_trend;
+			document.getElementById('trend-title').textContent = 'Open Roles, Last ' + company.window_days + ' Days';
+			if (!points.length) {
+				document.getElementById('company-trend').innerHTML = '<div class="muted">No data yet.</div>';
+				return;
+			}
+			const width = 640, height = 160, left = 36, right = 12, top = 10, bottom = 24;
+			const plotW = width - left - right, plotH = height - top - bottom;
+			const max = Math.max(1, Math.max.apply(null, points.map(function(p) { return p.value; })));
+			const x = function(i) { return left + (points.length === 1 ? plotW / 2 : i * plotW / (points.length - 1)); };
+			const y = function(v) { return top + plotH - v / max * plotH; };
+
+			let svg = '<svg class="chart" viewBox="0 0 ' + width + ' ' + height + '" role="img">';
+			[0, max / 2, max].forEach(function(v) {
+				svg += '<line class="grid" x1="' + left + '" x2="' + (width - right) + '" y1="' + y(v).toFixed(1) + '" y2="' + y(v).toFixed(1) + '"/>';
+				svg += '<text x="' + (left - 6) + '" y="' + y(v).toFixed(1) + '" dy="3" text-anchor="end">' + Math.round(v) + '</text>';
+			});
+			svg += '<polyline fill="none" stroke="#00ff88" stroke-width="2" points="' +
+				points.map(function(p, i) { return x(i).toFixed(1) + ',' + y(p.value).toFixed(1); }).join(' ') + '"/>';
+			const step = Math.max(1, Math.ceil(points.length / 8));
+			points.forEach(function(p, i) {
+				svg += '<circle cx="' + x(i).toFixed(1) + '" cy="' + y(p.value).toFixed(1) + '" r="3" fill="#00ff88"><title>' + escapeHtml(p.period) + ': ' + p.value + ' open</title></circle>';
+				if (i % step === 0) {
+					svg += '<text x="' + x(i).toFixed(1) + '" y="' + (height - 6) + '" text-anchor="middle">' + escapeHtml(p.period) + '</text>';
+				}
+			});
+			document.getElementById('company-trend').innerHTML = svg + '</svg>';
+		}
+
+		function renderRoles() {
+			const container = document.getElementById('company-roles');
+			if (!company.roles.length) {
+				container.innerHTML = '<div class="muted">No open roles.</div>';
+				return;
+			}
+			let html = '<table><thead><tr><th>Role</th><th>Location</th><th>Level</th><th>Posted</th><th>Levels.fyi</th><th>Source</th><th>Listed</th></tr></thead><tbody>';
+			company.roles.forEach(function(t) {
+				const job = t.job;
+				const days = Math.floor((new Date(job.last_seen) - new Date(job.first_seen)) / 86400000);
+				html += '<tr>' +
+					'<td><a href="' + escapeHtml(job.url) + '" target="_blank" rel="noopener">' + escapeHtml(job.title) + '</a>' +
+					(job.status === 'missing' ? '<div class="missing">Missing from the last scan</div>' : '') + '</td>' +
+					'<td>' + escapeHtml(job.location) + '</td>' +
+					'<td>' + escapeHtml(levelNames[t.tags.level] || t.tags.level) + '</td>' +
+					'<td class="salary">' + (job.salary_range ? escapeHtml(job.salary_range) : '<span class="muted">&mdash;</span>') + '</td>' +
+					'<td class="salary">' + (job.level_salary ? escapeHtml(job.level_salary) : '<span class="muted">&mdash;</span>') + '</td>' +
+					'<td>' + escapeHtml(job.source) + '</td>' +
+					'<td>' + days + 'd</td>' +
+					'</tr>';
+			});
+			container.innerHTML = html + '</tbody></table>';
+		}
+
+		renderHeader();
+		renderTrend();
+		renderRoles();
+	</script>
+</body>
+</html>`
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync/atomic"
 
+	"github.com/fr4nk3nst1ner/salarysleuth/jobtracker/analytics"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,11 +17,15 @@ type AppConfig struct {
 	Telegram TelegramConfig `yaml:"telegram"`
 	Filters  FiltersConfig  `yaml:"filters"`
 	Display  DisplayConfig  `yaml:"display"`
+	Scoring  ScoringConfig  `yaml:"scoring"`
 }
 
 type ScraperConfig struct {
 	Pages       int    `yaml:"pages"`
 	Description string `yaml:"description"`
+	// CloseAfterMisses is how many scrapes in a row a job must be missing
+	// from before it is marked closed (0 uses the default)
+	CloseAfterMisses int `yaml:"close_after_misses"`
 }
 
 type TelegramConfig struct {
@@ -36,14 +43,37 @@ type FiltersConfig struct {
 }
 
 type CategoryConfig struct {
-	Keywords    []string `yaml:"keywords"`
-	DisplayName string   `yaml:"display_name"`
+	Keywords []Keyword `yaml:"keywords"`
+	// Fields the keywords are matched against (title, company, location,
+	// description). Defaults to title, or title and location for remote.
+	Fields []string `yaml:"fields"`
+	// Query is an optional search expression that also matches this rule,
+	// e.g. `title:(pentest* OR "red team") -title:manager`
+	Query       string `yaml:"query"`
+	DisplayName string `yaml:"display_name"`
+	// Weight multiplies the points this rule adds to a job's score
+	// (default 1)
+	Weight float64 `yaml:"weight"`
+
+	keywordQueries []*query.Query
+	query          *query.Query
+	queryLine      int
+	fieldsLine     int
+	weightLine     int
 }
 
 type ExcludeConfig struct {
-	DefensiveRoles []string `yaml:"defensive_roles"`
-	NonSecurity    []string `yaml:"non_security"`
-	Compliance     []string `yaml:"compliance"`
+	DefensiveRoles []Keyword `yaml:"defensive_roles"`
+	NonSecurity    []Keyword `yaml:"non_security"`
+	Compliance     []Keyword `yaml:"compliance"`
+	// Queries exclude any job matching one of the search expressions
+	Queries []string `yaml:"queries"`
+
+	defensiveQueries   []*query.Query
+	nonSecurityQueries []*query.Query
+	complianceQueries  []*query.Query
+	queries            []*query.Query
+	queryLines         []int
 }
 
 type DisplayConfig struct {
@@ -51,6 +81,9 @@ type DisplayConfig struct {
 	DefaultFilters DefaultFiltersConfig `yaml:"default_filters"`
 	PageSize       int                  `yaml:"page_size"`
 	DefaultSort    string               `yaml:"default_sort"`
+	// RecentlyClosedDays is how long closed jobs stay on the dashboard's
+	// "Recently Closed" view (0 uses the default)
+	RecentlyClosedDays int `yaml:"recently_closed_days"`
 }
 
 type DefaultFiltersConfig struct {
@@ -70,43 +103,75 @@ type JobTags struct {
 	ExcludeReason  string   `json:"exclude_reason,omitempty"`
 }
 
-// TaggedJob represents a job with its tags
+// TaggedJob represents a job with its tags and relevance score
 type TaggedJob struct {
-	Job  Job     `json:"job"`
-	Tags JobTags `json:"tags"`
+	Job            Job         `json:"job"`
+	Tags           JobTags     `json:"tags"`
+	Score          float64     `json:"score"`
+	ScoreBreakdown []ScoreItem `json:"score_breakdown"`
+	CompanyID      string      `json:"company_id"` // for /company/{id} links
 }
 
-var appConfig *AppConfig
+// appConfig is the running configuration. It is replaced as a whole when
+// config.yaml is reloaded, so a caller holding a *AppConfig never sees a mix
+// of old and new rules.
+var appConfig atomic.Pointer[AppConfig]
 
-// LoadConfig loads the configuration from config.yaml
+// LoadConfig returns the running configuration, loading config.yaml on first
+// use. Once loaded it only changes through reloadConfig.
 func LoadConfig() (*AppConfig, error) {
-	if appConfig != nil {
-		return appConfig, nil
+	if cfg := appConfig.Load(); cfg != nil {
+		return cfg, nil
+	}
+
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+	if cfg := appConfig.Load(); cfg != nil {
+		return cfg, nil
 	}
 
 	configPath := findConfigPath()
-	
+	cfg, data, err := readConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	setRunningConfig(cfg, configPath, data)
+	return cfg, nil
+}
+
+// readConfig parses and validates a config file without installing it. A
+// missing file yields the built-in defaults and nil data.
+func readConfig(configPath string) (*AppConfig, []byte, error) {
 	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return getDefaultConfig(), nil, nil
+	}
 	if err != nil {
-		// Return default config if file not found
-		return getDefaultConfig(), nil
+		return nil, nil, err
 	}
 
-	var cfg AppConfig
+	// Scoring weights left out of the file keep their defaults
+	cfg := AppConfig{Scoring: defaultScoringConfig()}
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+		return nil, data, fmt.Errorf("%s: %v", configPath, err)
 	}
-
-	appConfig = &cfg
-	return appConfig, nil
+	if err := cfg.compileRules(configPath); err != nil {
+		return nil, data, err
+	}
+	return &cfg, data, nil
 }
 
 func findConfigPath() string {
-	// Check various locations for config.yaml
+	// Check various locations for config.yaml. It lives in config/ so
+	// Docker can mount the directory: a single-file bind mount keeps the
+	// old inode when an editor saves by renaming, and the reload never fires.
 	paths := []string{
+		filepath.Join("config", "config.yaml"),
+		filepath.Join(config.DataDir, "..", "config", "config.yaml"),
+		"/app/salarysleuth/jobtracker/config/config.yaml",
+		"/app/config/config.yaml",
 		"config.yaml",
 		filepath.Join(config.DataDir, "..", "config.yaml"),
-		"/app/salarysleuth/jobtracker/config.yaml",
 		"/app/config.yaml",
 	}
 
@@ -120,10 +185,11 @@ func findConfigPath() string {
 }
 
 func getDefaultConfig() *AppConfig {
-	return &AppConfig{
+	cfg := &AppConfig{
 		Scraper: ScraperConfig{
-			Pages:       20,
-			Description: "Offensive Security",
+			Pages:            20,
+			Description:      "Offensive Security",
+			CloseAfterMisses: defaultCloseAfterMisses,
 		},
 		Telegram: TelegramConfig{
 			Enabled: true,
@@ -131,77 +197,98 @@ func getDefaultConfig() *AppConfig {
 		Filters: FiltersConfig{
 			Categories: map[string]CategoryConfig{
 				"penetration_testing": {
-					Keywords:    []string{"penetration test", "pentest", "pen test", "ethical hack"},
+					Keywords:    keywords("penetration test", "pentest", "pen test", "ethical hack"),
 					DisplayName: "Penetration Testing",
 				},
 				"red_team": {
-					Keywords:    []string{"red team", "adversary", "purple team"},
+					Keywords:    keywords("red team", "adversary", "purple team"),
 					DisplayName: "Red Team",
 				},
 				"appsec": {
-					Keywords:    []string{"appsec", "application security", "product security"},
+					Keywords:    keywords("appsec", "application security", "product security"),
 					DisplayName: "AppSec",
 				},
 				"offensive_security": {
-					Keywords:    []string{"offensive security", "offensive"},
+					Keywords:    keywords("offensive security", "offensive"),
 					DisplayName: "Offensive Security",
 				},
 				"security_research": {
-					Keywords:    []string{"security research", "vulnerability research", "exploit"},
+					Keywords:    keywords("security research", "vulnerability research", "exploit"),
 					DisplayName: "Security Research",
 				},
 			},
 			Levels: map[string]CategoryConfig{
 				"management": {
-					Keywords:    []string{"manager", "head of", "lead", "director"},
+					Keywords:    keywords("manager", "head of", "lead", "director"),
 					DisplayName: "Management/Lead",
 				},
 				"senior": {
-					Keywords:    []string{"senior", "sr.", "sr ", "staff", "principal"},
+					Keywords:    keywords("senior", "sr.", "sr ", "staff", "principal"),
 					DisplayName: "Senior IC",
 				},
 				"mid": {
-					Keywords:    []string{"ii", "iii"},
+					Keywords:    keywords("ii", "iii"),
 					DisplayName: "Mid-Level",
 				},
 				"junior": {
-					Keywords:    []string{"junior", "jr.", "entry", "associate", "intern"},
+					Keywords:    keywords("junior", "jr.", "entry", "associate", "intern"),
 					DisplayName: "Junior/Entry",
 				},
 			},
 			Certifications: map[string]CategoryConfig{
 				"oscp": {
-					Keywords:    []string{"oscp"},
+					Keywords:    keywords("oscp"),
 					DisplayName: "OSCP",
 				},
 				"osce": {
-					Keywords:    []string{"osce", "oswe", "osep"},
+					Keywords:    keywords("osce", "oswe", "osep"),
 					DisplayName: "OSCE/OSWE/OSEP",
 				},
 			},
 			Remote: CategoryConfig{
-				Keywords:    []string{"remote", "work from home"},
+				Keywords:    keywords("remote", "work from home"),
 				DisplayName: "Remote",
 			},
 			Exclude: ExcludeConfig{
-				DefensiveRoles: []string{"soc analyst", "incident response", "blue team"},
-				NonSecurity:    []string{"software engineer", "devops", "product manager"},
-				Compliance:     []string{"compliance", "grc", "governance"},
+				DefensiveRoles: keywords("soc analyst", "incident response", "blue team"),
+				NonSecurity:    keywords("software engineer", "devops", "product manager"),
+				Compliance:     keywords("compliance", "grc", "governance"),
 			},
 		},
 		Display: DisplayConfig{
-			ShowAllJobs: true,
-			PageSize:    50,
-			DefaultSort: "newest",
+			ShowAllJobs:        true,
+			PageSize:           50,
+			DefaultSort:        "newest",
+			RecentlyClosedDays: defaultRecentlyClosedDays,
 		},
+		Scoring: defaultScoringConfig(),
 	}
+	// The built-in keywords are constants, so compiling them cannot fail
+	cfg.compileRules("built-in defaults")
+	return cfg
+}
+
+// jobDocument describes a job for query matching. The posted salary range is
+// used for salary comparisons, falling back to the Levels.fyi figure.
+func jobDocument(job Job) query.Document {
+	doc := query.Document{
+		Title:       job.Title,
+		Company:     job.Company,
+		Location:    job.Location,
+		Description: job.Description,
+	}
+	if salary, ok := query.ParseAmount(job.SalaryRange); ok {
+		doc.Salary = salary
+	} else if salary, ok := query.ParseAmount(job.LevelSalary); ok {
+		doc.Salary = salary
+	}
+	return doc
 }
 
 // TagJob applies tags to a job based on configuration
 func TagJob(job Job, cfg *AppConfig) TaggedJob {
-	titleLower := strings.ToLower(job.Title)
-	locationLower := strings.ToLower(job.Location)
-	
+	doc := jobDocument(job)
+
 	tags := JobTags{
 		Categories:     []string{},
 		Certifications: []string{},
@@ -209,27 +296,19 @@ func TagJob(job Job, cfg *AppConfig) TaggedJob {
 
 	// Check categories
 	for catID, cat := range cfg.Filters.Categories {
-		for _, kw := range cat.Keywords {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				tags.Categories = append(tags.Categories, catID)
-				break
-			}
+		if cat.matches(doc) {
+			tags.Categories = append(tags.Categories, catID)
 		}
 	}
 
 	// Check levels (take the first match)
 	for levelID, level := range cfg.Filters.Levels {
-		for _, kw := range level.Keywords {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				tags.Level = levelID
-				break
-			}
-		}
-		if tags.Level != "" {
+		if level.matches(doc) {
+			tags.Level = levelID
 			break
 		}
 	}
-	
+
 	// Default to mid-level if no level detected
 	if tags.Level == "" {
 		tags.Level = "mid"
@@ -237,56 +316,50 @@ func TagJob(job Job, cfg *AppConfig) TaggedJob {
 
 	// Check certifications
 	for certID, cert := range cfg.Filters.Certifications {
-		for _, kw := range cert.Keywords {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				tags.Certifications = append(tags.Certifications, certID)
-				break
-			}
+		if cert.matches(doc) {
+			tags.Certifications = append(tags.Certifications, certID)
 		}
 	}
 
 	// Check remote
-	for _, kw := range cfg.Filters.Remote.Keywords {
-		if strings.Contains(titleLower, strings.ToLower(kw)) ||
-			strings.Contains(locationLower, strings.ToLower(kw)) {
-			tags.IsRemote = true
-			break
-		}
-	}
+	tags.IsRemote = cfg.Filters.Remote.matches(doc)
 
 	// Check exclusions
-	for _, kw := range cfg.Filters.Exclude.DefensiveRoles {
-		if strings.Contains(titleLower, strings.ToLower(kw)) {
-			tags.IsExcluded = true
-			tags.ExcludeReason = "Defensive role"
-			break
-		}
-	}
-	
-	if !tags.IsExcluded {
-		for _, kw := range cfg.Filters.Exclude.NonSecurity {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				tags.IsExcluded = true
-				tags.ExcludeReason = "Non-security role"
-				break
-			}
-		}
-	}
-	
-	if !tags.IsExcluded {
-		for _, kw := range cfg.Filters.Exclude.Compliance {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				tags.IsExcluded = true
-				tags.ExcludeReason = "Compliance/GRC role"
-				break
-			}
-		}
+	excludeReasons := cfg.Filters.Exclude.matches(doc)
+	if len(excludeReasons) > 0 {
+		tags.IsExcluded = true
+		tags.ExcludeReason = excludeReasons[0]
 	}
 
+	score, breakdown := scoreJob(doc, tags, excludeReasons, cfg)
+
 	return TaggedJob{
-		Job:  job,
-		Tags: tags,
+		Job:            job,
+		Tags:           tags,
+		Score:          score,
+		ScoreBreakdown: breakdown,
+		CompanyID:      analytics.CompanyID(job.Company),
+	}
+}
+
+// matches returns the reason for every exclude group the job matches
+func (ex ExcludeConfig) matches(doc query.Document) []string {
+	var reasons []string
+	if matchAny(ex.defensiveQueries, doc) {
+		reasons = append(reasons, "Defensive role")
+	}
+	if matchAny(ex.nonSecurityQueries, doc) {
+		reasons = append(reasons, "Non-security role")
+	}
+	if matchAny(ex.complianceQueries, doc) {
+		reasons = append(reasons, "Compliance/GRC role")
+	}
+	for _, q := range ex.queries {
+		if q.MatchDocument(doc) {
+			reasons = append(reasons, "Excluded by query: "+q.String())
+		}
 	}
+	return reasons
 }
 
 // TagJobs applies tags to all jobs
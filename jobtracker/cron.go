@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed 5-field cron expression: minute, hour, day of month,
+// month and day of week. As in cron, when both day fields are restricted a
+// day matches if either does.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64 // bit n is set when value n matches
+	domAny, dowAny                bool   // the day fields start with *
+}
+
+// cronMacros are the shorthands cron accepts for common schedules
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // names for min, min+1, ... where the field has them
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is Sunday as well as 0
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// parseCron parses a 5-field cron expression or one of cronMacros. Fields
+// take *, numbers, names (JAN, MON), ranges (1-5), lists (1,15) and steps
+// (*/15, 9-17/2).
+func parseCron(expr string) (cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("cron expressions have 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return cronSpec{}, err
+		}
+		bits[i] = b
+	}
+	spec := cronSpec{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"),
+		dowAny: strings.HasPrefix(fields[4], "*"),
+	}
+	if spec.dow&(1<<7) != 0 {
+		spec.dow = spec.dow&^(1<<7) | 1
+	}
+	return spec, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepStr, f.name)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(loStr, f); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(hiStr, f); err != nil {
+					return 0, err
+				}
+				// SUN ending a range is 7, so MON-SUN is the whole week
+				if hi == 0 && strings.EqualFold(hiStr, "sun") {
+					hi = 7
+				}
+			} else if hasStep {
+				hi = f.max // 5/15 means 5-59/15
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q in %s field", rng, f.name)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, f cronField) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q (%d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// next returns the first matching minute after t, in t's location, or the
+// zero time if none comes within five years (such as for February 30th). A
+// time skipped when clocks go forward runs as they change, and one repeated
+// when they go back runs only the first time.
+func (c cronSpec) next(t time.Time) time.Time {
+	loc := t.Location()
+	// Search wall clock times in UTC, where every day has 24 hours, and
+	// only then place them in loc
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	for {
+		if wall = c.nextWall(wall); wall.IsZero() {
+			return time.Time{}
+		}
+		run := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+		if runWall := time.Date(run.Year(), run.Month(), run.Day(), run.Hour(), run.Minute(), 0, 0, time.UTC); !runWall.Equal(wall) {
+			// wall is in the hour skipped when clocks went forward, and
+			// time.Date placed it to one side; run at the change
+			start, end := run.ZoneBounds()
+			if run = start; runWall.Before(wall) {
+				run = end
+			}
+		}
+		if run.After(t) {
+			return run
+		}
+	}
+}
+
+// nextWall returns the first matching minute after the UTC wall clock time
+// t, or the zero time if none comes within five years
+func (c cronSpec) nextWall(t time.Time) time.Time {
+	t = t.Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// minGap is the shortest time between two runs in the year from t, stopping
+// early once a gap shorter than limit is found
+func (c cronSpec) minGap(t time.Time, limit time.Duration) time.Duration {
+	end := t.AddDate(1, 0, 0)
+	gap := time.Duration(1<<63 - 1)
+	prev := c.next(t)
+	for !prev.IsZero() && prev.Before(end) && gap >= limit {
+		run := c.next(prev)
+		if run.IsZero() {
+			break
+		}
+		if d := run.Sub(prev); d < gap {
+			gap = d
+		}
+		prev = run
+	}
+	return gap
+}
+
+// rrule returns the iCalendar recurrence rule matching the spec. There is
+// none when both day fields are restricted, since a rule can't match days
+// by day of month or weekday.
+func (c cronSpec) rrule() (string, bool) {
+	if !c.domAny && !c.dowAny {
+		return "", false
+	}
+	list := func(bits uint64, lo, hi int, name func(int) string) string {
+		var values []string
+		for v := lo; v <= hi; v++ {
+			if bits&(1<<uint(v)) != 0 {
+				values = append(values, name(v))
+			}
+		}
+		return strings.Join(values, ",")
+	}
+	all := func(bits uint64, lo, hi int) bool {
+		for v := lo; v <= hi; v++ {
+			if bits&(1<<uint(v)) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	rule := []string{"FREQ=DAILY"}
+	if !all(c.month, 1, 12) {
+		rule = append(rule, "BYMONTH="+list(c.month, 1, 12, strconv.Itoa))
+	}
+	if !c.domAny {
+		rule = append(rule, "BYMONTHDAY="+list(c.dom, 1, 31, strconv.Itoa))
+	}
+	if !c.dowAny && !all(c.dow, 0, 6) {
+		rule = append(rule, "BYDAY="+list(c.dow, 0, 6, func(d int) string { return rruleDays[d] }))
+	}
+	rule = append(rule,
+		"BYHOUR="+list(c.hour, 0, 23, strconv.Itoa),
+		"BYMINUTE="+list(c.minute, 0, 59, strconv.Itoa))
+	return strings.Join(rule, ";"), true
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		ok   bool
+	}{
+		{"0 9 * * 1-5", true},
+		{"*/15 9-17/2 1,15 jan-jun *", true},
+		{"@daily", true},
+		{"0 9 * * MON-SUN", true},
+		{"0 9 * * sun-mon", true},
+		{"0 9 * * 7", true},
+		{"0 9 * * sat-mon", false},
+		{"60 * * * *", false},
+		{"0 24 * * *", false},
+		{"0 0 0 * *", false},
+		{"*/0 * * * *", false},
+		{"0 9 * *", false},
+		{"0 9 * * funday", false},
+	}
+	for _, tt := range tests {
+		if _, err := parseCron(tt.expr); (err == nil) != tt.ok {
+			t.Errorf("parseCron(%q) = %v, want ok %v", tt.expr, err, tt.ok)
+		}
+	}
+
+	spec, err := parseCron("0 9 * * mon-sun")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.dow != 1<<7-1 {
+		t.Errorf("mon-sun matches weekdays %07b, want every day", spec.dow)
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		tz   string
+		from string
+		want string // empty when the expression never runs
+	}{
+		{"weekdays skip the weekend", "0 9 * * 1-5", "UTC", "2026-10-16T10:00:00Z", "2026-10-19T09:00:00Z"},
+		{"mon-sun includes Sunday", "0 9 * * mon-sun", "UTC", "2026-10-17T10:00:00Z", "2026-10-18T09:00:00Z"},
+		{"day of month alone", "0 0 13 * *", "UTC", "2026-10-01T00:00:00Z", "2026-10-13T00:00:00Z"},
+		{"day of week matches before day of month", "0 0 13 * 5", "UTC", "2026-10-01T00:00:00Z", "2026-10-02T00:00:00Z"},
+		{"day of month matches before day of week", "0 0 13 * 5", "UTC", "2026-10-10T00:00:00Z", "2026-10-13T00:00:00Z"},
+		{"leap day", "0 0 29 2 *", "UTC", "2026-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"February 30th", "0 0 30 2 *", "UTC", "2026-01-01T00:00:00Z", ""},
+		{"April 31st", "0 0 31 4 *", "UTC", "2026-01-01T00:00:00Z", ""},
+		{"in the time zone", "0 9 * * *", "Europe/Berlin", "2026-10-18T08:00:00Z", "2026-10-19T07:00:00Z"},
+
+		// New York's clocks go forward at 02:00 on March 8th 2026 and back
+		// at 02:00 on November 1st
+		{"skipped time runs as clocks change", "30 2 * * *", "America/New_York", "2026-03-07T12:00:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"day after skipped time", "30 2 * * *", "America/New_York", "2026-03-08T03:00:00-04:00", "2026-03-09T02:30:00-04:00"},
+		{"skipped times run once", "*/15 2 * * *", "America/New_York", "2026-03-08T01:59:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"after the skipped times", "*/15 2 * * *", "America/New_York", "2026-03-08T03:00:00-04:00", "2026-03-09T02:00:00-04:00"},
+		{"hour after clocks go forward", "0 * * * *", "America/New_York", "2026-03-08T01:00:00-05:00", "2026-03-08T03:00:00-04:00"},
+		{"repeated time runs first time", "30 1 * * *", "America/New_York", "2026-11-01T00:00:00-04:00", "2026-11-01T01:30:00-04:00"},
+		{"repeated time doesn't run again", "30 1 * * *", "America/New_York", "2026-11-01T01:30:00-04:00", "2026-11-02T01:30:00-05:00"},
+		{"repeated time during the repeat", "30 1 * * *", "America/New_York", "2026-11-01T01:20:00-05:00", "2026-11-02T01:30:00-05:00"},
+		{"hourly over clocks going back", "0 * * * *", "America/New_York", "2026-11-01T01:00:00-04:00", "2026-11-01T02:00:00-05:00"},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: parseCron(%q): %v", tt.name, tt.expr, err)
+		}
+		loc, err := time.LoadLocation(tt.tz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		from, err := time.Parse(time.RFC3339, tt.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := spec.next(from.In(loc))
+		if tt.want == "" {
+			if !got.IsZero() {
+				t.Errorf("%s: next = %s, want none", tt.name, got)
+			}
+			continue
+		}
+		want, err := time.Parse(time.RFC3339, tt.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: next(%s) = %s, want %s", tt.name, tt.from, got.Format(time.RFC3339), tt.want)
+		}
+		if got.Location() != loc {
+			t.Errorf("%s: next is in %s, want %s", tt.name, got.Location(), loc)
+		}
+	}
+}
+
+func TestCronRRule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // empty when there is no rule
+	}{
+		{"0 9 * * 1-5", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;BYHOUR=9;BYMINUTE=0"},
+		{"0 9 * * mon-sun", "FREQ=DAILY;BYHOUR=9;BYMINUTE=0"},
+		{"0 18 * * 0", "FREQ=DAILY;BYDAY=SU;BYHOUR=18;BYMINUTE=0"},
+		{"30 8 1,15 * *", "FREQ=DAILY;BYMONTHDAY=1,15;BYHOUR=8;BYMINUTE=30"},
+		{"@yearly", "FREQ=DAILY;BYMONTH=1;BYMONTHDAY=1;BYHOUR=0;BYMINUTE=0"},
+		{"0,30 9-10 * * *", "FREQ=DAILY;BYHOUR=9,10;BYMINUTE=0,30"},
+		{"0 0 13 * 5", ""},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		got, ok := spec.rrule()
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("rrule(%q) = %q, %v, want %q", tt.expr, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDispatchSchedule(t *testing.T) {
+	prevCatchUp := scheduleCatchUp
+	t.Cleanup(func() { scheduleCatchUp = prevCatchUp })
+	now := time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		catchUp time.Duration
+		nextRun string
+		run     bool
+		missed  bool
+	}{
+		{"on time", 6 * time.Hour, "2026-10-18T12:29:00Z", true, false},
+		{"not yet due", 6 * time.Hour, "2026-10-18T13:00:00Z", false, false},
+		{"late within the window", 6 * time.Hour, "2026-10-18T08:00:00Z", true, false},
+		{"at the end of the window", 6 * time.Hour, "2026-10-18T06:30:00Z", true, false},
+		{"past the window", 6 * time.Hour, "2026-10-18T06:29:00Z", false, true},
+		{"no catch-up, on time", 0, "2026-10-18T12:29:00Z", true, false},
+		{"no catch-up, late", 0, "2026-10-18T12:00:00Z", false, true},
+		{"no next run yet", 6 * time.Hour, "", false, false},
+	}
+	for _, tt := range tests {
+		newTestStore(t)
+		scheduleCatchUp = tt.catchUp
+		sched := ScheduledScan{ID: "s", Name: "Hourly", Cron: "0 * * * *", Timezone: "UTC", Enabled: true, NextRun: tt.nextRun}
+		if err := saveUserAlerts("alice", UserAlertConfig{Schedules: []ScheduledScan{sched}}); err != nil {
+			t.Fatal(err)
+		}
+
+		due, run := dispatchSchedule("alice", "s", now)
+		if run != tt.run {
+			t.Errorf("%s: run = %v, want %v", tt.name, run, tt.run)
+		}
+		if run && due.Format(time.RFC3339) != tt.nextRun {
+			t.Errorf("%s: due = %s, want %s", tt.name, due.Format(time.RFC3339), tt.nextRun)
+		}
+
+		stored := loadUserAlerts("alice").Schedules[0]
+		next, err := time.Parse(time.RFC3339, stored.NextRun)
+		if err != nil || !next.After(now) {
+			t.Errorf("%s: next run = %q, want one after now", tt.name, stored.NextRun)
+		}
+		if missed := strings.HasPrefix(stored.LastResult, "Missed run"); missed != tt.missed {
+			t.Errorf("%s: last result = %q, want missed %v", tt.name, stored.LastResult, tt.missed)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Delivery modes
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryHourly    = "hourly"
+	DeliveryDaily     = "daily"
+)
+
+// maxJobsPerAlert bounds the cap a user can set on jobs per alert
+const maxJobsPerAlert = 200
+
+// outboxMaxAttempts is how many times a queued alert is tried before it is
+// dropped, outboxRetryDelay apart
+const (
+	outboxMaxAttempts = 5
+	outboxRetryDelay  = 15 * time.Minute
+)
+
+// publicURL is the dashboard's address as users reach it, for links in
+// alerts. Without it alerts point to the dashboard's History in words.
+var publicURL = strings.TrimRight(os.Getenv("PUBLIC_URL"), "/")
+
+// DeliveryPrefs is when a user's alerts are sent and how long they can be
+type DeliveryPrefs struct {
+	Mode       string `json:"mode,omitempty"`        // DeliveryImmediate (the default), DeliveryHourly or DeliveryDaily
+	DailyHour  int    `json:"daily_hour"`            // hour daily digests are sent at
+	Timezone   string `json:"timezone,omitempty"`    // IANA name; the server's TZ when empty
+	QuietStart string `json:"quiet_start,omitempty"` // "22:00"; no quiet hours when empty
+	QuietEnd   string `json:"quiet_end,omitempty"`   // "07:00"
+	MaxJobs    int    `json:"max_jobs,omitempty"`    // most jobs listed in an alert, 0 for all
+}
+
+func (p DeliveryPrefs) location() *time.Location {
+	if p.Timezone != "" {
+		if loc, err := time.LoadLocation(p.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// parseClock parses "15:04" as minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// quietEnd reports whether t falls in the user's quiet hours and, if so,
+// when they end
+func (p DeliveryPrefs) quietEnd(t time.Time) (time.Time, bool) {
+	start, err := parseClock(p.QuietStart)
+	if err != nil {
+		return time.Time{}, false
+	}
+	end, err := parseClock(p.QuietEnd)
+	if err != nil || start == end {
+		return time.Time{}, false
+	}
+
+	loc := p.location()
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	quiet := now >= start && now < end
+	if start > end { // overnight, such as 22:00 to 07:00
+		quiet = now >= start || now < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, loc)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until, true
+}
+
+// nextDelivery is when an alert raised at now should be sent: now, the next
+// hourly or daily digest, or the end of quiet hours
+func (p DeliveryPrefs) nextDelivery(now time.Time) time.Time {
+	loc := p.location()
+	local := now.In(loc)
+	due := now
+	switch p.Mode {
+	case DeliveryHourly:
+		due = time.Date(local.Year(), local.Month(), local.Day(), local.Hour()+1, 0, 0, 0, loc)
+	case DeliveryDaily:
+		due = time.Date(local.Year(), local.Month(), local.Day(), p.DailyHour, 0, 0, 0, loc)
+		if due.Before(now) {
+			due = due.AddDate(0, 0, 1)
+		}
+	}
+	if end, quiet := p.quietEnd(due); quiet {
+		due = end
+	}
+	return due
+}
+
+func (p DeliveryPrefs) validate() error {
+	switch p.Mode {
+	case "", DeliveryImmediate, DeliveryHourly, DeliveryDaily:
+	default:
+		return fmt.Errorf("unknown delivery mode %q", p.Mode)
+	}
+	if p.DailyHour < 0 || p.DailyHour > 23 {
+		return fmt.Errorf("daily digest hour must be 0 to 23")
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", p.Timezone)
+		}
+	}
+	if (p.QuietStart == "") != (p.QuietEnd == "") {
+		return fmt.Errorf("set both ends of the quiet hours, or neither")
+	}
+	if p.QuietStart != "" {
+		start, err1 := parseClock(p.QuietStart)
+		end, err2 := parseClock(p.QuietEnd)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("quiet hours must be times like 22:00")
+		}
+		if start == end {
+			return fmt.Errorf("quiet hours must start and end at different times")
+		}
+	}
+	if p.MaxJobs < 0 || p.MaxJobs > maxJobsPerAlert {
+		return fmt.Errorf("max jobs per alert must be 0 to %d", maxJobsPerAlert)
+	}
+	return nil
+}
+
+// OutboxEntry is an alert waiting for the user's next digest or the end of
+// their quiet hours. The outbox is kept in the store so a restart doesn't
+// lose them.
+type OutboxEntry struct {
+	Username string    `json:"username"`
+	Digest   Digest    `json:"digest"`
+	DueAt    time.Time `json:"due_at"`
+	Attempts int       `json:"attempts,omitempty"`
+}
+
+// outbox tracks the users whose queued alerts are being sent, so a slow
+// send isn't started again by the next flush
+var outbox = struct {
+	sync.Mutex
+	sending map[string]bool
+}{sending: make(map[string]bool)}
+
+// deliverDigest sends the digest now, or queues it as the user's delivery
+// preferences ask, and reports whether it was sent or queued
+func deliverDigest(username string, cfg UserAlertConfig, d Digest) bool {
+	now := time.Now()
+	if due := cfg.Delivery.nextDelivery(now); due.After(now) {
+		err := dataStore.Update(func(tx Tx) error {
+			return tx.Put(collOutbox, username+"/"+generateEntryID(), OutboxEntry{Username: username, Digest: d, DueAt: due})
+		})
+		if err != nil {
+			log.Printf("Failed to queue alert for %s: %v", username, err)
+			return false
+		}
+		log.Printf("Queued alert for %s until %s (%d jobs)", username, due.Format(time.RFC3339), len(d.Jobs))
+		return true
+	}
+	return notifyUser(username, userNotifiers(cfg), capDigest(username, cfg.Delivery, d, generateEntryID())) > 0
+}
+
+// queuedAlerts counts the user's alerts waiting in the outbox
+func queuedAlerts(username string) int {
+	count := 0
+	err := dataStore.View(func(tx Tx) error {
+		return tx.ForEach(collOutbox, func(key string, data []byte) error {
+			if strings.HasPrefix(key, username+"/") {
+				count++
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to read the outbox: %v", err)
+	}
+	return count
+}
+
+// flushOutbox sends each user their queued alerts that are due, merged into
+// one digest, unless they are in their quiet hours
+func flushOutbox(now time.Time) {
+	due := make(map[string]map[string]OutboxEntry)
+	err := dataStore.View(func(tx Tx) error {
+		return tx.ForEach(collOutbox, func(key string, data []byte) error {
+			var entry OutboxEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				log.Printf("Skipping unreadable outbox entry %s: %v", key, err)
+				return nil
+			}
+			if entry.DueAt.After(now) {
+				return nil
+			}
+			if due[entry.Username] == nil {
+				due[entry.Username] = make(map[string]OutboxEntry)
+			}
+			due[entry.Username][key] = entry
+			return nil
+		})
+	})
+	if err != nil {
+		log.Printf("Failed to read the outbox: %v", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+
+	allAlerts := loadAllUserAlerts()
+	for username, entries := range due {
+		cfg := allAlerts[username]
+		if _, quiet := cfg.Delivery.quietEnd(now); quiet {
+			continue
+		}
+		outbox.Lock()
+		if outbox.sending[username] {
+			outbox.Unlock()
+			continue
+		}
+		outbox.sending[username] = true
+		outbox.Unlock()
+		go sendQueuedAlerts(username, cfg, entries)
+	}
+}
+
+// sendQueuedAlerts sends the entries as one digest. Entries that fail on
+// every channel are tried again later, up to outboxMaxAttempts times.
+func sendQueuedAlerts(username string, cfg UserAlertConfig, entries map[string]OutboxEntry) {
+	defer func() {
+		outbox.Lock()
+		delete(outbox.sending, username)
+		outbox.Unlock()
+	}()
+
+	var digests []Digest
+	keys := make([]string, 0, len(entries))
+	for key, entry := range entries {
+		digests = append(digests, entry.Digest)
+		keys = append(keys, key)
+	}
+	// Retries of the same entries link to the same history entry
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, ",")))
+	entryID := hex.EncodeToString(sum[:8])
+
+	notifiers := userNotifiers(cfg)
+	sent := 0
+	if len(notifiers) > 0 {
+		sent = notifyUser(username, notifiers, capDigest(username, cfg.Delivery, mergeDigests(digests), entryID))
+	} else {
+		log.Printf("Dropping %d queued alert(s) for %s: no alert channel", len(entries), username)
+	}
+
+	err := dataStore.Update(func(tx Tx) error {
+		for key, entry := range entries {
+			entry.Attempts++
+			if sent > 0 || len(notifiers) == 0 || entry.Attempts >= outboxMaxAttempts {
+				if sent == 0 && len(notifiers) > 0 {
+					log.Printf("Dropping queued alert for %s after %d attempts", username, entry.Attempts)
+				}
+				if err := tx.Delete(collOutbox, key); err != nil {
+					return err
+				}
+				continue
+			}
+			entry.DueAt = time.Now().Add(outboxRetryDelay)
+			if err := tx.Put(collOutbox, key, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update the outbox for %s: %v", username, err)
+	}
+}
+
+// mergeDigests combines queued digests into one, oldest first, listing each
+// job once
+func mergeDigests(digests []Digest) Digest {
+	if len(digests) == 1 {
+		return digests[0]
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Time.Before(digests[j].Time) })
+
+	merged := Digest{Time: time.Now()}
+	var names []string
+	named := make(map[string]bool)
+	listed := make(map[string]bool)
+	for _, d := range digests {
+		if !named[d.ScanName] {
+			named[d.ScanName] = true
+			names = append(names, d.ScanName)
+		}
+		for _, job := range d.Jobs {
+			if job.ID != "" && listed[job.ID] {
+				continue
+			}
+			listed[job.ID] = true
+			merged.Jobs = append(merged.Jobs, job)
+		}
+		merged.Omitted += d.Omitted
+	}
+	merged.ScanName = strings.Join(names, ", ")
+	return merged
+}
+
+// capDigest lists at most the user's maximum number of jobs in the digest.
+// The full list is kept in the user's search history as entryID, and the
+// digest links to it.
+func capDigest(username string, prefs DeliveryPrefs, d Digest, entryID string) Digest {
+	if prefs.MaxJobs <= 0 || len(d.Jobs) <= prefs.MaxJobs {
+		return d
+	}
+
+	saved := false
+	for _, entry := range getUserHistory(username) {
+		saved = saved || entry.ID == entryID
+	}
+	if !saved {
+		jobs := make([]Job, len(d.Jobs))
+		for i, dj := range d.Jobs {
+			jobs[i] = dj.job(d.Time)
+		}
+		resultsFile := saveSearchResults(entryID, TagJobs(jobs, configForUser(username)))
+		if resultsFile != "" {
+			addHistoryEntry(username, SearchHistoryEntry{
+				ID: entryID, Timestamp: d.Time,
+				Type: "alert", Query: d.ScanName,
+				ResultCount: len(jobs), ResultsFile: resultsFile,
+			})
+			saved = true
+		}
+	}
+	if saved && publicURL != "" {
+		d.MoreURL = publicURL + "/?history=" + entryID
+	}
+
+	d.More = len(d.Jobs) - prefs.MaxJobs
+	d.Jobs = d.Jobs[:prefs.MaxJobs]
+	return d
+}
+
+// handleDelivery sets the user's delivery preferences
+func handleDelivery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var prefs DeliveryPrefs
+	if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	prefs.Timezone = strings.TrimSpace(prefs.Timezone)
+	if err := prefs.validate(); err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if prefs.Mode == DeliveryImmediate {
+		prefs.Mode = ""
+	}
+
+	username := r.Header.Get("X-Auth-User")
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		cfg.Delivery = prefs
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save delivery preferences for %s: %v", username, err)
+		jsonError(w, "Failed to save", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuietEnd(t *testing.T) {
+	overnight := DeliveryPrefs{Timezone: "America/New_York", QuietStart: "22:00", QuietEnd: "07:00"}
+	midday := DeliveryPrefs{Timezone: "America/New_York", QuietStart: "12:00", QuietEnd: "13:00"}
+	tests := []struct {
+		name  string
+		prefs DeliveryPrefs
+		at    string
+		until string // empty when not quiet
+	}{
+		{"before overnight quiet hours", overnight, "2026-10-18T21:59:00-04:00", ""},
+		{"start of overnight quiet hours", overnight, "2026-10-18T22:00:00-04:00", "2026-10-19T07:00:00-04:00"},
+		{"before midnight", overnight, "2026-10-18T23:30:00-04:00", "2026-10-19T07:00:00-04:00"},
+		{"after midnight", overnight, "2026-10-19T03:00:00-04:00", "2026-10-19T07:00:00-04:00"},
+		{"end of overnight quiet hours", overnight, "2026-10-19T07:00:00-04:00", ""},
+		{"in the user's time zone", overnight, "2026-10-19T05:30:00Z", "2026-10-19T07:00:00-04:00"},
+		{"midday quiet hours", midday, "2026-10-18T12:30:00-04:00", "2026-10-18T13:00:00-04:00"},
+		{"after midday quiet hours", midday, "2026-10-18T13:00:00-04:00", ""},
+		{"no quiet hours", DeliveryPrefs{}, "2026-10-18T23:30:00-04:00", ""},
+	}
+	for _, tt := range tests {
+		at, err := time.Parse(time.RFC3339, tt.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		until, quiet := tt.prefs.quietEnd(at)
+		if quiet != (tt.until != "") {
+			t.Errorf("%s: quiet = %v, want %v", tt.name, quiet, !quiet)
+			continue
+		}
+		if quiet && until.Format(time.RFC3339) != tt.until {
+			t.Errorf("%s: quiet until %s, want %s", tt.name, until.Format(time.RFC3339), tt.until)
+		}
+	}
+}
+
+func TestNextDelivery(t *testing.T) {
+	quiet := func(mode string, hour int) DeliveryPrefs {
+		return DeliveryPrefs{Mode: mode, DailyHour: hour, Timezone: "America/New_York", QuietStart: "22:00", QuietEnd: "07:00"}
+	}
+	tests := []struct {
+		name  string
+		prefs DeliveryPrefs
+		now   string
+		want  string
+	}{
+		{"immediate", quiet(DeliveryImmediate, 0), "2026-10-18T12:00:00-04:00", "2026-10-18T12:00:00-04:00"},
+		{"immediate in quiet hours", quiet(DeliveryImmediate, 0), "2026-10-18T23:00:00-04:00", "2026-10-19T07:00:00-04:00"},
+		{"hourly", quiet(DeliveryHourly, 0), "2026-10-18T10:15:00-04:00", "2026-10-18T11:00:00-04:00"},
+		{"hourly into quiet hours", quiet(DeliveryHourly, 0), "2026-10-18T21:30:00-04:00", "2026-10-19T07:00:00-04:00"},
+		{"daily later today", quiet(DeliveryDaily, 8), "2026-10-18T07:30:00-04:00", "2026-10-18T08:00:00-04:00"},
+		{"daily tomorrow", quiet(DeliveryDaily, 8), "2026-10-18T09:00:00-04:00", "2026-10-19T08:00:00-04:00"},
+		{"daily in quiet hours", quiet(DeliveryDaily, 23), "2026-10-18T12:00:00-04:00", "2026-10-19T07:00:00-04:00"},
+	}
+	for _, tt := range tests {
+		now, err := time.Parse(time.RFC3339, tt.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tt.prefs.nextDelivery(now).In(now.Location()).Format(time.RFC3339); got != tt.want {
+			t.Errorf("%s: nextDelivery(%s) = %s, want %s", tt.name, tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestCapDigest(t *testing.T) {
+	newTestStore(t)
+	prevURL := publicURL
+	publicURL = "https://jobs.example.com"
+	t.Cleanup(func() { publicURL = prevURL })
+
+	d := Digest{ScanName: "Red team", Time: time.Now()}
+	for i := 0; i < 5; i++ {
+		d.Jobs = append(d.Jobs, DigestJob{ID: fmt.Sprintf("job-%d", i), Title: "Red Teamer", Company: "Acme", URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	if got := capDigest("alice", DeliveryPrefs{}, d, "entry-1"); len(got.Jobs) != 5 || got.More != 0 {
+		t.Errorf("uncapped digest lists %d jobs, %d more", len(got.Jobs), got.More)
+	}
+
+	prefs := DeliveryPrefs{MaxJobs: 2}
+	got := capDigest("alice", prefs, d, "entry-1")
+	if len(got.Jobs) != 2 || got.Jobs[0].ID != "job-0" || got.Jobs[1].ID != "job-1" {
+		t.Errorf("capped digest lists %+v, want the first 2 jobs", got.Jobs)
+	}
+	if got.More != 3 {
+		t.Errorf("More = %d, want 3", got.More)
+	}
+	if got.MoreURL != "https://jobs.example.com/?history=entry-1" {
+		t.Errorf("MoreURL = %q", got.MoreURL)
+	}
+	if len(d.Jobs) != 5 {
+		t.Error("capDigest truncated the caller's digest")
+	}
+
+	// A retry of the same alert reuses its history entry
+	capDigest("alice", prefs, d, "entry-1")
+	history := getUserHistory("alice")
+	if len(history) != 1 || history[0].ID != "entry-1" || history[0].ResultCount != 5 {
+		t.Errorf("history = %+v, want one entry-1 of 5 jobs", history)
+	}
+}
+
+// webhookSink is a generic webhook receiver answering with status
+type webhookSink struct {
+	mu       sync.Mutex
+	status   int
+	requests int
+}
+
+func newWebhookSink(t *testing.T) (*webhookSink, WebhookChannel) {
+	t.Helper()
+	sink := &webhookSink{status: http.StatusOK}
+	srv := httptest.NewServer(sink)
+	// the server is on loopback, which notifyClient refuses
+	prev := notifyClient
+	notifyClient = &http.Client{}
+	t.Cleanup(func() {
+		srv.Close()
+		notifyClient = prev
+	})
+	return sink, WebhookChannel{ID: "hook", Type: WebhookGeneric, Name: "Test", URL: srv.URL, Enabled: true, Verified: true}
+}
+
+func (s *webhookSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	w.WriteHeader(s.status)
+}
+
+func (s *webhookSink) answer(status int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+	n := s.requests
+	s.requests = 0
+	return n
+}
+
+func TestSendQueuedAlertsRetries(t *testing.T) {
+	newTestStore(t)
+	sink, hook := newWebhookSink(t)
+	cfg := UserAlertConfig{Webhooks: []WebhookChannel{hook}}
+	queue := func(attempts int) map[string]OutboxEntry {
+		entries := map[string]OutboxEntry{
+			"alice/1": {Username: "alice", Digest: newDigest("Daily", []Job{{ID: "a", Title: "A"}}), Attempts: attempts},
+			"alice/2": {Username: "alice", Digest: newDigest("Weekly", []Job{{ID: "b", Title: "B"}}), Attempts: attempts},
+		}
+		err := dataStore.Update(func(tx Tx) error {
+			for key, entry := range entries {
+				if err := tx.Put(collOutbox, key, entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+	stored := func() map[string]OutboxEntry {
+		var entries map[string]OutboxEntry
+		err := dataStore.View(func(tx Tx) error {
+			var err error
+			entries, err = loadCollection[OutboxEntry](tx, collOutbox)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	// A failed send is retried outboxRetryDelay later
+	sink.answer(http.StatusBadRequest)
+	start := time.Now()
+	sendQueuedAlerts("alice", cfg, queue(0))
+	if n := sink.answer(http.StatusBadRequest); n != 1 {
+		t.Errorf("queued alerts sent as %d requests, want one merged digest", n)
+	}
+	entries := stored()
+	if len(entries) != 2 {
+		t.Fatalf("after a failed send the outbox holds %d entries, want 2", len(entries))
+	}
+	for key, entry := range entries {
+		if entry.Attempts != 1 {
+			t.Errorf("%s: attempts = %d, want 1", key, entry.Attempts)
+		}
+		if retry := entry.DueAt.Sub(start); retry < outboxRetryDelay || retry > outboxRetryDelay+time.Minute {
+			t.Errorf("%s: retried in %s, want %s", key, retry, outboxRetryDelay)
+		}
+	}
+
+	// and dropped on the last attempt
+	sendQueuedAlerts("alice", cfg, queue(outboxMaxAttempts-1))
+	if entries := stored(); len(entries) != 0 {
+		t.Errorf("after the last attempt the outbox holds %+v", entries)
+	}
+
+	// A sent alert leaves the outbox
+	sink.answer(http.StatusOK)
+	sendQueuedAlerts("alice", cfg, queue(2))
+	if n := sink.answer(http.StatusOK); n != 1 {
+		t.Errorf("sent %d requests, want 1", n)
+	}
+	if entries := stored(); len(entries) != 0 {
+		t.Errorf("after sending the outbox holds %+v", entries)
+	}
+
+	// as does one with nowhere to go
+	sendQueuedAlerts("alice", UserAlertConfig{}, queue(0))
+	if entries := stored(); len(entries) != 0 {
+		t.Errorf("without a channel the outbox holds %+v", entries)
+	}
+}
@@ -38,13 +38,19 @@ case "${1:-web}" in
         run_scraper
         start_web
         ;;
+    validate-config)
+        # Check config.yaml and exit non-zero if it has errors
+        cd /app
+        exec /app/jobtracker -validate-config
+        ;;
     *)
-        echo "Usage: docker run ... [scrape|web|scrape-and-web]"
+        echo "Usage: docker run ... [scrape|web|scrape-and-web|validate-config]"
         echo ""
         echo "Modes:"
         echo "  scrape         - Run scraper once and exit"
         echo "  web            - Start web server only (default)"
         echo "  scrape-and-web - Run scraper then start web server"
+        echo "  validate-config - Check config.yaml and exit"
         echo ""
         echo "Environment variables:"
         echo "  TELEGRAM_BOT_TOKEN    - Telegram bot token for notifications"
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// SMTPConfig is the server's outgoing mail settings, read from the SMTP_*
+// environment variables. Email alerts are offered when Host and From are set.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // address or "Name <address>"
+	// TLS is "starttls" (upgrade the connection, the default), "tls"
+	// (implicit TLS, the default on port 465) or "none" for local relays
+	// and test sinks
+	TLS string
+}
+
+// smtpTimeout bounds a whole SMTP conversation
+const smtpTimeout = 30 * time.Second
+
+// smtpRootCAs verifies the SMTP server's certificate; nil uses the system
+// roots
+var smtpRootCAs *x509.CertPool
+
+// Verification codes expire after emailCodeTTL, allow emailCodeAttempts
+// guesses and can be resent every emailCodeInterval
+const (
+	emailCodeTTL      = 30 * time.Minute
+	emailCodeAttempts = 5
+	emailCodeInterval = time.Minute
+)
+
+// EmailAlertConfig is a user's email alert settings. The address is verified
+// by entering a code sent to it; alerts only go to verified addresses.
+type EmailAlertConfig struct {
+	Enabled  bool   `json:"enabled"`
+	Address  string `json:"address"`
+	Verified bool   `json:"verified"`
+	// CodeHash is the SHA-256 of the pending verification code
+	CodeHash     string    `json:"code_hash,omitempty"`
+	CodeSentAt   time.Time `json:"code_sent_at,omitempty"`
+	CodeAttempts int       `json:"code_attempts,omitempty"`
+}
+
+// active reports whether alerts should be emailed
+func (e EmailAlertConfig) active() bool {
+	return e.Enabled && e.Verified && e.Address != "" && config.SMTP.configured()
+}
+
+func loadSMTPConfig() (SMTPConfig, error) {
+	cfg := SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     587,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     getEnvOrDefault("SMTP_FROM", os.Getenv("SMTP_USERNAME")),
+		TLS:      strings.ToLower(os.Getenv("SMTP_TLS")),
+	}
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 {
+			return SMTPConfig{}, fmt.Errorf("invalid SMTP_PORT %q", port)
+		}
+		cfg.Port = n
+	}
+	switch cfg.TLS {
+	case "":
+		cfg.TLS = "starttls"
+		if cfg.Port == 465 {
+			cfg.TLS = "tls"
+		}
+	case "starttls", "tls", "none":
+	default:
+		return SMTPConfig{}, fmt.Errorf("invalid SMTP_TLS %q (expected starttls, tls or none)", cfg.TLS)
+	}
+	if cfg.From != "" {
+		if _, err := mail.ParseAddress(cfg.From); err != nil {
+			return SMTPConfig{}, fmt.Errorf("invalid SMTP_FROM %q: %v", cfg.From, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (c SMTPConfig) configured() bool {
+	return c.Host != "" && c.From != ""
+}
+
+// sendEmail sends a message with plain-text and HTML alternatives through
+// the configured SMTP server
+func sendEmail(to, subject, text, html string) error {
+	server := config.SMTP
+	if !server.configured() {
+		return fmt.Errorf("email is not configured on this server")
+	}
+	from, err := mail.ParseAddress(server.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %v", err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid address: %v", err)
+	}
+	msg, err := buildEmail(from, rcpt, subject, text, html)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+	tlsConfig := &tls.Config{ServerName: server.Host, RootCAs: smtpRootCAs}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if server.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, server.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer c.Close()
+
+	if server.TLS == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS (set SMTP_TLS=none to send unencrypted)", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if server.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", server.Username, server.Password, server.Host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return fmt.Errorf("mail from: %w", err)
+	}
+	if err := c.Rcpt(rcpt.Address); err != nil {
+		return fmt.Errorf("rcpt to: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	return c.Quit()
+}
+
+// buildEmail renders a multipart/alternative message
+func buildEmail(from, to *mail.Address, subject, text, html string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 12)
+	rand.Read(id)
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+	subject = strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' {
+			return ' '
+		}
+		return r
+	}, subject)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from.String())
+	fmt.Fprintf(&msg, "To: %s\r\n", to.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+var emailFuncs = map[string]interface{}{
+	"inc": func(i int) int { return i + 1 },
+}
+
+var digestText = texttemplate.Must(texttemplate.New("digest").Funcs(emailFuncs).Parse(
+	`{{if .Test}}SalarySleuth Test
+
+Email alerts are working. You will receive job alerts at this address.
+{{else}}SalarySleuth Alert
+Schedule: {{.ScanName}}
+{{if .Jobs}}Found {{.Total}} new job(s)
+{{range $i, $job := .Jobs}}
+{{inc $i}}. {{$job.Title}}{{if $job.ChangeLabel}} ({{$job.ChangeLabel}}){{end}}
+   {{$job.Company}}{{if $job.Location}} - {{$job.Location}}{{end}}
+{{- if $job.SalaryRange}}
+   Posted: {{$job.SalaryRange}}{{end}}
+{{- if $job.LevelSalary}}
+   Levels.fyi: {{$job.LevelSalary}}{{end}}
+   {{$job.URL}}
+{{end}}{{else}}
+No new jobs found this scan.
+{{end}}{{with .MoreNote}}
+{{.}}{{if $.MoreURL}} {{$.MoreURL}}{{end}}
+{{end}}{{with .OmittedNote}}
+{{.}}
+{{end}}{{end}}
+{{.Date}}
+`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(emailFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="margin:0;padding:24px;background:#0a0e14;font-family:Arial,Helvetica,sans-serif;color:#e6e6e6">
+<table role="presentation" width="100%" cellpadding="0" cellspacing="0" style="max-width:640px;margin:0 auto">
+<tr><td style="padding-bottom:16px">
+{{- if .Test}}
+	<div style="font-size:20px;font-weight:bold;color:#00ff88">SalarySleuth Test</div>
+	<div style="font-size:14px;color:#8b949e">Email alerts are working. You will receive job alerts at this address.</div>
+{{- else}}
+	<div style="font-size:20px;font-weight:bold;color:#00ff88">SalarySleuth Alert</div>
+	<div style="font-size:14px;color:#8b949e">Schedule: <strong style="color:#e6e6e6">{{.ScanName}}</strong></div>
+	<div style="font-size:14px;color:#8b949e">{{if .Jobs}}Found <strong style="color:#e6e6e6">{{.Total}}</strong> new job(s){{else}}No new jobs found this scan.{{end}}</div>
+{{- end}}
+</td></tr>
+{{range $i, $job := .Jobs}}
+<tr><td style="padding:12px 16px;background:#151b23;border:1px solid #30363d;border-radius:8px">
+	<div style="font-size:16px;font-weight:bold"><a href="{{$job.URL}}" style="color:#e6e6e6;text-decoration:none">{{inc $i}}. {{$job.Title}}</a></div>
+	{{if $job.ChangeLabel}}<div style="font-size:12px;color:#ffa502;margin-top:4px">{{$job.ChangeLabel}}</div>{{end}}
+	<div style="font-size:13px;color:#8b949e;margin-top:4px">{{$job.Company}}{{if $job.Location}} &middot; {{$job.Location}}{{end}}</div>
+	{{if $job.SalaryRange}}<div style="font-size:13px;color:#00ff88;margin-top:4px">Posted: {{$job.SalaryRange}}</div>{{end}}
+	{{if $job.LevelSalary}}<div style="font-size:13px;color:#00ff88;margin-top:4px">Levels.fyi: {{$job.LevelSalary}}</div>{{end}}
+	<div style="margin-top:8px"><a href="{{$job.URL}}" style="font-size:13px;color:#00d4ff">Apply &rarr;</a></div>
+</td></tr>
+<tr><td style="height:8px"></td></tr>
+{{end}}
+{{with .MoreNote}}<tr><td style="padding-top:8px;font-size:13px;color:#8b949e">{{.}}{{if $.MoreURL}} <a href="{{$.MoreURL}}" style="color:#00d4ff">See them all &rarr;</a>{{end}}</td></tr>{{end}}
+{{with .OmittedNote}}<tr><td style="padding-top:8px;font-size:13px;color:#8b949e">{{.}}</td></tr>{{end}}
+<tr><td style="padding-top:8px;font-size:12px;color:#8b949e">{{.Date}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// emailRetry rides out brief SMTP outages
+var emailRetry = retryPolicy{Attempts: 3, Backoff: 30 * time.Second, MaxBackoff: 2 * time.Minute}
+
+// emailNotifier emails digests to a user's verified address
+type emailNotifier struct {
+	cfg EmailAlertConfig
+}
+
+func (n emailNotifier) Channel() string { return "email" }
+
+func (n emailNotifier) Notify(d Digest) error {
+	subject, text, html, err := renderEmailDigest(d)
+	if err != nil {
+		return err
+	}
+	return withRetry(emailRetry, func() error {
+		err := sendEmail(n.cfg.Address, subject, text, html)
+		// 5xx replies are permanent failures, such as a rejected recipient
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return &permanentError{err}
+		}
+		return err
+	})
+}
+
+// renderEmailDigest renders a digest as one message with plain-text and
+// HTML parts
+func renderEmailDigest(d Digest) (subject, text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+	if err := digestText.Execute(&textBuf, d); err != nil {
+		return "", "", "", err
+	}
+	if err := digestHTML.Execute(&htmlBuf, d); err != nil {
+		return "", "", "", err
+	}
+	switch {
+	case d.Test:
+		subject = "SalarySleuth test message"
+	case len(d.Jobs) == 0:
+		subject = "SalarySleuth: no new jobs for " + d.ScanName
+	default:
+		subject = fmt.Sprintf("SalarySleuth: %d new job(s) for %s", d.Total(), d.ScanName)
+	}
+	return subject, textBuf.String(), htmlBuf.String(), nil
+}
+
+func hashEmailCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendEmailVerificationCode emails a new code to the user's address and
+// records its hash. The send is reserved in the store first, so requests
+// racing the SMTP conversation can't each send a code.
+func sendEmailVerificationCode(username string) error {
+	var address string
+	sentAt := time.Now()
+	err := updateUserAlerts(username, func(stored *UserAlertConfig) error {
+		if stored.Email.Address == "" {
+			return fmt.Errorf("enter an email address first")
+		}
+		if sentAt.Sub(stored.Email.CodeSentAt) < emailCodeInterval {
+			return fmt.Errorf("a code was just sent; wait a minute before asking for another")
+		}
+		address = stored.Email.Address
+		stored.Email.CodeHash = ""
+		stored.Email.CodeSentAt = sentAt
+		stored.Email.CodeAttempts = 0
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	text := fmt.Sprintf("Your SalarySleuth verification code is %s\n\nEnter it in Alerts & Schedules to start receiving job alerts at this address. It expires in %d minutes.\n", code, int(emailCodeTTL.Minutes()))
+	html := fmt.Sprintf(`<p>Your SalarySleuth verification code is</p><p style="font-size:28px;font-weight:bold;letter-spacing:4px">%s</p><p>Enter it in Alerts &amp; Schedules to start receiving job alerts at this address. It expires in %d minutes.</p>`, code, int(emailCodeTTL.Minutes()))
+	if err := sendEmail(address, "SalarySleuth verification code", text, html); err != nil {
+		return err
+	}
+
+	return updateUserAlerts(username, func(stored *UserAlertConfig) error {
+		// Don't attach the code to an address changed while sending
+		if stored.Email.Address != address || !stored.Email.CodeSentAt.Equal(sentAt) {
+			return nil
+		}
+		stored.Email.CodeHash = hashEmailCode(code)
+		return nil
+	})
+}
+
+// verifyEmailCode checks a code against the pending one, verifying and
+// enabling the address when it matches. Failed guesses count towards the
+// code's attempts.
+func verifyEmailCode(username, code string) (bool, error) {
+	var matched bool
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		em := &cfg.Email
+		if em.CodeHash == "" || time.Since(em.CodeSentAt) > emailCodeTTL || em.CodeAttempts >= emailCodeAttempts {
+			return nil
+		}
+		if subtle.ConstantTimeCompare([]byte(hashEmailCode(strings.TrimSpace(code))), []byte(em.CodeHash)) != 1 {
+			em.CodeAttempts++
+			return nil
+		}
+		matched = true
+		em.Verified = true
+		em.Enabled = true
+		em.CodeHash = ""
+		em.CodeAttempts = 0
+		return nil
+	})
+	return matched, err
+}
+
+// handleEmailAlerts saves the user's email alert address and whether alerts
+// are emailed. Changing the address requires verifying it again.
+func handleEmailAlerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Address string `json:"address"`
+		Enabled bool   `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Address = strings.TrimSpace(req.Address)
+	if req.Address != "" {
+		addr, err := mail.ParseAddress(req.Address)
+		if err != nil || addr.Name != "" {
+			jsonError(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+	}
+
+	username := r.Header.Get("X-Auth-User")
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		if !strings.EqualFold(cfg.Email.Address, req.Address) {
+			cfg.Email = EmailAlertConfig{Address: req.Address}
+		}
+		cfg.Email.Enabled = req.Enabled && req.Address != ""
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save email alerts for %s: %v", username, err)
+		jsonError(w, "Failed to save", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleVerifyEmail sends a verification code to the user's address, or
+// checks the code when the request has one
+func handleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	username := r.Header.Get("X-Auth-User")
+
+	if req.Code == "" {
+		if err := sendEmailVerificationCode(username); err != nil {
+			log.Printf("Failed to send verification email for %s: %v", username, err)
+			jsonError(w, fmt.Sprintf("Could not send code: %v", err), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Code sent! Check your inbox."})
+		return
+	}
+
+	matched, err := verifyEmailCode(username, req.Code)
+	if err != nil {
+		log.Printf("Failed to verify email for %s: %v", username, err)
+		jsonError(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !matched {
+		jsonError(w, "Incorrect or expired code", http.StatusBadRequest)
+		return
+	}
+	log.Printf("Email alerts verified for %s", username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Email verified! Alerts will be sent to this address."})
+}
@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sentMail is a message received by smtpStub
+type sentMail struct {
+	From, To string
+	Auth     bool
+	TLS      bool
+	Data     string
+}
+
+// smtpStub is a local SMTP sink accepting every message. With a TLS
+// config it offers STARTTLS.
+type smtpStub struct {
+	ln   net.Listener
+	tls  *tls.Config
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+func newSMTPStub(t *testing.T, tlsConfig *tls.Config) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln, tls: tlsConfig}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) messages() []sentMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentMail(nil), s.sent...)
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	var msg sentMail
+
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"):
+			if s.tls != nil && !msg.TLS {
+				reply("250-stub")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-stub")
+			}
+			reply("250 AUTH PLAIN")
+		case cmd == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, msg.TLS = tlsConn, bufio.NewReader(tlsConn), true
+		case strings.HasPrefix(cmd, "AUTH"):
+			msg.Auth = true
+			reply("235 ok")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			msg.From = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			msg.To = strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>")
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.Data = data.String()
+			s.mu.Lock()
+			s.sent = append(s.sent, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// stubTLS returns a server config with a self-signed certificate for
+// 127.0.0.1 and a pool that trusts it
+func stubTLS(t *testing.T) (*tls.Config, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "smtp stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, pool
+}
+
+// useSMTP points the server's SMTP settings at the stub for the test
+func useSMTP(t *testing.T, stub *smtpStub, mode string, roots *x509.CertPool) {
+	t.Helper()
+	prevSMTP, prevRoots := config.SMTP, smtpRootCAs
+	config.SMTP = SMTPConfig{Host: "127.0.0.1", Port: stub.port(), From: "SalarySleuth <alerts@example.com>", TLS: mode}
+	smtpRootCAs = roots
+	t.Cleanup(func() { config.SMTP, smtpRootCAs = prevSMTP, prevRoots })
+}
+
+func TestSendEmailPlain(t *testing.T) {
+	stub := newSMTPStub(t, nil)
+	useSMTP(t, stub, "none", nil)
+
+	if err := sendEmail("user@example.com", "Hello", "plain body", "<p>html body</p>"); err != nil {
+		t.Fatal(err)
+	}
+	sent := stub.messages()
+	if len(sent) != 1 {
+		t.Fatalf("stub received %d messages, want 1", len(sent))
+	}
+	if sent[0].From != "alerts@example.com" || sent[0].To != "user@example.com" {
+		t.Errorf("envelope = %s -> %s", sent[0].From, sent[0].To)
+	}
+	if sent[0].TLS || sent[0].Auth {
+		t.Errorf("plain send used TLS %v, auth %v", sent[0].TLS, sent[0].Auth)
+	}
+	if !strings.Contains(sent[0].Data, "Subject: Hello\r\n") {
+		t.Errorf("message has no subject:\n%s", sent[0].Data)
+	}
+}
+
+func TestSendEmailSTARTTLS(t *testing.T) {
+	serverTLS, roots := stubTLS(t)
+	stub := newSMTPStub(t, serverTLS)
+	useSMTP(t, stub, "starttls", roots)
+	config.SMTP.Username, config.SMTP.Password = "alerts", "secret"
+
+	if err := sendEmail("user@example.com", "Hello", "plain body", "<p>html body</p>"); err != nil {
+		t.Fatal(err)
+	}
+	sent := stub.messages()
+	if len(sent) != 1 {
+		t.Fatalf("stub received %d messages, want 1", len(sent))
+	}
+	if !sent[0].TLS || !sent[0].Auth {
+		t.Errorf("STARTTLS send used TLS %v, auth %v", sent[0].TLS, sent[0].Auth)
+	}
+
+	// Without the stub's certificate trusted the upgrade fails
+	smtpRootCAs = x509.NewCertPool()
+	if err := sendEmail("user@example.com", "Hello", "plain", "<p>html</p>"); err == nil {
+		t.Error("sent over STARTTLS to an untrusted server")
+	}
+}
+
+func TestSendEmailSTARTTLSUnsupported(t *testing.T) {
+	stub := newSMTPStub(t, nil)
+	useSMTP(t, stub, "starttls", nil)
+
+	err := sendEmail("user@example.com", "Hello", "plain", "<p>html</p>")
+	if err == nil || !strings.Contains(err.Error(), "does not support STARTTLS") {
+		t.Errorf("sendEmail = %v, want a STARTTLS error", err)
+	}
+	if n := len(stub.messages()); n != 0 {
+		t.Errorf("stub received %d messages over an unencrypted connection", n)
+	}
+}
+
+func TestBuildEmail(t *testing.T) {
+	from := &mail.Address{Name: "SalarySleuth", Address: "alerts@example.com"}
+	to := &mail.Address{Address: "user@example.com"}
+	raw, err := buildEmail(from, to, "Jobs für dich\r\nBcc: evil@example.com", "plain text", "<p>html</p>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Bcc"); got != "" {
+		t.Errorf("subject injected a Bcc header: %q", got)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Jobs für dich  Bcc: evil@example.com" {
+		t.Errorf("Subject = %q (%v)", subject, err)
+	}
+	if got := msg.Header.Get("From"); got != `"SalarySleuth" <alerts@example.com>` {
+		t.Errorf("From = %q", got)
+	}
+	if got := msg.Header.Get("To"); got != "<user@example.com>" {
+		t.Errorf("To = %q", got)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v)", msg.Header.Get("Content-Type"), err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", "plain text"},
+		{"text/html; charset=utf-8", "<p>html</p>"},
+	} {
+		part, err := parts.NextRawPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("part Content-Type = %q, want %q", got, want.contentType)
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(part))
+		if string(body) != want.body {
+			t.Errorf("part body = %q, want %q", body, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("message has more than two parts (%v)", err)
+	}
+}
+
+var sentCode = regexp.MustCompile(`verification code is (\d{6})`)
+
+// lastCode returns the code in the last message the stub received
+func lastCode(t *testing.T, stub *smtpStub) string {
+	t.Helper()
+	sent := stub.messages()
+	if len(sent) == 0 {
+		t.Fatal("no code was sent")
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(sent[len(sent)-1].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	decoded, _ := io.ReadAll(quotedprintable.NewReader(strings.NewReader(string(body))))
+	m := sentCode.FindSubmatch(decoded)
+	if m == nil {
+		t.Fatalf("no code in message:\n%s", decoded)
+	}
+	return string(m[1])
+}
+
+// allowResend backdates the user's last code so another can be sent
+func allowResend(t *testing.T, username string) {
+	t.Helper()
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		cfg.Email.CodeSentAt = cfg.Email.CodeSentAt.Add(-emailCodeInterval)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmailVerification(t *testing.T) {
+	newTestStore(t)
+	stub := newSMTPStub(t, nil)
+	useSMTP(t, stub, "none", nil)
+
+	if err := sendEmailVerificationCode("alice"); err == nil {
+		t.Error("sent a code without an address")
+	}
+	err := updateUserAlerts("alice", func(cfg *UserAlertConfig) error {
+		cfg.Email.Address = "alice@example.com"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sendEmailVerificationCode("alice"); err != nil {
+		t.Fatal(err)
+	}
+	code := lastCode(t, stub)
+	if err := sendEmailVerificationCode("alice"); err == nil {
+		t.Error("resent a code straight away")
+	}
+
+	if ok, err := verifyEmailCode("alice", "not-it"); ok || err != nil {
+		t.Errorf("wrong code verified = %v, %v", ok, err)
+	}
+	if ok, err := verifyEmailCode("alice", " "+code+" "); !ok || err != nil {
+		t.Fatalf("right code verified = %v, %v", ok, err)
+	}
+	em := loadUserAlerts("alice").Email
+	if !em.Verified || !em.Enabled || em.CodeHash != "" {
+		t.Errorf("after verifying, email = %+v", em)
+	}
+	if ok, _ := verifyEmailCode("alice", code); ok {
+		t.Error("code verified twice")
+	}
+}
+
+func TestEmailVerificationAttempts(t *testing.T) {
+	newTestStore(t)
+	stub := newSMTPStub(t, nil)
+	useSMTP(t, stub, "none", nil)
+	updateUserAlerts("bob", func(cfg *UserAlertConfig) error {
+		cfg.Email.Address = "bob@example.com"
+		return nil
+	})
+
+	if err := sendEmailVerificationCode("bob"); err != nil {
+		t.Fatal(err)
+	}
+	code := lastCode(t, stub)
+	for i := 0; i < emailCodeAttempts; i++ {
+		verifyEmailCode("bob", "000000x")
+	}
+	if ok, _ := verifyEmailCode("bob", code); ok {
+		t.Error("code verified after the attempt limit")
+	}
+
+	// A new code starts over
+	allowResend(t, "bob")
+	if err := sendEmailVerificationCode("bob"); err != nil {
+		t.Fatal(err)
+	}
+	newCode := lastCode(t, stub)
+	if newCode != code {
+		if ok, _ := verifyEmailCode("bob", code); ok {
+			t.Error("the old code verified after a new one was sent")
+		}
+	}
+	if ok, err := verifyEmailCode("bob", newCode); !ok || err != nil {
+		t.Errorf("new code verified = %v, %v", ok, err)
+	}
+}
+
+func TestEmailVerificationParallel(t *testing.T) {
+	newTestStore(t)
+	stub := newSMTPStub(t, nil)
+	useSMTP(t, stub, "none", nil)
+	updateUserAlerts("carol", func(cfg *UserAlertConfig) error {
+		cfg.Email.Address = "carol@example.com"
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- sendEmailVerificationCode("carol")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	sent := 0
+	for err := range errs {
+		if err == nil {
+			sent++
+		}
+	}
+	if sent != 1 || len(stub.messages()) != 1 {
+		t.Errorf("%d parallel requests succeeded and %d emails were sent, want 1", sent, len(stub.messages()))
+	}
+}
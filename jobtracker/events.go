@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// events is where custom searches and manual refreshes publish their
+// progress for the dashboard to stream
+var events = newEventHub()
+
+// eventPing is how often an idle stream gets a comment, so proxies don't
+// close it
+const eventPing = 15 * time.Second
+
+// eventBuffer is how many events a slow subscriber can fall behind by before
+// it is dropped. The browser reconnects and replays from Last-Event-ID.
+const eventBuffer = 64
+
+// eventLogSize is how many of a topic's latest events are kept to replay
+const eventLogSize = 256
+
+// StreamEvent is an event on a topic. Data is sent as the event's JSON.
+type StreamEvent struct {
+	ID   int
+	Type string
+	Data map[string]interface{}
+}
+
+// eventTopic keeps the last eventLogSize events published since it was
+// reset, so a subscriber that connects late or reconnects gets the ones it
+// missed. It also keeps the latest event of each type from each source, a
+// snapshot of where things stand for a subscriber that missed more.
+type eventTopic struct {
+	log     []StreamEvent // a ring, oldest at head once full
+	head    int
+	evicted int // the ID of the newest event dropped from log
+	latest  map[string]StreamEvent
+	subs    map[chan StreamEvent]bool
+}
+
+// record adds an event to the topic's log and snapshot
+func (t *eventTopic) record(ev StreamEvent) {
+	if len(t.log) < eventLogSize {
+		t.log = append(t.log, ev)
+	} else {
+		t.evicted = t.log[t.head].ID
+		t.log[t.head] = ev
+		t.head = (t.head + 1) % eventLogSize
+	}
+	t.latest[fmt.Sprintf("%s/%v", ev.Type, ev.Data["source"])] = ev
+}
+
+// since returns the topic's events after the one with ID after. Once that
+// one has left the log, it returns the snapshot instead.
+func (t *eventTopic) since(after int) []StreamEvent {
+	var list []StreamEvent
+	if after < t.evicted {
+		for _, ev := range t.latest {
+			list = append(list, ev)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		return list
+	}
+	for i := range t.log {
+		if ev := t.log[(t.head+i)%len(t.log)]; ev.ID > after {
+			list = append(list, ev)
+		}
+	}
+	return list
+}
+
+type eventHub struct {
+	mu     sync.Mutex
+	seq    int // the last event ID, shared by every topic so IDs stay increasing across resets
+	topics map[string]*eventTopic
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{topics: make(map[string]*eventTopic)}
+}
+
+func (h *eventHub) topic(name string) *eventTopic {
+	t := h.topics[name]
+	if t == nil {
+		t = &eventTopic{latest: make(map[string]StreamEvent), subs: make(map[chan StreamEvent]bool)}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// publish sends an event to the topic's subscribers
+func (h *eventHub) publish(name, typ string, data map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t := h.topic(name)
+	h.seq++
+	ev := StreamEvent{ID: h.seq, Type: typ, Data: data}
+	t.record(ev)
+	for ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+			delete(t.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// subscribe returns the topic's events after the one with ID after, or its
+// snapshot if some of them are no longer kept, and a channel for those that
+// follow. The channel is closed if the subscriber
+// falls behind or the topic is reset or dropped.
+func (h *eventHub) subscribe(name string, after int) ([]StreamEvent, chan StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	t := h.topic(name)
+	ch := make(chan StreamEvent, eventBuffer)
+	t.subs[ch] = true
+	return t.since(after), ch
+}
+
+func (h *eventHub) unsubscribe(name string, ch chan StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t := h.topics[name]; t != nil && t.subs[ch] {
+		delete(t.subs, ch)
+		close(ch)
+	}
+}
+
+// reset starts the topic afresh, for a new run of what it reports on
+func (h *eventHub) reset(name string) {
+	h.drop(name)
+	h.mu.Lock()
+	h.topic(name)
+	h.mu.Unlock()
+}
+
+// drop forgets the topic and ends its subscribers' streams
+func (h *eventHub) drop(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t := h.topics[name]; t != nil {
+		for ch := range t.subs {
+			close(ch)
+		}
+		delete(h.topics, name)
+	}
+}
+
+// serveEvents streams a topic as Server-Sent Events until its "done" event,
+// replaying those after the request's Last-Event-ID
+func serveEvents(w http.ResponseWriter, r *http.Request, topic string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	after, _ := strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	missed, ch := events.subscribe(topic, after)
+	defer events.unsubscribe(topic, ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(ev StreamEvent) bool {
+		data, _ := json.Marshal(ev.Data)
+		fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+		flusher.Flush()
+		return ev.Type != "done"
+	}
+	for _, ev := range missed {
+		if !send(ev) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ping := time.NewTicker(eventPing)
+	defer ping.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok || !send(ev) {
+				return
+			}
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
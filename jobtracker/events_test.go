@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestEventReplay(t *testing.T) {
+	h := newEventHub()
+	h.publish("search/1", "source_started", map[string]interface{}{"source": "lever"})
+	h.publish("search/1", "jobs", map[string]interface{}{"source": "lever", "count": 2})
+	for i := 1; i <= eventLogSize+50; i++ {
+		h.publish("search/1", "page", map[string]interface{}{"source": "linkedin", "pages": i})
+	}
+	h.publish("search/1", "jobs", map[string]interface{}{"source": "linkedin", "count": 5})
+	last := h.seq
+
+	if n := len(h.topics["search/1"].log); n != eventLogSize {
+		t.Fatalf("topic keeps %d events, want %d", n, eventLogSize)
+	}
+
+	// Recent events replay as they were
+	missed, ch := h.subscribe("search/1", last-3)
+	h.unsubscribe("search/1", ch)
+	if len(missed) != 3 || missed[0].ID != last-2 || missed[2].ID != last {
+		t.Fatalf("replay after %d = %v, want the last 3 events", last-3, missed)
+	}
+
+	// Once the requested event has gone, the snapshot replays instead
+	for _, after := range []int{0, 5} {
+		missed, ch := h.subscribe("search/1", after)
+		h.unsubscribe("search/1", ch)
+		var types []string
+		for i, ev := range missed {
+			types = append(types, ev.Type+"/"+ev.Data["source"].(string))
+			if i > 0 && ev.ID <= missed[i-1].ID {
+				t.Errorf("snapshot out of order: %v", missed)
+			}
+		}
+		want := []string{"source_started/lever", "jobs/lever", "page/linkedin", "jobs/linkedin"}
+		if len(types) != len(want) {
+			t.Fatalf("snapshot after %d = %v, want %v", after, types, want)
+		}
+		for i := range want {
+			if types[i] != want[i] {
+				t.Fatalf("snapshot after %d = %v, want %v", after, types, want)
+			}
+		}
+		if pages := missed[2].Data["pages"]; pages != eventLogSize+50 {
+			t.Errorf("snapshot has page %v, want the latest", pages)
+		}
+	}
+
+	// A reset topic starts with nothing to replay
+	h.reset("search/1")
+	missed, ch = h.subscribe("search/1", 0)
+	h.unsubscribe("search/1", ch)
+	if len(missed) != 0 {
+		t.Errorf("reset topic replays %v", missed)
+	}
+}
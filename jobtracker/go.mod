@@ -1,5 +1,18 @@
 module github.com/fr4nk3nst1ner/salarysleuth/jobtracker
 
-go 1.18
+go 1.23.0
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	github.com/fr4nk3nst1ner/salarysleuth v0.0.0
+	go.etcd.io/bbolt v1.4.3
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/PuerkitoBio/goquery v1.9.2 // indirect
+	github.com/andybalholm/cascadia v1.3.2 // indirect
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+)
+
+replace github.com/fr4nk3nst1ner/salarysleuth => ../
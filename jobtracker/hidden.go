@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// UserHiddenJobs are the jobs a user has hidden, from the dashboard or the
+// Hide button on a Telegram alert. Hidden jobs are left out of the user's
+// alerts and only shown on the dashboard when asked for.
+type UserHiddenJobs struct {
+	Jobs map[string]time.Time `json:"jobs"` // job ID to when it was hidden
+}
+
+func loadUserHiddenJobs(username string) map[string]bool {
+	var hidden UserHiddenJobs
+	err := dataStore.View(func(tx Tx) error {
+		_, err := tx.Get(collHiddenJobs, username, &hidden)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to load hidden jobs for %s: %v", username, err)
+	}
+	ids := make(map[string]bool, len(hidden.Jobs))
+	for id := range hidden.Jobs {
+		ids[id] = true
+	}
+	return ids
+}
+
+// setJobHidden hides or unhides a job for the user and reports whether that
+// changed anything
+func setJobHidden(username, jobID string, hide bool) (bool, error) {
+	changed := false
+	err := dataStore.Update(func(tx Tx) error {
+		var hidden UserHiddenJobs
+		if _, err := tx.Get(collHiddenJobs, username, &hidden); err != nil {
+			return err
+		}
+		if hidden.Jobs == nil {
+			hidden.Jobs = make(map[string]time.Time)
+		}
+		if _, ok := hidden.Jobs[jobID]; ok == hide {
+			return nil
+		}
+		if hide {
+			hidden.Jobs[jobID] = time.Now()
+		} else {
+			delete(hidden.Jobs, jobID)
+		}
+		changed = true
+		return tx.Put(collHiddenJobs, username, hidden)
+	})
+	return changed, err
+}
+
+// withoutHidden drops the jobs the user has hidden
+func withoutHidden(username string, jobs []Job) []Job {
+	hidden := loadUserHiddenJobs(username)
+	if len(hidden) == 0 {
+		return jobs
+	}
+	var result []Job
+	for _, job := range jobs {
+		if !hidden[job.ID] {
+			result = append(result, job)
+		}
+	}
+	return result
+}
+
+// handleHiddenJobs lists the IDs of the user's hidden jobs (GET), hides a
+// job (POST {"job_id"}) or unhides one (DELETE {"job_id"})
+func handleHiddenJobs(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet {
+		ids := make([]string, 0)
+		for id := range loadUserHiddenJobs(username) {
+			ids = append(ids, id)
+		}
+		json.NewEncoder(w).Encode(ids)
+		return
+	}
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		JobID string `json:"job_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.JobID == "" {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if _, err := setJobHidden(username, req.JobID, r.Method == http.MethodPost); err != nil {
+		log.Printf("Failed to save hidden jobs for %s: %v", username, err)
+		jsonError(w, "Failed to save", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
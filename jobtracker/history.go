@@ -2,24 +2,24 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
-	"os"
-	"path/filepath"
-	"sync"
 	"time"
 )
 
 const historyRetentionDays = 30
 
 type SearchFilters struct {
-	Category     string `json:"category,omitempty"`
-	Level        string `json:"level,omitempty"`
-	Cert         string `json:"certification,omitempty"`
-	Sort         string `json:"sort,omitempty"`
-	RemoteOnly   bool   `json:"remote_only,omitempty"`
-	ShowExcluded bool   `json:"show_excluded,omitempty"`
-	SalaryOnly   bool   `json:"salary_only,omitempty"`
-	SearchText   string `json:"search_text,omitempty"`
+	Category     string  `json:"category,omitempty"`
+	Level        string  `json:"level,omitempty"`
+	Cert         string  `json:"certification,omitempty"`
+	Sort         string  `json:"sort,omitempty"`
+	Status       string  `json:"status,omitempty"` // "" (open jobs), "closed" or "all"
+	RemoteOnly   bool    `json:"remote_only,omitempty"`
+	ShowExcluded bool    `json:"show_excluded,omitempty"`
+	SalaryOnly   bool    `json:"salary_only,omitempty"`
+	MinScore     float64 `json:"min_score,omitempty"`
+	SearchText   string  `json:"search_text,omitempty"`
 }
 
 type SearchHistoryEntry struct {
@@ -37,106 +37,85 @@ type UserHistory struct {
 	Entries  []SearchHistoryEntry `json:"entries"`
 }
 
-var historyMu sync.RWMutex
-
-func historyDir() string {
-	return filepath.Join(config.DataDir, "history")
-}
-
-func userHistoryFile(username string) string {
-	return filepath.Join(historyDir(), username+".json")
-}
-
-func searchResultsDir() string {
-	return filepath.Join(config.DataDir, "search_results")
-}
-
-func ensureHistoryDir() {
-	os.MkdirAll(historyDir(), 0700)
-	os.MkdirAll(searchResultsDir(), 0700)
-}
-
+// saveSearchResults stores a search's results and returns the key its
+// history entry refers to them by
 func saveSearchResults(entryID string, results interface{}) string {
-	filename := entryID + ".json"
-	fp := filepath.Join(searchResultsDir(), filename)
-	data, err := json.Marshal(results)
+	key := entryID + ".json"
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Put(collSearchResults, key, results)
+	})
 	if err != nil {
-		log.Printf("Failed to marshal search results: %v", err)
-		return ""
-	}
-	if err := os.WriteFile(fp, data, 0600); err != nil {
 		log.Printf("Failed to save search results: %v", err)
 		return ""
 	}
-	return filename
-}
-
-func loadSearchResults(filename string) (json.RawMessage, error) {
-	fp := filepath.Join(searchResultsDir(), filename)
-	data, err := os.ReadFile(fp)
-	if err != nil {
-		return nil, err
-	}
-	return json.RawMessage(data), nil
+	return key
 }
 
-func deleteSearchResults(filename string) {
-	if filename == "" {
-		return
-	}
-	fp := filepath.Join(searchResultsDir(), filename)
-	os.Remove(fp)
+func loadSearchResults(key string) (json.RawMessage, error) {
+	var data json.RawMessage
+	err := dataStore.View(func(tx Tx) error {
+		found, err := tx.Get(collSearchResults, key, &data)
+		if err == nil && !found {
+			err = fmt.Errorf("search results %s not found", key)
+		}
+		return err
+	})
+	return data, err
 }
 
 func loadUserHistory(username string) *UserHistory {
-	historyMu.RLock()
-	defer historyMu.RUnlock()
-	return loadUserHistoryUnsafe(username)
-}
-
-func loadUserHistoryUnsafe(username string) *UserHistory {
-	data, err := os.ReadFile(userHistoryFile(username))
+	var hist *UserHistory
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		hist, err = readUserHistory(tx, username)
+		return err
+	})
 	if err != nil {
+		log.Printf("Failed to load history for %s: %v", username, err)
 		return &UserHistory{Username: username, Entries: []SearchHistoryEntry{}}
 	}
-	var hist UserHistory
-	if err := json.Unmarshal(data, &hist); err != nil {
-		return &UserHistory{Username: username, Entries: []SearchHistoryEntry{}}
+	return hist
+}
+
+func readUserHistory(tx Tx, username string) (*UserHistory, error) {
+	hist := &UserHistory{Username: username}
+	if _, err := tx.Get(collHistory, username, hist); err != nil {
+		return nil, err
 	}
 	if hist.Entries == nil {
 		hist.Entries = []SearchHistoryEntry{}
 	}
-	return &hist
-}
-
-func saveUserHistoryUnsafe(hist *UserHistory) error {
-	ensureHistoryDir()
-	data, err := json.MarshalIndent(hist, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(userHistoryFile(hist.Username), data, 0600)
+	return hist, nil
 }
 
-func addHistoryEntry(username string, entry SearchHistoryEntry) {
-	historyMu.Lock()
-	defer historyMu.Unlock()
-
-	hist := loadUserHistoryUnsafe(username)
-	hist.Entries = append(hist.Entries, entry)
-
+// pruneHistory drops entries older than the retention period, along with
+// their stored results
+func pruneHistory(tx Tx, hist *UserHistory) error {
 	cutoff := time.Now().AddDate(0, 0, -historyRetentionDays)
 	pruned := make([]SearchHistoryEntry, 0, len(hist.Entries))
 	for _, e := range hist.Entries {
 		if e.Timestamp.After(cutoff) {
 			pruned = append(pruned, e)
-		} else {
-			deleteSearchResults(e.ResultsFile)
+		} else if e.ResultsFile != "" {
+			if err := tx.Delete(collSearchResults, e.ResultsFile); err != nil {
+				return err
+			}
 		}
 	}
 	hist.Entries = pruned
+	return tx.Put(collHistory, hist.Username, hist)
+}
 
-	if err := saveUserHistoryUnsafe(hist); err != nil {
+func addHistoryEntry(username string, entry SearchHistoryEntry) {
+	err := dataStore.Update(func(tx Tx) error {
+		hist, err := readUserHistory(tx, username)
+		if err != nil {
+			return err
+		}
+		hist.Entries = append(hist.Entries, entry)
+		return pruneHistory(tx, hist)
+	})
+	if err != nil {
 		log.Printf("Failed to save history for %s: %v", username, err)
 	}
 }
@@ -160,38 +139,38 @@ func getUserHistory(username string) []SearchHistoryEntry {
 }
 
 func clearUserHistory(username string) error {
-	historyMu.Lock()
-	defer historyMu.Unlock()
-	existing := loadUserHistoryUnsafe(username)
-	for _, e := range existing.Entries {
-		deleteSearchResults(e.ResultsFile)
-	}
-	hist := &UserHistory{Username: username, Entries: []SearchHistoryEntry{}}
-	return saveUserHistoryUnsafe(hist)
+	return dataStore.Update(func(tx Tx) error {
+		existing, err := readUserHistory(tx, username)
+		if err != nil {
+			return err
+		}
+		for _, e := range existing.Entries {
+			if e.ResultsFile != "" {
+				if err := tx.Delete(collSearchResults, e.ResultsFile); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Put(collHistory, username, &UserHistory{Username: username, Entries: []SearchHistoryEntry{}})
+	})
 }
 
 // getAllUsersHistory returns history for every user (admin-only)
 func getAllUsersHistory() map[string][]SearchHistoryEntry {
-	historyMu.RLock()
-	defer historyMu.RUnlock()
-
 	result := make(map[string][]SearchHistoryEntry)
-	entries, err := os.ReadDir(historyDir())
+	var all map[string]UserHistory
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		all, err = loadCollection[UserHistory](tx, collHistory)
+		return err
+	})
 	if err != nil {
+		log.Printf("Failed to load history: %v", err)
 		return result
 	}
 
 	cutoff := time.Now().AddDate(0, 0, -historyRetentionDays)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		if len(name) < 6 || name[len(name)-5:] != ".json" {
-			continue
-		}
-		username := name[:len(name)-5]
-		hist := loadUserHistoryUnsafe(username)
+	for username, hist := range all {
 		valid := make([]SearchHistoryEntry, 0, len(hist.Entries))
 		for _, e := range hist.Entries {
 			if e.Timestamp.After(cutoff) {
@@ -220,34 +199,20 @@ func runHistoryCleanup() {
 }
 
 func pruneAllHistory() {
-	historyMu.Lock()
-	defer historyMu.Unlock()
-
-	entries, err := os.ReadDir(historyDir())
-	if err != nil {
-		return
-	}
-
-	cutoff := time.Now().AddDate(0, 0, -historyRetentionDays)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		name := entry.Name()
-		if len(name) < 6 || name[len(name)-5:] != ".json" {
-			continue
+	err := dataStore.Update(func(tx Tx) error {
+		all, err := loadCollection[UserHistory](tx, collHistory)
+		if err != nil {
+			return err
 		}
-		username := name[:len(name)-5]
-		hist := loadUserHistoryUnsafe(username)
-		pruned := make([]SearchHistoryEntry, 0, len(hist.Entries))
-		for _, e := range hist.Entries {
-			if e.Timestamp.After(cutoff) {
-				pruned = append(pruned, e)
-			} else {
-				deleteSearchResults(e.ResultsFile)
+		for username, hist := range all {
+			hist.Username = username
+			if err := pruneHistory(tx, &hist); err != nil {
+				return err
 			}
 		}
-		hist.Entries = pruned
-		saveUserHistoryUnsafe(hist)
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to prune history: %v", err)
 	}
 }
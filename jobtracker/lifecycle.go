@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Job statuses. Jobs scraped before statuses existed have none and count as
+// active.
+const (
+	JobActive  = "active"
+	JobMissing = "missing" // absent from recent scrapes, not yet closed
+	JobClosed  = "closed"
+)
+
+const (
+	defaultCloseAfterMisses   = 2
+	defaultRecentlyClosedDays = 14
+)
+
+// JobHistory records every scrape a job was seen in and how its salary and
+// status changed, kept in the job_history collection under the job ID
+type JobHistory struct {
+	Sightings     []time.Time    `json:"sightings"`
+	SalaryChanges []SalaryChange `json:"salary_changes,omitempty"`
+	StatusChanges []StatusChange `json:"status_changes,omitempty"`
+}
+
+// SalaryChange is a change to the posted (salary_range) or Levels.fyi
+// (level_salary) salary of a job
+type SalaryChange struct {
+	At    time.Time `json:"at"`
+	Field string    `json:"field"`
+	From  string    `json:"from,omitempty"`
+	To    string    `json:"to,omitempty"`
+}
+
+type StatusChange struct {
+	At   time.Time `json:"at"`
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+}
+
+func jobStatus(job Job) string {
+	if job.Status == "" {
+		return JobActive
+	}
+	return job.Status
+}
+
+func countOpenJobs(jobs []Job) int {
+	n := 0
+	for _, job := range jobs {
+		if jobStatus(job) != JobClosed {
+			n++
+		}
+	}
+	return n
+}
+
+// daysOpen is how long a job was listed: from the first scrape that found it
+// to the last, which for a closed job is its time to close
+func daysOpen(job Job) float64 {
+	if job.FirstSeen.IsZero() || job.LastSeen.Before(job.FirstSeen) {
+		return 0
+	}
+	days := job.LastSeen.Sub(job.FirstSeen).Hours() / 24
+	return float64(int(days*10)) / 10
+}
+
+func closeAfterMisses() int {
+	cfg, err := LoadConfig()
+	if err != nil || cfg.Scraper.CloseAfterMisses <= 0 {
+		return defaultCloseAfterMisses
+	}
+	return cfg.Scraper.CloseAfterMisses
+}
+
+func recentlyClosedDays(cfg *AppConfig) int {
+	if cfg.Display.RecentlyClosedDays <= 0 {
+		return defaultRecentlyClosedDays
+	}
+	return cfg.Display.RecentlyClosedDays
+}
+
+// dashboardJobs returns the jobs the dashboard shows: open ones and those
+// closed within display.recently_closed_days
+func dashboardJobs(jobs []Job, cfg *AppConfig) []Job {
+	cutoff := time.Now().AddDate(0, 0, -recentlyClosedDays(cfg))
+	result := make([]Job, 0, len(jobs))
+	for _, job := range jobs {
+		if jobStatus(job) == JobClosed && (job.ClosedAt == nil || job.ClosedAt.Before(cutoff)) {
+			continue
+		}
+		result = append(result, job)
+	}
+	return result
+}
+
+// recordJobHistory adds the sightings, salary changes and status changes
+// between two versions of the job store to each job's history. It runs in
+// the transaction that writes the new version.
+func recordJobHistory(tx Tx, before, after JobStore) error {
+	previous := make(map[string]Job, len(before.Jobs))
+	for _, job := range before.Jobs {
+		previous[job.ID] = job
+	}
+
+	for _, job := range after.Jobs {
+		prev, existed := previous[job.ID]
+		var hist JobHistory
+		if _, err := tx.Get(collJobHistory, job.ID, &hist); err != nil {
+			return err
+		}
+		changed := false
+
+		if !existed || !job.LastSeen.Equal(prev.LastSeen) {
+			hist.Sightings = append(hist.Sightings, job.LastSeen)
+			changed = true
+		}
+		for _, field := range []struct {
+			name     string
+			from, to string
+		}{
+			{"salary_range", prev.SalaryRange, job.SalaryRange},
+			{"level_salary", prev.LevelSalary, job.LevelSalary},
+		} {
+			if field.from != field.to {
+				hist.SalaryChanges = append(hist.SalaryChanges, SalaryChange{
+					At: after.LastUpdated, Field: field.name, From: field.from, To: field.to,
+				})
+				changed = true
+			}
+		}
+		from := ""
+		if existed {
+			from = jobStatus(prev)
+		}
+		if to := jobStatus(job); from != to {
+			hist.StatusChanges = append(hist.StatusChanges, StatusChange{At: after.LastUpdated, From: from, To: to})
+			changed = true
+		}
+
+		if changed {
+			if err := tx.Put(collJobHistory, job.ID, hist); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleJobHistory returns a stored job with its sightings, salary and
+// status changes and how long it has been (or was) open
+func handleJobHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	jobID := strings.TrimPrefix(r.URL.Path, "/api/jobs/history/")
+	if jobID == "" {
+		jsonError(w, "Missing job ID", http.StatusBadRequest)
+		return
+	}
+
+	var job Job
+	var hist JobHistory
+	found := false
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		if found, err = tx.Get(collJobs, jobID, &job); err != nil || !found {
+			return err
+		}
+		_, err = tx.Get(collJobHistory, jobID, &hist)
+		return err
+	})
+	if err != nil {
+		jsonError(w, "Failed to load job history", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		jsonError(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if hist.Sightings == nil {
+		hist.Sightings = []time.Time{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job":       job,
+		"status":    jobStatus(job),
+		"days_open": daysOpen(job),
+		"history":   hist,
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessJobs(t *testing.T) {
+	earlier := time.Now().Add(-24 * time.Hour)
+	closedAt := earlier
+	stored := func(id, status string, misses int) Job {
+		job := Job{ID: id, Title: id, FirstSeen: earlier, LastSeen: earlier, Status: status, Misses: misses}
+		if status == JobClosed {
+			job.ClosedAt = &closedAt
+		}
+		return job
+	}
+	type state struct {
+		status string
+		misses int
+	}
+	tests := []struct {
+		name     string
+		existing []Job
+		scraped  []Job
+		wantNew  []string
+		want     map[string]state
+	}{
+		{
+			name:     "new and seen again",
+			existing: []Job{stored("a", JobActive, 0)},
+			scraped:  []Job{{ID: "a"}, {ID: "b"}},
+			wantNew:  []string{"b"},
+			want:     map[string]state{"a": {JobActive, 0}, "b": {JobActive, 0}},
+		},
+		{
+			name:     "first miss",
+			existing: []Job{stored("a", JobActive, 0), stored("b", "", 0)},
+			scraped:  []Job{{ID: "b"}},
+			want:     map[string]state{"a": {JobMissing, 1}, "b": {JobActive, 0}},
+		},
+		{
+			name:     "closed after closeAfter misses",
+			existing: []Job{stored("a", JobMissing, 1), stored("b", JobActive, 0)},
+			scraped:  []Job{{ID: "b"}},
+			want:     map[string]state{"a": {JobClosed, 2}, "b": {JobActive, 0}},
+		},
+		{
+			name:     "closed jobs stop counting misses",
+			existing: []Job{stored("a", JobClosed, 2), stored("b", JobActive, 0)},
+			scraped:  []Job{{ID: "b"}},
+			want:     map[string]state{"a": {JobClosed, 2}, "b": {JobActive, 0}},
+		},
+		{
+			name:     "missing job seen again",
+			existing: []Job{stored("a", JobMissing, 1)},
+			scraped:  []Job{{ID: "a"}},
+			want:     map[string]state{"a": {JobActive, 0}},
+		},
+		{
+			name:     "closed job reopens",
+			existing: []Job{stored("a", JobClosed, 2)},
+			scraped:  []Job{{ID: "a"}},
+			want:     map[string]state{"a": {JobActive, 0}},
+		},
+		{
+			name:     "empty scrape",
+			existing: []Job{stored("a", JobActive, 0), stored("b", JobMissing, 1)},
+			want:     map[string]state{"a": {JobActive, 0}, "b": {JobMissing, 1}},
+		},
+	}
+	for _, tt := range tests {
+		existing := JobStore{LastUpdated: earlier, Jobs: tt.existing}
+		newJobs, store := processJobs(tt.scraped, existing, 2)
+
+		var gotNew []string
+		for _, job := range newJobs {
+			gotNew = append(gotNew, job.ID)
+		}
+		sort.Strings(gotNew)
+		if strings.Join(gotNew, ",") != strings.Join(tt.wantNew, ",") {
+			t.Errorf("%s: new jobs = %v, want %v", tt.name, gotNew, tt.wantNew)
+		}
+		if len(store.Jobs) != len(tt.want) {
+			t.Errorf("%s: store holds %d jobs, want %d", tt.name, len(store.Jobs), len(tt.want))
+		}
+		for _, job := range store.Jobs {
+			want, ok := tt.want[job.ID]
+			if !ok {
+				t.Errorf("%s: unexpected job %s", tt.name, job.ID)
+				continue
+			}
+			if got := (state{jobStatus(job), job.Misses}); got != want {
+				t.Errorf("%s: job %s = %+v, want %+v", tt.name, job.ID, got, want)
+			}
+			if (job.Status == JobClosed) != (job.ClosedAt != nil) {
+				t.Errorf("%s: job %s is %s with ClosedAt %v", tt.name, job.ID, job.Status, job.ClosedAt)
+			}
+		}
+	}
+}
+
+func TestProcessJobsKeepsSalary(t *testing.T) {
+	earlier := time.Now().Add(-24 * time.Hour)
+	existing := JobStore{Jobs: []Job{
+		{ID: "a", SalaryRange: "$150k-$180k", LevelSalary: "$200k", FirstSeen: earlier, LastSeen: earlier},
+		{ID: "b", SalaryRange: "$120k", FirstSeen: earlier, LastSeen: earlier},
+	}}
+	_, store := processJobs([]Job{{ID: "a"}, {ID: "b", SalaryRange: "$130k", LevelSalary: "$140k"}}, existing, 2)
+
+	jobs := make(map[string]Job)
+	for _, job := range store.Jobs {
+		jobs[job.ID] = job
+	}
+	if a := jobs["a"]; a.SalaryRange != "$150k-$180k" || a.LevelSalary != "$200k" {
+		t.Errorf("job without salary in the scrape = %q, %q, want the stored salary", a.SalaryRange, a.LevelSalary)
+	}
+	if b := jobs["b"]; b.SalaryRange != "$130k" || b.LevelSalary != "$140k" {
+		t.Errorf("job with new salary = %q, %q, want the scraped salary", b.SalaryRange, b.LevelSalary)
+	}
+	if a := jobs["a"]; !a.FirstSeen.Equal(earlier) || !a.LastSeen.After(earlier) {
+		t.Errorf("seen job FirstSeen = %s, LastSeen = %s", a.FirstSeen, a.LastSeen)
+	}
+}
+
+func TestRecordJobHistory(t *testing.T) {
+	newTestStore(t)
+	history := func(id string) JobHistory {
+		var hist JobHistory
+		err := dataStore.View(func(tx Tx) error {
+			_, err := tx.Get(collJobHistory, id, &hist)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hist
+	}
+	scrape := func(before, after JobStore) {
+		err := dataStore.Update(func(tx Tx) error {
+			return recordJobHistory(tx, before, after)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t1 := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	t2 := t1.Add(24 * time.Hour)
+	t3 := t2.Add(24 * time.Hour)
+
+	first := JobStore{LastUpdated: t1, Jobs: []Job{{ID: "a", SalaryRange: "$150k", LastSeen: t1, Status: JobActive}}}
+	scrape(JobStore{}, first)
+	second := JobStore{LastUpdated: t2, Jobs: []Job{{ID: "a", SalaryRange: "$160k", LastSeen: t2, Status: JobActive}}}
+	scrape(first, second)
+	// missed in the third scrape: no sighting, but a status change
+	third := JobStore{LastUpdated: t3, Jobs: []Job{{ID: "a", SalaryRange: "$160k", LastSeen: t2, Status: JobMissing, Misses: 1}}}
+	scrape(second, third)
+	// an unchanged store records nothing
+	scrape(third, third)
+
+	hist := history("a")
+	if len(hist.Sightings) != 2 || !hist.Sightings[0].Equal(t1) || !hist.Sightings[1].Equal(t2) {
+		t.Errorf("sightings = %v, want %s and %s", hist.Sightings, t1, t2)
+	}
+	wantSalary := []SalaryChange{
+		{At: t1, Field: "salary_range", To: "$150k"},
+		{At: t2, Field: "salary_range", From: "$150k", To: "$160k"},
+	}
+	if len(hist.SalaryChanges) != len(wantSalary) {
+		t.Fatalf("salary changes = %+v, want %+v", hist.SalaryChanges, wantSalary)
+	}
+	for i, want := range wantSalary {
+		if got := hist.SalaryChanges[i]; !got.At.Equal(want.At) || got.Field != want.Field || got.From != want.From || got.To != want.To {
+			t.Errorf("salary change %d = %+v, want %+v", i, got, want)
+		}
+	}
+	wantStatus := []StatusChange{{At: t1, To: JobActive}, {At: t3, From: JobActive, To: JobMissing}}
+	if len(hist.StatusChanges) != len(wantStatus) {
+		t.Fatalf("status changes = %+v, want %+v", hist.StatusChanges, wantStatus)
+	}
+	for i, want := range wantStatus {
+		if got := hist.StatusChanges[i]; !got.At.Equal(want.At) || got.From != want.From || got.To != want.To {
+			t.Errorf("status change %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
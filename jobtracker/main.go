@@ -1,19 +1,27 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+	bolt "go.etcd.io/bbolt"
 )
 
 // stripANSI removes ANSI escape codes from a string
@@ -53,8 +61,13 @@ type Job struct {
 	SalaryRange string    `json:"salary_range,omitempty"`
 	LevelSalary string    `json:"level_salary,omitempty"`
 	Source      string    `json:"source"`
+	Description string    `json:"description,omitempty"` // Matched excerpt (Greenhouse/Lever only)
 	FirstSeen   time.Time `json:"first_seen"`
 	LastSeen    time.Time `json:"last_seen"`
+	// Status is active, missing (absent from recent scrapes) or closed
+	Status   string     `json:"status,omitempty"`
+	Misses   int        `json:"misses,omitempty"` // consecutive scrapes without this job
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
 }
 
 // JobStore represents the stored job data
@@ -72,6 +85,7 @@ type Config struct {
 	RunScraper       bool
 	Pages            int
 	Description      string
+	SMTP             SMTPConfig
 }
 
 var config Config
@@ -83,6 +97,8 @@ func main() {
 	webPort := flag.Int("port", 8080, "Web server port")
 	pages := flag.Int("pages", 20, "Number of pages to scrape")
 	description := flag.String("description", "Offensive Security", "Job description to search for")
+	validateConfig := flag.Bool("validate-config", false, "Check config.yaml, report any errors and exit")
+	importJSON := flag.String("import-json", "", "Import JSON files from an older data directory into the store and exit")
 	flag.Parse()
 
 	// Determine data directory
@@ -112,11 +128,50 @@ func main() {
 		Description:      *description,
 	}
 
+	if *validateConfig {
+		os.Exit(runValidateConfig())
+	}
+
+	if smtpConfig, err := loadSMTPConfig(); err != nil {
+		log.Printf("Email alerts disabled: %v", err)
+	} else {
+		config.SMTP = smtpConfig
+	}
+
 	// Ensure data directory exists
 	if err := os.MkdirAll(config.DataDir, 0755); err != nil {
 		log.Fatalf("Failed to create data directory: %v", err)
 	}
 
+	// Open the data store, importing the JSON files of older versions the
+	// first time
+	store, err := openStore(filepath.Join(config.DataDir, storeFile))
+	if errors.Is(err, bolt.ErrTimeout) && *runScraper && !*webOnly {
+		// The web server has it open; it runs the scrape for us
+		if err := requestRefresh(); err != nil {
+			log.Fatalf("Data store is in use and the web server could not be asked to scrape: %v", err)
+		}
+		log.Println("The web server has the data store open, so it will run the scrape within a minute (see its log)")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to open data store (stop the web server first if it is running): %v", err)
+	}
+	dataStore = store
+	defer store.Close()
+
+	if *importJSON != "" {
+		code := runImportJSON(*importJSON)
+		store.Close()
+		os.Exit(code)
+	}
+
+	// Refuse to start with a config.yaml whose rules don't compile, rather
+	// than silently filtering with a partial rule set
+	if _, err := LoadConfig(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	if *webOnly {
 		// Run only the web server
 		log.Printf("Starting web server on port %d...\n", config.WebPort)
@@ -181,27 +236,28 @@ func main() {
 		filteredJobs := filterOffsecJobs(jobs)
 		log.Printf("After filtering: %d OSCP/offsec jobs\n", len(filteredJobs))
 
-		// Load existing jobs
-		existingStore := loadJobStore()
-
-		// Find new jobs and update store
-		newJobs, updatedStore := processJobs(filteredJobs, existingStore)
-
-		// Save updated store
-		if err := saveJobStore(updatedStore); err != nil {
+		// Find new jobs and update the store in one transaction
+		var newJobs []Job
+		var updatedStore JobStore
+		err := updateJobStore(func(existingStore JobStore) (JobStore, error) {
+			newJobs, updatedStore = processJobs(filteredJobs, existingStore, closeAfterMisses())
+			return updatedStore, nil
+		})
+		if err != nil {
 			log.Fatalf("Failed to save job store: %v", err)
 		}
 
+		enrichCompanies(updatedStore.Jobs)
+
 		// Notify users who have Telegram alerts configured
 		if len(newJobs) > 0 {
 			log.Printf("Found %d new jobs, notifying subscribed users...\n", len(newJobs))
-			ensureAlertsDir()
 			notifySubscribedUsers("Default OffSec Scan", newJobs)
 		} else {
 			log.Println("No new jobs found")
 		}
 
-		log.Printf("Job tracking complete. %d active jobs in store.\n", len(updatedStore.Jobs))
+		log.Printf("Job tracking complete. %d open jobs in store.\n", countOpenJobs(updatedStore.Jobs))
 	}
 
 	// Start web server if requested or by default after scraping
@@ -212,6 +268,7 @@ func main() {
 		fmt.Println("  -port N    Web server port (default: 8080)")
 		fmt.Println("  -pages N   Number of pages to scrape (default: 20)")
 		fmt.Println("  -description \"text\"  Job description to search for")
+		fmt.Println("  -import-json DIR  Import JSON data files from an older version")
 		fmt.Println("\nExamples:")
 		fmt.Println("  go run . -scrape              # Run scraper and send notifications")
 		fmt.Println("  go run . -web                 # Start web server only")
@@ -318,19 +375,52 @@ func runSalarySleuthWithContext(ctx context.Context, description string, pages i
 	return allJobs, nil
 }
 
-// runSingleSourceSearch runs the scraper for a single source (linkedin, greenhouse, lever)
-func runSingleSourceSearch(ctx context.Context, description string, pages int, source string) ([]Job, error) {
+// scraperProgress is a line the scraper writes to stderr with -progress
+type scraperProgress struct {
+	Event  string `json:"event"` // "page" or "enrich"
+	Source string `json:"source,omitempty"`
+	Pages  int    `json:"pages,omitempty"`
+	Found  int    `json:"found,omitempty"`
+	Done   int    `json:"done,omitempty"`  // companies looked up on Levels.fyi
+	Total  int    `json:"total,omitempty"` // companies to look up
+}
+
+// runSingleSourceSearch runs the scraper for a single source (linkedin, greenhouse, lever).
+// onProgress, when set, is called for each progress line the scraper writes.
+func runSingleSourceSearch(ctx context.Context, description string, pages int, source string, onProgress func(scraperProgress)) ([]Job, error) {
 	salarysleuthPath := findSalarySleuthExecutable()
 
 	cmd := exec.CommandContext(ctx, salarysleuthPath,
 		"-nobanner",
+		"-progress",
 		"-pages", fmt.Sprintf("%d", pages),
 		"-source", source,
 		"-description", description,
 	)
-
-	output, err := cmd.CombinedOutput()
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		return nil, fmt.Errorf("%s error: %v", source, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%s error: %v", source, err)
+	}
+
+	lines := bufio.NewScanner(stderr)
+	for lines.Scan() {
+		line, ok := strings.CutPrefix(lines.Text(), "PROGRESS ")
+		if !ok || onProgress == nil {
+			continue
+		}
+		var p scraperProgress
+		if json.Unmarshal([]byte(line), &p) == nil {
+			onProgress(p)
+		}
+	}
+	io.Copy(io.Discard, stderr) // in case a line was too long to scan
+
+	if err := cmd.Wait(); err != nil {
 		if ctx.Err() == context.Canceled {
 			return nil, fmt.Errorf("search cancelled")
 		}
@@ -340,7 +430,7 @@ func runSingleSourceSearch(ctx context.Context, description string, pages int, s
 		return nil, fmt.Errorf("%s error: %v", source, err)
 	}
 
-	return parseScraperOutput(string(output))
+	return parseScraperOutput(output.String())
 }
 
 func findSalarySleuthExecutable() string {
@@ -374,7 +464,7 @@ func findSalarySleuthExecutable() string {
 	}
 	
 	// Fall back to error - don't use "go" as it causes issues
-	log.Fatal("ERROR: Could not find salarysleuth executable. Please build it first with: go build -o salarysleuth ./cmd/salarysleuth/main.go")
+	log.Fatal("ERROR: Could not find salarysleuth executable. Please build it first with: go build -o salarysleuth ./cmd/salarysleuth")
 	return ""
 }
 
@@ -462,6 +552,8 @@ func parseScraperOutput(output string) ([]Job, error) {
 			currentJob.URL = strings.TrimPrefix(line, "URL: ")
 		} else if strings.HasPrefix(line, "Source: ") {
 			currentJob.Source = strings.TrimPrefix(line, "Source: ")
+		} else if strings.HasPrefix(line, "Match: ") {
+			currentJob.Description = stripANSI(strings.TrimPrefix(line, "Match: "))
 	} else if strings.HasPrefix(line, "Salary Range: ") {
 		salary := strings.TrimPrefix(line, "Salary Range: ")
 		salary = stripANSI(salary) // Remove ANSI color codes
@@ -504,68 +596,33 @@ func generateJobID(job Job) string {
 	return base
 }
 
+// securityTitleQuery keeps uncategorised jobs whose title mentions security,
+// including compounds like "Cybersecurity"
+var securityTitleQuery = query.MustParse("title:*security*")
+
 // filterOffsecJobs filters jobs based on configuration rules
-// Now uses config.yaml for filtering rules
+// Now uses config.yaml for filtering rules. Jobs matching exclude rules are
+// kept with a score penalty; the dashboard hides them unless asked and
+// alertableJobs keeps them out of notifications.
 func filterOffsecJobs(jobs []Job) []Job {
 	cfg, err := LoadConfig()
 	if err != nil {
 		log.Printf("Warning: Could not load config, using defaults: %v", err)
 		cfg = getDefaultConfig()
 	}
+	return filterJobs(jobs, cfg)
+}
 
+// filterJobs keeps the jobs that match a category under cfg or have
+// "security" in the title
+func filterJobs(jobs []Job, cfg *AppConfig) []Job {
 	var filtered []Job
 
 	for _, job := range jobs {
-		titleLower := strings.ToLower(job.Title)
-
-		// Check exclusion rules from config
-		excluded := false
-		
-		for _, kw := range cfg.Filters.Exclude.DefensiveRoles {
-			if strings.Contains(titleLower, strings.ToLower(kw)) {
-				excluded = true
-				break
-			}
-		}
-		
-		if !excluded {
-			for _, kw := range cfg.Filters.Exclude.NonSecurity {
-				if strings.Contains(titleLower, strings.ToLower(kw)) {
-					excluded = true
-					break
-				}
-			}
-		}
-		
-		if !excluded {
-			for _, kw := range cfg.Filters.Exclude.Compliance {
-				if strings.Contains(titleLower, strings.ToLower(kw)) {
-					excluded = true
-					break
-				}
-			}
-		}
-
-		if excluded {
-			continue
-		}
-
-		// Check if job matches any category from config
-		matchesCategory := false
-		for _, cat := range cfg.Filters.Categories {
-			for _, kw := range cat.Keywords {
-				if strings.Contains(titleLower, strings.ToLower(kw)) {
-					matchesCategory = true
-					break
-				}
-			}
-			if matchesCategory {
-				break
-			}
-		}
+		tagged := TagJob(job, cfg)
 
 		// Must match at least one category or contain "security"
-		if matchesCategory || strings.Contains(titleLower, "security") {
+		if len(tagged.Tags.Categories) > 0 || securityTitleQuery.MatchDocument(jobDocument(job)) {
 			filtered = append(filtered, job)
 		}
 	}
@@ -573,10 +630,19 @@ func filterOffsecJobs(jobs []Job) []Job {
 	return filtered
 }
 
-// processJobs compares new jobs with existing ones, returns new jobs and updated store
-func processJobs(newJobs []Job, existingStore JobStore) ([]Job, JobStore) {
+// processJobs compares new jobs with existing ones, returns new jobs and
+// updated store. Jobs missing from the scrape are kept: they are marked
+// missing, then closed after closeAfter consecutive misses, so one flaky
+// scrape doesn't lose them.
+func processJobs(newJobs []Job, existingStore JobStore, closeAfter int) ([]Job, JobStore) {
 	now := time.Now()
 
+	// A scrape that found nothing almost certainly failed, so it doesn't
+	// count as a miss for every job
+	if len(newJobs) == 0 {
+		return nil, existingStore
+	}
+
 	// Create a map of existing jobs by ID
 	existingMap := make(map[string]*Job)
 	for i := range existingStore.Jobs {
@@ -595,15 +661,19 @@ func processJobs(newJobs []Job, existingStore JobStore) ([]Job, JobStore) {
 	// Process new jobs
 	for _, job := range newJobs {
 		if existing, found := existingMap[job.ID]; found {
-			// Job exists, update last seen
+			// Job exists, update last seen. A closed job that reappears is
+			// open again.
 			existing.LastSeen = now
-			
-			// Update salary data if the new scrape has it and existing doesn't
-			// This ensures salary data is captured even if it wasn't available initially
-			if job.LevelSalary != "" && existing.LevelSalary == "" {
+			existing.Status = JobActive
+			existing.Misses = 0
+			existing.ClosedAt = nil
+
+			// Take the latest salary data, keeping what we had if this scrape
+			// has none. Changes are recorded in the job's history.
+			if job.LevelSalary != "" {
 				existing.LevelSalary = job.LevelSalary
 			}
-			if job.SalaryRange != "" && existing.SalaryRange == "" {
+			if job.SalaryRange != "" {
 				existing.SalaryRange = job.SalaryRange
 			}
 			
@@ -612,13 +682,28 @@ func processJobs(newJobs []Job, existingStore JobStore) ([]Job, JobStore) {
 			// Truly new job
 			job.FirstSeen = now
 			job.LastSeen = now
+			job.Status = JobActive
 			updatedJobs = append(updatedJobs, job)
 			actuallyNewJobs = append(actuallyNewJobs, job)
 		}
 	}
 
-	// Jobs not in the new scrape are removed (not added to updatedJobs)
-	// This implements the "remove from page if not in latest run" requirement
+	// Jobs not in the new scrape count a miss
+	for _, job := range existingStore.Jobs {
+		if newMap[job.ID] {
+			continue
+		}
+		if job.Status != JobClosed {
+			job.Misses++
+			job.Status = JobMissing
+			if job.Misses >= closeAfter {
+				job.Status = JobClosed
+				closedAt := now
+				job.ClosedAt = &closedAt
+			}
+		}
+		updatedJobs = append(updatedJobs, job)
+	}
 
 	return actuallyNewJobs, JobStore{
 		LastUpdated: now,
@@ -627,40 +712,80 @@ func processJobs(newJobs []Job, existingStore JobStore) ([]Job, JobStore) {
 }
 
 func loadJobStore() JobStore {
-	dataFile := filepath.Join(config.DataDir, "jobs.json")
-
-	data, err := os.ReadFile(dataFile)
+	var store JobStore
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		store, err = readJobStore(tx)
+		return err
+	})
 	if err != nil {
-		// File doesn't exist yet
+		log.Printf("Warning: Could not load job store: %v", err)
 		return JobStore{Jobs: []Job{}}
 	}
+	return store
+}
 
-	var store JobStore
-	if err := json.Unmarshal(data, &store); err != nil {
-		log.Printf("Warning: Could not parse job store: %v", err)
-		return JobStore{Jobs: []Job{}}
-	}
+// updateJobStore applies fn to the stored jobs and writes the result in a
+// single transaction, so a crash leaves either the old or the new job list
+func updateJobStore(fn func(store JobStore) (JobStore, error)) error {
+	return dataStore.Update(func(tx Tx) error {
+		store, err := readJobStore(tx)
+		if err != nil {
+			return err
+		}
+		// fn may modify the jobs in place, so keep a copy to compare against
+		before := JobStore{LastUpdated: store.LastUpdated, Jobs: append([]Job(nil), store.Jobs...)}
+		updated, err := fn(store)
+		if err != nil {
+			return err
+		}
+		if err := recordJobHistory(tx, before, updated); err != nil {
+			return err
+		}
+		if err := recordScan(tx, before, updated); err != nil {
+			return err
+		}
+		return writeJobStore(tx, updated)
+	})
+}
 
-	// Clean up any invalid salary data
-	for i := range store.Jobs {
-		if !isValidSalary(store.Jobs[i].LevelSalary) {
-			store.Jobs[i].LevelSalary = ""
+func readJobStore(tx Tx) (JobStore, error) {
+	store := JobStore{Jobs: []Job{}}
+	if _, err := tx.Get(collMeta, metaJobsLastUpdated, &store.LastUpdated); err != nil {
+		return store, err
+	}
+	err := tx.ForEach(collJobs, func(key string, data []byte) error {
+		var job Job
+		if err := json.Unmarshal(data, &job); err != nil {
+			return fmt.Errorf("job %s: %v", key, err)
+		}
+		// Clean up any invalid salary data
+		if !isValidSalary(job.LevelSalary) {
+			job.LevelSalary = ""
 		}
-		if !isValidSalary(store.Jobs[i].SalaryRange) {
-			store.Jobs[i].SalaryRange = ""
+		if !isValidSalary(job.SalaryRange) {
+			job.SalaryRange = ""
 		}
+		store.Jobs = append(store.Jobs, job)
+		return nil
+	})
+	if err != nil {
+		return store, err
 	}
-
-	return store
+	sort.SliceStable(store.Jobs, func(i, j int) bool {
+		return store.Jobs[i].FirstSeen.After(store.Jobs[j].FirstSeen)
+	})
+	return store, nil
 }
 
-func saveJobStore(store JobStore) error {
-	dataFile := filepath.Join(config.DataDir, "jobs.json")
-
-	data, err := json.MarshalIndent(store, "", "  ")
-	if err != nil {
+// writeJobStore replaces the stored jobs with store.Jobs
+func writeJobStore(tx Tx, store JobStore) error {
+	jobs := make(map[string]Job, len(store.Jobs))
+	for _, job := range store.Jobs {
+		jobs[job.ID] = job
+	}
+	if err := replaceCollection(tx, collJobs, jobs); err != nil {
 		return err
 	}
-
-	return os.WriteFile(dataFile, data, 0644)
+	return tx.Put(collMeta, metaJobsLastUpdated, store.LastUpdated)
 }
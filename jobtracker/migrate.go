@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// migrations bring the store's schema up to date, in order. The schema
+// version stored in meta is the number of migrations applied; append new
+// ones to the end and never reorder them.
+var migrations = []func(tx Tx) error{
+	// 1: import the JSON files earlier versions kept in the data directory
+	func(tx Tx) error {
+		return importJSONData(tx, config.DataDir)
+	},
+	// 2: give stored jobs a status and start their history
+	migrateJobLifecycle,
+	// 3: turn frequency schedules into cron expressions
+	migrateScheduleCron,
+	// 4: store calendar feed tokens by their hash
+	migrateCalendarTokens,
+}
+
+// migrate applies the migrations the store has not seen yet
+func migrate(tx Tx) error {
+	var version int
+	if _, err := tx.Get(collMeta, metaSchemaVersion, &version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("store schema version %d is newer than this build supports (%d)", version, len(migrations))
+	}
+	for i := version; i < len(migrations); i++ {
+		if err := migrations[i](tx); err != nil {
+			return fmt.Errorf("migration %d: %v", i+1, err)
+		}
+		if err := tx.Put(collMeta, metaSchemaVersion, i+1); err != nil {
+			return err
+		}
+		log.Printf("Store migrated to schema version %d", i+1)
+	}
+	return nil
+}
+
+// migrateJobLifecycle marks the jobs stored before statuses existed as
+// active and records their first and last sightings
+func migrateJobLifecycle(tx Tx) error {
+	store, err := readJobStore(tx)
+	if err != nil {
+		return err
+	}
+	for i := range store.Jobs {
+		job := &store.Jobs[i]
+		job.Status = JobActive
+
+		hist := JobHistory{
+			StatusChanges: []StatusChange{{At: job.FirstSeen, To: JobActive}},
+		}
+		for _, seen := range []time.Time{job.FirstSeen, job.LastSeen} {
+			if !seen.IsZero() && (len(hist.Sightings) == 0 || !hist.Sightings[0].Equal(seen)) {
+				hist.Sightings = append(hist.Sightings, seen)
+			}
+		}
+		if err := tx.Put(collJobHistory, job.ID, hist); err != nil {
+			return err
+		}
+	}
+	return writeJobStore(tx, store)
+}
+
+// migrateScheduleCron rewrites the daily, weekday, weekly and custom-day
+// schedules stored before cron expressions as the matching expression.
+// Their next run is worked out by the scheduler from their last run, so one
+// missed during the upgrade is caught up.
+func migrateScheduleCron(tx Tx) error {
+	all, err := loadCollection[UserAlertConfig](tx, collAlerts)
+	if err != nil {
+		return err
+	}
+	for username, cfg := range all {
+		changed := false
+		for i := range cfg.Schedules {
+			sched := &cfg.Schedules[i]
+			if sched.Schedule == "" {
+				continue
+			}
+			sched.Cron = sched.cronExpr()
+			if _, err := parseCron(sched.Cron); err != nil {
+				// A custom schedule with no days never ran
+				sched.Cron = fmt.Sprintf("%d %d * * *", sched.Minute, sched.Hour)
+				sched.Enabled = false
+			}
+			sched.Schedule, sched.Days, sched.Hour, sched.Minute = "", nil, 0, 0
+			changed = true
+		}
+		if changed {
+			if err := tx.Put(collAlerts, username, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrateCalendarTokens rekeys calendar feed tokens, stored as plain keys
+// before, by their SHA-256 so existing feed URLs keep working
+func migrateCalendarTokens(tx Tx) error {
+	tokens, err := loadCollection[CalendarToken](tx, collCalendarTokens)
+	if err != nil {
+		return err
+	}
+	for token, info := range tokens {
+		if err := tx.Delete(collCalendarTokens, token); err != nil {
+			return err
+		}
+		if err := tx.Put(collCalendarTokens, hashToken(token), info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importJSONData copies the JSON files written by earlier versions in dir
+// into the store: jobs.json, users.json, tokens.json, last_refresh.json and
+// the per-user alerts, history, saved_jobs and profiles directories along
+// with search_results. Records already in the store with the same key are
+// overwritten and the stored job list is replaced by jobs.json. Files that
+// cannot be parsed are logged and skipped; the files are left in place.
+func importJSONData(tx Tx, dir string) error {
+	imported := 0
+	skip := func(path string, err error) {
+		log.Printf("Import: skipping %s: %v", path, err)
+	}
+
+	var jobs JobStore
+	if ok, err := readJSONFile(filepath.Join(dir, "jobs.json"), &jobs); err != nil {
+		skip("jobs.json", err)
+	} else if ok {
+		if err := writeJobStore(tx, jobs); err != nil {
+			return err
+		}
+		imported += len(jobs.Jobs)
+	}
+
+	var users UserStoreData
+	if ok, err := readJSONFile(filepath.Join(dir, "users.json"), &users); err != nil {
+		skip("users.json", err)
+	} else if ok {
+		for username, u := range users.Users {
+			if err := tx.Put(collUsers, username, u); err != nil {
+				return err
+			}
+			imported++
+		}
+	}
+
+	var tokens TokenStoreData
+	if ok, err := readJSONFile(filepath.Join(dir, "tokens.json"), &tokens); err != nil {
+		skip("tokens.json", err)
+	} else if ok {
+		for token, t := range tokens.Tokens {
+			if err := tx.Put(collTokens, token, t); err != nil {
+				return err
+			}
+			imported++
+		}
+	}
+
+	var refresh RefreshState
+	if ok, err := readJSONFile(filepath.Join(dir, "last_refresh.json"), &refresh); err != nil {
+		skip("last_refresh.json", err)
+	} else if ok {
+		if err := tx.Put(collMeta, metaRefreshState, refresh); err != nil {
+			return err
+		}
+		imported++
+	}
+
+	for _, d := range []struct {
+		dir        string
+		collection string
+		record     func() interface{}
+	}{
+		{"alerts", collAlerts, func() interface{} { return &UserAlertConfig{} }},
+		{"history", collHistory, func() interface{} { return &UserHistory{} }},
+		{"saved_jobs", collSavedJobs, func() interface{} { return &UserSavedJobs{} }},
+		{"profiles", collProfiles, func() interface{} { return &UserProfile{} }},
+		{"search_results", collSearchResults, func() interface{} { return &json.RawMessage{} }},
+	} {
+		entries, err := os.ReadDir(filepath.Join(dir, d.dir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+				continue
+			}
+			// Search results keep their file name as the key, since history
+			// entries refer to them by it
+			key := strings.TrimSuffix(name, ".json")
+			if d.collection == collSearchResults {
+				key = name
+			}
+
+			v := d.record()
+			if _, err := readJSONFile(filepath.Join(dir, d.dir, name), v); err != nil {
+				skip(filepath.Join(d.dir, name), err)
+				continue
+			}
+			if err := tx.Put(d.collection, key, v); err != nil {
+				return err
+			}
+			imported++
+		}
+	}
+
+	if imported > 0 {
+		log.Printf("Imported %d records from JSON files in %s", imported, dir)
+	}
+	return nil
+}
+
+// readJSONFile decodes a file, reporting false if it does not exist
+func readJSONFile(path string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runImportJSON implements -import-json: it imports the JSON files in dir
+// into the store and returns the process exit code
+func runImportJSON(dir string) int {
+	err := dataStore.Update(func(tx Tx) error {
+		return importJSONData(tx, dir)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Import failed: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Digest is an alert for one user in a channel-neutral form: the jobs a scan
+// found, or none when the user asked to hear about empty scans. Each channel
+// renders it in its own format.
+type Digest struct {
+	ScanName string      `json:"scan_name"`
+	Jobs     []DigestJob `json:"jobs"`
+	Time     time.Time   `json:"time"`
+	// Omitted counts the jobs left out because the schedule alerted about
+	// them before
+	Omitted int `json:"omitted,omitempty"`
+	// More counts the jobs left out by the user's cap on jobs per alert,
+	// and MoreURL links to the full list on the dashboard
+	More    int    `json:"more,omitempty"`
+	MoreURL string `json:"more_url,omitempty"`
+	// Test marks a message sent to check a channel works
+	Test bool `json:"test,omitempty"`
+}
+
+// DigestJob is a job as shown in alerts. Salaries are empty when unknown.
+type DigestJob struct {
+	ID          string `json:"id,omitempty"`
+	Title       string `json:"title"`
+	Company     string `json:"company"`
+	Location    string `json:"location,omitempty"`
+	SalaryRange string `json:"salary_range,omitempty"`
+	LevelSalary string `json:"levels_salary,omitempty"`
+	URL         string `json:"url"`
+	Source      string `json:"source,omitempty"`
+	// Change is set for jobs alerted about before: changeSalary or changeSeen
+	Change string `json:"change,omitempty"`
+}
+
+func newDigest(scanName string, jobs []Job) Digest {
+	d := Digest{ScanName: scanName, Time: time.Now()}
+	for _, job := range jobs {
+		dj := DigestJob{
+			ID:       job.ID,
+			Title:    job.Title,
+			Company:  job.Company,
+			Location: job.Location,
+			URL:      job.URL,
+			Source:   job.Source,
+		}
+		if job.SalaryRange != "" && job.SalaryRange != "Not Available" {
+			dj.SalaryRange = job.SalaryRange
+		}
+		if job.LevelSalary != "" && job.LevelSalary != "No Data" {
+			dj.LevelSalary = job.LevelSalary
+		}
+		d.Jobs = append(d.Jobs, dj)
+	}
+	return d
+}
+
+// testDigest is sent when a user checks a channel
+func testDigest() Digest {
+	return Digest{ScanName: "Test", Time: time.Now(), Test: true}
+}
+
+// Total counts the jobs found, including those left out by the cap
+func (d Digest) Total() int {
+	return len(d.Jobs) + d.More
+}
+
+// MoreNote is the footer noting jobs left out by the cap, or empty if there
+// are none
+func (d Digest) MoreNote() string {
+	if d.More == 0 {
+		return ""
+	}
+	note := fmt.Sprintf("Showing %d of %d jobs.", len(d.Jobs), d.Total())
+	if d.MoreURL == "" {
+		note += " See them all under History on the dashboard."
+	}
+	return note
+}
+
+// OmittedNote is the footer noting jobs left out, or empty if there are none
+func (d Digest) OmittedNote() string {
+	switch d.Omitted {
+	case 0:
+		return ""
+	case 1:
+		return "1 previously seen job omitted"
+	}
+	return fmt.Sprintf("%d previously seen jobs omitted", d.Omitted)
+}
+
+// job is the alerted job as stored with search results
+func (j DigestJob) job(seen time.Time) Job {
+	return Job{
+		ID:          j.ID,
+		Company:     j.Company,
+		Title:       j.Title,
+		Location:    j.Location,
+		URL:         j.URL,
+		SalaryRange: j.SalaryRange,
+		LevelSalary: j.LevelSalary,
+		Source:      j.Source,
+		FirstSeen:   seen,
+		LastSeen:    seen,
+	}
+}
+
+// ChangeLabel describes why a job alerted about before is in the digest
+func (j DigestJob) ChangeLabel() string {
+	switch j.Change {
+	case changeSalary:
+		return "Salary changed"
+	case changeSeen:
+		return "Seen before"
+	}
+	return ""
+}
+
+// Date is the digest's time as shown in messages
+func (d Digest) Date() string {
+	return d.Time.Format("Jan 2, 2006 3:04 PM")
+}
+
+// Notifier delivers digests to one of a user's channels, retrying as suits
+// the channel
+type Notifier interface {
+	// Channel names the channel in logs
+	Channel() string
+	Notify(d Digest) error
+}
+
+// userNotifiers returns the user's verified and enabled channels
+func userNotifiers(cfg UserAlertConfig) []Notifier {
+	var list []Notifier
+	if cfg.telegramActive() {
+		list = append(list, telegramNotifier{cfg.Telegram})
+	}
+	if cfg.Email.active() {
+		list = append(list, emailNotifier{cfg.Email})
+	}
+	for _, ch := range cfg.Webhooks {
+		if ch.active() {
+			list = append(list, webhookNotifier{ch})
+		}
+	}
+	return list
+}
+
+// notifyUser sends the digest over each channel at once, so a channel that
+// is backing off doesn't hold up the others, and returns how many succeeded
+func notifyUser(username string, notifiers []Notifier, d Digest) int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sent := 0
+	for _, n := range notifiers {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			if err := n.Notify(d); err != nil {
+				log.Printf("Failed to notify %s via %s: %v", username, n.Channel(), err)
+				return
+			}
+			log.Printf("Sent %s alert to %s (%d jobs)", n.Channel(), username, len(d.Jobs))
+			mu.Lock()
+			sent++
+			mu.Unlock()
+		}(n)
+	}
+	wg.Wait()
+	return sent
+}
+
+// notifyClient posts to webhook URLs. These come from users, so it
+// won't connect to internal addresses or follow redirects,
+// which could otherwise point it at one. It doesn't use a proxy either, as
+// that would connect to the proxy and leave the address check to it.
+var notifyClient = &http.Client{
+	Timeout:   20 * time.Second,
+	Transport: publicTransport(),
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		// hand back the 3xx, which callers report as a failed send
+		return http.ErrUseLastResponse
+	},
+}
+
+// errInternalAddress is a connection refused because of where it goes
+var errInternalAddress = errors.New("refusing to connect to an internal address")
+
+func publicTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		// Control runs after DNS resolution, on the address actually dialled
+		Control: func(network, address string, c syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isInternalAddr(addr.Addr()) {
+				return fmt.Errorf("%s: %w", address, errInternalAddress)
+			}
+			return nil
+		},
+	}
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// internalPrefixes are the ranges isInternalAddr refuses that netip doesn't
+// classify: "this network", carrier-grade NAT, and NAT64, which reaches IPv4
+// hosts through a gateway on the server's network
+var internalPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// isInternalAddr reports whether ip is anything but a public unicast
+// address: loopback, private, link-local, multicast, unspecified or one of
+// internalPrefixes
+func isInternalAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return true
+	}
+	for _, p := range internalPrefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryPolicy is how a channel retries a failed send: up to Attempts tries,
+// waiting Backoff after the first failure and doubling each time up to
+// MaxBackoff. A Retry-After from the service takes precedence.
+type retryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// withRetry calls send until it succeeds, fails with an error retrying
+// can't fix, or runs out of attempts
+func withRetry(p retryPolicy, send func() error) error {
+	wait := p.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = send(); err == nil {
+			return nil
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		var status *statusError
+		if errors.As(err, &status) && !status.retryable() {
+			return err
+		}
+		if attempt >= p.Attempts {
+			return err
+		}
+
+		delay := wait
+		if status != nil && status.RetryAfter > 0 {
+			delay = status.RetryAfter
+		}
+		if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+		time.Sleep(delay)
+		wait *= 2
+	}
+}
+
+// permanentError marks an error that retrying won't fix
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+// statusError is an unsuccessful response from a chat or webhook API
+type statusError struct {
+	Service    string
+	Code       int
+	RetryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Service, e.Code)
+}
+
+// retryable reports whether the request may succeed if sent again: rate
+// limits, timeouts and server errors
+func (e *statusError) retryable() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout || e.Code >= 500
+}
+
+func newStatusError(service string, resp *http.Response) *statusError {
+	err := &statusError{Service: service, Code: resp.StatusCode}
+	if secs, perr := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); perr == nil && secs > 0 {
+		err.RetryAfter = time.Duration(secs * float64(time.Second))
+	}
+	return err
+}
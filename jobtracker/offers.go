@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/jobtracker/analytics"
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/offers"
+)
+
+// OfferComparison is a compared offer and the saved job it was recorded
+// against, if any
+type OfferComparison struct {
+	JobID string `json:"job_id,omitempty"`
+	offers.Comparison
+}
+
+// savedJobOffers returns the offers recorded against the user's saved jobs,
+// or only the given jobs when jobIDs isn't empty. Each is benchmarked
+// against the job's posted range and its company's Levels.fyi median, taken
+// from the job store when the scraper recorded them.
+func savedJobOffers(username string, jobIDs []string) ([]offers.Offer, []string, error) {
+	var store JobStore
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		store, err = readJobStore(tx)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	stored := make(map[string]Job, len(store.Jobs))
+	for _, job := range store.Jobs {
+		stored[job.ID] = job
+	}
+
+	selected := make(map[string]bool, len(jobIDs))
+	for _, id := range jobIDs {
+		selected[id] = true
+	}
+
+	var list []offers.Offer
+	var ids []string
+	for _, saved := range loadUserSavedJobs(username).Jobs {
+		if saved.Offer == nil || len(selected) > 0 && !selected[saved.JobID] {
+			continue
+		}
+		offer := offers.Offer{
+			Company:     saved.Company,
+			Title:       saved.Title,
+			Terms:       saved.Offer.Terms,
+			PostedRange: saved.Salary,
+		}
+		if job, ok := stored[saved.JobID]; ok {
+			if job.SalaryRange != "" {
+				offer.PostedRange = job.SalaryRange
+			}
+			if isValidSalary(job.LevelSalary) && job.LevelSalary != "No Data" {
+				offer.LevelsMedian = job.LevelSalary
+			}
+		}
+		list = append(list, offer)
+		ids = append(ids, saved.JobID)
+	}
+	return list, ids, nil
+}
+
+// maxCompareOffers is how many offers a POST can compare at once
+const maxCompareOffers = 10
+
+// storedLevelsMedians fills in the Levels.fyi median of offers that don't
+// have one from the company's most recently seen job in the store. Live
+// lookups are left to the scraper, so a comparison never waits on (or sends
+// traffic to) Levels.fyi.
+func storedLevelsMedians(list []offers.Offer) error {
+	var store JobStore
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		store, err = readJobStore(tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	medians := make(map[string]Job)
+	for _, job := range store.Jobs {
+		if !isValidSalary(job.LevelSalary) || job.LevelSalary == "No Data" {
+			continue
+		}
+		id := analytics.CompanyID(job.Company)
+		if latest, ok := medians[id]; !ok || job.LastSeen.After(latest.LastSeen) {
+			medians[id] = job
+		}
+	}
+	for i := range list {
+		if list[i].LevelsMedian != "" {
+			continue
+		}
+		if job, ok := medians[analytics.CompanyID(list[i].Company)]; ok && list[i].Company != "" {
+			list[i].LevelsMedian = job.LevelSalary
+		}
+	}
+	return nil
+}
+
+// handleOfferCompare compares offers side by side. GET compares the offers
+// recorded against the user's saved jobs (only those named by job_id
+// parameters, if given); POST compares the offers in the request body, for
+// trying out terms that haven't been recorded.
+func handleOfferCompare(w http.ResponseWriter, r *http.Request) {
+	var list []offers.Offer
+	var ids []string
+	switch r.Method {
+	case http.MethodGet:
+		var err error
+		list, ids, err = savedJobOffers(r.Header.Get("X-Auth-User"), r.URL.Query()["job_id"])
+		if err != nil {
+			jsonError(w, "Failed to load offers", http.StatusInternalServerError)
+			return
+		}
+
+	case http.MethodPost:
+		var req struct {
+			Offers []offers.Offer `json:"offers"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if len(req.Offers) > maxCompareOffers {
+			jsonError(w, fmt.Sprintf("Compare at most %d offers at a time", maxCompareOffers), http.StatusBadRequest)
+			return
+		}
+		list = req.Offers
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := storedLevelsMedians(list); err != nil {
+		jsonError(w, "Failed to load Levels.fyi medians", http.StatusInternalServerError)
+		return
+	}
+	comparisons, err := offers.Compare(list)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result := make([]OfferComparison, len(comparisons))
+	for i, c := range comparisons {
+		result[i].Comparison = c
+		if i < len(ids) {
+			result[i].JobID = ids[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"offers": result,
+		"count":  len(result),
+	})
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PasswordReset is a one-time token an admin creates so a user can choose
+// a new password, stored in the password_resets collection under the
+// token's SHA-256. A user has at most one.
+type PasswordReset struct {
+	Username  string    `json:"username"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// passwordResetTTL is how long a reset token can be used
+const passwordResetTTL = 24 * time.Hour
+
+// minPasswordLength is the shortest password accepted
+const minPasswordLength = 8
+
+var (
+	errWrongPassword     = errors.New("current password is incorrect")
+	errInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+// upgradePasswordHash replaces the user's hash of password in an old format
+// or with too few iterations, unless it changed since it was checked
+func upgradePasswordHash(username, password, old string) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for %s: %v", username, err)
+		return
+	}
+	err = dataStore.Update(func(tx Tx) error {
+		var user User
+		if found, err := tx.Get(collUsers, username, &user); err != nil || !found || user.PasswordHash != old {
+			return err
+		}
+		user.PasswordHash = hash
+		return tx.Put(collUsers, username, user)
+	})
+	if err != nil {
+		log.Printf("Failed to save rehashed password for %s: %v", username, err)
+		return
+	}
+	log.Printf("Upgraded password hash for %s", username)
+}
+
+// setPassword stores a new password for the user and logs them out of every
+// session but keep (a stored session key, or "" for all of them)
+func setPassword(tx Tx, username, hash, keep string) error {
+	var user User
+	if found, err := tx.Get(collUsers, username, &user); err != nil {
+		return err
+	} else if !found {
+		return errUserNotFound
+	}
+	user.PasswordHash = hash
+	if err := tx.Put(collUsers, username, user); err != nil {
+		return err
+	}
+	return revokeUserSessions(tx, username, keep)
+}
+
+// revokePasswordResets deletes the user's reset tokens
+func revokePasswordResets(tx Tx, username string) error {
+	resets, err := loadCollection[PasswordReset](tx, collPasswordResets)
+	if err != nil {
+		return err
+	}
+	for key, reset := range resets {
+		if reset.Username == username {
+			if err := tx.Delete(collPasswordResets, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// handleChangePassword changes the user's password given
+// {"current_password", "new_password"}. Their other sessions are logged out.
+func handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username := r.Header.Get("X-Auth-User")
+	var req struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		jsonError(w, fmt.Sprintf("Password must be at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+
+	user, exists := loadUsers().Users[username]
+	if !exists {
+		jsonError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if ok, _ := verifyPassword(req.CurrentPassword, user.PasswordHash); !ok {
+		jsonError(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	}
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		jsonError(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+	err = dataStore.Update(func(tx Tx) error {
+		var current User
+		if _, err := tx.Get(collUsers, username, &current); err != nil {
+			return err
+		}
+		// Changed by another request since it was checked
+		if current.PasswordHash != user.PasswordHash {
+			return errWrongPassword
+		}
+		return setPassword(tx, username, hash, currentSessionKey(r))
+	})
+	switch {
+	case err == errWrongPassword:
+		jsonError(w, "Current password is incorrect", http.StatusForbidden)
+		return
+	case err != nil:
+		log.Printf("Failed to change password for %s: %v", username, err)
+		jsonError(w, "Failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Password changed by %s", username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Password changed. Your other sessions have been logged out.",
+	})
+}
+
+// handleAdminPasswordReset creates a one-time token for {"username"} to
+// choose a new password with, replacing any earlier one. The admin passes
+// it on; it is only shown here.
+func handleAdminPasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	b := make([]byte, 18)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	reset := PasswordReset{
+		Username:  req.Username,
+		CreatedBy: r.Header.Get("X-Auth-User"),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	err := dataStore.Update(func(tx Tx) error {
+		var user User
+		if found, err := tx.Get(collUsers, req.Username, &user); err != nil {
+			return err
+		} else if !found {
+			return errUserNotFound
+		}
+		if err := revokePasswordResets(tx, req.Username); err != nil {
+			return err
+		}
+		return tx.Put(collPasswordResets, hashToken(token), reset)
+	})
+	switch {
+	case err == errUserNotFound:
+		jsonError(w, "User not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Failed to create password reset for %s: %v", req.Username, err)
+		jsonError(w, "Failed to create reset token", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Password reset token for %s created by %s", req.Username, reset.CreatedBy)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"username":   req.Username,
+		"token":      token,
+		"expires_at": reset.ExpiresAt,
+	})
+}
+
+// handlePasswordReset sets a new password with an admin's reset token,
+// given {"token", "new_password"}, and logs the user out everywhere
+func handlePasswordReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		jsonError(w, "Reset token is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		jsonError(w, fmt.Sprintf("Password must be at least %d characters", minPasswordLength), http.StatusBadRequest)
+		return
+	}
+	hash, err := hashPassword(req.NewPassword)
+	if err != nil {
+		jsonError(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	var reset PasswordReset
+	err = dataStore.Update(func(tx Tx) error {
+		key := hashToken(req.Token)
+		found, err := tx.Get(collPasswordResets, key, &reset)
+		if err != nil {
+			return err
+		}
+		if !found || time.Now().After(reset.ExpiresAt) {
+			return errInvalidResetToken
+		}
+		if err := tx.Delete(collPasswordResets, key); err != nil {
+			return err
+		}
+		return setPassword(tx, reset.Username, hash, "")
+	})
+	switch {
+	case err == errInvalidResetToken, err == errUserNotFound:
+		jsonError(w, "Invalid or expired reset token", http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Printf("Failed to reset password: %v", err)
+		jsonError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("Password reset for %s", reset.Username)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"message":  fmt.Sprintf("Password for '%s' has been reset", reset.Username),
+		"username": reset.Username,
+	})
+}
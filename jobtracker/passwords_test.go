@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// legacyHash hashes password in the hex(salt)$hex(key) format used before
+// versioned hashes
+func legacyHash(password string) string {
+	salt := []byte("0123456789abcdef")
+	return hex.EncodeToString(salt) + "$" + hex.EncodeToString(legacyDeriveKey(password, salt))
+}
+
+// putTestUser stores a user with the given password hash
+func putTestUser(t *testing.T, username, hash string) {
+	t.Helper()
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Put(collUsers, username, User{Username: username, PasswordHash: hash, Role: "user", CreatedAt: time.Now()})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// postJSON calls handler with a POST of body as username, holding the
+// session cookie if one is given
+func postJSON(handler http.HandlerFunc, username, cookie, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if username != "" {
+		req.Header.Set("X-Auth-User", username)
+	}
+	if cookie != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: cookie})
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func TestVerifyPassword(t *testing.T) {
+	current, err := hashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(current, "$pbkdf2-sha256$i=600000$") {
+		t.Errorf("hashPassword = %q, want the $pbkdf2-sha256$ format", current)
+	}
+	salt := []byte("saltsaltsaltsalt")
+	weak := "$pbkdf2-sha256$i=1000$" + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(pbkdf2SHA256([]byte("correct horse"), salt, 1000, sha256.Size))
+
+	tests := []struct {
+		name, password, stored string
+		ok, rehash             bool
+	}{
+		{"current", "correct horse", current, true, false},
+		{"current, wrong password", "battery staple", current, false, false},
+		{"legacy", "correct horse", legacyHash("correct horse"), true, true},
+		{"legacy, wrong password", "battery staple", legacyHash("correct horse"), false, false},
+		{"too few iterations", "correct horse", weak, true, true},
+		{"too few iterations, wrong password", "battery staple", weak, false, false},
+		{"unknown scheme", "correct horse", "$bcrypt$i=10$c2FsdA$a2V5", false, false},
+		{"malformed", "correct horse", "not-a-hash", false, false},
+	}
+	for _, tt := range tests {
+		ok, rehash := verifyPassword(tt.password, tt.stored)
+		if ok != tt.ok || rehash != tt.rehash {
+			t.Errorf("%s: verifyPassword = %v, %v, want %v, %v", tt.name, ok, rehash, tt.ok, tt.rehash)
+		}
+	}
+}
+
+func TestLoginRehashesLegacyPassword(t *testing.T) {
+	newTestStore(t)
+	putTestUser(t, "alice", legacyHash("correct horse"))
+
+	if rec := postJSON(handleLoginAPI, "", "", `{"username":"alice","password":"battery staple"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("login with the wrong password: status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if got := loadUsers().Users["alice"].PasswordHash; got != legacyHash("correct horse") {
+		t.Errorf("failed login changed the hash to %q", got)
+	}
+
+	if rec := postJSON(handleLoginAPI, "", "", `{"username":"alice","password":"correct horse"}`); rec.Code != http.StatusOK {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body)
+	}
+	stored := loadUsers().Users["alice"].PasswordHash
+	if !strings.HasPrefix(stored, "$pbkdf2-sha256$") {
+		t.Fatalf("hash after login = %q, want the $pbkdf2-sha256$ format", stored)
+	}
+	if ok, rehash := verifyPassword("correct horse", stored); !ok || rehash {
+		t.Errorf("rehashed password: verifyPassword = %v, %v, want true, false", ok, rehash)
+	}
+
+	if rec := postJSON(handleLoginAPI, "", "", `{"username":"alice","password":"correct horse"}`); rec.Code != http.StatusOK {
+		t.Errorf("login after rehash: status %d", rec.Code)
+	}
+	if got := loadUsers().Users["alice"].PasswordHash; got != stored {
+		t.Error("second login rehashed the password again")
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	newTestStore(t)
+	putTestUser(t, "alice", legacyHash("correct horse"))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	current, err := createSession("alice", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := createSession("alice", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := postJSON(handleChangePassword, "alice", current, `{"current_password":"battery staple","new_password":"new password"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("change with the wrong password: status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if getSession(other) == nil {
+		t.Fatal("failed change revoked the other session")
+	}
+
+	if rec := postJSON(handleChangePassword, "alice", current, `{"current_password":"correct horse","new_password":"new password"}`); rec.Code != http.StatusOK {
+		t.Fatalf("change: status %d: %s", rec.Code, rec.Body)
+	}
+	if getSession(current) == nil {
+		t.Error("change logged out the session that made it")
+	}
+	if getSession(other) != nil {
+		t.Error("change didn't log out the other session")
+	}
+	if ok, _ := verifyPassword("new password", loadUsers().Users["alice"].PasswordHash); !ok {
+		t.Error("new password doesn't verify")
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	newTestStore(t)
+	putTestUser(t, "alice", legacyHash("correct horse"))
+	session, err := createSession("alice", httptest.NewRequest(http.MethodPost, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := postJSON(handleAdminPasswordReset, "admin", "", `{"username":"alice"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("creating a reset: status %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := time.Until(created.ExpiresAt); ttl > 24*time.Hour || ttl < 23*time.Hour {
+		t.Errorf("reset token expires in %s, want 24h", ttl)
+	}
+
+	reset := func(token string) int {
+		return postJSON(handlePasswordReset, "", "", `{"token":"`+token+`","new_password":"new password"}`).Code
+	}
+	if code := reset(created.Token); code != http.StatusOK {
+		t.Fatalf("reset: status %d", code)
+	}
+	if ok, _ := verifyPassword("new password", loadUsers().Users["alice"].PasswordHash); !ok {
+		t.Error("reset password doesn't verify")
+	}
+	if getSession(session) != nil {
+		t.Error("reset didn't log out alice's session")
+	}
+	if code := reset(created.Token); code != http.StatusBadRequest {
+		t.Errorf("reusing the token: status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// a token older than passwordResetTTL
+	err = dataStore.Update(func(tx Tx) error {
+		return tx.Put(collPasswordResets, hashToken("stale-token"), PasswordReset{
+			Username:  "alice",
+			CreatedAt: time.Now().Add(-25 * time.Hour),
+			ExpiresAt: time.Now().Add(-time.Hour),
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := reset("stale-token"); code != http.StatusBadRequest {
+		t.Errorf("expired token: status %d, want %d", code, http.StatusBadRequest)
+	}
+	if ok, _ := verifyPassword("new password", loadUsers().Users["alice"].PasswordHash); !ok {
+		t.Error("expired token changed the password")
+	}
+}
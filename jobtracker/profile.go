@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserProfile holds one user's overrides of the global config.yaml filters.
+// Anything left unset is inherited, so a profile only lists what differs.
+type UserProfile struct {
+	// Rules replace the global rules with the same ID (keeping the display
+	// name unless one is given); a rule with "disabled": true removes the
+	// inherited one
+	Categories     map[string]ProfileRule `json:"categories,omitempty"`
+	Levels         map[string]ProfileRule `json:"levels,omitempty"`
+	Certifications map[string]ProfileRule `json:"certifications,omitempty"`
+	Exclude        ProfileExclude         `json:"exclude"`
+	Scoring        ProfileScoring         `json:"scoring"`
+	// Dashboard holds the filters the dashboard starts with
+	Dashboard *SearchFilters `json:"dashboard,omitempty"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ProfileRule is a category, level or certification rule in a profile
+type ProfileRule struct {
+	Keywords    []Keyword `json:"keywords,omitempty"`
+	Fields      []string  `json:"fields,omitempty"`
+	Query       string    `json:"query,omitempty"`
+	DisplayName string    `json:"display_name,omitempty"`
+	Weight      float64   `json:"weight,omitempty"`
+	Disabled    bool      `json:"disabled,omitempty"`
+}
+
+// ProfileExclude adjusts the global exclude rules
+type ProfileExclude struct {
+	// Disable turns off inherited exclude groups: defensive_roles,
+	// non_security, compliance or queries
+	Disable []string `json:"disable,omitempty"`
+	// Queries are added to the inherited exclude queries
+	Queries []string `json:"queries,omitempty"`
+}
+
+// ProfileScoring overrides scoring settings; empty values are inherited
+type ProfileScoring struct {
+	MinScore           *float64  `json:"min_score,omitempty"`
+	TargetSalary       string    `json:"target_salary,omitempty"`
+	PreferredLevels    []string  `json:"preferred_levels,omitempty"`
+	PreferredCompanies []Keyword `json:"preferred_companies,omitempty"`
+}
+
+// excludeGroups are the names accepted in ProfileExclude.Disable
+var excludeGroups = []string{"defensive_roles", "non_security", "compliance", "queries"}
+
+// dashboardSorts are the dashboard's sort options
+var dashboardSorts = []string{"newest", "score", "salary_high", "salary_low", "company"}
+
+// loadUserProfile returns the user's profile and whether one exists
+func loadUserProfile(username string) (UserProfile, bool) {
+	var p UserProfile
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		found, err = tx.Get(collProfiles, username, &p)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to load profile for %s: %v", username, err)
+		return UserProfile{}, false
+	}
+	return p, found
+}
+
+func saveUserProfile(username string, p UserProfile) error {
+	return dataStore.Update(func(tx Tx) error {
+		return tx.Put(collProfiles, username, p)
+	})
+}
+
+func deleteUserProfile(username string) error {
+	return dataStore.Update(func(tx Tx) error {
+		return tx.Delete(collProfiles, username)
+	})
+}
+
+// apply returns a copy of base with the profile's overrides merged in and
+// compiled. The result is validated like config.yaml.
+func (p UserProfile) apply(base *AppConfig) (*AppConfig, error) {
+	cfg := *base
+	errs := &ConfigError{Path: "profile"}
+
+	f := &cfg.Filters
+	f.Categories = mergeRules(base.Filters.Categories, p.Categories)
+	f.Levels = mergeRules(base.Filters.Levels, p.Levels)
+	f.Certifications = mergeRules(base.Filters.Certifications, p.Certifications)
+
+	ex := &f.Exclude
+	for i, group := range p.Exclude.Disable {
+		switch group {
+		case "defensive_roles":
+			ex.DefensiveRoles = nil
+		case "non_security":
+			ex.NonSecurity = nil
+		case "compliance":
+			ex.Compliance = nil
+		case "queries":
+			ex.Queries, ex.queryLines = nil, nil
+		default:
+			errs.add(0, fmt.Sprintf("exclude.disable[%d]", i), fmt.Errorf("unknown group %q (expected one of %s)", group, strings.Join(excludeGroups, ", ")))
+		}
+	}
+	ex.Queries = append(append([]string(nil), ex.Queries...), p.Exclude.Queries...)
+
+	s := &cfg.Scoring
+	if p.Scoring.MinScore != nil {
+		s.MinScore = *p.Scoring.MinScore
+	}
+	if p.Scoring.TargetSalary != "" {
+		s.TargetSalary, s.targetSalaryLine = p.Scoring.TargetSalary, 0
+	}
+	if len(p.Scoring.PreferredLevels) > 0 {
+		s.PreferredLevels, s.levelsLine = p.Scoring.PreferredLevels, 0
+	}
+	if len(p.Scoring.PreferredCompanies) > 0 {
+		s.PreferredCompanies = p.Scoring.PreferredCompanies
+	}
+
+	if d := p.Dashboard; d != nil {
+		if d.Category != "" {
+			if _, ok := f.Categories[d.Category]; !ok {
+				errs.add(0, "dashboard.category", fmt.Errorf("unknown category %q", d.Category))
+			}
+		}
+		if d.Level != "" {
+			if _, ok := f.Levels[d.Level]; !ok {
+				errs.add(0, "dashboard.level", fmt.Errorf("unknown level %q", d.Level))
+			}
+		}
+		if d.Cert != "" {
+			if _, ok := f.Certifications[d.Cert]; !ok {
+				errs.add(0, "dashboard.certification", fmt.Errorf("unknown certification %q", d.Cert))
+			}
+		}
+		if d.Status != "" && d.Status != JobClosed && d.Status != "all" {
+			errs.add(0, "dashboard.status", fmt.Errorf("unknown status %q (expected closed or all)", d.Status))
+		}
+		if d.Sort != "" && !containsString(dashboardSorts, d.Sort) {
+			errs.add(0, "dashboard.sort", fmt.Errorf("unknown sort %q (expected one of %s)", d.Sort, strings.Join(dashboardSorts, ", ")))
+		}
+	}
+
+	if err := cfg.compileRules("profile"); err != nil {
+		if ce, ok := err.(*ConfigError); ok {
+			errs.Problems = append(errs.Problems, ce.Problems...)
+		} else {
+			return nil, err
+		}
+	}
+	if len(errs.Problems) > 0 {
+		return nil, errs
+	}
+	return &cfg, nil
+}
+
+// mergeRules copies the global rules and applies a profile's overrides
+func mergeRules(global map[string]CategoryConfig, overrides map[string]ProfileRule) map[string]CategoryConfig {
+	merged := make(map[string]CategoryConfig, len(global)+len(overrides))
+	for id, rule := range global {
+		merged[id] = rule
+	}
+	for id, o := range overrides {
+		if o.Disabled {
+			delete(merged, id)
+			continue
+		}
+		rule := CategoryConfig{
+			Keywords:    o.Keywords,
+			Fields:      o.Fields,
+			Query:       o.Query,
+			DisplayName: o.DisplayName,
+			Weight:      o.Weight,
+		}
+		if rule.DisplayName == "" {
+			rule.DisplayName = global[id].DisplayName
+		}
+		merged[id] = rule
+	}
+	return merged
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// profileConfig caches a user's effective config along with the global
+// config it was built from, so a new global config rebuilds it
+type profileConfig struct {
+	base *AppConfig
+	cfg  *AppConfig
+}
+
+var (
+	profileConfigsMu sync.Mutex
+	profileConfigs   = make(map[string]profileConfig)
+)
+
+// configForUser returns the global config with the user's profile applied.
+// Visitors who are not logged in (empty username) get the global config, as
+// do users whose stored profile no longer validates.
+func configForUser(username string) *AppConfig {
+	base, err := LoadConfig()
+	if err != nil {
+		base = getDefaultConfig()
+	}
+	if username == "" {
+		return base
+	}
+
+	profileConfigsMu.Lock()
+	defer profileConfigsMu.Unlock()
+	if c, ok := profileConfigs[username]; ok && c.base == base {
+		return c.cfg
+	}
+
+	cfg := base
+	if p, ok := loadUserProfile(username); ok {
+		effective, err := p.apply(base)
+		if err != nil {
+			log.Printf("Profile for %s is invalid, using the global config: %v", username, err)
+		} else {
+			cfg = effective
+		}
+	}
+	profileConfigs[username] = profileConfig{base: base, cfg: cfg}
+	return cfg
+}
+
+func forgetProfileConfig(username string) {
+	profileConfigsMu.Lock()
+	defer profileConfigsMu.Unlock()
+	delete(profileConfigs, username)
+}
+
+// handleProfile reads, replaces or resets the user's filter profile
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		p, _ := loadUserProfile(username)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"profile":   p,
+			"effective": sanitizeConfigForPublic(configForUser(username)),
+		})
+
+	case http.MethodPut, http.MethodPost:
+		var p UserProfile
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&p); err != nil {
+			jsonError(w, "Invalid profile: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		base, err := LoadConfig()
+		if err != nil {
+			base = getDefaultConfig()
+		}
+		effective, err := p.apply(base)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			resp := map[string]interface{}{"error": err.Error()}
+			if ce, ok := err.(*ConfigError); ok {
+				resp["problems"] = ce.Problems
+			}
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+
+		p.UpdatedAt = time.Now()
+		if err := saveUserProfile(username, p); err != nil {
+			log.Printf("Failed to save profile for %s: %v", username, err)
+			jsonError(w, "Failed to save profile", http.StatusInternalServerError)
+			return
+		}
+		forgetProfileConfig(username)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"profile":   p,
+			"effective": sanitizeConfigForPublic(effective),
+		})
+
+	case http.MethodDelete:
+		if err := deleteUserProfile(username); err != nil {
+			log.Printf("Failed to delete profile for %s: %v", username, err)
+			jsonError(w, "Failed to reset profile", http.StatusInternalServerError)
+			return
+		}
+		forgetProfileConfig(username)
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
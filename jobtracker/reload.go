@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configPollInterval is how often config.yaml is checked for changes
+const configPollInterval = 5 * time.Second
+
+// configReloadMu serialises loading and reloading config.yaml and guards
+// configStatus
+var configReloadMu sync.Mutex
+
+// configStatus describes the running config and the last file seen on disk
+var configStatus struct {
+	path     string
+	fromFile bool // false when running on the built-in defaults
+	loadedAt time.Time
+
+	// The last file contents seen, valid or not, so a broken file is only
+	// reported once rather than on every poll
+	seenPath string
+	seenHash [sha256.Size]byte
+
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// setRunningConfig installs a validated config. The caller holds
+// configReloadMu.
+func setRunningConfig(cfg *AppConfig, path string, data []byte) {
+	appConfig.Store(cfg)
+	configStatus.path = path
+	configStatus.fromFile = data != nil
+	configStatus.loadedAt = time.Now()
+	configStatus.seenPath = path
+	configStatus.seenHash = sha256.Sum256(data)
+	configStatus.lastError = ""
+}
+
+// reloadConfig re-reads config.yaml and swaps it in if it is valid. An
+// invalid or missing file is logged and the running config is kept, so a bad
+// edit never changes filtering.
+func reloadConfig(reason string) error {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	path := findConfigPath()
+	cfg, data, err := readConfig(path)
+	configStatus.seenPath = path
+	configStatus.seenHash = sha256.Sum256(data)
+	if err == nil && data == nil && configStatus.fromFile {
+		err = fmt.Errorf("%s not found", path)
+	}
+	if err != nil {
+		configStatus.lastError = err.Error()
+		configStatus.lastErrorAt = time.Now()
+		log.Printf("Config reload (%s) failed, keeping the running config: %v", reason, err)
+		return err
+	}
+
+	changes := diffConfigs(appConfig.Load(), cfg)
+	setRunningConfig(cfg, path, data)
+
+	keys := make([]string, 0, len(changes))
+	for _, c := range changes {
+		keys = append(keys, c.Key)
+	}
+	if len(keys) == 0 {
+		log.Printf("Config reloaded from %s (%s): no changes", path, reason)
+	} else {
+		log.Printf("Config reloaded from %s (%s): %d change(s): %s", path, reason, len(keys), strings.Join(keys, ", "))
+	}
+	return nil
+}
+
+// configFileChanged reports whether config.yaml differs from the last
+// version seen
+func configFileChanged() bool {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	path := findConfigPath()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false
+	}
+	return path != configStatus.seenPath || sha256.Sum256(data) != configStatus.seenHash
+}
+
+// watchConfig reloads config.yaml when it changes on disk or when the
+// process receives SIGHUP
+func watchConfig() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(configPollInterval)
+
+	go func() {
+		for {
+			select {
+			case <-hup:
+				reloadConfig("SIGHUP")
+			case <-ticker.C:
+				if configFileChanged() {
+					reloadConfig("file changed")
+				}
+			}
+		}
+	}()
+	log.Printf("Watching %s for changes (send SIGHUP to reload immediately)", findConfigPath())
+}
+
+// runValidateConfig implements -validate-config: it checks config.yaml and
+// returns the process exit code
+func runValidateConfig() int {
+	path := findConfigPath()
+	cfg, data, err := readConfig(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if data == nil {
+		fmt.Fprintf(os.Stderr, "%s not found\n", path)
+		return 1
+	}
+
+	f := cfg.Filters
+	fmt.Printf("%s is valid: %d categories, %d levels, %d certifications, %d exclude queries\n",
+		path, len(f.Categories), len(f.Levels), len(f.Certifications), len(f.Exclude.Queries))
+	return 0
+}
+
+// ConfigChange is one setting that differs between two configs
+type ConfigChange struct {
+	Key     string      `json:"key"`
+	Running interface{} `json:"running,omitempty"`
+	File    interface{} `json:"file,omitempty"`
+}
+
+// configView converts a config to plain maps keyed like config.yaml, with
+// Telegram credentials redacted
+func configView(cfg *AppConfig) map[string]interface{} {
+	if cfg == nil {
+		return nil
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil
+	}
+	var view map[string]interface{}
+	if err := yaml.Unmarshal(data, &view); err != nil {
+		return nil
+	}
+	if tg, ok := view["telegram"].(map[string]interface{}); ok {
+		for _, key := range []string{"bot_token", "chat_id"} {
+			if v, _ := tg[key].(string); v != "" {
+				tg[key] = "[redacted]"
+			}
+		}
+	}
+	return view
+}
+
+// diffConfigs lists the settings that differ between the running config and
+// a candidate, keyed by their dotted path in config.yaml. Lists are compared
+// as a whole.
+func diffConfigs(running, file *AppConfig) []ConfigChange {
+	a := make(map[string]interface{})
+	b := make(map[string]interface{})
+	flattenView("", configView(running), a)
+	flattenView("", configView(file), b)
+
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []ConfigChange{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			changes = append(changes, ConfigChange{Key: k, Running: a[k], File: b[k]})
+		}
+	}
+	return changes
+}
+
+func flattenView(prefix string, v interface{}, out map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if prefix != "" {
+			out[prefix] = v
+		}
+		return
+	}
+	for k, child := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		flattenView(key, child, out)
+	}
+}
+
+// handleAdminConfig shows the running config next to the file on disk: the
+// parsed file, its validation errors and what would change if it were
+// loaded. POST reloads the file, as SIGHUP does.
+func handleAdminConfig(w http.ResponseWriter, r *http.Request) {
+	reloaded := false
+	var reloadErr error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reloadErr = reloadConfig("requested by " + r.Header.Get("X-Auth-User"))
+		reloaded = reloadErr == nil
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	running, _ := LoadConfig()
+	path := findConfigPath()
+	fileCfg, data, err := readConfig(path)
+
+	problems := []ConfigProblem{}
+	if err != nil {
+		if ce, ok := err.(*ConfigError); ok {
+			problems = ce.Problems
+		} else {
+			problems = append(problems, ConfigProblem{Key: "config", Message: err.Error()})
+		}
+	}
+
+	configReloadMu.Lock()
+	status := map[string]interface{}{
+		"path":      configStatus.path,
+		"from_file": configStatus.fromFile,
+		"loaded_at": configStatus.loadedAt,
+	}
+	if configStatus.lastError != "" {
+		status["last_reload_error"] = configStatus.lastError
+		status["last_reload_error_at"] = configStatus.lastErrorAt
+	}
+	configReloadMu.Unlock()
+
+	resp := map[string]interface{}{
+		"running_config": status,
+		"path":           path,
+		"file_exists":    data != nil,
+		"valid":          err == nil,
+		"errors":         problems,
+		"running":        configView(running),
+	}
+	if err == nil && data != nil {
+		resp["config"] = configView(fileCfg)
+		resp["diff"] = diffConfigs(running, fileCfg)
+	}
+	if r.Method == http.MethodPost {
+		resp["reloaded"] = reloaded
+		if reloadErr != nil {
+			resp["error"] = reloadErr.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if reloadErr != nil {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
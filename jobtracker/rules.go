@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+	"gopkg.in/yaml.v3"
+)
+
+// Keyword is one entry in a rule's keyword list. In config.yaml it is either
+// a string or a mapping:
+//
+//	keywords:
+//	  - "pentest"                  # word, matched on word boundaries
+//	  - "red team"                 # phrase
+//	  - 'regex:\bsr\.?\s'          # regular expression (case-insensitive)
+//	  - regex: '^(senior|staff)\b'
+//	    fields: [title, location]  # overrides the rule's fields
+type Keyword struct {
+	Word   string   `yaml:"word,omitempty" json:"word,omitempty"`
+	Phrase string   `yaml:"phrase,omitempty" json:"phrase,omitempty"`
+	Regex  string   `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Fields []string `yaml:"fields,omitempty" json:"fields,omitempty"`
+
+	line int
+}
+
+// keywordFromString classifies a keyword written as a plain string
+func keywordFromString(s string) Keyword {
+	switch {
+	case strings.HasPrefix(s, query.RegexPrefix):
+		return Keyword{Regex: strings.TrimPrefix(s, query.RegexPrefix)}
+	case len(strings.Fields(s)) > 1:
+		return Keyword{Phrase: s}
+	default:
+		return Keyword{Word: s}
+	}
+}
+
+// keywords builds a keyword list from plain strings, for the built-in defaults
+func keywords(list ...string) []Keyword {
+	kws := make([]Keyword, 0, len(list))
+	for _, s := range list {
+		kws = append(kws, keywordFromString(s))
+	}
+	return kws
+}
+
+// UnmarshalYAML accepts either the string or the mapping form and records
+// the line for error messages
+func (k *Keyword) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*k = keywordFromString(node.Value)
+	} else {
+		type plain Keyword
+		if err := node.Decode((*plain)(k)); err != nil {
+			return err
+		}
+	}
+	k.line = node.Line
+	return nil
+}
+
+// MarshalYAML writes simple keywords back in the string form
+func (k Keyword) MarshalYAML() (interface{}, error) {
+	if len(k.Fields) > 0 {
+		type plain Keyword
+		return plain(k), nil
+	}
+	return k.String(), nil
+}
+
+// String returns the keyword in the form understood by query.Keywords
+func (k Keyword) String() string {
+	switch {
+	case k.Regex != "":
+		return query.RegexPrefix + k.Regex
+	case k.Phrase != "":
+		return k.Phrase
+	default:
+		return k.Word
+	}
+}
+
+// UnmarshalJSON accepts the same string or object forms as the YAML config,
+// for keywords sent to /api/profile
+func (k *Keyword) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*k = keywordFromString(s)
+		return nil
+	}
+	type plain Keyword
+	return json.Unmarshal(data, (*plain)(k))
+}
+
+// MarshalJSON writes simple keywords back in the string form
+func (k Keyword) MarshalJSON() ([]byte, error) {
+	if len(k.Fields) > 0 {
+		type plain Keyword
+		return json.Marshal(plain(k))
+	}
+	return json.Marshal(k.String())
+}
+
+// validate checks that exactly one form is used and that a word is a word
+func (k Keyword) validate() error {
+	set := 0
+	for _, v := range []string{k.Word, k.Phrase, k.Regex} {
+		if strings.TrimSpace(v) != "" {
+			set++
+		}
+	}
+	switch {
+	case set == 0:
+		return fmt.Errorf("empty keyword; set one of word, phrase or regex")
+	case set > 1:
+		return fmt.Errorf("keyword sets more than one of word, phrase and regex")
+	case k.Word != "" && len(strings.Fields(k.Word)) > 1:
+		return fmt.Errorf("word %q contains spaces; use phrase instead", k.Word)
+	case k.Regex != "":
+		if _, err := regexp.Compile(k.Regex); err != nil {
+			return fmt.Errorf("invalid regex %q: %v", k.Regex, err)
+		}
+	}
+	return validateFields(k.Fields)
+}
+
+// UnmarshalYAML records the lines of values that are validated later
+func (c *CategoryConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain CategoryConfig
+	if err := node.Decode((*plain)(c)); err != nil {
+		return err
+	}
+	c.queryLine = valueLine(node, "query")
+	c.fieldsLine = valueLine(node, "fields")
+	c.weightLine = valueLine(node, "weight")
+	return nil
+}
+
+// UnmarshalYAML records the line of each exclude query
+func (ex *ExcludeConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ExcludeConfig
+	if err := node.Decode((*plain)(ex)); err != nil {
+		return err
+	}
+	ex.queryLines = nil
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "queries" {
+			for _, item := range node.Content[i+1].Content {
+				ex.queryLines = append(ex.queryLines, item.Line)
+			}
+		}
+	}
+	return nil
+}
+
+// valueLine returns the line of key's value in a mapping node, falling back
+// to the line of the mapping itself
+func valueLine(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1].Line
+		}
+	}
+	return node.Line
+}
+
+// keywordFields are the job fields keyword rules can match against
+var keywordFields = []string{query.FieldTitle, query.FieldCompany, query.FieldLocation, query.FieldDescription}
+
+func validateFields(fields []string) error {
+	for _, f := range fields {
+		valid := false
+		for _, known := range keywordFields {
+			if f == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown field %q (expected one of %s)", f, strings.Join(keywordFields, ", "))
+		}
+	}
+	return nil
+}
+
+// ConfigError lists every problem found while validating config.yaml, so
+// they can all be fixed in one pass
+type ConfigError struct {
+	Path     string
+	Problems []ConfigProblem
+}
+
+// ConfigProblem is a single validation error. Line is 0 when unknown.
+type ConfigProblem struct {
+	Line    int    `json:"line,omitempty"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+func (p ConfigProblem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", p.Line, p.Key, p.Message)
+	}
+	return fmt.Sprintf("%s: %s", p.Key, p.Message)
+}
+
+func (e *ConfigError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+	return fmt.Sprintf("%s has %d error(s):\n  %s", e.Path, len(e.Problems), strings.Join(lines, "\n  "))
+}
+
+// add records a problem at the given line of the config file
+func (e *ConfigError) add(line int, key string, err error) {
+	e.Problems = append(e.Problems, ConfigProblem{Line: line, Key: key, Message: err.Error()})
+}
+
+// compileRules validates the filters and scoring sections and turns keyword lists and
+// query strings into queries. Keywords are matched as whole words, phrases
+// or regexes against the job title by default (title and location for
+// remote), so "ii" no longer matches "Engineering".
+func (cfg *AppConfig) compileRules(path string) error {
+	errs := &ConfigError{Path: path}
+	f := &cfg.Filters
+
+	for _, group := range []struct {
+		name  string
+		rules map[string]CategoryConfig
+	}{
+		{"categories", f.Categories},
+		{"levels", f.Levels},
+		{"certifications", f.Certifications},
+	} {
+		for id, rule := range group.rules {
+			rule.compile(errs, "filters."+group.name+"."+id, query.FieldTitle)
+			group.rules[id] = rule
+		}
+	}
+
+	f.Remote.compile(errs, "filters.remote", query.FieldTitle, query.FieldLocation)
+
+	ex := &f.Exclude
+	ex.defensiveQueries = compileKeywords(errs, "filters.exclude.defensive_roles", ex.DefensiveRoles, nil)
+	ex.nonSecurityQueries = compileKeywords(errs, "filters.exclude.non_security", ex.NonSecurity, nil)
+	ex.complianceQueries = compileKeywords(errs, "filters.exclude.compliance", ex.Compliance, nil)
+	ex.queries = nil
+	for i, raw := range ex.Queries {
+		line := 0
+		if i < len(ex.queryLines) {
+			line = ex.queryLines[i]
+		}
+		q, err := query.Parse(raw)
+		if err != nil {
+			errs.add(line, fmt.Sprintf("filters.exclude.queries[%d]", i), err)
+			continue
+		}
+		ex.queries = append(ex.queries, q)
+	}
+
+	cfg.Scoring.compile(errs, f.Levels)
+
+	if cfg.Scraper.CloseAfterMisses < 0 {
+		errs.add(0, "scraper.close_after_misses", fmt.Errorf("must not be negative"))
+	}
+	if cfg.Display.RecentlyClosedDays < 0 {
+		errs.add(0, "display.recently_closed_days", fmt.Errorf("must not be negative"))
+	}
+
+	if len(errs.Problems) > 0 {
+		// Rules live in maps, so sort to report them in file order
+		sort.SliceStable(errs.Problems, func(i, j int) bool {
+			return errs.Problems[i].Line < errs.Problems[j].Line
+		})
+		return errs
+	}
+	return nil
+}
+
+// compile prepares the rule's keywords and query for matching. defaults are
+// the fields used when neither the rule nor the keyword sets any.
+func (c *CategoryConfig) compile(errs *ConfigError, path string, defaults ...string) {
+	fields := defaults
+	if len(c.Fields) > 0 {
+		if err := validateFields(c.Fields); err != nil {
+			errs.add(c.fieldsLine, path+".fields", err)
+		} else {
+			fields = c.Fields
+		}
+	}
+	c.keywordQueries = compileKeywords(errs, path+".keywords", c.Keywords, fields)
+
+	if c.Weight < 0 {
+		errs.add(c.weightLine, path+".weight", fmt.Errorf("must not be negative"))
+	}
+
+	c.query = nil
+	if c.Query != "" {
+		q, err := query.Parse(c.Query)
+		if err != nil {
+			errs.add(c.queryLine, path+".query", err)
+			return
+		}
+		c.query = q
+	}
+}
+
+// matches reports whether the job matches any of the rule's keywords or its query
+func (c CategoryConfig) matches(doc query.Document) bool {
+	return matchAny(c.keywordQueries, doc) || (c.query != nil && c.query.MatchDocument(doc))
+}
+
+// compileKeywords builds one query per keyword, scoped to the keyword's own
+// fields, else fields, else the job title. Invalid keywords are reported to
+// errs and skipped.
+func compileKeywords(errs *ConfigError, path string, kws []Keyword, fields []string) []*query.Query {
+	var queries []*query.Query
+	for i, kw := range kws {
+		kwPath := fmt.Sprintf("%s[%d]", path, i)
+		if err := kw.validate(); err != nil {
+			errs.add(kw.line, kwPath, err)
+			continue
+		}
+
+		scope := fields
+		if len(kw.Fields) > 0 {
+			scope = kw.Fields
+		}
+		if len(scope) == 0 {
+			scope = []string{query.FieldTitle}
+		}
+
+		q, err := query.Keywords([]string{kw.String()}, scope...)
+		if err != nil {
+			errs.add(kw.line, kwPath, err)
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+func matchAny(queries []*query.Query, doc query.Document) bool {
+	for _, q := range queries {
+		if q.MatchDocument(doc) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadTestConfig parses and validates the config file text
+func loadTestConfig(t *testing.T, text string) (*AppConfig, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := readConfig(path)
+	return cfg, err
+}
+
+func TestKeywordFromString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Keyword
+	}{
+		{"pentest", Keyword{Word: "pentest"}},
+		{"red team", Keyword{Phrase: "red team"}},
+		{`regex:\bsr\.?\s`, Keyword{Regex: `\bsr\.?\s`}},
+		{"regex:red team", Keyword{Regex: "red team"}},
+	}
+	for _, tt := range tests {
+		got := keywordFromString(tt.in)
+		if got.Word != tt.want.Word || got.Phrase != tt.want.Phrase || got.Regex != tt.want.Regex {
+			t.Errorf("keywordFromString(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.in {
+			t.Errorf("keywordFromString(%q).String() = %q", tt.in, got.String())
+		}
+	}
+}
+
+func TestKeywordEncoding(t *testing.T) {
+	var kws []Keyword
+	err := yaml.Unmarshal([]byte(`
+- lead
+- "red team"
+- regex: '^staff\b'
+  fields: [title, company]
+`), &kws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kws) != 3 || kws[0].Word != "lead" || kws[1].Phrase != "red team" || kws[2].Regex != `^staff\b` {
+		t.Fatalf("decoded %+v", kws)
+	}
+	if kws[2].line != 4 || len(kws[2].Fields) != 2 {
+		t.Errorf("mapping keyword = %+v on line %d, want line 4 with 2 fields", kws[2], kws[2].line)
+	}
+
+	data, err := json.Marshal(kws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `["lead","red team",{"regex":"^staff\\b","fields":["title","company"]}]`
+	if string(data) != want {
+		t.Errorf("JSON = %s, want %s", data, want)
+	}
+	var back []Keyword
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back) != 3 || back[1].Phrase != "red team" || back[2].Regex != `^staff\b` || len(back[2].Fields) != 2 {
+		t.Errorf("JSON round trip = %+v", back)
+	}
+}
+
+func TestKeywordMatching(t *testing.T) {
+	cfg, err := loadTestConfig(t, `
+filters:
+  levels:
+    mid:
+      keywords: ["ii", "lead"]
+    senior:
+      keywords:
+        - 'regex:\bsr\.?\s'
+        - "principal engineer"
+  categories:
+    appsec:
+      keywords: ["appsec"]
+      fields: [title, description]
+    acme:
+      keywords:
+        - word: acme
+          fields: [company]
+  remote:
+    keywords: ["remote"]
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		rule CategoryConfig
+		job  Job
+		want bool
+	}{
+		{"word on boundaries", cfg.Filters.Levels["mid"], Job{Title: "Security Engineer II"}, true},
+		{"word inside another", cfg.Filters.Levels["mid"], Job{Title: "Security Engineering Manager"}, false},
+		{"word is case-insensitive", cfg.Filters.Levels["mid"], Job{Title: "LEAD Pentester"}, true},
+		{"word as a prefix", cfg.Filters.Levels["mid"], Job{Title: "Leadership Development"}, false},
+		{"phrase", cfg.Filters.Levels["senior"], Job{Title: "Principal Engineer, Offensive Security"}, true},
+		{"phrase out of order", cfg.Filters.Levels["senior"], Job{Title: "Engineer, Principal"}, false},
+		{"regex", cfg.Filters.Levels["senior"], Job{Title: "Sr. Penetration Tester"}, true},
+		{"regex miss", cfg.Filters.Levels["senior"], Job{Title: "Srinivasan Team Pentester"}, false},
+		{"title only by default", cfg.Filters.Levels["mid"], Job{Title: "Pentester", Description: "lead engagements"}, false},
+		{"rule fields", cfg.Filters.Categories["appsec"], Job{Title: "Engineer", Description: "Join our AppSec team"}, true},
+		{"rule fields leave out location", cfg.Filters.Categories["appsec"], Job{Title: "Engineer", Location: "AppSec HQ"}, false},
+		{"keyword fields", cfg.Filters.Categories["acme"], Job{Title: "Pentester", Company: "Acme Corp"}, true},
+		{"keyword fields override the rule", cfg.Filters.Categories["acme"], Job{Title: "Acme Pentester", Company: "Initech"}, false},
+		{"remote checks the location", cfg.Filters.Remote, Job{Title: "Pentester", Location: "Remote, US"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.matches(jobDocument(tt.job)); got != tt.want {
+			t.Errorf("%s: matches(%+v) = %v, want %v", tt.name, tt.job, got, tt.want)
+		}
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := loadTestConfig(t, `filters:
+  categories:
+    red_team:
+      keywords:
+        - "red team"
+        - 'regex:(unclosed'
+      fields: [title, salary]
+      weight: -1
+    appsec:
+      keywords:
+        - word: "app sec"
+        - phrase: "application security"
+          regex: "appsec"
+  exclude:
+    queries:
+      - "title:(manager"
+`)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("err = %v, want a ConfigError", err)
+	}
+	want := []struct {
+		line int
+		key  string
+		msg  string
+	}{
+		{6, "filters.categories.red_team.keywords[1]", "invalid regex"},
+		{7, "filters.categories.red_team.fields", `unknown field "salary"`},
+		{8, "filters.categories.red_team.weight", "must not be negative"},
+		{11, "filters.categories.appsec.keywords[0]", "use phrase instead"},
+		{12, "filters.categories.appsec.keywords[1]", "more than one"},
+		{16, "filters.exclude.queries[0]", ""},
+	}
+	if len(cfgErr.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(cfgErr.Problems), len(want), err)
+	}
+	for i, w := range want {
+		p := cfgErr.Problems[i]
+		if p.Line != w.line || p.Key != w.key || !strings.Contains(p.Message, w.msg) {
+			t.Errorf("problem %d = %s, want line %d: %s: %s", i, p, w.line, w.key, w.msg)
+		}
+	}
+	if !strings.Contains(err.Error(), "line 6: filters.categories.red_team.keywords[1]") {
+		t.Errorf("error message doesn't give lines:\n%v", err)
+	}
+}
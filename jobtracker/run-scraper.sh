@@ -38,7 +38,7 @@ SALARYSLEUTH_BIN="$PROJECT_ROOT/salarysleuth"
 if [ ! -f "$SALARYSLEUTH_BIN" ]; then
     log "Building salarysleuth executable..."
     cd "$PROJECT_ROOT"
-    go build -o "$SALARYSLEUTH_BIN" ./cmd/salarysleuth/main.go
+    go build -o "$SALARYSLEUTH_BIN" ./cmd/salarysleuth
     if [ $? -ne 0 ]; then
         log "ERROR: Failed to build salarysleuth executable"
         exit 1
@@ -1,11 +1,7 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
-	"os"
-	"path/filepath"
-	"sync"
 	"time"
 )
 
@@ -19,6 +15,18 @@ type SavedJob struct {
 	Salary   string    `json:"salary,omitempty"`
 	SavedAt  time.Time `json:"saved_at"`
 	Note     string    `json:"note,omitempty"`
+
+	// Application tracking; see applications.go. A saved job starts at the
+	// interested stage.
+	Stage        string        `json:"stage,omitempty"`
+	StageHistory []StageChange `json:"stage_history,omitempty"`
+	Contacts     []Contact     `json:"contacts,omitempty"`
+	Interviews   []Interview   `json:"interviews,omitempty"`
+	Offer        *Offer        `json:"offer,omitempty"`
+	FollowUp     string        `json:"follow_up,omitempty"` // YYYY-MM-DD
+	FollowUpNote string        `json:"follow_up_note,omitempty"`
+	// FollowUpSent is the follow-up date a reminder was last sent for
+	FollowUpSent string `json:"follow_up_sent,omitempty"`
 }
 
 type UserSavedJobs struct {
@@ -26,90 +34,81 @@ type UserSavedJobs struct {
 	Jobs     []SavedJob `json:"jobs"`
 }
 
-var savedJobsMu sync.RWMutex
-
-func savedJobsDir() string {
-	return filepath.Join(config.DataDir, "saved_jobs")
-}
-
-func userSavedJobsFile(username string) string {
-	return filepath.Join(savedJobsDir(), username+".json")
-}
-
-func ensureSavedJobsDir() {
-	os.MkdirAll(savedJobsDir(), 0700)
-}
-
 func loadUserSavedJobs(username string) *UserSavedJobs {
-	savedJobsMu.RLock()
-	defer savedJobsMu.RUnlock()
-	return loadUserSavedJobsUnsafe(username)
-}
-
-func loadUserSavedJobsUnsafe(username string) *UserSavedJobs {
-	data, err := os.ReadFile(userSavedJobsFile(username))
+	var saved *UserSavedJobs
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		saved, err = readUserSavedJobs(tx, username)
+		return err
+	})
 	if err != nil {
+		log.Printf("Failed to load saved jobs for %s: %v", username, err)
 		return &UserSavedJobs{Username: username, Jobs: []SavedJob{}}
 	}
-	var saved UserSavedJobs
-	if err := json.Unmarshal(data, &saved); err != nil {
-		return &UserSavedJobs{Username: username, Jobs: []SavedJob{}}
+	return saved
+}
+
+func readUserSavedJobs(tx Tx, username string) (*UserSavedJobs, error) {
+	saved := &UserSavedJobs{Username: username}
+	if _, err := tx.Get(collSavedJobs, username, saved); err != nil {
+		return nil, err
 	}
 	if saved.Jobs == nil {
 		saved.Jobs = []SavedJob{}
 	}
-	return &saved
-}
-
-func saveUserSavedJobsUnsafe(data *UserSavedJobs) error {
-	ensureSavedJobsDir()
-	out, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(userSavedJobsFile(data.Username), out, 0600)
+	return saved, nil
 }
 
 func addSavedJob(username string, job SavedJob) (bool, error) {
-	savedJobsMu.Lock()
-	defer savedJobsMu.Unlock()
-
-	store := loadUserSavedJobsUnsafe(username)
-	for _, j := range store.Jobs {
-		if j.JobID == job.JobID {
-			return false, nil // already saved
+	added := false
+	err := dataStore.Update(func(tx Tx) error {
+		store, err := readUserSavedJobs(tx, username)
+		if err != nil {
+			return err
 		}
-	}
-	store.Jobs = append(store.Jobs, job)
-	if err := saveUserSavedJobsUnsafe(store); err != nil {
+		for _, j := range store.Jobs {
+			if j.JobID == job.JobID {
+				return nil // already saved
+			}
+		}
+		job.Stage = StageInterested
+		job.StageHistory = []StageChange{{At: job.SavedAt, To: StageInterested}}
+		store.Jobs = append(store.Jobs, job)
+		added = true
+		return tx.Put(collSavedJobs, username, store)
+	})
+	if err != nil {
 		return false, err
 	}
-	return true, nil
+	return added, nil
 }
 
 func removeSavedJob(username, jobID string) bool {
-	savedJobsMu.Lock()
-	defer savedJobsMu.Unlock()
-
-	store := loadUserSavedJobsUnsafe(username)
 	found := false
-	filtered := make([]SavedJob, 0, len(store.Jobs))
-	for _, j := range store.Jobs {
-		if j.JobID == jobID {
-			found = true
-			continue
+	err := dataStore.Update(func(tx Tx) error {
+		store, err := readUserSavedJobs(tx, username)
+		if err != nil {
+			return err
 		}
-		filtered = append(filtered, j)
-	}
-	if !found {
-		return false
-	}
-	store.Jobs = filtered
-	if err := saveUserSavedJobsUnsafe(store); err != nil {
+		filtered := make([]SavedJob, 0, len(store.Jobs))
+		for _, j := range store.Jobs {
+			if j.JobID == jobID {
+				found = true
+				continue
+			}
+			filtered = append(filtered, j)
+		}
+		if !found {
+			return nil
+		}
+		store.Jobs = filtered
+		return tx.Put(collSavedJobs, username, store)
+	})
+	if err != nil {
 		log.Printf("Failed to save jobs for %s: %v", username, err)
 		return false
 	}
-	return true
+	return found
 }
 
 func getUserSavedJobIDs(username string) map[string]bool {
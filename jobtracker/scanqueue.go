@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scanSources are the scraper's sources, run in parallel within a scan
+var scanSources = []string{"linkedin", "greenhouse", "lever"}
+
+// errSearchCancelled is returned for a scan cancelled before it finished
+var errSearchCancelled = errors.New("search cancelled")
+
+// errSourceTimedOut is wrapped by the error for a source that took longer
+// than scanSourceTimeout
+var errSourceTimedOut = errors.New("timed out")
+
+// scanSourceTimeout bounds each source's scrape, from when it gets a slot
+var scanSourceTimeout = 3 * time.Minute
+
+// scans is the queue every scheduled scan, custom search and manual refresh
+// runs through. SCAN_WORKERS scans run at once, and each source is scraped
+// by at most SCAN_SOURCE_CONCURRENCY of them at a time.
+var scans = newScanQueue(envInt("SCAN_WORKERS", 2), envInt("SCAN_SOURCE_CONCURRENCY", 1))
+
+func envInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		log.Printf("Invalid %s %q, using %d", key, val, def)
+		return def
+	}
+	return n
+}
+
+// Source states in a scan's progress
+const (
+	SourceWaiting = "waiting" // for the scan to start or a slot for the source
+	SourceRunning = "running"
+	SourceDone    = "done"
+	SourceFailed  = "failed"
+)
+
+// SourceProgress is how far a scan has got with one source
+type SourceProgress struct {
+	Status string `json:"status"`
+	Jobs   int    `json:"jobs"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Scan events, as sent to the tickets watching a scan
+const (
+	EventSourceStarted = "source_started" // the source got a slot and is being scraped
+	EventPage          = "page"           // the source fetched a page
+	EventEnrich        = "enrich"         // a company was looked up on Levels.fyi
+	EventJobs          = "jobs"           // the source finished, with the jobs it found
+	EventSourceFailed  = "source_failed"
+)
+
+// ScanEvent is a step in a scan's progress
+type ScanEvent struct {
+	Type     string
+	Source   string
+	Pages    int   // pages the source has fetched
+	Found    int   // jobs the source has found so far
+	Jobs     []Job // for EventJobs; only the source's, not the whole scan's
+	Done     int   // companies looked up, for EventEnrich
+	Total    int   // companies to look up, for EventEnrich
+	Error    string
+	TimedOut bool // for EventSourceFailed, when the source hit scanSourceTimeout
+}
+
+// scanTask is a queued or running scan of one query. Identical scans
+// submitted while it waits or runs share it rather than scraping again.
+type scanTask struct {
+	key    string
+	query  string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	waiters int // tickets that haven't cancelled, guarded by the queue's mutex
+
+	mu      sync.Mutex
+	jobs    []Job // from the sources that have finished
+	sources map[string]SourceProgress
+	err     error         // set when every source failed
+	tickets []*scanTicket // those watching for events
+}
+
+// scanTicket is one submitter's interest in a scan
+type scanTicket struct {
+	q         *scanQueue
+	task      *scanTask
+	once      sync.Once
+	cancelled chan struct{}
+	watch     func(ScanEvent) // called with task.mu held, so it mustn't call back into the ticket
+}
+
+type scanQueue struct {
+	mu      sync.Mutex
+	wake    *sync.Cond
+	pending []*scanTask
+	tasks   map[string]*scanTask // queued or running, by key
+	slots   map[string]chan struct{}
+
+	// scrapeSource scrapes one source for a query
+	scrapeSource func(ctx context.Context, query string, pages int, source string, onProgress func(scraperProgress)) ([]Job, error)
+}
+
+func newScanQueue(workers, perSource int) *scanQueue {
+	q := &scanQueue{
+		tasks:        make(map[string]*scanTask),
+		slots:        make(map[string]chan struct{}),
+		scrapeSource: runSingleSourceSearch,
+	}
+	q.wake = sync.NewCond(&q.mu)
+	for _, source := range scanSources {
+		q.slots[source] = make(chan struct{}, perSource)
+	}
+	for i := 0; i < workers; i++ {
+		go q.worker()
+	}
+	return q
+}
+
+// scanKey identifies scans that would scrape the same thing
+func scanKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// submit queues a scan of query, or joins the identical scan already waiting
+// or running
+func (q *scanQueue) submit(query string) *scanTicket {
+	return q.submitWatched(query, nil)
+}
+
+// submitWatched is submit, calling watch with each of the scan's events. On
+// joining a scan already running, watch first gets events for how far it has
+// got.
+func (q *scanQueue) submitWatched(query string, watch func(ScanEvent)) *scanTicket {
+	key := scanKey(query)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	task := q.tasks[key]
+	// A cancelled scan still running can't be joined
+	if task == nil || task.ctx.Err() != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		task = &scanTask{key: key, query: query, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+		task.sources = make(map[string]SourceProgress, len(scanSources))
+		for _, source := range scanSources {
+			task.sources[source] = SourceProgress{Status: SourceWaiting}
+		}
+		q.tasks[key] = task
+		q.pending = append(q.pending, task)
+		q.wake.Signal()
+	} else {
+		log.Printf("Scan queue: joining the scan already queued for %q", query)
+	}
+	task.waiters++
+	t := &scanTicket{q: q, task: task, cancelled: make(chan struct{}), watch: watch}
+	if watch != nil {
+		task.mu.Lock()
+		task.catchUp(t)
+		task.tickets = append(task.tickets, t)
+		task.mu.Unlock()
+	}
+	return t
+}
+
+// catchUp sends a ticket joining the task events for how far it has got.
+// task.mu must be held.
+func (task *scanTask) catchUp(t *scanTicket) {
+	for _, source := range scanSources {
+		switch p := task.sources[source]; p.Status {
+		case SourceRunning:
+			t.watch(ScanEvent{Type: EventSourceStarted, Source: source})
+		case SourceFailed:
+			t.watch(ScanEvent{Type: EventSourceFailed, Source: source, Error: p.Error})
+		}
+	}
+	if len(task.jobs) > 0 {
+		t.watch(ScanEvent{Type: EventJobs, Jobs: append([]Job(nil), task.jobs...)})
+	}
+}
+
+// emit sends an event to the tickets watching the task. task.mu must be held.
+func (task *scanTask) emit(ev ScanEvent) {
+	for _, t := range task.tickets {
+		if !t.isCancelled() {
+			t.watch(ev)
+		}
+	}
+}
+
+// runQueuedScan scans query through the queue and waits for the results
+func runQueuedScan(query string) ([]Job, error) {
+	return scans.submit(query).wait()
+}
+
+func (q *scanQueue) worker() {
+	for {
+		q.mu.Lock()
+		for len(q.pending) == 0 {
+			q.wake.Wait()
+		}
+		task := q.pending[0]
+		q.pending = q.pending[1:]
+		q.mu.Unlock()
+
+		q.run(task)
+
+		q.mu.Lock()
+		if q.tasks[task.key] == task {
+			delete(q.tasks, task.key)
+		}
+		q.mu.Unlock()
+		task.cancel()
+		close(task.done)
+	}
+}
+
+// run scrapes every source for the task, each once a slot for it is free
+func (q *scanQueue) run(task *scanTask) {
+	log.Printf("Scan queue: scanning %q", task.query)
+	var wg sync.WaitGroup
+	var failed []error
+	for _, src := range scanSources {
+		wg.Add(1)
+		go func(source string) {
+			defer wg.Done()
+			jobs, err := q.scrape(task, source)
+			task.mu.Lock()
+			defer task.mu.Unlock()
+			if err != nil {
+				if task.ctx.Err() == nil {
+					log.Printf("Scan queue: source %s failed for %q: %v", source, task.query, err)
+				}
+				task.sources[source] = SourceProgress{Status: SourceFailed, Error: err.Error()}
+				failed = append(failed, err)
+				if err != errSearchCancelled {
+					task.emit(ScanEvent{Type: EventSourceFailed, Source: source, Error: err.Error(),
+						TimedOut: errors.Is(err, errSourceTimedOut)})
+				}
+				return
+			}
+			task.sources[source] = SourceProgress{Status: SourceDone, Jobs: len(jobs)}
+			task.jobs = append(task.jobs, jobs...)
+			task.emit(ScanEvent{Type: EventJobs, Source: source, Found: len(jobs), Jobs: jobs})
+		}(src)
+	}
+	wg.Wait()
+
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	switch {
+	case task.ctx.Err() != nil:
+		task.err = errSearchCancelled
+	case len(failed) == len(scanSources):
+		task.err = failed[0]
+	}
+	log.Printf("Scan queue: %q found %d jobs", task.query, len(task.jobs))
+}
+
+func (q *scanQueue) scrape(task *scanTask, source string) ([]Job, error) {
+	slot := q.slots[source]
+	select {
+	case slot <- struct{}{}:
+		defer func() { <-slot }()
+	case <-task.ctx.Done():
+		return nil, errSearchCancelled
+	}
+	task.mu.Lock()
+	task.sources[source] = SourceProgress{Status: SourceRunning}
+	task.emit(ScanEvent{Type: EventSourceStarted, Source: source})
+	task.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(task.ctx, scanSourceTimeout)
+	defer cancel()
+	jobs, err := q.scrapeSource(ctx, task.query, config.Pages, source, func(p scraperProgress) {
+		ev := ScanEvent{Type: EventPage, Source: source, Pages: p.Pages, Found: p.Found}
+		if p.Event == "enrich" {
+			ev = ScanEvent{Type: EventEnrich, Source: source, Done: p.Done, Total: p.Total}
+		}
+		task.mu.Lock()
+		task.emit(ev)
+		task.mu.Unlock()
+	})
+	switch {
+	case err == nil:
+		return jobs, nil
+	case task.ctx.Err() != nil:
+		return nil, errSearchCancelled
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("%s %w after %s", source, errSourceTimedOut, scanSourceTimeout)
+	}
+	return nil, err
+}
+
+// wait blocks until the scan finishes or the ticket is cancelled and
+// returns the scan's results
+func (t *scanTicket) wait() ([]Job, error) {
+	select {
+	case <-t.task.done:
+	case <-t.cancelled:
+	}
+	if t.isCancelled() {
+		return nil, errSearchCancelled
+	}
+	return t.results()
+}
+
+// results are the jobs found so far and, once the scan is done, its error
+func (t *scanTicket) results() ([]Job, error) {
+	t.task.mu.Lock()
+	defer t.task.mu.Unlock()
+	return append([]Job(nil), t.task.jobs...), t.task.err
+}
+
+// isCancelled reports whether the ticket has been cancelled
+func (t *scanTicket) isCancelled() bool {
+	select {
+	case <-t.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// progress is how far the scan has got with each source
+func (t *scanTicket) progress() map[string]SourceProgress {
+	t.task.mu.Lock()
+	defer t.task.mu.Unlock()
+	progress := make(map[string]SourceProgress, len(t.task.sources))
+	for source, p := range t.task.sources {
+		progress[source] = p
+	}
+	return progress
+}
+
+// cancel gives up the ticket's interest in the scan, stopping it when no
+// one else is waiting for it
+func (t *scanTicket) cancel() {
+	t.once.Do(func() {
+		close(t.cancelled)
+		q, task := t.q, t.task
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		task.waiters--
+		if task.waiters > 0 {
+			return
+		}
+		task.cancel()
+		for i, p := range q.pending {
+			if p == task {
+				// It never started, so finish it here
+				q.pending = append(q.pending[:i:i], q.pending[i+1:]...)
+				delete(q.tasks, task.key)
+				task.mu.Lock()
+				task.err = errSearchCancelled
+				task.mu.Unlock()
+				close(task.done)
+				break
+			}
+		}
+	})
+}
+
+// position is the scan's place in the queue: 0 once it is running or done,
+// otherwise 1 for the next scan to start and so on
+func (t *scanTicket) position() int {
+	t.q.mu.Lock()
+	defer t.q.mu.Unlock()
+	for i, p := range t.q.pending {
+		if p == t.task {
+			return i + 1
+		}
+	}
+	return 0
+}
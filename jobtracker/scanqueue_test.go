@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeScraper stands in for the scraper. Each query's scrapes block until
+// the query is released or their context ends.
+type fakeScraper struct {
+	mu         sync.Mutex
+	gates      map[string]chan struct{}
+	calls      map[string]int // scrapes started, by query
+	running    map[string]int // scrapes running, by source
+	maxRunning map[string]int
+}
+
+// newTestScanQueue returns a queue scraping with a fakeScraper
+func newTestScanQueue(workers, perSource int) (*scanQueue, *fakeScraper) {
+	f := &fakeScraper{
+		gates:      make(map[string]chan struct{}),
+		calls:      make(map[string]int),
+		running:    make(map[string]int),
+		maxRunning: make(map[string]int),
+	}
+	q := newScanQueue(workers, perSource)
+	q.scrapeSource = f.scrape
+	return q, f
+}
+
+func (f *fakeScraper) gate(query string) chan struct{} {
+	if f.gates[query] == nil {
+		f.gates[query] = make(chan struct{})
+	}
+	return f.gates[query]
+}
+
+func (f *fakeScraper) release(query string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.gate(query))
+}
+
+func (f *fakeScraper) scrape(ctx context.Context, query string, pages int, source string, onProgress func(scraperProgress)) ([]Job, error) {
+	f.mu.Lock()
+	gate := f.gate(query)
+	f.calls[query]++
+	f.running[source]++
+	if f.running[source] > f.maxRunning[source] {
+		f.maxRunning[source] = f.running[source]
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running[source]--
+		f.mu.Unlock()
+	}()
+
+	select {
+	case <-gate:
+		return []Job{{ID: query + "/" + source, Title: query}}, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s killed", source)
+	}
+}
+
+// started is how many scrapes of query have started
+func (f *fakeScraper) started(query string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[query]
+}
+
+// waitFor fails the test unless cond becomes true within a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScanQueueSharesIdenticalScans(t *testing.T) {
+	q, f := newTestScanQueue(1, 1)
+
+	a := q.submit("Red Team")
+	b := q.submit("  red   TEAM ")
+	if a.task != b.task {
+		t.Fatal("identical queries got separate scans")
+	}
+	other := q.submit("blue team")
+	defer other.cancel()
+	if other.task == a.task {
+		t.Fatal("different queries share a scan")
+	}
+
+	f.release("Red Team")
+	for _, ticket := range []*scanTicket{a, b} {
+		jobs, err := ticket.wait()
+		if err != nil || len(jobs) != len(scanSources) {
+			t.Errorf("wait = %d jobs, %v, want %d jobs", len(jobs), err, len(scanSources))
+		}
+	}
+	if n := f.started("Red Team"); n != len(scanSources) {
+		t.Errorf("shared scan scraped %d times, want once per source", n)
+	}
+
+	// A finished scan isn't joined
+	waitFor(t, "the scan to leave the queue", func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return q.tasks[scanKey("red team")] == nil
+	})
+	again := q.submit("red team")
+	defer again.cancel()
+	if again.task == a.task {
+		t.Error("a new scan joined the finished one")
+	}
+}
+
+func TestScanQueueWorkers(t *testing.T) {
+	q, f := newTestScanQueue(1, len(scanSources))
+
+	first := q.submit("first")
+	second := q.submit("second")
+	waitFor(t, "the first scan to start", func() bool { return f.started("first") == len(scanSources) })
+	if pos := first.position(); pos != 0 {
+		t.Errorf("running scan's position = %d, want 0", pos)
+	}
+	if pos := second.position(); pos != 1 {
+		t.Errorf("waiting scan's position = %d, want 1", pos)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if n := f.started("second"); n != 0 {
+		t.Fatalf("second scan started %d scrapes with one worker busy", n)
+	}
+
+	f.release("first")
+	if _, err := first.wait(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the second scan to start", func() bool { return f.started("second") == len(scanSources) })
+	f.release("second")
+	if _, err := second.wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScanQueueSourceSlots(t *testing.T) {
+	q, f := newTestScanQueue(2, 1)
+
+	first := q.submit("first")
+	second := q.submit("second")
+	waitFor(t, "a scrape of every source", func() bool { return f.started("first")+f.started("second") == len(scanSources) })
+	time.Sleep(10 * time.Millisecond)
+	if n := f.started("first") + f.started("second"); n != len(scanSources) {
+		t.Errorf("%d scrapes started with one slot per source, want %d", n, len(scanSources))
+	}
+	statuses := make(map[string]int)
+	for _, ticket := range []*scanTicket{first, second} {
+		for _, p := range ticket.progress() {
+			statuses[p.Status]++
+		}
+	}
+	if statuses[SourceRunning] != len(scanSources) || statuses[SourceWaiting] != len(scanSources) {
+		t.Errorf("source states = %v, want %d running and %d waiting for a slot", statuses, len(scanSources), len(scanSources))
+	}
+
+	f.release("first")
+	f.release("second")
+	for _, ticket := range []*scanTicket{first, second} {
+		if jobs, err := ticket.wait(); err != nil || len(jobs) != len(scanSources) {
+			t.Errorf("wait = %d jobs, %v, want %d jobs", len(jobs), err, len(scanSources))
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, source := range scanSources {
+		if n := f.maxRunning[source]; n != 1 {
+			t.Errorf("%s scraped by %d scans at once, want 1", source, n)
+		}
+	}
+}
+
+func TestScanQueueTimeout(t *testing.T) {
+	prev := scanSourceTimeout
+	scanSourceTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { scanSourceTimeout = prev })
+	q, _ := newTestScanQueue(1, 1)
+
+	var mu sync.Mutex
+	var failed []ScanEvent
+	ticket := q.submitWatched("slow", func(ev ScanEvent) {
+		if ev.Type == EventSourceFailed {
+			mu.Lock()
+			failed = append(failed, ev)
+			mu.Unlock()
+		}
+	})
+	if _, err := ticket.wait(); !errors.Is(err, errSourceTimedOut) {
+		t.Errorf("wait err = %v, want errSourceTimedOut", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(failed) != len(scanSources) {
+		t.Fatalf("got %d source_failed events, want %d", len(failed), len(scanSources))
+	}
+	for _, ev := range failed {
+		if !ev.TimedOut {
+			t.Errorf("%s failed with %q, not marked as timed out", ev.Source, ev.Error)
+		}
+	}
+}
+
+func TestScanQueueCancel(t *testing.T) {
+	q, f := newTestScanQueue(1, len(scanSources))
+
+	running := q.submit("running")
+	joined := q.submit("running")
+	queued := q.submit("queued")
+	waitFor(t, "the scan to start", func() bool { return f.started("running") == len(scanSources) })
+
+	queued.cancel()
+	if _, err := queued.wait(); err != errSearchCancelled {
+		t.Errorf("cancelled queued scan: err = %v, want errSearchCancelled", err)
+	}
+	<-queued.task.done
+	if pos := queued.position(); pos != 0 {
+		t.Errorf("cancelled scan still queued at %d", pos)
+	}
+
+	// The scan keeps going while another ticket wants it
+	running.cancel()
+	if running.task.ctx.Err() != nil {
+		t.Fatal("cancelling one of two tickets stopped the scan")
+	}
+	joined.cancel()
+	<-joined.task.done
+	if _, err := joined.results(); err != errSearchCancelled {
+		t.Errorf("cancelled scan: err = %v, want errSearchCancelled", err)
+	}
+	if n := f.started("queued"); n != 0 {
+		t.Errorf("cancelled queued scan scraped %d times", n)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -22,23 +24,120 @@ func startScheduler() {
 	schedulerRunning = true
 	schedulerMu.Unlock()
 
-	log.Println("Scan scheduler started (checks every 60 seconds)")
+	log.Println("Scan scheduler started (checks schedules, -scrape requests, queued alerts and follow-ups every 60 seconds)")
 	go schedulerLoop()
 }
 
 func schedulerLoop() {
 	for {
 		time.Sleep(60 * time.Second)
+		checkRefreshRequest()
 		checkAndRunSchedules()
+		flushOutbox(time.Now())
+		checkFollowUps(time.Now())
 	}
 }
 
+// scheduleCatchUp is how late a run missed while the server was down can
+// still start, from SCHEDULE_CATCHUP_WINDOW; older runs are skipped
+var scheduleCatchUp = catchUpWindow()
+
+// scheduleLateness is how late a run can start and still count as on time,
+// since schedules are checked once a minute
+const scheduleLateness = 2 * time.Minute
+
+// minScheduleInterval is the shortest time allowed between a schedule's runs
+const minScheduleInterval = time.Hour
+
+// runningSchedules holds the schedules whose scan hasn't finished, by
+// username and schedule ID, so a slow scan isn't started twice
+var (
+	runningMu        sync.Mutex
+	runningSchedules = make(map[string]bool)
+)
+
+func catchUpWindow() time.Duration {
+	val := os.Getenv("SCHEDULE_CATCHUP_WINDOW")
+	if val == "" {
+		return 6 * time.Hour
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d < 0 {
+		log.Printf("Invalid SCHEDULE_CATCHUP_WINDOW %q, missed runs will not be caught up", val)
+		return 0
+	}
+	return d
+}
+
+// location is the zone the schedule's cron expression runs in
+func (s ScheduledScan) location() *time.Location {
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// cronExpr is the schedule's cron expression, converting the frequency
+// schedules stored before cron expressions
+func (s ScheduledScan) cronExpr() string {
+	if s.Cron != "" || s.Schedule == "" {
+		return s.Cron
+	}
+	days := "*"
+	switch s.Schedule {
+	case "weekdays":
+		days = "1-5"
+	case "weekly":
+		days = "1" // Monday
+	case "custom":
+		var list []string
+		for _, d := range s.Days {
+			list = append(list, strconv.Itoa(d))
+		}
+		days = strings.Join(list, ",")
+	}
+	return fmt.Sprintf("%d %d * * %s", s.Minute, s.Hour, days)
+}
+
+// nextRun is the schedule's first run after t, or the zero time if its
+// expression is invalid or never matches
+func (s ScheduledScan) nextRun(t time.Time) time.Time {
+	spec, err := parseCron(s.cronExpr())
+	if err != nil {
+		return time.Time{}
+	}
+	return spec.next(t.In(s.location()))
+}
+
+// validateSchedule checks a schedule's cron expression and time zone
+func validateSchedule(s ScheduledScan) error {
+	spec, err := parseCron(s.Cron)
+	if err != nil {
+		return err
+	}
+	if s.Timezone != "" {
+		if _, err := time.LoadLocation(s.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", s.Timezone)
+		}
+	}
+	now := time.Now().In(s.location())
+	if spec.next(now).IsZero() {
+		return fmt.Errorf("cron expression %q never runs", s.Cron)
+	}
+	if spec.minGap(now, minScheduleInterval) < minScheduleInterval {
+		return fmt.Errorf("schedules can run at most once an hour")
+	}
+	return nil
+}
+
 func checkAndRunSchedules() {
 	now := time.Now()
 	allAlerts := loadAllUserAlerts()
 
 	for username, cfg := range allAlerts {
-		if !cfg.Telegram.Enabled || !cfg.Telegram.Verified {
+		if !cfg.hasAlertChannel() {
 			continue
 		}
 
@@ -46,134 +145,189 @@ func checkAndRunSchedules() {
 			if !sched.Enabled {
 				continue
 			}
-			if !shouldRunNow(sched, now) {
+			due, ok := dispatchSchedule(username, sched.ID, now)
+			if !ok {
 				continue
 			}
 
-			log.Printf("Scheduler: running scan '%s' for user %s", sched.Name, username)
-			go executeScheduledScan(username, cfg, i)
+			key := username + "/" + sched.ID
+			runningMu.Lock()
+			running := runningSchedules[key]
+			runningSchedules[key] = true
+			runningMu.Unlock()
+			if running {
+				log.Printf("Scheduler: skipping scan '%s' for user %s, the last run is still going", sched.Name, username)
+				continue
+			}
+
+			late := now.Sub(due) > scheduleLateness
+			if late {
+				log.Printf("Scheduler: catching up scan '%s' for user %s, due %s", sched.Name, username, due.Format(time.RFC3339))
+			} else {
+				log.Printf("Scheduler: running scan '%s' for user %s", sched.Name, username)
+			}
+			go func(i int) {
+				defer func() {
+					runningMu.Lock()
+					delete(runningSchedules, key)
+					runningMu.Unlock()
+				}()
+				executeScheduledScan(username, cfg, i, late)
+			}(i)
 		}
 	}
 }
 
-func shouldRunNow(sched ScheduledScan, now time.Time) bool {
-	if now.Hour() != sched.Hour || now.Minute() != sched.Minute {
-		return false
-	}
+// dispatchSchedule moves the schedule's next run past now once it is due and
+// reports the run that was due and whether to start it. Runs missed by more
+// than scheduleCatchUp are skipped, and several missed runs start only once.
+// A schedule without a next run, such as one stored before next runs were
+// kept, gets one after its last run without starting.
+func dispatchSchedule(username, schedID string, now time.Time) (time.Time, bool) {
+	var due time.Time
+	var run bool
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		for i := range cfg.Schedules {
+			sched := &cfg.Schedules[i]
+			if sched.ID != schedID || !sched.Enabled {
+				continue
+			}
+			if sched.NextRun == "" {
+				from := now
+				if last, err := time.Parse(time.RFC3339, sched.LastRun); err == nil {
+					from = last
+				}
+				sched.NextRun = formatNextRun(sched.nextRun(from))
+				return nil
+			}
+			next, err := time.Parse(time.RFC3339, sched.NextRun)
+			if err != nil || next.After(now) {
+				return nil
+			}
 
-	if sched.LastRun != "" {
-		lastRun, err := time.Parse(time.RFC3339, sched.LastRun)
-		if err == nil && now.Sub(lastRun) < 23*time.Hour {
-			return false
+			due = next
+			sched.NextRun = formatNextRun(sched.nextRun(now))
+			if late := now.Sub(due); late > scheduleLateness && late > scheduleCatchUp {
+				sched.LastResult = fmt.Sprintf("Missed run due %s", due.In(sched.location()).Format("Jan 2 15:04 MST"))
+				return nil
+			}
+			run = true
 		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Scheduler: failed to update next run for %s: %v", username, err)
+		return due, false
 	}
+	return due, run
+}
 
-	weekday := int(now.Weekday())
-
-	switch sched.Schedule {
-	case "daily":
-		return true
-	case "weekdays":
-		return weekday >= 1 && weekday <= 5
-	case "weekly":
-		return weekday == 1 // Monday
-	case "custom":
-		for _, d := range sched.Days {
-			if d == weekday {
-				return true
-			}
-		}
-		return false
+// formatNextRun stores a next run, empty for a schedule that never runs
+func formatNextRun(t time.Time) string {
+	if t.IsZero() {
+		return ""
 	}
-	return false
+	return t.Format(time.RFC3339)
 }
 
-func executeScheduledScan(username string, cfg UserAlertConfig, schedIdx int) {
+// executeScheduledScan runs a schedule's scan and sends its alert; catchUp
+// marks a run started late, after the server missed it
+func executeScheduledScan(username string, cfg UserAlertConfig, schedIdx int, catchUp bool) {
 	sched := cfg.Schedules[schedIdx]
 	var jobs []Job
-	var err error
+	var scanErr error
 	var resultMsg string
 
 	if sched.Type == "default" {
-		jobs, err = runScheduledDefaultScan()
-		if err != nil {
-			resultMsg = fmt.Sprintf("Error: %v", err)
-			log.Printf("Scheduler: default scan failed for %s: %v", username, err)
+		jobs, scanErr = runScheduledDefaultScan(username)
+		if scanErr != nil {
+			resultMsg = fmt.Sprintf("Error: %v", scanErr)
+			log.Printf("Scheduler: default scan failed for %s: %v", username, scanErr)
 		} else {
 			resultMsg = fmt.Sprintf("Found %d jobs", len(jobs))
 		}
 	} else if sched.Type == "custom" && sched.Query != "" {
-		jobs, err = runScheduledCustomScan(sched.Query)
-		if err != nil {
-			resultMsg = fmt.Sprintf("Error: %v", err)
-			log.Printf("Scheduler: custom scan '%s' failed for %s: %v", sched.Query, username, err)
+		jobs, scanErr = runScheduledCustomScan(sched.Query)
+		if scanErr != nil {
+			resultMsg = fmt.Sprintf("Error: %v", scanErr)
+			log.Printf("Scheduler: custom scan '%s' failed for %s: %v", sched.Query, username, scanErr)
 		} else {
 			resultMsg = fmt.Sprintf("Found %d jobs for '%s'", len(jobs), sched.Query)
 		}
 	}
 
-	cfg.Schedules[schedIdx].LastRun = time.Now().Format(time.RFC3339)
-	cfg.Schedules[schedIdx].LastResult = resultMsg
-	if err := saveUserAlerts(username, cfg); err != nil {
+	jobs = withoutHidden(username, jobs)
+	diff := diffSeenJobs(jobs, loadSeenJobs(username, sched.ID), sched.NotifySalaryChange)
+	if scanErr == nil && resultMsg != "" {
+		resultMsg += fmt.Sprintf(" (%d new)", len(diff.New))
+	}
+	if catchUp && resultMsg != "" {
+		resultMsg += " [catch-up]"
+	}
+
+	// The scan can take minutes, so record the result against the stored
+	// schedules rather than the copy taken when it started
+	err := updateUserAlerts(username, func(stored *UserAlertConfig) error {
+		for i := range stored.Schedules {
+			if stored.Schedules[i].ID == sched.ID {
+				stored.Schedules[i].LastRun = time.Now().Format(time.RFC3339)
+				stored.Schedules[i].LastResult = resultMsg
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		log.Printf("Scheduler: failed to save state for %s: %v", username, err)
 	}
 
-	if len(jobs) > 0 {
-		if err := sendUserTelegramJobAlert(cfg.Telegram, sched.Name, jobs); err != nil {
-			log.Printf("Scheduler: failed to send alert to %s: %v", username, err)
-		} else {
-			log.Printf("Scheduler: sent %d jobs to %s for '%s'", len(jobs), username, sched.Name)
+	d := scheduleDigest(sched, diff)
+	if len(d.Jobs) > 0 || sched.NotifyEmpty && scanErr == nil {
+		// Jobs only count as seen once an alert has reached the user or been
+		// queued, so those in an alert that failed on every channel are sent
+		// next time
+		if !deliverDigest(username, cfg, d) {
+			return
 		}
-	} else if sched.NotifyEmpty && err == nil {
-		noResultMsg := fmt.Sprintf("📋 *SalarySleuth Schedule*\n\n*%s*\n\nNo new jobs found this scan\\.\n\n📅 %s",
-			escapeMarkdown(sched.Name),
-			escapeMarkdown(time.Now().Format("Jan 2, 2006 3:04 PM")))
-		_ = sendUserTelegramNotification(cfg.Telegram, noResultMsg)
+	}
+	if err := recordSeenJobs(username, sched.ID, jobs); err != nil {
+		log.Printf("Scheduler: failed to record seen jobs for %s: %v", username, err)
 	}
 }
 
-func runScheduledDefaultScan() ([]Job, error) {
-	jobs, err := runSalarySleuth(config.Description, config.Pages)
-	if err != nil {
-		return nil, err
+// scheduleDigest is the alert for a schedule's run: the jobs it hasn't
+// alerted about before, those whose salary changed if asked for, and the
+// rest if the schedule sends the full list
+func scheduleDigest(sched ScheduledScan, diff seenDiff) Digest {
+	jobs := append(append([]Job{}, diff.New...), diff.SalaryChanged...)
+	if sched.NotifySeen {
+		jobs = append(jobs, diff.Seen...)
 	}
-	filteredJobs := filterOffsecJobs(jobs)
-	cfg, _ := LoadConfig()
-	tagged := TagJobs(filteredJobs, cfg)
-	result := make([]Job, len(tagged))
-	for i, t := range tagged {
-		result[i] = t.Job
+	d := newDigest(sched.Name, jobs)
+	for i := range d.Jobs {
+		switch {
+		case i >= len(diff.New)+len(diff.SalaryChanged):
+			d.Jobs[i].Change = changeSeen
+		case i >= len(diff.New):
+			d.Jobs[i].Change = changeSalary
+		}
 	}
-	return result, nil
+	if !sched.NotifySeen {
+		d.Omitted = len(diff.Seen)
+	}
+	return d
 }
 
-func runScheduledCustomScan(query string) ([]Job, error) {
-	sources := []string{"linkedin", "greenhouse", "lever"}
-	perSourceTimeout := 3 * time.Minute
-	var allJobs []Job
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-
-	for _, src := range sources {
-		wg.Add(1)
-		go func(source string) {
-			defer wg.Done()
-			ctx, cancel := context.WithTimeout(context.Background(), perSourceTimeout)
-			defer cancel()
-
-			srcJobs, err := runSingleSourceSearch(ctx, query, config.Pages, source)
-			if err != nil {
-				log.Printf("Scheduler: source %s error for '%s': %v", source, query, err)
-				return
-			}
-			if len(srcJobs) > 0 {
-				mu.Lock()
-				allJobs = append(allJobs, srcJobs...)
-				mu.Unlock()
-			}
-		}(src)
+// runScheduledDefaultScan runs the default scan and filters the results with
+// the user's profile
+func runScheduledDefaultScan(username string) ([]Job, error) {
+	jobs, err := runQueuedScan(config.Description)
+	if err != nil {
+		return nil, err
 	}
+	cfg := configForUser(username)
+	return alertableJobs(filterJobs(jobs, cfg), cfg), nil
+}
 
-	wg.Wait()
-	return allJobs, nil
+func runScheduledCustomScan(query string) ([]Job, error) {
+	return runQueuedScan(query)
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+	"gopkg.in/yaml.v3"
+)
+
+// ScoringConfig controls the relevance score given to every tagged job.
+// Points are added for each rule a job matches and subtracted for each
+// exclude rule, so excluded jobs sink to the bottom instead of disappearing.
+type ScoringConfig struct {
+	Weights ScoringWeights `yaml:"weights"`
+	// TargetSalary earns the salary points when the posted (or Levels.fyi)
+	// salary is at or above it, e.g. "200k"
+	TargetSalary string `yaml:"target_salary"`
+	// PreferredLevels are level IDs from filters.levels that fit you
+	PreferredLevels []string `yaml:"preferred_levels"`
+	// PreferredCompanies are matched as keywords against the company name
+	PreferredCompanies []Keyword `yaml:"preferred_companies"`
+	// MinScore hides lower-scoring jobs on the dashboard by default and
+	// keeps them out of new-job alerts
+	MinScore float64 `yaml:"min_score"`
+
+	targetSalary       float64
+	preferredCompanies []*query.Query
+	targetSalaryLine   int
+	levelsLine         int
+}
+
+// ScoringWeights are the points awarded for each kind of match
+type ScoringWeights struct {
+	Keyword          float64 `yaml:"keyword"`           // per matching keyword in a category
+	Certification    float64 `yaml:"certification"`     // per certification mentioned
+	Seniority        float64 `yaml:"seniority"`         // level is one of preferred_levels
+	Salary           float64 `yaml:"salary"`            // salary at or above target_salary
+	PreferredCompany float64 `yaml:"preferred_company"` // company is in preferred_companies
+	ExcludePenalty   float64 `yaml:"exclude_penalty"`   // subtracted per matching exclude group
+}
+
+// ScoreItem is one line of a job's score breakdown
+type ScoreItem struct {
+	Reason string  `json:"reason"`
+	Points float64 `json:"points"`
+}
+
+func defaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		Weights: ScoringWeights{
+			Keyword:          10,
+			Certification:    5,
+			Seniority:        5,
+			Salary:           10,
+			PreferredCompany: 10,
+			ExcludePenalty:   50,
+		},
+	}
+}
+
+// UnmarshalYAML records the lines of values that are validated later
+func (s *ScoringConfig) UnmarshalYAML(node *yaml.Node) error {
+	type plain ScoringConfig
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.targetSalaryLine = valueLine(node, "target_salary")
+	s.levelsLine = valueLine(node, "preferred_levels")
+	return nil
+}
+
+// compile validates the scoring section against the level rules it refers to
+func (s *ScoringConfig) compile(errs *ConfigError, levels map[string]CategoryConfig) {
+	s.targetSalary = 0
+	if s.TargetSalary != "" {
+		amount, ok := query.ParseAmount(s.TargetSalary)
+		if !ok || amount <= 0 {
+			errs.add(s.targetSalaryLine, "scoring.target_salary", fmt.Errorf("invalid amount %q (expected e.g. 200k or $185,000)", s.TargetSalary))
+		} else {
+			s.targetSalary = amount
+		}
+	}
+
+	for i, id := range s.PreferredLevels {
+		if _, ok := levels[id]; !ok {
+			errs.add(s.levelsLine, fmt.Sprintf("scoring.preferred_levels[%d]", i), fmt.Errorf("unknown level %q", id))
+		}
+	}
+
+	s.preferredCompanies = compileKeywords(errs, "scoring.preferred_companies", s.PreferredCompanies, []string{query.FieldCompany})
+
+	w := s.Weights
+	for _, weight := range []struct {
+		key   string
+		value float64
+	}{
+		{"keyword", w.Keyword},
+		{"certification", w.Certification},
+		{"seniority", w.Seniority},
+		{"salary", w.Salary},
+		{"preferred_company", w.PreferredCompany},
+		{"exclude_penalty", w.ExcludePenalty},
+	} {
+		if weight.value < 0 {
+			errs.add(0, "scoring.weights."+weight.key, fmt.Errorf("must not be negative"))
+		}
+	}
+}
+
+// ruleWeight is the multiplier for a rule's points; unset means 1
+func (c CategoryConfig) ruleWeight() float64 {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// hits counts the rule's keywords that match the job, plus one if its
+// query matches
+func (c CategoryConfig) hits(doc query.Document) int {
+	n := 0
+	for _, q := range c.keywordQueries {
+		if q.MatchDocument(doc) {
+			n++
+		}
+	}
+	if c.query != nil && c.query.MatchDocument(doc) {
+		n++
+	}
+	return n
+}
+
+// scoreJob computes the relevance score of a tagged job and the breakdown
+// explaining it. excludeReasons lists every exclude group the job matched.
+func scoreJob(doc query.Document, tags JobTags, excludeReasons []string, cfg *AppConfig) (float64, []ScoreItem) {
+	s := cfg.Scoring
+	w := s.Weights
+	var items []ScoreItem
+
+	for _, catID := range tags.Categories {
+		cat := cfg.Filters.Categories[catID]
+		n := cat.hits(doc)
+		reason := fmt.Sprintf("%s: %d keyword", displayName(cat, catID), n)
+		if n != 1 {
+			reason += "s"
+		}
+		items = append(items, ScoreItem{Reason: reason, Points: w.Keyword * cat.ruleWeight() * float64(n)})
+	}
+
+	for _, certID := range tags.Certifications {
+		cert := cfg.Filters.Certifications[certID]
+		items = append(items, ScoreItem{
+			Reason: "Certification: " + displayName(cert, certID),
+			Points: w.Certification * cert.ruleWeight(),
+		})
+	}
+
+	for _, id := range s.PreferredLevels {
+		if tags.Level == id {
+			items = append(items, ScoreItem{
+				Reason: "Preferred level: " + displayName(cfg.Filters.Levels[id], id),
+				Points: w.Seniority,
+			})
+			break
+		}
+	}
+
+	if s.targetSalary > 0 && doc.Salary >= s.targetSalary {
+		items = append(items, ScoreItem{
+			Reason: fmt.Sprintf("Salary at or above target (%s)", s.TargetSalary),
+			Points: w.Salary,
+		})
+	}
+
+	if matchAny(s.preferredCompanies, doc) {
+		items = append(items, ScoreItem{Reason: "Preferred company", Points: w.PreferredCompany})
+	}
+
+	for _, reason := range excludeReasons {
+		items = append(items, ScoreItem{Reason: reason, Points: -w.ExcludePenalty})
+	}
+
+	score := 0.0
+	for _, item := range items {
+		score += item.Points
+	}
+	return math.Round(score*10) / 10, items
+}
+
+func displayName(c CategoryConfig, id string) string {
+	if c.DisplayName != "" {
+		return c.DisplayName
+	}
+	return id
+}
+
+// alertableJobs returns the jobs worth a notification under cfg: not
+// excluded and scoring at least scoring.min_score
+func alertableJobs(jobs []Job, cfg *AppConfig) []Job {
+	var result []Job
+	for _, job := range jobs {
+		tagged := TagJob(job, cfg)
+		if tagged.Tags.IsExcluded || tagged.Score < cfg.Scoring.MinScore {
+			continue
+		}
+		result = append(result, job)
+	}
+	return result
+}
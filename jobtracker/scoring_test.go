@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const scoringTestConfig = `
+filters:
+  categories:
+    red_team:
+      display_name: Red Team
+      keywords: ["red team", "adversary"]
+      weight: 2
+    pentest:
+      keywords: ["pentester"]
+  levels:
+    senior:
+      keywords: ["senior"]
+    mid:
+      keywords: ["ii"]
+  certifications:
+    oscp:
+      display_name: OSCP
+      keywords: ["oscp"]
+      fields: [title, description]
+  exclude:
+    defensive_roles: ["soc"]
+    queries:
+      - "title:manager"
+scoring:
+  weights:
+    certification: 4
+  target_salary: 200k
+  preferred_levels: [senior]
+  preferred_companies: ["acme"]
+  min_score: 20
+`
+
+func TestScoreJob(t *testing.T) {
+	cfg, err := loadTestConfig(t, scoringTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		job   Job
+		score float64
+		items []string // reasons in the breakdown
+	}{
+		{
+			name:  "weighted keywords",
+			job:   Job{Title: "Red Team Adversary Emulation Engineer II"},
+			score: 40,
+			items: []string{"Red Team: 2 keywords"},
+		},
+		{
+			name:  "one keyword",
+			job:   Job{Title: "Pentester"},
+			score: 10,
+			items: []string{"pentest: 1 keyword"},
+		},
+		{
+			name:  "certification, level, salary and company",
+			job:   Job{Title: "Senior Pentester", Company: "Acme", Description: "OSCP preferred", SalaryRange: "$210,000"},
+			score: 10 + 4 + 5 + 10 + 10,
+			items: []string{"pentest: 1 keyword", "Certification: OSCP", "Preferred level: senior", "Salary at or above target (200k)", "Preferred company"},
+		},
+		{
+			name:  "salary below target",
+			job:   Job{Title: "Pentester", SalaryRange: "$150,000"},
+			score: 10,
+			items: []string{"pentest: 1 keyword"},
+		},
+		{
+			name:  "Levels.fyi salary",
+			job:   Job{Title: "Pentester", LevelSalary: "$250k"},
+			score: 20,
+			items: []string{"pentest: 1 keyword", "Salary at or above target (200k)"},
+		},
+		{
+			name:  "each exclude group is a penalty",
+			job:   Job{Title: "SOC Manager"},
+			score: -100,
+			items: []string{"Defensive role", "Excluded by query: title:manager"},
+		},
+		{
+			name:  "excluded jobs keep their points",
+			job:   Job{Title: "Red Team Manager"},
+			score: 20 - 50,
+			items: []string{"Red Team: 1 keyword", "Excluded by query: title:manager"},
+		},
+	}
+	for _, tt := range tests {
+		tagged := TagJob(tt.job, cfg)
+		if tagged.Score != tt.score {
+			t.Errorf("%s: score = %v, want %v (%+v)", tt.name, tagged.Score, tt.score, tagged.ScoreBreakdown)
+		}
+		var reasons []string
+		for _, item := range tagged.ScoreBreakdown {
+			reasons = append(reasons, item.Reason)
+		}
+		if strings.Join(reasons, "; ") != strings.Join(tt.items, "; ") {
+			t.Errorf("%s: breakdown = %q, want %q", tt.name, reasons, tt.items)
+		}
+	}
+}
+
+func TestAlertableJobs(t *testing.T) {
+	cfg, err := loadTestConfig(t, scoringTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []Job{
+		{ID: "strong", Title: "Red Team Operator"},            // 20, at min_score
+		{ID: "weak", Title: "Pentester"},                      // 10
+		{ID: "excluded", Title: "Red Team Adversary Manager"}, // 40 - 50
+		{ID: "boosted", Title: "Pentester", Company: "Acme"},  // 20
+	}
+	var ids []string
+	for _, job := range alertableJobs(jobs, cfg) {
+		ids = append(ids, job.ID)
+	}
+	if got := strings.Join(ids, ","); got != "strong,boosted" {
+		t.Errorf("alertableJobs = %s, want strong,boosted", got)
+	}
+
+	cfg.Scoring.MinScore = 0
+	ids = nil
+	for _, job := range alertableJobs(jobs, cfg) {
+		ids = append(ids, job.ID)
+	}
+	if got := strings.Join(ids, ","); got != "strong,weak,boosted" {
+		t.Errorf("alertableJobs without min_score = %s, want every job that isn't excluded", got)
+	}
+}
+
+func TestScoringConfigErrors(t *testing.T) {
+	_, err := loadTestConfig(t, `filters:
+  levels:
+    senior:
+      keywords: ["senior"]
+scoring:
+  weights:
+    keyword: -5
+  target_salary: "lots"
+  preferred_levels: [senior, wizard]
+  preferred_companies:
+    - regex: "(acme"
+`)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("err = %v, want a ConfigError", err)
+	}
+	want := []struct {
+		line int
+		key  string
+		msg  string
+	}{
+		{0, "scoring.weights.keyword", "must not be negative"},
+		{8, "scoring.target_salary", `invalid amount "lots"`},
+		{9, "scoring.preferred_levels[1]", `unknown level "wizard"`},
+		{11, "scoring.preferred_companies[0]", "invalid regex"},
+	}
+	if len(cfgErr.Problems) != len(want) {
+		t.Fatalf("got %d problems, want %d:\n%v", len(cfgErr.Problems), len(want), err)
+	}
+	for i, w := range want {
+		p := cfgErr.Problems[i]
+		if p.Line != w.line || p.Key != w.key || !strings.Contains(p.Message, w.msg) {
+			t.Errorf("problem %d = %s, want line %d: %s: %s", i, p, w.line, w.key, w.msg)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Custom search states
+const (
+	SearchQueued    = "queued"
+	SearchRunning   = "running"
+	SearchDone      = "done"
+	SearchCancelled = "cancelled"
+	SearchFailed    = "failed"
+)
+
+// maxUserSearches is how many custom searches a user can run at once
+const maxUserSearches = 3
+
+// searchRetention is how long a finished search stays listed. Its results
+// stay in the user's History.
+const searchRetention = time.Hour
+
+// customSearch is a user's custom search, kept in memory while it runs and
+// for searchRetention after
+type customSearch struct {
+	ID        string
+	Username  string
+	Query     string
+	StartedAt time.Time
+	ticket    *scanTicket
+
+	// set when the search finishes, guarded by searchesMu
+	status     string
+	finishedAt time.Time
+	results    []TaggedJob
+	err        string
+}
+
+// SearchStatus is a custom search as the API reports it
+type SearchStatus struct {
+	ID            string                    `json:"id"`
+	Query         string                    `json:"query"`
+	Status        string                    `json:"status"`
+	StartedAt     time.Time                 `json:"started_at"`
+	FinishedAt    *time.Time                `json:"finished_at,omitempty"`
+	QueuePosition int                       `json:"queue_position"` // 1 when next to start, 0 once running
+	Sources       map[string]SourceProgress `json:"sources"`
+	Count         int                       `json:"count"`
+	Results       []TaggedJob               `json:"results,omitempty"`
+	Error         string                    `json:"error,omitempty"`
+}
+
+var (
+	searchesMu sync.Mutex
+	searches   = make(map[string]*customSearch)
+)
+
+// startSearch queues a custom search for the user, or returns nil if they
+// already have maxUserSearches running
+func startSearch(username, query string) *customSearch {
+	searchesMu.Lock()
+	defer searchesMu.Unlock()
+	pruneSearches(time.Now())
+
+	running := 0
+	for _, s := range searches {
+		if s.Username == username && s.status == "" {
+			running++
+		}
+	}
+	if running >= maxUserSearches {
+		return nil
+	}
+
+	s := &customSearch{
+		ID:        generateEntryID(),
+		Username:  username,
+		Query:     query,
+		StartedAt: time.Now(),
+	}
+	s.ticket = scans.submitWatched(query, s.publish)
+	searches[s.ID] = s
+	go s.run()
+	return s
+}
+
+// pruneSearches forgets searches finished more than searchRetention ago
+func pruneSearches(now time.Time) {
+	for id, s := range searches {
+		if s.status != "" && now.Sub(s.finishedAt) > searchRetention {
+			delete(searches, id)
+			events.drop(s.topic())
+		}
+	}
+}
+
+// userSearch returns the user's search with the ID, if it is still kept
+func userSearch(username, id string) *customSearch {
+	searchesMu.Lock()
+	defer searchesMu.Unlock()
+	if s := searches[id]; s != nil && s.Username == username {
+		return s
+	}
+	return nil
+}
+
+// userSearches returns the user's running and recent searches, newest first
+func userSearches(username string) []*customSearch {
+	searchesMu.Lock()
+	defer searchesMu.Unlock()
+	pruneSearches(time.Now())
+	var list []*customSearch
+	for _, s := range searches {
+		if s.Username == username {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.After(list[j].StartedAt) })
+	return list
+}
+
+// topic is where the search's events are published
+func (s *customSearch) topic() string {
+	return "search/" + s.ID
+}
+
+// publish sends a scan event to the search's stream. Jobs are sent as each
+// source finishes, only that source's.
+func (s *customSearch) publish(ev ScanEvent) {
+	data := map[string]interface{}{"source": ev.Source}
+	switch ev.Type {
+	case EventPage:
+		data["pages"], data["found"] = ev.Pages, ev.Found
+	case EventEnrich:
+		data["done"], data["total"] = ev.Done, ev.Total
+	case EventJobs:
+		data["jobs"] = TagJobs(ev.Jobs, configForUser(s.Username))
+		data["count"] = len(ev.Jobs)
+	case EventSourceFailed:
+		data["error"], data["timed_out"] = ev.Error, ev.TimedOut
+	}
+	events.publish(s.topic(), ev.Type, data)
+}
+
+// run waits for the search's scan, then records its results in the user's
+// History under the search's ID
+func (s *customSearch) run() {
+	log.Printf("Custom search %s started by %s: %q", s.ID, s.Username, s.Query)
+	jobs, err := s.ticket.wait()
+
+	var results []TaggedJob
+	status, errMsg := SearchDone, ""
+	switch {
+	case err == errSearchCancelled:
+		status, errMsg = SearchCancelled, "Search was cancelled"
+	case err != nil:
+		status, errMsg = SearchFailed, err.Error()
+	default:
+		results = TagJobs(jobs, configForUser(s.Username))
+	}
+
+	searchesMu.Lock()
+	s.status = status
+	s.finishedAt = time.Now()
+	s.results = results
+	s.err = errMsg
+	searchesMu.Unlock()
+	events.publish(s.topic(), "done", map[string]interface{}{
+		"status": status, "count": len(results), "error": errMsg,
+	})
+
+	if status != SearchDone {
+		log.Printf("Custom search %s %s: %q", s.ID, status, s.Query)
+		return
+	}
+	resultsFile := ""
+	if len(results) > 0 {
+		resultsFile = saveSearchResults(s.ID, results)
+	}
+	addHistoryEntry(s.Username, SearchHistoryEntry{
+		ID: s.ID, Timestamp: time.Now(),
+		Type: "custom_search", Query: s.Query,
+		ResultCount: len(results), ResultsFile: resultsFile,
+	})
+	log.Printf("Custom search %s complete: %q found %d total jobs", s.ID, s.Query, len(results))
+}
+
+// finished reports whether the search is over
+func (s *customSearch) finished() bool {
+	searchesMu.Lock()
+	defer searchesMu.Unlock()
+	return s.status != ""
+}
+
+// snapshot reports the search, with its results (those found so far while
+// it runs) when withResults is set
+func (s *customSearch) snapshot(withResults bool) SearchStatus {
+	searchesMu.Lock()
+	status, finishedAt, results, errMsg := s.status, s.finishedAt, s.results, s.err
+	searchesMu.Unlock()
+
+	st := SearchStatus{
+		ID:        s.ID,
+		Query:     s.Query,
+		Status:    status,
+		StartedAt: s.StartedAt,
+		Sources:   s.ticket.progress(),
+		Error:     errMsg,
+	}
+	if status == "" && s.ticket.isCancelled() {
+		// It finishes as soon as its goroutine notices
+		st.Status, st.Error = SearchCancelled, "Search was cancelled"
+		return st
+	}
+	if status != "" {
+		st.FinishedAt = &finishedAt
+		st.Count = len(results)
+		if withResults {
+			st.Results = results
+		}
+		return st
+	}
+
+	st.Status = SearchRunning
+	if st.QueuePosition = s.ticket.position(); st.QueuePosition > 0 {
+		st.Status = SearchQueued
+	}
+	partial, _ := s.ticket.results()
+	st.Count = len(partial)
+	if withResults && len(partial) > 0 {
+		st.Results = TagJobs(partial, configForUser(s.Username))
+	}
+	return st
+}
+
+// handleSearches starts a custom search (POST) or lists the user's running
+// and recent ones (GET)
+func handleSearches(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+
+	switch r.Method {
+	case http.MethodGet:
+		list := []SearchStatus{}
+		for _, s := range userSearches(username) {
+			list = append(list, s.snapshot(false))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"searches": list})
+
+	case http.MethodPost:
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		req.Query = strings.TrimSpace(req.Query)
+		if req.Query == "" || len(req.Query) < 3 {
+			jsonError(w, "Search query must be at least 3 characters", http.StatusBadRequest)
+			return
+		}
+		if len(req.Query) > 100 {
+			jsonError(w, "Search query too long", http.StatusBadRequest)
+			return
+		}
+
+		s := startSearch(username, req.Query)
+		if s == nil {
+			jsonError(w, fmt.Sprintf("You already have %d searches running. Wait for one to finish or cancel it.", maxUserSearches), http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"id":      s.ID,
+			"message": "Search started for: " + req.Query,
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSearch serves /api/search/{id}: GET reports the search with its
+// results and per-source progress, DELETE cancels it. GET
+// /api/search/{id}/events streams its progress as Server-Sent Events.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	id, stream := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/search/"), "/events")
+	s := userSearch(username, id)
+	if s == nil {
+		jsonError(w, "Search not found", http.StatusNotFound)
+		return
+	}
+	if stream {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		serveEvents(w, r, s.topic())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.snapshot(true))
+
+	case http.MethodDelete:
+		s.ticket.cancel()
+		log.Printf("Custom search %s cancelled by %s: %q", s.ID, username, s.Query)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Search for \"" + s.Query + "\" has been cancelled",
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCancelSearch cancels the user's latest running search. Prefer
+// DELETE /api/search/{id}.
+func handleCancelSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	username := r.Header.Get("X-Auth-User")
+	w.Header().Set("Content-Type", "application/json")
+	for _, s := range userSearches(username) {
+		if s.finished() {
+			continue
+		}
+		s.ticket.cancel()
+		log.Printf("Custom search %s cancelled by %s: %q", s.ID, username, s.Query)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": "Search for \"" + s.Query + "\" has been cancelled",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"message": "No search is currently running",
+	})
+}
+
+// handleSearchStatus reports the user's latest search in the shape the
+// dashboard polls for. GET /api/search/{id} reports any search.
+func handleSearchStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	list := userSearches(r.Header.Get("X-Auth-User"))
+	if len(list) == 0 {
+		json.NewEncoder(w).Encode(map[string]interface{}{"is_searching": false})
+		return
+	}
+	st := list[0].snapshot(true)
+
+	resp := map[string]interface{}{
+		"id":           st.ID,
+		"query":        st.Query,
+		"is_searching": st.Status == SearchQueued || st.Status == SearchRunning,
+	}
+	switch st.Status {
+	case SearchQueued:
+		resp["queue_position"] = st.QueuePosition
+		resp["partial_count"] = 0
+		resp["message"] = fmt.Sprintf("Queued: %d scan(s) ahead of your search for %s...", st.QueuePosition-1, st.Query)
+	case SearchRunning:
+		resp["queue_position"] = 0
+		resp["partial_count"] = st.Count
+		resp["message"] = fmt.Sprintf("Searching for: %s... (LinkedIn, Greenhouse, Lever running in parallel)", st.Query)
+		if st.Count > 0 {
+			resp["message"] = fmt.Sprintf("Searching for: %s... Found %d jobs so far, waiting for more sources...", st.Query, st.Count)
+			resp["partial_results"] = st.Results
+		}
+	case SearchCancelled:
+		resp["cancelled"] = true
+		resp["message"] = st.Error
+	case SearchFailed:
+		resp["error"] = st.Error
+	default:
+		results := st.Results
+		if results == nil {
+			results = []TaggedJob{}
+		}
+		resp["results"] = results
+		resp["count"] = st.Count
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// seenJobTTL is how long a schedule remembers a job after last finding it.
+// A job that drops out of the results for longer, such as one reposted
+// months later, is alerted as new again.
+const seenJobTTL = 30 * 24 * time.Hour
+
+// Changes noted on jobs in a schedule's alert
+const (
+	changeSalary = "salary" // seen before, but its posted salary changed
+	changeSeen   = "seen"   // seen before; sent because the schedule sends the full list
+)
+
+// SeenJob is a job a schedule has already alerted about
+type SeenJob struct {
+	Salary   string    `json:"salary,omitempty"` // posted salary when last alerted
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// UserSeenJobs are the jobs each of a user's schedules has already alerted
+// about, by schedule ID and then job ID
+type UserSeenJobs struct {
+	Schedules map[string]map[string]SeenJob `json:"schedules"`
+}
+
+// seenDiff splits a scan's results against what the schedule has already
+// alerted about
+type seenDiff struct {
+	New           []Job
+	SalaryChanged []Job
+	Seen          []Job
+}
+
+// postedSalary is the job's posted salary, or empty when it has none
+func postedSalary(job Job) string {
+	if job.SalaryRange == "Not Available" {
+		return ""
+	}
+	return job.SalaryRange
+}
+
+func loadSeenJobs(username, schedID string) map[string]SeenJob {
+	var seen UserSeenJobs
+	err := dataStore.View(func(tx Tx) error {
+		_, err := tx.Get(collSeenJobs, username, &seen)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to load seen jobs for %s: %v", username, err)
+	}
+	return seen.Schedules[schedID]
+}
+
+// diffSeenJobs sorts jobs into those the schedule hasn't alerted about, those
+// whose posted salary has changed since (when salaryChanges is set) and the
+// rest. Duplicates are dropped.
+func diffSeenJobs(jobs []Job, seen map[string]SeenJob, salaryChanges bool) seenDiff {
+	var diff seenDiff
+	done := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if done[job.ID] {
+			continue
+		}
+		done[job.ID] = true
+
+		prev, ok := seen[job.ID]
+		salary := postedSalary(job)
+		switch {
+		case !ok:
+			diff.New = append(diff.New, job)
+		case salaryChanges && salary != "" && salary != prev.Salary:
+			diff.SalaryChanged = append(diff.SalaryChanged, job)
+		default:
+			diff.Seen = append(diff.Seen, job)
+		}
+	}
+	return diff
+}
+
+// recordSeenJobs marks the jobs as seen by the schedule now and forgets those
+// not seen within seenJobTTL
+func recordSeenJobs(username, schedID string, jobs []Job) error {
+	now := time.Now()
+	return dataStore.Update(func(tx Tx) error {
+		var seen UserSeenJobs
+		if _, err := tx.Get(collSeenJobs, username, &seen); err != nil {
+			return err
+		}
+		if seen.Schedules == nil {
+			seen.Schedules = make(map[string]map[string]SeenJob)
+		}
+		sched := seen.Schedules[schedID]
+		if sched == nil {
+			sched = make(map[string]SeenJob)
+			seen.Schedules[schedID] = sched
+		}
+		for _, job := range jobs {
+			entry := sched[job.ID]
+			if salary := postedSalary(job); salary != "" {
+				entry.Salary = salary
+			}
+			entry.LastSeen = now
+			sched[job.ID] = entry
+		}
+		for id, entry := range sched {
+			if now.Sub(entry.LastSeen) > seenJobTTL {
+				delete(sched, id)
+			}
+		}
+		return tx.Put(collSeenJobs, username, seen)
+	})
+}
+
+// forgetSeenJobs drops a deleted schedule's seen jobs
+func forgetSeenJobs(username, schedID string) error {
+	return dataStore.Update(func(tx Tx) error {
+		var seen UserSeenJobs
+		found, err := tx.Get(collSeenJobs, username, &seen)
+		if err != nil || !found {
+			return err
+		}
+		if _, ok := seen.Schedules[schedID]; !ok {
+			return nil
+		}
+		delete(seen.Schedules, schedID)
+		return tx.Put(collSeenJobs, username, seen)
+	})
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Session is a login, stored in the sessions collection under the SHA-256
+// of its cookie so a leaked store can't be used to log in. Sessions expire
+// after SESSION_IDLE_TIMEOUT without a request, and SESSION_MAX_AGE after
+// login however active they are.
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+var (
+	sessionIdleTimeout = envDuration("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	sessionMaxAge      = envDuration("SESSION_MAX_AGE", 30*24*time.Hour)
+)
+
+// sessionTouchInterval is how stale a session's LastSeen gets before a
+// request updates it and slides its expiry, so every request doesn't write
+// to the store
+const sessionTouchInterval = time.Minute
+
+func envDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", key, val, def)
+		return def
+	}
+	return d
+}
+
+// expiry is when a session last seen at lastSeen expires
+func (s Session) expiry(lastSeen time.Time) time.Time {
+	idle := lastSeen.Add(sessionIdleTimeout)
+	if limit := s.CreatedAt.Add(sessionMaxAge); idle.After(limit) {
+		return limit
+	}
+	return idle
+}
+
+// trustedProxies are the reverse proxies whose X-Forwarded-For and
+// X-Real-IP headers are believed, from TRUSTED_PROXIES: addresses or CIDR
+// ranges, separated by commas
+var trustedProxies = parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+
+func parseTrustedProxies(val string) []netip.Prefix {
+	var list []netip.Prefix
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(item)
+			if aerr != nil {
+				log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q", item)
+				continue
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		list = append(list, prefix.Masked())
+	}
+	return list
+}
+
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address the request came from. Behind a trusted proxy
+// that is the last address in X-Forwarded-For that isn't another trusted
+// proxy, or X-Real-IP; anyone else could put what they like in those.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+	if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+		hops := strings.Split(strings.Join(fwd, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop != "" && !isTrustedProxy(hop) {
+				return hop
+			}
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	return host
+}
+
+// createSession logs the user in from the request and returns the
+// session's cookie value
+func createSession(username string, r *http.Request) (string, error) {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	sess := Session{
+		ID:        generateEntryID(),
+		Username:  username,
+		UserAgent: r.UserAgent(),
+		IP:        clientIP(r),
+		CreatedAt: now,
+		LastSeen:  now,
+	}
+	sess.ExpiresAt = sess.expiry(now)
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Put(collSessions, hashToken(token), sess)
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// getSession returns the unexpired session for the cookie value, sliding
+// its expiry forward
+func getSession(token string) *Session {
+	key := hashToken(token)
+	var sess Session
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		found, err = tx.Get(collSessions, key, &sess)
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to look up session: %v", err)
+		return nil
+	}
+	now := time.Now()
+	if !found || now.After(sess.ExpiresAt) {
+		return nil
+	}
+
+	if now.Sub(sess.LastSeen) > sessionTouchInterval {
+		sess.LastSeen = now
+		sess.ExpiresAt = sess.expiry(now)
+		touchSession(key, sess)
+	}
+	return &sess
+}
+
+// touchSession stores the session's new LastSeen and expiry, unless it has
+// been revoked since it was read
+func touchSession(key string, sess Session) {
+	err := dataStore.Update(func(tx Tx) error {
+		var current Session
+		if found, err := tx.Get(collSessions, key, &current); err != nil || !found {
+			return err
+		}
+		return tx.Put(collSessions, key, sess)
+	})
+	if err != nil {
+		log.Printf("Failed to update session: %v", err)
+	}
+}
+
+func deleteSession(token string) {
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Delete(collSessions, hashToken(token))
+	})
+	if err != nil {
+		log.Printf("Failed to delete session: %v", err)
+	}
+}
+
+func cleanExpiredSessions() {
+	now := time.Now()
+	err := dataStore.Update(func(tx Tx) error {
+		sessions, err := loadCollection[Session](tx, collSessions)
+		if err != nil {
+			return err
+		}
+		for key, sess := range sessions {
+			if now.After(sess.ExpiresAt) {
+				if err := tx.Delete(collSessions, key); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to clean expired sessions: %v", err)
+	}
+}
+
+// userSessions returns the user's unexpired sessions by key, most recently
+// seen first
+func userSessions(tx Tx, username string) ([]string, []Session, error) {
+	sessions, err := loadCollection[Session](tx, collSessions)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	var keys []string
+	for key, sess := range sessions {
+		if sess.Username == username && !now.After(sess.ExpiresAt) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return sessions[keys[i]].LastSeen.After(sessions[keys[j]].LastSeen) })
+	list := make([]Session, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, sessions[key])
+	}
+	return keys, list, nil
+}
+
+// revokeUserSessions logs the user out everywhere except the session
+// stored under keep, if set
+func revokeUserSessions(tx Tx, username, keep string) error {
+	sessions, err := loadCollection[Session](tx, collSessions)
+	if err != nil {
+		return err
+	}
+	for key, sess := range sessions {
+		if sess.Username == username && key != keep {
+			if err := tx.Delete(collSessions, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// currentSessionKey is the stored key of the request's session, if it has
+// one
+func currentSessionKey(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return ""
+	}
+	return hashToken(cookie.Value)
+}
+
+// handleSessions lets users see where they are logged in: GET lists their
+// sessions, marking the one making the request, and DELETE revokes {"id"},
+// or every other session with {"others": true}
+func handleSessions(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	current := currentSessionKey(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		type info struct {
+			Session
+			Current bool `json:"current"`
+		}
+		var keys []string
+		var list []Session
+		err := dataStore.View(func(tx Tx) error {
+			var err error
+			keys, list, err = userSessions(tx, username)
+			return err
+		})
+		if err != nil {
+			log.Printf("Failed to load sessions for %s: %v", username, err)
+			jsonError(w, "Failed to load sessions", http.StatusInternalServerError)
+			return
+		}
+		sessions := make([]info, 0, len(list))
+		for i, sess := range list {
+			sessions = append(sessions, info{Session: sess, Current: keys[i] == current})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"sessions": sessions})
+
+	case http.MethodDelete:
+		var req struct {
+			ID     string `json:"id"`
+			Others bool   `json:"others"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		found := false
+		err := dataStore.Update(func(tx Tx) error {
+			if req.Others {
+				found = true
+				return revokeUserSessions(tx, username, current)
+			}
+			keys, list, err := userSessions(tx, username)
+			if err != nil {
+				return err
+			}
+			for i, sess := range list {
+				if sess.ID == req.ID {
+					found = true
+					return tx.Delete(collSessions, keys[i])
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to revoke sessions for %s: %v", username, err)
+			jsonError(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			jsonError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		if req.Others {
+			log.Printf("%s logged out their other sessions", username)
+		} else {
+			log.Printf("Session %s revoked by %s", req.ID, username)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminSessions force-logs out {"username"} everywhere (DELETE)
+func handleAdminSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	err := dataStore.Update(func(tx Tx) error {
+		var user User
+		if exists, err := tx.Get(collUsers, req.Username, &user); err != nil {
+			return err
+		} else if !exists {
+			return errUserNotFound
+		}
+		return revokeUserSessions(tx, req.Username, "")
+	})
+	switch {
+	case err == errUserNotFound:
+		jsonError(w, "User not found", http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("Failed to log out %s: %v", req.Username, err)
+		jsonError(w, "Failed to log out user", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("User %s logged out everywhere by %s", req.Username, r.Header.Get("X-Auth-User"))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSession logs username in and returns the cookie value and the
+// session's stored key
+func newTestSession(t *testing.T, username string) (string, string) {
+	t.Helper()
+	token, err := createSession(username, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, hashToken(token)
+}
+
+// storedSession reads the session under key
+func storedSession(t *testing.T, key string) (Session, bool) {
+	t.Helper()
+	var sess Session
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		found, err = tx.Get(collSessions, key, &sess)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess, found
+}
+
+// ageSession moves the session's login and last request back by the given
+// durations, as if time had passed
+func ageSession(t *testing.T, key string, created, seen time.Duration) {
+	t.Helper()
+	sess, _ := storedSession(t, key)
+	sess.CreatedAt = sess.CreatedAt.Add(-created)
+	sess.LastSeen = sess.LastSeen.Add(-seen)
+	sess.ExpiresAt = sess.expiry(sess.LastSeen)
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Put(collSessions, key, sess)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setSessionLimits sets the idle timeout and maximum age for the test
+func setSessionLimits(t *testing.T, idle, maxAge time.Duration) {
+	prevIdle, prevMax := sessionIdleTimeout, sessionMaxAge
+	sessionIdleTimeout, sessionMaxAge = idle, maxAge
+	t.Cleanup(func() { sessionIdleTimeout, sessionMaxAge = prevIdle, prevMax })
+}
+
+func TestSessionStorage(t *testing.T) {
+	newTestStore(t)
+	token, key := newTestSession(t, "alice")
+
+	if _, found := storedSession(t, token); found {
+		t.Error("session stored under its cookie value")
+	}
+	if sess, found := storedSession(t, key); !found || sess.Username != "alice" {
+		t.Fatalf("session under the cookie's hash = %+v, %v", sess, found)
+	}
+	if sess := getSession(token); sess == nil || sess.Username != "alice" {
+		t.Errorf("getSession = %+v, want alice's session", sess)
+	}
+	if sess := getSession(key); sess != nil {
+		t.Error("the stored key works as a cookie")
+	}
+	deleteSession(token)
+	if _, found := storedSession(t, key); found {
+		t.Error("deleteSession left the session stored")
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	newTestStore(t)
+	setSessionLimits(t, time.Hour, 24*time.Hour)
+
+	tests := []struct {
+		name    string
+		created time.Duration // how long ago the user logged in
+		seen    time.Duration // and last made a request
+		valid   bool
+	}{
+		{"fresh", 0, 0, true},
+		{"active within the idle timeout", 2 * time.Hour, 50 * time.Minute, true},
+		{"idle too long", 2 * time.Hour, 61 * time.Minute, false},
+		{"active but past the maximum age", 24*time.Hour + time.Minute, time.Minute, false},
+		{"active near the maximum age", 23*time.Hour + 30*time.Minute, 2 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		token, key := newTestSession(t, "alice")
+		ageSession(t, key, tt.created, tt.seen)
+		if sess := getSession(token); (sess != nil) != tt.valid {
+			t.Errorf("%s: valid = %v, want %v", tt.name, sess != nil, tt.valid)
+		}
+	}
+}
+
+func TestSessionSlidingExpiry(t *testing.T) {
+	newTestStore(t)
+	setSessionLimits(t, time.Hour, 24*time.Hour)
+
+	// A request slides the idle expiry forward
+	token, key := newTestSession(t, "alice")
+	ageSession(t, key, 2*time.Hour, 50*time.Minute)
+	before := time.Now()
+	if getSession(token) == nil {
+		t.Fatal("active session rejected")
+	}
+	sess, _ := storedSession(t, key)
+	if sess.LastSeen.Before(before) || sess.ExpiresAt.Before(before.Add(time.Hour)) {
+		t.Errorf("after a request LastSeen = %s, ExpiresAt = %s, want now and an hour on", sess.LastSeen, sess.ExpiresAt)
+	}
+
+	// but never past the maximum age
+	token, key = newTestSession(t, "alice")
+	ageSession(t, key, 23*time.Hour+30*time.Minute, 2*time.Minute)
+	if getSession(token) == nil {
+		t.Fatal("active session rejected")
+	}
+	sess, _ = storedSession(t, key)
+	if limit := sess.CreatedAt.Add(24 * time.Hour); !sess.ExpiresAt.Equal(limit) {
+		t.Errorf("ExpiresAt = %s, want the maximum age %s", sess.ExpiresAt, limit)
+	}
+
+	// A session revoked before its touch is stored stays revoked
+	_, key = newTestSession(t, "alice")
+	sess, _ = storedSession(t, key)
+	err := dataStore.Update(func(tx Tx) error {
+		return tx.Delete(collSessions, key)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	touchSession(key, sess)
+	if _, found := storedSession(t, key); found {
+		t.Error("touching a revoked session restored it")
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	newTestStore(t)
+	putTestUser(t, "alice", "")
+	current, currentKey := newTestSession(t, "alice")
+	_, otherKey := newTestSession(t, "alice")
+	_, bobKey := newTestSession(t, "bob")
+
+	// Logging out other sessions keeps the one making the request
+	req := httptest.NewRequest(http.MethodDelete, "/api/sessions", strings.NewReader(`{"others":true}`))
+	req.Header.Set("X-Auth-User", "alice")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: current})
+	rec := httptest.NewRecorder()
+	handleSessions(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("revoking other sessions: status %d: %s", rec.Code, rec.Body)
+	}
+	for key, want := range map[string]bool{currentKey: true, otherKey: false, bobKey: true} {
+		if _, found := storedSession(t, key); found != want {
+			t.Errorf("session %s stored = %v, want %v", key[:8], found, want)
+		}
+	}
+
+	// An admin logs a user out everywhere
+	req = httptest.NewRequest(http.MethodDelete, "/api/admin/sessions", strings.NewReader(`{"username":"alice"}`))
+	req.Header.Set("X-Auth-User", "root")
+	rec = httptest.NewRecorder()
+	handleAdminSessions(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("admin logout: status %d: %s", rec.Code, rec.Body)
+	}
+	if getSession(current) != nil {
+		t.Error("alice's session survived an admin logout")
+	}
+	if _, found := storedSession(t, bobKey); !found {
+		t.Error("an admin logout of alice revoked bob's session")
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/admin/sessions", strings.NewReader(`{"username":"nobody"}`))
+	rec = httptest.NewRecorder()
+	handleAdminSessions(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("admin logout of an unknown user: status %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	prev := trustedProxies
+	trustedProxies = parseTrustedProxies("10.0.0.1, 172.16.0.0/12, bogus")
+	t.Cleanup(func() { trustedProxies = prev })
+
+	tests := []struct {
+		name   string
+		remote string
+		fwd    string
+		realIP string
+		want   string
+	}{
+		{"direct", "203.0.113.7:5123", "", "", "203.0.113.7"},
+		{"spoofed X-Forwarded-For", "203.0.113.7:5123", "198.51.100.1", "", "203.0.113.7"},
+		{"spoofed X-Real-IP", "203.0.113.7:5123", "", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:443", "198.51.100.1", "", "198.51.100.1"},
+		{"trusted proxy range", "172.18.0.2:443", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed hop before the proxy", "10.0.0.1:443", "192.0.2.9, 198.51.100.1", "", "198.51.100.1"},
+		{"chained proxies", "10.0.0.1:443", "198.51.100.1, 172.20.0.5", "", "198.51.100.1"},
+		{"trusted proxy with X-Real-IP", "10.0.0.1:443", "", "198.51.100.1", "198.51.100.1"},
+		{"trusted proxy without headers", "10.0.0.1:443", "", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.fwd != "" {
+			r.Header.Set("X-Forwarded-For", tt.fwd)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("%s: clientIP = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
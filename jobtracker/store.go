@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store is jobtracker's persistent storage: JSON records grouped into
+// collections and keyed by ID. All reads and writes happen in transactions,
+// so an update touching several records is applied completely or not at all.
+type Store interface {
+	View(fn func(tx Tx) error) error
+	Update(fn func(tx Tx) error) error
+}
+
+// Tx reads and writes records inside a transaction. Records are stored as
+// JSON, so the structs keep their existing json tags.
+type Tx interface {
+	// Get decodes the record into v and reports whether it exists
+	Get(collection, key string, v interface{}) (bool, error)
+	Put(collection, key string, v interface{}) error
+	Delete(collection, key string) error
+	// ForEach calls fn for every record in key order
+	ForEach(collection string, fn func(key string, data []byte) error) error
+}
+
+// Collections and the meta keys stored alongside them
+const (
+	collMeta           = "meta"
+	collJobs           = "jobs"
+	collJobHistory     = "job_history"
+	collScans          = "scans"
+	collCompanies      = "companies"
+	collUsers          = "users"
+	collTokens         = "registration_tokens"
+	collAlerts         = "alerts"
+	collHistory        = "history"
+	collSearchResults  = "search_results"
+	collSavedJobs      = "saved_jobs"
+	collProfiles       = "profiles"
+	collCalendarTokens = "calendar_tokens"
+	collHiddenJobs     = "hidden_jobs"
+	collTelegramJobs   = "telegram_jobs"
+	collSeenJobs       = "seen_jobs"
+	collOutbox         = "outbox"
+	collAPITokens      = "api_tokens"
+	collSessions       = "sessions"
+	collPasswordResets = "password_resets"
+
+	metaSchemaVersion   = "schema_version"
+	metaJobsLastUpdated = "jobs_last_updated"
+	metaRefreshState    = "refresh_state"
+)
+
+var collections = []string{
+	collMeta, collJobs, collJobHistory, collScans, collCompanies, collUsers,
+	collTokens, collAlerts, collHistory, collSearchResults, collSavedJobs,
+	collProfiles, collCalendarTokens, collHiddenJobs, collTelegramJobs,
+	collSeenJobs, collOutbox, collAPITokens, collSessions, collPasswordResets,
+}
+
+// storeFile is the database file inside DataDir
+const storeFile = "jobtracker.db"
+
+// storeOpenTimeout bounds how long to wait for another process (the web
+// server or a -scrape run) to release the database
+const storeOpenTimeout = 30 * time.Second
+
+// dataStore is the store opened by main
+var dataStore Store
+
+// boltStore keeps its data in a bbolt file, opened by main for the life of
+// the process. bbolt locks the file for a single process, so a -scrape run
+// started while the web server is up hands its scrape to the server (see
+// requestRefresh).
+type boltStore struct {
+	db *bolt.DB
+}
+
+// openStore opens (creating if needed) the store at path and brings its
+// schema up to date
+func openStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: storeOpenTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	s := &boltStore{db: db}
+	err = s.Update(func(tx Tx) error {
+		return migrate(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Close releases the database file
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *boltStore) View(fn func(tx Tx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (s *boltStore) Update(fn func(tx Tx) error) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range collections {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return fn(boltTx{tx})
+	})
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) Get(collection, key string, v interface{}) (bool, error) {
+	b := t.tx.Bucket([]byte(collection))
+	if b == nil {
+		return false, nil
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("%s/%s: %v", collection, key, err)
+	}
+	return true, nil
+}
+
+func (t boltTx) Put(collection, key string, v interface{}) error {
+	b := t.tx.Bucket([]byte(collection))
+	if b == nil {
+		return fmt.Errorf("unknown collection %q", collection)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s/%s: %v", collection, key, err)
+	}
+	return b.Put([]byte(key), data)
+}
+
+func (t boltTx) Delete(collection, key string) error {
+	b := t.tx.Bucket([]byte(collection))
+	if b == nil {
+		return nil
+	}
+	return b.Delete([]byte(key))
+}
+
+func (t boltTx) ForEach(collection string, fn func(key string, data []byte) error) error {
+	b := t.tx.Bucket([]byte(collection))
+	if b == nil {
+		return nil
+	}
+	return b.ForEach(func(k, v []byte) error {
+		return fn(string(k), v)
+	})
+}
+
+// loadCollection decodes every record in a collection, keyed by ID
+func loadCollection[T any](tx Tx, collection string) (map[string]T, error) {
+	records := make(map[string]T)
+	err := tx.ForEach(collection, func(key string, data []byte) error {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("%s/%s: %v", collection, key, err)
+		}
+		records[key] = v
+		return nil
+	})
+	return records, err
+}
+
+// replaceCollection makes the collection hold exactly the given records
+func replaceCollection[T any](tx Tx, collection string, records map[string]T) error {
+	keys, err := collectionKeys(tx, collection)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, ok := records[key]; !ok {
+			if err := tx.Delete(collection, key); err != nil {
+				return err
+			}
+		}
+	}
+	for key, v := range records {
+		if err := tx.Put(collection, key, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectionKeys returns the keys in a collection, sorted
+func collectionKeys(tx Tx, collection string) ([]string, error) {
+	var keys []string
+	err := tx.ForEach(collection, func(key string, data []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// newTestStore points dataStore at an empty store for the length of the test
+func newTestStore(t *testing.T) {
+	t.Helper()
+	prevDir, prevStore := config.DataDir, dataStore
+	config.DataDir = t.TempDir()
+	store, err := openStore(filepath.Join(config.DataDir, storeFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataStore = store
+	t.Cleanup(func() {
+		store.Close()
+		config.DataDir, dataStore = prevDir, prevStore
+	})
+}
@@ -10,11 +10,37 @@ import (
 	"time"
 )
 
+// telegramAPIBase is the Bot API server, which TELEGRAM_API_URL can point
+// at a self-hosted server or a local stand-in
+var telegramAPIBase = strings.TrimRight(getEnvOrDefault("TELEGRAM_API_URL", "https://api.telegram.org"), "/")
+
+// telegramClient makes Bot API calls other than polling. The server is set
+// by the operator rather than by users, so unlike notifyClient it may be on
+// the local network.
+var telegramClient = &http.Client{Timeout: 20 * time.Second}
+
 // TelegramMessage represents a message to send via Telegram
 type TelegramMessage struct {
-	ChatID    string `json:"chat_id"`
-	Text      string `json:"text"`
-	ParseMode string `json:"parse_mode"`
+	ChatID      string          `json:"chat_id"`
+	Text        string          `json:"text"`
+	ParseMode   string          `json:"parse_mode"`
+	ReplyMarkup *telegramMarkup `json:"reply_markup,omitempty"`
+}
+
+// telegramMarkup is an inline keyboard shown under a message
+type telegramMarkup struct {
+	InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+}
+
+type telegramButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+// telegramOutgoing is a rendered message and its buttons, if any
+type telegramOutgoing struct {
+	Text     string
+	Keyboard *telegramMarkup
 }
 
 // sendTelegramNotifications sends notifications for new jobs
@@ -124,17 +150,147 @@ func escapeMarkdown(text string) string {
 	return replacer.Replace(text)
 }
 
+// telegramRetry retries sends through brief outages and rate limits
+var telegramRetry = retryPolicy{Attempts: 3, Backoff: 2 * time.Second, MaxBackoff: 30 * time.Second}
+
+// telegramBatchSize is the most jobs per message, keeping messages under
+// Telegram's length limit
+const telegramBatchSize = 10
+
+// telegramNotifier sends digests to a user's Telegram chat
+type telegramNotifier struct {
+	cfg TelegramAlertConfig
+}
+
+func (n telegramNotifier) Channel() string { return "Telegram" }
+
+func (n telegramNotifier) Notify(d Digest) error {
+	// Keep the jobs the Save and Hide buttons refer to
+	rememberTelegramJobs(d.Jobs)
+	for i, msg := range renderTelegramDigest(d) {
+		if i > 0 {
+			time.Sleep(2 * time.Second)
+		}
+		if err := n.send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send sends one message, retrying as telegramRetry allows
+func (n telegramNotifier) send(msg telegramOutgoing) error {
+	return withRetry(telegramRetry, func() error {
+		return sendTelegramMessageWithMarkup(n.cfg.BotToken, n.cfg.ChatID, msg.Text, msg.Keyboard)
+	})
+}
+
+// renderTelegramDigest formats a digest as MarkdownV2 messages of up to
+// telegramBatchSize jobs each, with Save and Hide buttons for every job
+func renderTelegramDigest(d Digest) []telegramOutgoing {
+	if d.Test {
+		return []telegramOutgoing{{Text: "🧪 *SalarySleuth Test*\n\nTelegram integration is working\\! You will receive job alerts here\\."}}
+	}
+	footer := "📅 " + escapeMarkdown(d.Date())
+	if note := d.OmittedNote(); note != "" {
+		footer = "🙈 " + escapeMarkdown(note) + "\n" + footer
+	}
+	if note := d.MoreNote(); note != "" {
+		if d.MoreURL != "" {
+			note = escapeMarkdown(note) + fmt.Sprintf(" [See them all](%s)", escapeMarkdownURL(d.MoreURL))
+		} else {
+			note = escapeMarkdown(note)
+		}
+		footer = "📋 " + note + "\n" + footer
+	}
+	if len(d.Jobs) == 0 {
+		return []telegramOutgoing{{Text: fmt.Sprintf("📋 *SalarySleuth Schedule*\n\n*%s*\n\nNo new jobs found this scan\\.\n\n%s",
+			escapeMarkdown(d.ScanName), footer)}}
+	}
+	header := "🎯 *SalarySleuth Alert*\n\n" +
+		fmt.Sprintf("📋 Schedule: *%s*\n", escapeMarkdown(d.ScanName)) +
+		fmt.Sprintf("Found *%d* new job\\(s\\)\\!", d.Total())
+	return renderTelegramJobs(header, d.Jobs, footer)
+}
+
+// renderTelegramJobs lists jobs under a header in messages of up to
+// telegramBatchSize jobs, ending with the footer. header and footer are
+// already MarkdownV2.
+func renderTelegramJobs(header string, jobs []DigestJob, footer string) []telegramOutgoing {
+	var messages []telegramOutgoing
+	for i := 0; i < len(jobs); i += telegramBatchSize {
+		end := i + telegramBatchSize
+		if end > len(jobs) {
+			end = len(jobs)
+		}
+
+		var sb strings.Builder
+		var keyboard [][]telegramButton
+		if i == 0 {
+			sb.WriteString(header + "\n\n")
+			sb.WriteString("━━━━━━━━━━━━━━━━━━━━\n\n")
+		}
+		for j, job := range jobs[i:end] {
+			n := i + j + 1
+			sb.WriteString(fmt.Sprintf("*%d\\.* %s\n", n, escapeMarkdown(job.Title)))
+			if label := job.ChangeLabel(); label != "" {
+				sb.WriteString(fmt.Sprintf("   🔁 _%s_\n", escapeMarkdown(label)))
+			}
+			sb.WriteString(fmt.Sprintf("   🏢 %s\n", escapeMarkdown(job.Company)))
+			if job.Location != "" {
+				sb.WriteString(fmt.Sprintf("   📍 %s\n", escapeMarkdown(job.Location)))
+			}
+			if job.LevelSalary != "" {
+				sb.WriteString(fmt.Sprintf("   💰 %s\n", escapeMarkdown(job.LevelSalary)))
+			} else if job.SalaryRange != "" {
+				sb.WriteString(fmt.Sprintf("   💰 %s\n", escapeMarkdown(job.SalaryRange)))
+			}
+			sb.WriteString(fmt.Sprintf("   🔗 [Apply](%s)\n\n", escapeMarkdownURL(job.URL)))
+			if job.ID != "" {
+				key := telegramJobKey(job.ID)
+				keyboard = append(keyboard, []telegramButton{
+					{Text: fmt.Sprintf("💾 Save %d", n), CallbackData: "save:" + key},
+					{Text: fmt.Sprintf("🙈 Hide %d", n), CallbackData: "hide:" + key},
+				})
+			}
+		}
+		if end == len(jobs) && footer != "" {
+			sb.WriteString("━━━━━━━━━━━━━━━━━━━━\n")
+			sb.WriteString(footer)
+		}
+		msg := telegramOutgoing{Text: sb.String()}
+		if len(keyboard) > 0 {
+			msg.Keyboard = &telegramMarkup{InlineKeyboard: keyboard}
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// escapeMarkdownURL escapes the characters MarkdownV2 requires escaping
+// inside a link target
+func escapeMarkdownURL(url string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(url)
+}
+
 func sendTelegramMessage(text string) error {
 	return sendTelegramMessageWithCreds(config.TelegramBotToken, config.TelegramChatID, text)
 }
 
 func sendTelegramMessageWithCreds(botToken, chatID, text string) error {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", botToken)
+	return sendTelegramMessageWithMarkup(botToken, chatID, text, nil)
+}
+
+// sendTelegramMessageWithMarkup sends a MarkdownV2 message with an optional
+// inline keyboard
+func sendTelegramMessageWithMarkup(botToken, chatID, text string, markup *telegramMarkup) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", telegramAPIBase, botToken)
 
 	msg := TelegramMessage{
-		ChatID:    chatID,
-		Text:      text,
-		ParseMode: "MarkdownV2",
+		ChatID:      chatID,
+		Text:        text,
+		ParseMode:   "MarkdownV2",
+		ReplyMarkup: markup,
 	}
 
 	jsonData, err := json.Marshal(msg)
@@ -142,29 +298,46 @@ func sendTelegramMessageWithCreds(botToken, chatID, text string) error {
 		return fmt.Errorf("failed to marshal message: %v", err)
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := telegramClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	// A 400 is usually Markdown Telegram couldn't parse, so retry as plain text
+	if resp.StatusCode == http.StatusBadRequest {
 		msg.ParseMode = ""
 		msg.Text = stripMarkdown(text)
 		jsonData, _ = json.Marshal(msg)
-		resp2, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+		resp2, err := telegramClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
 		if err != nil {
 			return fmt.Errorf("failed to send request (retry): %v", err)
 		}
 		defer resp2.Body.Close()
-		if resp2.StatusCode != http.StatusOK {
-			return fmt.Errorf("telegram API returned status: %d", resp2.StatusCode)
-		}
+		resp = resp2
+	}
+	if resp.StatusCode != http.StatusOK {
+		return telegramStatusError(resp)
 	}
 
 	return nil
 }
 
+// telegramStatusError reads the wait Telegram asks for when rate limiting,
+// which it puts in the response body
+func telegramStatusError(resp *http.Response) error {
+	err := newStatusError("telegram API", resp)
+	var body struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.NewDecoder(resp.Body).Decode(&body) == nil && body.Parameters.RetryAfter > 0 && err.RetryAfter == 0 {
+		err.RetryAfter = time.Duration(body.Parameters.RetryAfter) * time.Second
+	}
+	return err
+}
+
 func stripMarkdown(text string) string {
 	// Remove markdown formatting for plain text fallback
 	replacer := strings.NewReplacer(
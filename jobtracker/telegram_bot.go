@@ -0,0 +1,617 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// telegramPollTimeout is how long, in seconds, getUpdates waits for an update
+// before returning empty
+const telegramPollTimeout = 50
+
+// telegramJobTTL is how long the Save and Hide buttons on an alert work
+const telegramJobTTL = 60 * 24 * time.Hour
+
+// telegramListLimit is the most saved jobs /saved lists
+const telegramListLimit = 15
+
+var telegramPollClient = &http.Client{Timeout: (telegramPollTimeout + 15) * time.Second}
+
+// telegramBots tracks the bots being polled for commands, by token. Users
+// can share a bot, each talking to it from their own chat.
+var telegramBots = struct {
+	sync.Mutex
+	enabled bool
+	running map[string]context.CancelFunc
+}{running: make(map[string]context.CancelFunc)}
+
+// telegramSearches are the users with a /search running
+var telegramSearches = struct {
+	sync.Mutex
+	running map[string]bool
+}{running: make(map[string]bool)}
+
+type telegramUpdate struct {
+	UpdateID      int                    `json:"update_id"`
+	Message       *telegramIncoming      `json:"message"`
+	CallbackQuery *telegramCallbackQuery `json:"callback_query"`
+}
+
+type telegramIncoming struct {
+	MessageID int `json:"message_id"`
+	Chat      struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+	Text string `json:"text"`
+}
+
+type telegramCallbackQuery struct {
+	ID      string            `json:"id"`
+	Data    string            `json:"data"`
+	Message *telegramIncoming `json:"message"`
+}
+
+// telegramJobRef is a job that the Save and Hide buttons on an alert refer
+// to. Callback data is limited to 64 bytes, too short for job IDs, so the
+// buttons carry telegramJobKey of the ID instead.
+type telegramJobRef struct {
+	Job DigestJob `json:"job"`
+	At  time.Time `json:"at"`
+}
+
+func telegramJobKey(jobID string) string {
+	sum := sha256.Sum256([]byte(jobID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// rememberTelegramJobs records the jobs about to be sent with buttons
+func rememberTelegramJobs(jobs []DigestJob) {
+	err := dataStore.Update(func(tx Tx) error {
+		for _, job := range jobs {
+			if job.ID == "" {
+				continue
+			}
+			if err := tx.Put(collTelegramJobs, telegramJobKey(job.ID), telegramJobRef{Job: job, At: time.Now()}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to record Telegram alert jobs: %v", err)
+	}
+}
+
+// pruneTelegramJobs forgets the jobs of alerts older than telegramJobTTL
+func pruneTelegramJobs() {
+	cutoff := time.Now().Add(-telegramJobTTL)
+	err := dataStore.Update(func(tx Tx) error {
+		var expired []string
+		err := tx.ForEach(collTelegramJobs, func(key string, data []byte) error {
+			var ref telegramJobRef
+			if json.Unmarshal(data, &ref) != nil || ref.At.Before(cutoff) {
+				expired = append(expired, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := tx.Delete(collTelegramJobs, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to prune Telegram alert jobs: %v", err)
+	}
+}
+
+// startTelegramBots answers commands sent to the bots of users with verified
+// Telegram alerts, checking every minute for bots added or removed.
+// TELEGRAM_COMMANDS=off leaves Telegram send-only, for bots that another
+// program already receives updates for.
+func startTelegramBots() {
+	if strings.EqualFold(os.Getenv("TELEGRAM_COMMANDS"), "off") {
+		log.Println("Telegram bot commands disabled (TELEGRAM_COMMANDS=off)")
+		return
+	}
+	telegramBots.Lock()
+	telegramBots.enabled = true
+	telegramBots.Unlock()
+
+	log.Println("Telegram bot commands enabled (polling the bots of verified users)")
+	go func() {
+		var lastPrune time.Time
+		for {
+			syncTelegramBots()
+			if time.Since(lastPrune) > 24*time.Hour {
+				pruneTelegramJobs()
+				lastPrune = time.Now()
+			}
+			time.Sleep(time.Minute)
+		}
+	}()
+}
+
+// syncTelegramBots starts polling bots that users have verified and stops
+// polling those no longer in use
+func syncTelegramBots() {
+	tokens := make(map[string]bool)
+	for _, cfg := range loadAllUserAlerts() {
+		if cfg.Telegram.Verified && cfg.Telegram.BotToken != "" {
+			tokens[cfg.Telegram.BotToken] = true
+		}
+	}
+
+	telegramBots.Lock()
+	defer telegramBots.Unlock()
+	if !telegramBots.enabled {
+		return
+	}
+	for token, cancel := range telegramBots.running {
+		if !tokens[token] {
+			cancel()
+			delete(telegramBots.running, token)
+			log.Printf("Telegram %s: stopped polling", telegramBotName(token))
+		}
+	}
+	for token := range tokens {
+		if _, ok := telegramBots.running[token]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		telegramBots.running[token] = cancel
+		log.Printf("Telegram %s: polling for commands", telegramBotName(token))
+		go pollTelegramBot(ctx, token)
+	}
+}
+
+// telegramBotName identifies a bot in logs by the public ID at the start of
+// its token
+func telegramBotName(token string) string {
+	id, _, _ := strings.Cut(token, ":")
+	return "bot " + id
+}
+
+// pollTelegramBot long-polls getUpdates until ctx is cancelled
+func pollTelegramBot(ctx context.Context, token string) {
+	offset := 0
+	for {
+		var updates []telegramUpdate
+		err := telegramCall(ctx, telegramPollClient, token, "getUpdates", map[string]interface{}{
+			"offset":          offset,
+			"timeout":         telegramPollTimeout,
+			"allowed_updates": []string{"message", "callback_query"},
+		}, &updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			// 409 means a webhook is set or something else is polling the
+			// bot, and 401 a revoked token; neither clears up quickly
+			wait := 10 * time.Second
+			var status *statusError
+			if errors.As(err, &status) {
+				switch {
+				case status.Code == http.StatusConflict || status.Code == http.StatusUnauthorized:
+					wait = 5 * time.Minute
+				case status.RetryAfter > 0:
+					wait = status.RetryAfter
+				}
+			}
+			log.Printf("Telegram %s: getUpdates failed: %v", telegramBotName(token), err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			continue
+		}
+		for _, u := range updates {
+			offset = u.UpdateID + 1
+			handleTelegramUpdate(token, u)
+		}
+	}
+}
+
+// telegramCall calls a Bot API method and decodes its result
+func telegramCall(ctx context.Context, client *http.Client, token, method string, params, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/bot%s/%s", telegramAPIBase, token, method), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return telegramStatusError(resp)
+	}
+	var reply struct {
+		OK     bool            `json:"ok"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return err
+	}
+	if !reply.OK {
+		return fmt.Errorf("telegram API %s failed", method)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+// telegramChatUser finds the user whose verified Telegram alerts go to the
+// chat through the bot
+func telegramChatUser(token, chatID string) (string, bool) {
+	return telegramChatOwner(loadAllUserAlerts(), token, chatID)
+}
+
+// telegramChatOwner finds the user in all who verified chatID with the bot.
+// Verifying refuses a chat someone else has, but older versions didn't, so
+// users are checked in name order to settle any clash the same way each time.
+func telegramChatOwner(all map[string]UserAlertConfig, token, chatID string) (string, bool) {
+	names := make([]string, 0, len(all))
+	for username := range all {
+		names = append(names, username)
+	}
+	sort.Strings(names)
+	for _, username := range names {
+		cfg := all[username].Telegram
+		if cfg.Verified && cfg.BotToken == token && strings.TrimSpace(cfg.ChatID) == chatID {
+			return username, true
+		}
+	}
+	return "", false
+}
+
+// errTelegramChatTaken is returned when verifying a bot and chat another
+// user has already verified
+var errTelegramChatTaken = errors.New("this chat is already linked to another account")
+
+// verifyTelegramChat marks the user's Telegram settings verified and
+// enabled, provided they're still token and chatID and no other user has
+// the same pair
+func verifyTelegramChat(username, token, chatID string) error {
+	return dataStore.Update(func(tx Tx) error {
+		all, err := loadCollection[UserAlertConfig](tx, collAlerts)
+		if err != nil {
+			return err
+		}
+		cfg := all[username]
+		if cfg.Telegram.BotToken != token || strings.TrimSpace(cfg.Telegram.ChatID) != chatID {
+			return fmt.Errorf("telegram settings changed during the test")
+		}
+		delete(all, username)
+		if _, taken := telegramChatOwner(all, token, chatID); taken {
+			return errTelegramChatTaken
+		}
+		cfg.Telegram.Verified = true
+		cfg.Telegram.Enabled = true
+		return tx.Put(collAlerts, username, cfg)
+	})
+}
+
+func handleTelegramUpdate(token string, u telegramUpdate) {
+	switch {
+	case u.Message != nil && strings.HasPrefix(u.Message.Text, "/"):
+		chatID := strconv.FormatInt(u.Message.Chat.ID, 10)
+		username, ok := telegramChatUser(token, chatID)
+		if !ok {
+			telegramReply(token, chatID, escapeMarkdown(fmt.Sprintf(
+				"This chat isn't linked to a SalarySleuth account. Enter chat ID %s under Alerts & Schedules on the dashboard and send a test message.", chatID)))
+			return
+		}
+		runTelegramCommand(token, chatID, username, u.Message.Text)
+
+	case u.CallbackQuery != nil:
+		handleTelegramCallback(token, *u.CallbackQuery)
+	}
+}
+
+func telegramReply(token, chatID, text string) {
+	if err := sendTelegramMessageWithCreds(token, chatID, text); err != nil {
+		log.Printf("Telegram %s: reply to %s failed: %v", telegramBotName(token), chatID, err)
+	}
+}
+
+// telegramSendJobs sends a list of jobs with Save and Hide buttons
+func telegramSendJobs(token, chatID, header string, jobs []Job, footer string) {
+	digest := newDigest("", jobs)
+	rememberTelegramJobs(digest.Jobs)
+	for i, msg := range renderTelegramJobs(header, digest.Jobs, footer) {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		if err := sendTelegramMessageWithMarkup(token, chatID, msg.Text, msg.Keyboard); err != nil {
+			log.Printf("Telegram %s: reply to %s failed: %v", telegramBotName(token), chatID, err)
+			return
+		}
+	}
+}
+
+const telegramHelp = `🤖 *SalarySleuth Bot*
+
+/search ` + "`query`" + ` \- search LinkedIn, Greenhouse and Lever
+/latest \- the newest jobs matching your profile
+/saved \- your saved jobs
+/pause ` + "`name`" + ` \- pause your scan schedules, or those whose name contains ` + "`name`" + `
+/resume ` + "`name`" + ` \- resume them
+
+Tap 💾 Save or 🙈 Hide under a job to save it or stop hearing about it\.`
+
+func runTelegramCommand(token, chatID, username, text string) {
+	command, arg, _ := strings.Cut(strings.TrimSpace(text), " ")
+	command, _, _ = strings.Cut(strings.ToLower(command), "@") // "/latest@MyBot" in groups
+	arg = strings.TrimSpace(arg)
+
+	switch command {
+	case "/start", "/help":
+		telegramReply(token, chatID, telegramHelp)
+	case "/search":
+		telegramSearch(token, chatID, username, arg)
+	case "/latest":
+		telegramLatest(token, chatID, username)
+	case "/saved":
+		telegramSaved(token, chatID, username)
+	case "/pause":
+		telegramSetSchedules(token, chatID, username, arg, false)
+	case "/resume":
+		telegramSetSchedules(token, chatID, username, arg, true)
+	default:
+		telegramReply(token, chatID, escapeMarkdown("Unknown command. Send /help for the list."))
+	}
+}
+
+// telegramSearch runs a search like the dashboard's custom search, in the
+// background, and replies with the best matches. The results are kept in
+// the user's search history.
+func telegramSearch(token, chatID, username, q string) {
+	if len(q) < 3 || len(q) > 100 {
+		telegramReply(token, chatID, escapeMarkdown("Usage: /search <query>, e.g. /search red team. Queries are 3 to 100 characters."))
+		return
+	}
+	telegramSearches.Lock()
+	if telegramSearches.running[username] {
+		telegramSearches.Unlock()
+		telegramReply(token, chatID, escapeMarkdown("A search is already running. Results will arrive here when it finishes."))
+		return
+	}
+	telegramSearches.running[username] = true
+	telegramSearches.Unlock()
+
+	telegramReply(token, chatID, fmt.Sprintf("🔍 Searching LinkedIn, Greenhouse and Lever for *%s*\\. This takes a few minutes\\.", escapeMarkdown(q)))
+	go func() {
+		defer func() {
+			telegramSearches.Lock()
+			delete(telegramSearches.running, username)
+			telegramSearches.Unlock()
+		}()
+
+		log.Printf("Telegram search started by %s: %q", username, q)
+		jobs, err := runScheduledCustomScan(q)
+		if err != nil {
+			log.Printf("Telegram search by %s failed: %q: %v", username, q, err)
+			telegramReply(token, chatID, fmt.Sprintf("⚠️ Search for *%s* failed: %s", escapeMarkdown(q), escapeMarkdown(err.Error())))
+			return
+		}
+		tagged := TagJobs(jobs, configForUser(username))
+		sort.SliceStable(tagged, func(i, j int) bool { return tagged[i].Score > tagged[j].Score })
+
+		entryID := generateEntryID()
+		resultsFile := ""
+		if len(tagged) > 0 {
+			resultsFile = saveSearchResults(entryID, tagged)
+		}
+		addHistoryEntry(username, SearchHistoryEntry{
+			ID: entryID, Timestamp: time.Now(),
+			Type: "custom_search", Query: q,
+			ResultCount: len(tagged), ResultsFile: resultsFile,
+		})
+		log.Printf("Telegram search complete: %q found %d jobs", q, len(tagged))
+
+		hidden := loadUserHiddenJobs(username)
+		var best []Job
+		for _, t := range tagged {
+			if !hidden[t.Job.ID] {
+				best = append(best, t.Job)
+			}
+		}
+		if len(best) == 0 {
+			telegramReply(token, chatID, fmt.Sprintf("🔍 No jobs found for *%s*\\.", escapeMarkdown(q)))
+			return
+		}
+		header := fmt.Sprintf("🔍 *Results for %s*\n\nFound *%d* job\\(s\\), best matches first\\.", escapeMarkdown(q), len(best))
+		footer := ""
+		if len(best) > telegramBatchSize {
+			footer = escapeMarkdown(fmt.Sprintf("Showing %d of %d. See them all under History on the dashboard.", telegramBatchSize, len(best)))
+			best = best[:telegramBatchSize]
+		}
+		telegramSendJobs(token, chatID, header, best, footer)
+	}()
+}
+
+// telegramLatest replies with the newest open jobs that would be alerted
+// for the user's profile
+func telegramLatest(token, chatID, username string) {
+	cfg := configForUser(username)
+	var jobs []Job
+	for _, job := range alertableJobs(dashboardJobs(loadJobStore().Jobs, cfg), cfg) {
+		if jobStatus(job) != JobClosed {
+			jobs = append(jobs, job)
+		}
+	}
+	jobs = withoutHidden(username, jobs)
+	if len(jobs) == 0 {
+		telegramReply(token, chatID, escapeMarkdown("No open jobs match your profile yet."))
+		return
+	}
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].FirstSeen.After(jobs[j].FirstSeen) })
+	total := len(jobs)
+	if len(jobs) > telegramBatchSize {
+		jobs = jobs[:telegramBatchSize]
+	}
+	header := fmt.Sprintf("🆕 *Latest jobs*\n\nThe %d newest of *%d* open jobs matching your profile\\.", len(jobs), total)
+	telegramSendJobs(token, chatID, header, jobs, "")
+}
+
+func telegramSaved(token, chatID, username string) {
+	saved := getUserSavedJobsList(username)
+	if len(saved) == 0 {
+		telegramReply(token, chatID, escapeMarkdown("No saved jobs yet. Tap 💾 Save under an alerted job to save it."))
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("⭐ *Saved jobs* \\(%d\\)\n\n", len(saved)))
+	for i, job := range saved {
+		if i == telegramListLimit {
+			sb.WriteString(escapeMarkdown(fmt.Sprintf("…and %d more on the dashboard.", len(saved)-telegramListLimit)))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("*%d\\.* %s\n", i+1, escapeMarkdown(job.Title)))
+		sb.WriteString(fmt.Sprintf("   🏢 %s · 📌 %s\n", escapeMarkdown(job.Company), escapeMarkdown(stageLabel(applicationStage(job)))))
+		sb.WriteString(fmt.Sprintf("   🔗 [Listing](%s)\n\n", escapeMarkdownURL(job.URL)))
+	}
+	telegramReply(token, chatID, sb.String())
+}
+
+// telegramSetSchedules pauses or resumes the user's schedules, or only those
+// whose name contains match
+func telegramSetSchedules(token, chatID, username, match string, enabled bool) {
+	var matched, changed []string
+	err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+		for i := range cfg.Schedules {
+			s := &cfg.Schedules[i]
+			if match != "" && !strings.Contains(strings.ToLower(s.Name), strings.ToLower(match)) {
+				continue
+			}
+			matched = append(matched, s.Name)
+			if s.Enabled != enabled {
+				s.Enabled = enabled
+				// A resumed schedule picks up from now rather than catching
+				// up on the runs it missed while paused
+				s.NextRun = formatNextRun(s.nextRun(time.Now()))
+				changed = append(changed, s.Name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to update schedules for %s: %v", username, err)
+		telegramReply(token, chatID, escapeMarkdown("Couldn't update your schedules. Try again shortly."))
+		return
+	}
+
+	verb, icon := "Paused", "⏸"
+	if enabled {
+		verb, icon = "Resumed", "▶️"
+	}
+	switch {
+	case len(matched) == 0 && match != "":
+		telegramReply(token, chatID, escapeMarkdown(fmt.Sprintf("No schedule matches %q.", match)))
+	case len(matched) == 0:
+		telegramReply(token, chatID, escapeMarkdown("You have no scan schedules. Add them under Alerts & Schedules on the dashboard."))
+	case len(changed) == 0:
+		telegramReply(token, chatID, escapeMarkdown(fmt.Sprintf("Already %s: %s", strings.ToLower(verb), strings.Join(matched, ", "))))
+	default:
+		telegramReply(token, chatID, escapeMarkdown(fmt.Sprintf("%s %s: %s", icon, verb, strings.Join(changed, ", "))))
+	}
+}
+
+// handleTelegramCallback handles the Save and Hide buttons under alerted jobs
+func handleTelegramCallback(token string, cb telegramCallbackQuery) {
+	answer := func(text string) {
+		err := telegramCall(context.Background(), telegramClient, token, "answerCallbackQuery", map[string]interface{}{
+			"callback_query_id": cb.ID,
+			"text":              text,
+		}, nil)
+		if err != nil {
+			log.Printf("Telegram %s: answerCallbackQuery failed: %v", telegramBotName(token), err)
+		}
+	}
+	if cb.Message == nil {
+		answer("This message is too old to use.")
+		return
+	}
+	username, ok := telegramChatUser(token, strconv.FormatInt(cb.Message.Chat.ID, 10))
+	if !ok {
+		answer("This chat isn't linked to a SalarySleuth account.")
+		return
+	}
+
+	action, key, _ := strings.Cut(cb.Data, ":")
+	var ref telegramJobRef
+	var found bool
+	err := dataStore.View(func(tx Tx) error {
+		var err error
+		found, err = tx.Get(collTelegramJobs, key, &ref)
+		return err
+	})
+	if err != nil || !found {
+		answer("This alert has expired. Find the job on the dashboard instead.")
+		return
+	}
+	job := ref.Job
+
+	switch action {
+	case "save":
+		salary := job.LevelSalary
+		if salary == "" {
+			salary = job.SalaryRange
+		}
+		added, err := addSavedJob(username, SavedJob{
+			JobID:    job.ID,
+			Company:  job.Company,
+			Title:    job.Title,
+			Location: job.Location,
+			URL:      job.URL,
+			Source:   job.Source,
+			Salary:   salary,
+			SavedAt:  time.Now(),
+		})
+		switch {
+		case err != nil:
+			log.Printf("Failed to save job for %s: %v", username, err)
+			answer("Couldn't save the job. Try again shortly.")
+		case added:
+			answer("💾 Saved: " + job.Title)
+		default:
+			answer("Already saved: " + job.Title)
+		}
+
+	case "hide":
+		if _, err := setJobHidden(username, job.ID, true); err != nil {
+			log.Printf("Failed to hide job for %s: %v", username, err)
+			answer("Couldn't hide the job. Try again shortly.")
+			return
+		}
+		answer("🙈 Hidden: you won't be alerted about " + job.Title + " again.")
+
+	default:
+		answer("Unknown button.")
+	}
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBotToken = "123:test-token"
+
+// fakeBotAPI stands in for the Telegram Bot API. It serves batches of
+// updates to getUpdates in turn, then holds the last poll open, and records
+// what the bot sends.
+type fakeBotAPI struct {
+	mu      sync.Mutex
+	batches [][]telegramUpdate
+	offsets []int
+	sent    []string // sendMessage texts
+	answers []string // answerCallbackQuery texts
+	drained chan struct{}
+	once    sync.Once
+}
+
+func newFakeBotAPI(t *testing.T, batches ...[]telegramUpdate) *fakeBotAPI {
+	t.Helper()
+	api := &fakeBotAPI{batches: batches, drained: make(chan struct{})}
+	srv := httptest.NewServer(api)
+	prevBase := telegramAPIBase
+	telegramAPIBase = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		telegramAPIBase = prevBase
+	})
+	return api
+}
+
+func (api *fakeBotAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Offset int    `json:"offset"`
+		Text   string `json:"text"`
+	}
+	json.NewDecoder(r.Body).Decode(&params)
+	var result interface{} = true
+
+	switch strings.TrimPrefix(r.URL.Path, "/bot"+testBotToken+"/") {
+	case "getUpdates":
+		api.mu.Lock()
+		api.offsets = append(api.offsets, params.Offset)
+		var batch []telegramUpdate
+		more := len(api.batches) > 0
+		if more {
+			batch, api.batches = api.batches[0], api.batches[1:]
+		}
+		api.mu.Unlock()
+		if !more {
+			api.once.Do(func() { close(api.drained) })
+			<-r.Context().Done()
+			return
+		}
+		result = batch
+	case "sendMessage":
+		api.mu.Lock()
+		api.sent = append(api.sent, params.Text)
+		api.mu.Unlock()
+	case "answerCallbackQuery":
+		api.mu.Lock()
+		api.answers = append(api.answers, params.Text)
+		api.mu.Unlock()
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+}
+
+// last returns the last text in list, or "" if there is none
+func (api *fakeBotAPI) last(list *[]string) string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(*list) == 0 {
+		return ""
+	}
+	return (*list)[len(*list)-1]
+}
+
+// linkTestChat gives alice verified Telegram alerts in chat 42
+func linkTestChat(t *testing.T, schedules ...ScheduledScan) {
+	t.Helper()
+	cfg := UserAlertConfig{
+		Telegram:  TelegramAlertConfig{Enabled: true, Verified: true, BotToken: testBotToken, ChatID: "42"},
+		Schedules: schedules,
+	}
+	if err := saveUserAlerts("alice", cfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commandUpdate(chatID int64, text string) telegramUpdate {
+	msg := &telegramIncoming{Text: text}
+	msg.Chat.ID = chatID
+	return telegramUpdate{Message: msg}
+}
+
+func TestTelegramLocalServer(t *testing.T) {
+	// A Bot API server on loopback is the operator's choice, so sends reach
+	// it even though webhooks can't
+	newTestStore(t)
+	api := newFakeBotAPI(t)
+
+	if err := sendTelegramMessageWithCreds(testBotToken, "42", "hello"); err != nil {
+		t.Fatalf("sendMessage to a local server: %v", err)
+	}
+	if got := api.last(&api.sent); got != "hello" {
+		t.Errorf("sent %q, want %q", got, "hello")
+	}
+
+	handleTelegramCallback(testBotToken, telegramCallbackQuery{ID: "cb"})
+	if got := api.last(&api.answers); got == "" {
+		t.Error("answerCallbackQuery didn't reach a local server")
+	}
+}
+
+func TestPollTelegramBotOffsets(t *testing.T) {
+	newTestStore(t)
+	api := newFakeBotAPI(t,
+		[]telegramUpdate{{UpdateID: 10}, {UpdateID: 11}},
+		nil,
+		[]telegramUpdate{{UpdateID: 15}},
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		pollTelegramBot(ctx, testBotToken)
+		close(stopped)
+	}()
+	select {
+	case <-api.drained:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bot stopped polling")
+	}
+	cancel()
+	<-stopped
+
+	want := []int{0, 12, 12, 16}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.offsets) != len(want) {
+		t.Fatalf("getUpdates offsets = %v, want %v", api.offsets, want)
+	}
+	for i := range want {
+		if api.offsets[i] != want[i] {
+			t.Fatalf("getUpdates offsets = %v, want %v", api.offsets, want)
+		}
+	}
+}
+
+func TestTelegramPauseResume(t *testing.T) {
+	newTestStore(t)
+	api := newFakeBotAPI(t)
+	linkTestChat(t,
+		ScheduledScan{ID: "a", Name: "Daily red team", Cron: "0 9 * * *", Enabled: true},
+		ScheduledScan{ID: "b", Name: "Weekly", Cron: "0 9 * * 1", Enabled: true},
+	)
+	enabled := func() (daily, weekly bool) {
+		s := loadUserAlerts("alice").Schedules
+		return s[0].Enabled, s[1].Enabled
+	}
+
+	handleTelegramUpdate(testBotToken, commandUpdate(42, "/pause daily"))
+	if daily, weekly := enabled(); daily || !weekly {
+		t.Errorf("after /pause daily: enabled = %v, %v, want false, true", daily, weekly)
+	}
+	if got := api.last(&api.sent); !strings.Contains(got, "Paused: Daily red team") {
+		t.Errorf("/pause daily replied %q", got)
+	}
+
+	handleTelegramUpdate(testBotToken, commandUpdate(42, "/pause nightly"))
+	if got := api.last(&api.sent); !strings.Contains(got, `No schedule matches "nightly"`) {
+		t.Errorf("/pause nightly replied %q", got)
+	}
+
+	handleTelegramUpdate(testBotToken, commandUpdate(42, "/resume@SleuthBot"))
+	if daily, weekly := enabled(); !daily || !weekly {
+		t.Errorf("after /resume: enabled = %v, %v, want true, true", daily, weekly)
+	}
+	if got := api.last(&api.sent); !strings.Contains(got, "Resumed: Daily red team") || strings.Contains(got, "Weekly") {
+		t.Errorf("/resume replied %q", got)
+	}
+	if next := loadUserAlerts("alice").Schedules[0].NextRun; next == "" {
+		t.Error("resumed schedule has no next run")
+	}
+
+	handleTelegramUpdate(testBotToken, commandUpdate(99, "/pause"))
+	if got := api.last(&api.sent); !strings.Contains(got, "isn't linked") {
+		t.Errorf("/pause from an unlinked chat replied %q", got)
+	}
+	if daily, weekly := enabled(); !daily || !weekly {
+		t.Error("/pause from an unlinked chat paused alice's schedules")
+	}
+}
+
+func TestTelegramCallbacks(t *testing.T) {
+	newTestStore(t)
+	api := newFakeBotAPI(t)
+	linkTestChat(t)
+	rememberTelegramJobs([]DigestJob{{ID: "job-1", Title: "Red Teamer", Company: "Acme", URL: "https://example.com/1"}})
+
+	press := func(chatID int64, data string) string {
+		msg := &telegramIncoming{}
+		msg.Chat.ID = chatID
+		handleTelegramCallback(testBotToken, telegramCallbackQuery{ID: "cb", Data: data, Message: msg})
+		return api.last(&api.answers)
+	}
+	key := telegramJobKey("job-1")
+
+	if got := press(99, "save:"+key); !strings.Contains(got, "isn't linked") {
+		t.Errorf("save from an unlinked chat answered %q", got)
+	}
+	if saved := getUserSavedJobsList("alice"); len(saved) != 0 {
+		t.Errorf("save from an unlinked chat saved %+v", saved)
+	}
+
+	if got := press(42, "save:"+key); got != "💾 Saved: Red Teamer" {
+		t.Errorf("save answered %q", got)
+	}
+	if saved := getUserSavedJobsList("alice"); len(saved) != 1 || saved[0].JobID != "job-1" || saved[0].Company != "Acme" {
+		t.Errorf("saved jobs = %+v", saved)
+	}
+	if got := press(42, "save:"+key); got != "Already saved: Red Teamer" {
+		t.Errorf("second save answered %q", got)
+	}
+
+	if got := press(42, "hide:"+key); !strings.HasPrefix(got, "🙈 Hidden") {
+		t.Errorf("hide answered %q", got)
+	}
+	if !loadUserHiddenJobs("alice")["job-1"] {
+		t.Error("hide didn't hide the job")
+	}
+
+	if got := press(42, "save:"+telegramJobKey("job-2")); !strings.Contains(got, "expired") {
+		t.Errorf("save of an unknown job answered %q", got)
+	}
+}
+
+func TestVerifyTelegramChat(t *testing.T) {
+	newTestStore(t)
+	linkTestChat(t)
+	bob := UserAlertConfig{Telegram: TelegramAlertConfig{BotToken: testBotToken, ChatID: " 42 "}}
+	if err := saveUserAlerts("bob", bob); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verifyTelegramChat("bob", testBotToken, "42"); err != errTelegramChatTaken {
+		t.Errorf("verifying alice's chat for bob: err = %v, want errTelegramChatTaken", err)
+	}
+	if loadUserAlerts("bob").Telegram.Verified {
+		t.Error("bob verified alice's chat")
+	}
+	if err := verifyTelegramChat("alice", testBotToken, "42"); err != nil {
+		t.Errorf("re-verifying alice: %v", err)
+	}
+
+	// a clash stored before verifying checked for one resolves by name
+	all := map[string]UserAlertConfig{"zed": {}, "bob": {}, "carol": {}}
+	for name := range all {
+		all[name] = UserAlertConfig{Telegram: TelegramAlertConfig{Verified: true, BotToken: testBotToken, ChatID: "42"}}
+	}
+	for i := 0; i < 20; i++ {
+		if owner, _ := telegramChatOwner(all, testBotToken, "42"); owner != "bob" {
+			t.Fatalf("telegramChatOwner = %q, want bob", owner)
+		}
+	}
+}
+
+func TestCheckFollowUps(t *testing.T) {
+	newTestStore(t)
+	api := newFakeBotAPI(t)
+	linkTestChat(t)
+	// bob's chat was never set, so he has no Telegram to remind him on
+	bob := UserAlertConfig{Telegram: TelegramAlertConfig{Enabled: true, Verified: true, BotToken: testBotToken}}
+	if err := saveUserAlerts("bob", bob); err != nil {
+		t.Fatal(err)
+	}
+	job := SavedJob{JobID: "job-1", Title: "Red Teamer", Company: "Acme", URL: "https://example.com/1", SavedAt: time.Now(), FollowUp: "2026-10-18"}
+	for _, username := range []string{"alice", "bob"} {
+		if _, err := addSavedJob(username, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2026, 10, 18, followUpHour, 0, 0, 0, time.Local)
+	checkFollowUps(now)
+	api.mu.Lock()
+	sent := len(api.sent)
+	api.mu.Unlock()
+	if sent != 1 || !strings.Contains(api.last(&api.sent), "Red Teamer") {
+		t.Fatalf("sent %d reminders, last %q, want one for alice", sent, api.last(&api.sent))
+	}
+	if got := loadUserSavedJobs("alice").Jobs[0].FollowUpSent; got != job.FollowUp {
+		t.Errorf("alice's FollowUpSent = %q, want %q", got, job.FollowUp)
+	}
+	if got := loadUserSavedJobs("bob").Jobs[0].FollowUpSent; got != "" {
+		t.Errorf("bob's FollowUpSent = %q without a chat", got)
+	}
+
+	// A reminder goes once per follow-up date
+	checkFollowUps(now.Add(time.Hour))
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.sent) != 1 {
+		t.Errorf("sent %d reminders after a second check, want 1", len(api.sent))
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
 )
 
 // RefreshState tracks when jobs were last manually refreshed
@@ -24,32 +28,24 @@ type RefreshState struct {
 var (
 	refreshMutex    sync.Mutex
 	isRefreshing    bool
-	refreshStateFile string
 )
 
-func getRefreshStateFile() string {
-	if refreshStateFile == "" {
-		refreshStateFile = filepath.Join(config.DataDir, "last_refresh.json")
-	}
-	return refreshStateFile
-}
-
 func loadRefreshState() RefreshState {
-	data, err := os.ReadFile(getRefreshStateFile())
+	var state RefreshState
+	err := dataStore.View(func(tx Tx) error {
+		_, err := tx.Get(collMeta, metaRefreshState, &state)
+		return err
+	})
 	if err != nil {
-		return RefreshState{}
+		log.Printf("Failed to load refresh state: %v", err)
 	}
-	var state RefreshState
-	json.Unmarshal(data, &state)
 	return state
 }
 
 func saveRefreshState(state RefreshState) error {
-	data, err := json.MarshalIndent(state, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(getRefreshStateFile(), data, 0644)
+	return dataStore.Update(func(tx Tx) error {
+		return tx.Put(collMeta, metaRefreshState, state)
+	})
 }
 
 func canRefreshToday() bool {
@@ -72,39 +68,42 @@ func getNextRefreshTime() time.Time {
 	return time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 0, 0, 0, 0, state.LastRefresh.Location())
 }
 
-// Custom search state
-var (
-	searchMutex    sync.Mutex
-	isSearching    bool
-	searchResults  []TaggedJob
-	searchQuery    string
-	searchError    string
-	searchCancel   context.CancelFunc
-	searchCancelled bool
-)
+// shutdownTimeout is how long requests in flight get to finish on SIGINT or
+// SIGTERM
+const shutdownTimeout = 10 * time.Second
 
 func startWebServer() {
 	seedAdminUser()
 	startSessionCleanup()
-	ensureHistoryDir()
-	ensureSavedJobsDir()
-	ensureAlertsDir()
 	runHistoryCleanup()
 	startScheduler()
+	startTelegramBots()
+	watchConfig()
 
 	// Public endpoints
 	http.HandleFunc("/health", handleHealth)
 	http.HandleFunc("/", handleIndex)
+	http.HandleFunc("/company/", handleCompanyPage)
 	http.HandleFunc("/api/refresh/status", handleRefreshStatus)
+	http.HandleFunc("/api/refresh/events", handleRefreshEvents)
 	http.HandleFunc("/api/auth/register", handleRegister)
 	http.HandleFunc("/api/auth/check", handleAuthCheck)
 	http.HandleFunc("/login", handleLoginPage)
 	http.HandleFunc("/api/auth/login", handleLoginAPI)
+	http.HandleFunc("/api/auth/reset", handlePasswordReset)
 	http.HandleFunc("/logout", handleLogout)
 
 	// Authenticated endpoints (any role)
 	http.HandleFunc("/api/jobs", requireAuth(handleAPIJobs))
-	http.HandleFunc("/api/search", requireAuth(handleCustomSearch))
+	http.HandleFunc("/api/jobs/match", requireAuth(handleJobsMatch))
+	http.HandleFunc("/api/jobs/history/", requireAuth(handleJobHistory))
+	http.HandleFunc("/api/analytics/salaries", requireAuth(handleAnalyticsSalaries))
+	http.HandleFunc("/api/analytics/open-roles", requireAuth(handleAnalyticsOpenRoles))
+	http.HandleFunc("/api/analytics/scans", requireAuth(handleAnalyticsScans))
+	http.HandleFunc("/api/analytics/top-companies", requireAuth(handleAnalyticsTopCompanies))
+	http.HandleFunc("/api/companies/", requireAuth(handleAPICompany))
+	http.HandleFunc("/api/search", requireAuth(handleSearches))
+	http.HandleFunc("/api/search/", requireAuth(handleSearch))
 	http.HandleFunc("/api/search/status", requireAuth(handleSearchStatus))
 	http.HandleFunc("/api/search/cancel", requireAuth(handleCancelSearch))
 	http.HandleFunc("/api/history", requireAuth(handleHistory))
@@ -112,16 +111,34 @@ func startWebServer() {
 	http.HandleFunc("/api/history/clear", requireAuth(handleClearHistory))
 	http.HandleFunc("/api/saved", requireAuth(handleSavedJobs))
 	http.HandleFunc("/api/saved/ids", requireAuth(handleSavedJobIDs))
+	http.HandleFunc("/api/hidden", requireAuth(handleHiddenJobs))
+	http.HandleFunc("/api/applications", requireAuth(handleApplications))
+	http.HandleFunc("/api/applications/", requireAuth(handleApplication))
+	http.HandleFunc("/api/offers/compare", requireAuth(handleOfferCompare))
 	http.HandleFunc("/api/alerts/config", requireAuth(handleAlertsConfig))
 	http.HandleFunc("/api/alerts/telegram/test", requireAuth(handleTestTelegram))
+	http.HandleFunc("/api/alerts/email", requireAuth(handleEmailAlerts))
+	http.HandleFunc("/api/alerts/email/verify", requireAuth(handleVerifyEmail))
+	http.HandleFunc("/api/alerts/webhooks", requireAuth(handleWebhooks))
+	http.HandleFunc("/api/alerts/webhooks/test", requireAuth(handleTestWebhook))
+	http.HandleFunc("/api/alerts/delivery", requireAuth(handleDelivery))
 	http.HandleFunc("/api/alerts/schedules", requireAuth(handleSchedules))
 	http.HandleFunc("/api/alerts/schedules/delete", requireAuth(handleDeleteSchedule))
+	http.HandleFunc("/api/profile", requireAuth(handleProfile))
+	http.HandleFunc("/api/calendar", requireAuth(handleCalendarToken))
+	http.HandleFunc("/api/tokens", requireAuth(handleAPITokens))
+	http.HandleFunc("/api/sessions", requireAuth(handleSessions))
+	http.HandleFunc("/api/auth/password", requireAuth(handleChangePassword))
+	http.HandleFunc("/api/calendar/", handleCalendarFeed)
 
 	// Admin-only endpoints
 	http.HandleFunc("/api/refresh", requireAdmin(handleRefresh))
 	http.HandleFunc("/api/admin/tokens", requireAdmin(handleAdminTokens))
 	http.HandleFunc("/api/admin/users", requireAdmin(handleAdminUsers))
+	http.HandleFunc("/api/admin/users/reset", requireAdmin(handleAdminPasswordReset))
+	http.HandleFunc("/api/admin/sessions", requireAdmin(handleAdminSessions))
 	http.HandleFunc("/api/admin/history", requireAdmin(handleAdminHistory))
+	http.HandleFunc("/api/admin/config", requireAdmin(handleAdminConfig))
 
 	addr := fmt.Sprintf(":%d", config.WebPort)
 
@@ -134,221 +151,19 @@ func startWebServer() {
 		log.Printf("Web server listening on http://localhost%s (all features PUBLIC - set WEB_USERNAME/WEB_PASSWORD to create admin)", addr)
 	}
 
-	log.Fatal(http.ListenAndServe(addr, nil))
-}
-
-func cancelCurrentSearch() {
-	if searchCancel != nil {
-		searchCancel()
-		searchCancel = nil
-	}
-}
-
-func handleCustomSearch(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Query string `json:"query"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		jsonError(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-	req.Query = strings.TrimSpace(req.Query)
-	if req.Query == "" || len(req.Query) < 3 {
-		jsonError(w, "Search query must be at least 3 characters", http.StatusBadRequest)
-		return
-	}
-	if len(req.Query) > 100 {
-		jsonError(w, "Search query too long", http.StatusBadRequest)
-		return
-	}
-
-	searchMutex.Lock()
-	if isSearching {
-		log.Printf("Cancelling previous search %q to start new search %q", searchQuery, req.Query)
-		cancelCurrentSearch()
-		// Wait briefly for the goroutine to clean up
-		searchMutex.Unlock()
-		time.Sleep(500 * time.Millisecond)
-		searchMutex.Lock()
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	isSearching = true
-	searchQuery = req.Query
-	searchResults = nil
-	searchError = ""
-	searchCancel = cancel
-	searchCancelled = false
-	searchMutex.Unlock()
-
-	user := r.Header.Get("X-Auth-User")
-	queryForHistory := req.Query
+	server := &http.Server{Addr: addr}
 	go func() {
-		defer func() {
-			searchMutex.Lock()
-			isSearching = false
-			searchCancel = nil
-			cancel()
-			searchMutex.Unlock()
-		}()
-
-		log.Printf("Custom search started by %s: %q (sources run in parallel, 3 min per source)", user, queryForHistory)
-
-		sources := []string{"linkedin", "greenhouse", "lever"}
-		perSourceTimeout := 3 * time.Minute
-		var wg sync.WaitGroup
-
-		for _, src := range sources {
-			wg.Add(1)
-			go func(source string) {
-				defer wg.Done()
-				srcCtx, srcCancel := context.WithTimeout(ctx, perSourceTimeout)
-				defer srcCancel()
-
-				jobs, err := runSingleSourceSearch(srcCtx, queryForHistory, config.Pages, source)
-				if err != nil {
-					if ctx.Err() == context.Canceled {
-						return
-					}
-					log.Printf("Custom search source %s error: %v", source, err)
-					return
-				}
-
-				if len(jobs) > 0 {
-					cfg, _ := LoadConfig()
-					tagged := TagJobs(jobs, cfg)
-					searchMutex.Lock()
-					searchResults = append(searchResults, tagged...)
-					searchMutex.Unlock()
-					log.Printf("Custom search source %s returned %d jobs (total so far: %d)", source, len(tagged), len(searchResults))
-				} else {
-					log.Printf("Custom search source %s returned 0 jobs", source)
-				}
-			}(src)
-		}
-
-		wg.Wait()
-
-		if ctx.Err() == context.Canceled {
-			log.Printf("Custom search cancelled: %q", queryForHistory)
-			searchMutex.Lock()
-			searchCancelled = true
-			searchError = "Search was cancelled"
-			searchMutex.Unlock()
-			return
-		}
-
-		searchMutex.Lock()
-		totalResults := len(searchResults)
-		resultsSnapshot := make([]TaggedJob, len(searchResults))
-		copy(resultsSnapshot, searchResults)
-		searchMutex.Unlock()
-
-		entryID := generateEntryID()
-		resultsFile := ""
-		if totalResults > 0 {
-			resultsFile = saveSearchResults(entryID, resultsSnapshot)
-		}
-		addHistoryEntry(user, SearchHistoryEntry{
-			ID: entryID, Timestamp: time.Now(),
-			Type: "custom_search", Query: queryForHistory,
-			ResultCount: totalResults, ResultsFile: resultsFile,
-		})
-
-		log.Printf("Custom search complete: %q found %d total jobs", queryForHistory, totalResults)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		log.Println("Shutting down web server...")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
 	}()
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Search started for: " + req.Query,
-	})
-}
-
-func handleCancelSearch(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	searchMutex.Lock()
-	if !isSearching {
-		searchMutex.Unlock()
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "No search is currently running",
-		})
-		return
-	}
-	q := searchQuery
-	cancelCurrentSearch()
-	searchMutex.Unlock()
-
-	log.Printf("Search cancelled via API: %q", q)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Search for \"" + q + "\" has been cancelled",
-	})
-}
-
-func handleSearchStatus(w http.ResponseWriter, r *http.Request) {
-	searchMutex.Lock()
-	searching := isSearching
-	query := searchQuery
-	results := searchResults
-	errMsg := searchError
-	cancelled := searchCancelled
-	searchMutex.Unlock()
-
-	w.Header().Set("Content-Type", "application/json")
-	if searching {
-		partialCount := len(results)
-		msg := fmt.Sprintf("Searching for: %s... (LinkedIn, Greenhouse, Lever running in parallel)", query)
-		resp := map[string]interface{}{
-			"is_searching":  true,
-			"query":         query,
-			"partial_count": partialCount,
-		}
-		if partialCount > 0 {
-			msg = fmt.Sprintf("Searching for: %s... Found %d jobs so far, waiting for more sources...", query, partialCount)
-			resp["partial_results"] = results
-		}
-		resp["message"] = msg
-		json.NewEncoder(w).Encode(resp)
-		return
-	}
-
-	if cancelled {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"is_searching": false,
-			"cancelled":    true,
-			"query":        query,
-			"message":      "Search was cancelled",
-		})
-		return
-	}
-
-	if errMsg != "" {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"is_searching": false,
-			"query":        query,
-			"error":        errMsg,
-		})
-		return
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
 	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"is_searching": false,
-		"query":        query,
-		"results":      results,
-		"count":        len(results),
-	})
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -398,16 +213,38 @@ func sanitizeConfigForPublic(cfg *AppConfig) map[string]interface{} {
 	
 	// Include display settings (safe to expose)
 	sanitized["Display"] = cfg.Display
+	sanitized["Scoring"] = map[string]float64{
+		"MinScore": cfg.Scoring.MinScore,
+	}
 	
 	return sanitized
 }
 
 func handleAPIJobs(w http.ResponseWriter, r *http.Request) {
 	store := loadJobStore()
-	cfg, _ := LoadConfig()
+	cfg := configForUser(r.Header.Get("X-Auth-User"))
+
+	// By default return what the dashboard shows: open jobs and recently
+	// closed ones. ?status= selects active, missing, closed or all jobs.
+	jobs := dashboardJobs(store.Jobs, cfg)
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case "all":
+		jobs = store.Jobs
+	case JobActive, JobMissing, JobClosed:
+		jobs = nil
+		for _, job := range store.Jobs {
+			if jobStatus(job) == status {
+				jobs = append(jobs, job)
+			}
+		}
+	default:
+		jsonError(w, "status must be active, missing, closed or all", http.StatusBadRequest)
+		return
+	}
 
 	// Tag all jobs
-	taggedJobs := TagJobs(store.Jobs, cfg)
+	taggedJobs := TagJobs(jobs, cfg)
 
 	// Sanitize config to only include display information
 	sanitizedConfig := sanitizeConfigForPublic(cfg)
@@ -428,6 +265,86 @@ func handleAPIJobs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// handleJobsMatch evaluates the dashboard search box query against the jobs
+// the dashboard shows, or against the results of the user's custom search or
+// history entry {"search_id"}, so the browser uses the same query language as
+// the CLI and config.yaml rules. It returns the IDs of the matching jobs.
+func handleJobsMatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Query    string `json:"query"`
+		SearchID string `json:"search_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	q, err := query.Parse(req.Query)
+	if err != nil {
+		jsonError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jobs, ok := matchCandidates(r.Header.Get("X-Auth-User"), req.SearchID)
+	if !ok {
+		jsonError(w, "Search results not found", http.StatusNotFound)
+		return
+	}
+
+	ids := make([]string, 0)
+	for _, job := range jobs {
+		if q.MatchDocument(jobDocument(job)) {
+			ids = append(ids, job.ID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ids": ids,
+	})
+}
+
+// matchCandidates returns the jobs the search box filters for the user: the
+// dashboard's jobs, or those found by the custom search with the ID, which
+// is also the ID of its history entry once it finishes
+func matchCandidates(username, searchID string) ([]Job, bool) {
+	if searchID == "" {
+		return dashboardJobs(loadJobStore().Jobs, configForUser(username)), true
+	}
+
+	var results []TaggedJob
+	if s := userSearch(username, searchID); s != nil {
+		results = s.snapshot(true).Results
+	} else {
+		var entry *SearchHistoryEntry
+		entries := getUserHistory(username)
+		for i := range entries {
+			if entries[i].ID == searchID {
+				entry = &entries[i]
+				break
+			}
+		}
+		if entry == nil || entry.ResultsFile == "" {
+			return nil, false
+		}
+		data, err := loadSearchResults(entry.ResultsFile)
+		if err != nil || json.Unmarshal(data, &results) != nil {
+			return nil, false
+		}
+	}
+
+	jobs := make([]Job, 0, len(results))
+	for _, t := range results {
+		jobs = append(jobs, t.Job)
+	}
+	return jobs, true
+}
+
 func handleRefreshStatus(w http.ResponseWriter, r *http.Request) {
 	refreshMutex.Lock()
 	currentlyRefreshing := isRefreshing
@@ -469,19 +386,10 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 
 	refreshMutex.Lock()
-	if isRefreshing {
-		refreshMutex.Unlock()
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusConflict)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"message": "A refresh is already in progress. Please wait.",
-		})
-		return
-	}
+	busy := isRefreshing
+	refreshMutex.Unlock()
 
-	if !canRefreshToday() {
-		refreshMutex.Unlock()
+	if !busy && !canRefreshToday() {
 		state := loadRefreshState()
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusTooManyRequests)
@@ -493,7 +401,34 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if busy || !startRefresh("Manual refresh triggered via web UI") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": false,
+			"message": "A refresh is already in progress. Please wait.",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Refresh started. This may take a few minutes. The page will update automatically when complete.",
+	})
+}
+
+// startRefresh runs the default scan in the background and updates the job
+// store with its results, as -scrape does, unless a refresh is already
+// running. It reports whether it started one.
+func startRefresh(reason string) bool {
+	refreshMutex.Lock()
+	if isRefreshing {
+		refreshMutex.Unlock()
+		return false
+	}
 	isRefreshing = true
+	events.reset(refreshTopic)
 	refreshMutex.Unlock()
 
 	go func() {
@@ -502,76 +437,141 @@ func handleRefresh(w http.ResponseWriter, r *http.Request) {
 			isRefreshing = false
 			refreshMutex.Unlock()
 		}()
+		log.Println(reason)
+		runRefresh()
+	}()
+	return true
+}
 
-		log.Println("Manual refresh triggered via web UI")
-
-		const scrapeRuns = 3
-		allJobs := make(map[string]Job)
+func runRefresh() {
+	const scrapeRuns = 3
+	allJobs := make(map[string]Job)
 
-		for run := 1; run <= scrapeRuns; run++ {
-			log.Printf("  Refresh pass %d/%d...\n", run, scrapeRuns)
+	for run := 1; run <= scrapeRuns; run++ {
+		log.Printf("  Refresh pass %d/%d...\n", run, scrapeRuns)
+		events.publish(refreshTopic, "pass", map[string]interface{}{"pass": run, "passes": scrapeRuns})
 
-			jobs, err := runSalarySleuth(config.Description, config.Pages)
-			if err != nil {
-				log.Printf("  Warning: Pass %d failed: %v\n", run, err)
-				continue
-			}
+		pass := run
+		jobs, err := scans.submitWatched(config.Description, func(ev ScanEvent) {
+			publishRefreshEvent(pass, ev)
+		}).wait()
+		if err != nil {
+			log.Printf("  Warning: Pass %d failed: %v\n", run, err)
+			continue
+		}
 
-			for _, job := range jobs {
-				if _, exists := allJobs[job.ID]; !exists {
-					allJobs[job.ID] = job
-				} else {
-					existing := allJobs[job.ID]
-					if job.LevelSalary != "" && existing.LevelSalary == "" {
-						existing.LevelSalary = job.LevelSalary
-						allJobs[job.ID] = existing
-					}
-					if job.SalaryRange != "" && existing.SalaryRange == "" {
-						existing.SalaryRange = job.SalaryRange
-						allJobs[job.ID] = existing
-					}
+		for _, job := range jobs {
+			if _, exists := allJobs[job.ID]; !exists {
+				allJobs[job.ID] = job
+			} else {
+				existing := allJobs[job.ID]
+				if job.LevelSalary != "" && existing.LevelSalary == "" {
+					existing.LevelSalary = job.LevelSalary
+					allJobs[job.ID] = existing
+				}
+				if job.SalaryRange != "" && existing.SalaryRange == "" {
+					existing.SalaryRange = job.SalaryRange
+					allJobs[job.ID] = existing
 				}
-			}
-
-			if run < scrapeRuns {
-				time.Sleep(5 * time.Second)
 			}
 		}
 
-		var jobs []Job
-		for _, job := range allJobs {
-			jobs = append(jobs, job)
+		if run < scrapeRuns {
+			time.Sleep(5 * time.Second)
 		}
+	}
 
-		log.Printf("Refresh found %d total unique jobs\n", len(jobs))
+	var jobs []Job
+	for _, job := range allJobs {
+		jobs = append(jobs, job)
+	}
 
-		filteredJobs := filterOffsecJobs(jobs)
-		log.Printf("After filtering: %d OSCP/offsec jobs\n", len(filteredJobs))
+	log.Printf("Refresh found %d total unique jobs\n", len(jobs))
 
-		existingStore := loadJobStore()
-		newJobs, updatedStore := processJobs(filteredJobs, existingStore)
+	filteredJobs := filterOffsecJobs(jobs)
+	log.Printf("After filtering: %d OSCP/offsec jobs\n", len(filteredJobs))
 
-		if err := saveJobStore(updatedStore); err != nil {
-			log.Printf("Failed to save job store: %v", err)
-			return
-		}
+	var newJobs []Job
+	var updatedStore JobStore
+	err := updateJobStore(func(existingStore JobStore) (JobStore, error) {
+		newJobs, updatedStore = processJobs(filteredJobs, existingStore, closeAfterMisses())
+		return updatedStore, nil
+	})
+	if err != nil {
+		log.Printf("Failed to save job store: %v", err)
+		events.publish(refreshTopic, "done", map[string]interface{}{"error": "Failed to save jobs"})
+		return
+	}
 
-		if len(newJobs) > 0 {
-			log.Printf("Found %d new jobs, notifying subscribed users...\n", len(newJobs))
-			notifySubscribedUsers("Default OffSec Refresh", newJobs)
-		}
+	enrichCompanies(updatedStore.Jobs)
 
-		saveRefreshState(RefreshState{LastRefresh: time.Now()})
-		log.Printf("Manual refresh complete. %d active jobs in store.\n", len(updatedStore.Jobs))
-	}()
+	if len(newJobs) > 0 {
+		log.Printf("Found %d new jobs, notifying subscribed users...\n", len(newJobs))
+		notifySubscribedUsers("Default OffSec Refresh", newJobs)
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Refresh started. This may take a few minutes. The page will update automatically when complete.",
+	saveRefreshState(RefreshState{LastRefresh: time.Now()})
+	log.Printf("Refresh complete. %d open jobs in store.\n", countOpenJobs(updatedStore.Jobs))
+	events.publish(refreshTopic, "done", map[string]interface{}{
+		"found": len(filteredJobs), "new": len(newJobs), "open": countOpenJobs(updatedStore.Jobs),
 	})
 }
 
+// refreshRequestFile is left in DataDir by a -scrape run that found the web
+// server holding the data store, for the server to run the scrape instead
+const refreshRequestFile = "refresh.request"
+
+// requestRefresh asks the web server to run the scrape
+func requestRefresh() error {
+	path := filepath.Join(config.DataDir, refreshRequestFile)
+	return os.WriteFile(path, []byte(time.Now().Format(time.RFC3339)+"\n"), 0644)
+}
+
+// checkRefreshRequest starts the refresh a -scrape run asked for, if any.
+// Like -scrape, it isn't limited to once a day.
+func checkRefreshRequest() {
+	path := filepath.Join(config.DataDir, refreshRequestFile)
+	if err := os.Remove(path); err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read refresh request: %v", err)
+		}
+		return
+	}
+	if !startRefresh("Refresh requested by -scrape") {
+		log.Println("Refresh requested by -scrape, but one is already running")
+	}
+}
+
+// refreshTopic is where a manual refresh publishes its progress
+const refreshTopic = "refresh"
+
+// publishRefreshEvent sends a refresh pass's scan event to the refresh
+// stream. Anyone can watch it, so jobs are sent as counts only.
+func publishRefreshEvent(pass int, ev ScanEvent) {
+	data := map[string]interface{}{"pass": pass, "source": ev.Source}
+	switch ev.Type {
+	case EventPage:
+		data["pages"], data["found"] = ev.Pages, ev.Found
+	case EventEnrich:
+		data["done"], data["total"] = ev.Done, ev.Total
+	case EventJobs:
+		data["count"] = len(ev.Jobs)
+	case EventSourceFailed:
+		data["error"], data["timed_out"] = ev.Error, ev.TimedOut
+	}
+	events.publish(refreshTopic, ev.Type, data)
+}
+
+// handleRefreshEvents streams the current or last manual refresh's progress
+// as Server-Sent Events
+func handleRefreshEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	serveEvents(w, r, refreshTopic)
+}
+
 func generateEntryID() string {
 	b := make([]byte, 8)
 	rand.Read(b)
@@ -675,7 +675,7 @@ func handleHistoryResults(w http.ResponseWriter, r *http.Request) {
 
 	data, err := loadSearchResults(found.ResultsFile)
 	if err != nil {
-		jsonError(w, "Results file not found", http.StatusNotFound)
+		jsonError(w, "Stored results not found", http.StatusNotFound)
 		return
 	}
 
@@ -805,7 +805,17 @@ func handleAlertsConfig(w http.ResponseWriter, r *http.Request) {
 				"has_chatid": cfg.Telegram.ChatID != "",
 				"verified":  cfg.Telegram.Verified,
 			},
+			"email": map[string]interface{}{
+				"available":    config.SMTP.configured(),
+				"enabled":      cfg.Email.Enabled,
+				"address":      cfg.Email.Address,
+				"verified":     cfg.Email.Verified,
+				"code_pending": cfg.Email.CodeHash != "" && time.Since(cfg.Email.CodeSentAt) < emailCodeTTL,
+			},
+			"webhooks":  webhookViews(cfg.Webhooks),
 			"schedules": cfg.Schedules,
+			"delivery":  cfg.Delivery,
+			"queued":    queuedAlerts(username),
 		}
 		json.NewEncoder(w).Encode(safe)
 		return
@@ -860,7 +870,14 @@ func handleTestTelegram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	testMsg := "🧪 *SalarySleuth Test*\n\nTelegram integration is working\\! You will receive job alerts here\\."
+	chatID := strings.TrimSpace(cfg.Telegram.ChatID)
+	// Commands in a chat act for the user who verified it, so only one can
+	if owner, ok := telegramChatUser(cfg.Telegram.BotToken, chatID); ok && owner != username {
+		jsonError(w, errTelegramChatTaken.Error(), http.StatusConflict)
+		return
+	}
+
+	testMsg := renderTelegramDigest(testDigest())[0].Text
 	err := sendTelegramMessageWithCreds(cfg.Telegram.BotToken, cfg.Telegram.ChatID, testMsg)
 
 	w.Header().Set("Content-Type", "application/json")
@@ -870,11 +887,15 @@ func handleTestTelegram(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cfg.Telegram.Verified = true
-	cfg.Telegram.Enabled = true
-	if err := saveUserAlerts(username, cfg); err != nil {
+	switch err := verifyTelegramChat(username, cfg.Telegram.BotToken, chatID); {
+	case err == errTelegramChatTaken:
+		jsonError(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
 		log.Printf("Failed to save verified state for %s: %v", username, err)
 	}
+	// Start answering commands sent to the bot
+	go syncTelegramBots()
 
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Test message sent! Check your Telegram."})
 }
@@ -906,17 +927,22 @@ func handleSchedules(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Search query is required for custom scans"})
 			return
 		}
-		if sched.Hour < 0 || sched.Hour > 23 || sched.Minute < 0 || sched.Minute > 59 {
+		// Clients from before cron expressions send a frequency and time
+		sched.Cron = strings.TrimSpace(sched.cronExpr())
+		sched.Schedule, sched.Days, sched.Hour, sched.Minute = "", nil, 0, 0
+		sched.Timezone = strings.TrimSpace(sched.Timezone)
+		if err := validateSchedule(sched); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid time"})
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
+		sched.NextRun = formatNextRun(sched.nextRun(time.Now()))
 
 		cfg := loadUserAlerts(username)
 
-		if !cfg.Telegram.Verified {
+		if !cfg.hasAlertChannel() {
 			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Configure and verify Telegram first"})
+			json.NewEncoder(w).Encode(map[string]string{"error": "Configure and verify an alert channel (Telegram, email or a webhook) first"})
 			return
 		}
 
@@ -993,31 +1019,57 @@ func handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save"})
 		return
 	}
+	if err := forgetSeenJobs(username, req.ID); err != nil {
+		log.Printf("Failed to remove seen jobs of schedule %s for %s: %v", req.ID, username, err)
+	}
 	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "schedules": cfg.Schedules})
 }
 
 func handleIndex(w http.ResponseWriter, r *http.Request) {
 	store := loadJobStore()
-	cfg, _ := LoadConfig()
+
+	// The dashboard is public, so only apply a profile for a logged-in user
+	username := dashboardUser(r)
+	cfg := configForUser(username)
+	var dashboard *SearchFilters
+	if username != "" {
+		if p, ok := loadUserProfile(username); ok {
+			dashboard = p.Dashboard
+		}
+	}
 
 	// Sort jobs by FirstSeen (newest first)
-	sort.Slice(store.Jobs, func(i, j int) bool {
-		return store.Jobs[i].FirstSeen.After(store.Jobs[j].FirstSeen)
+	jobs := dashboardJobs(store.Jobs, cfg)
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].FirstSeen.After(jobs[j].FirstSeen)
 	})
 
 	// Tag all jobs
-	taggedJobs := TagJobs(store.Jobs, cfg)
+	taggedJobs := TagJobs(jobs, cfg)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	html := generateInteractiveHTML(taggedJobs, store.LastUpdated, cfg)
+	html := generateInteractiveHTML(taggedJobs, store.LastUpdated, cfg, dashboard)
 	w.Write([]byte(html))
 }
 
-func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppConfig) string {
+// dashboardUser returns the user viewing a public page: the logged-in user,
+// "anonymous" when auth is disabled, or "" otherwise
+func dashboardUser(r *http.Request) string {
+	if !isAuthEnabled() {
+		return "anonymous"
+	}
+	if user := authenticateRequest(r); user != nil {
+		return user.Username
+	}
+	return ""
+}
+
+func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppConfig, dashboard *SearchFilters) string {
 	// Convert jobs to JSON for JavaScript
 	jobsJSON, _ := json.Marshal(jobs)
 	configJSON, _ := json.Marshal(cfg)
+	dashboardJSON, _ := json.Marshal(dashboard)
 
 	lastUpdatedStr := "Never"
 	if !lastUpdated.IsZero() {
@@ -1251,6 +1303,14 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			border-color: var(--danger);
 		}
 
+		.job-card.hidden-job {
+			opacity: 0.5;
+		}
+
+		.job-card.closed {
+			opacity: 0.7;
+		}
+
 		.job-header {
 			display: flex;
 			justify-content: space-between;
@@ -1267,6 +1327,8 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			text-transform: uppercase;
 			letter-spacing: 0.3px;
 		}
+		.company-name a, .company-bar-name a { color: inherit; text-decoration: none; }
+		.company-name a:hover, .company-bar-name a:hover { color: var(--accent-primary); text-decoration: underline; }
 
 		.badge {
 			font-size: 0.6rem;
@@ -1313,6 +1375,30 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			border: 1px solid rgba(255, 71, 87, 0.3);
 		}
 
+		.badge-score {
+			background: rgba(255, 255, 255, 0.08);
+			color: var(--text-primary);
+			border: 1px solid rgba(255, 255, 255, 0.2);
+			cursor: help;
+		}
+
+		.badge-score.low {
+			color: var(--text-muted);
+		}
+
+		.badge-missing {
+			background: rgba(255, 165, 2, 0.15);
+			color: var(--warning);
+			border: 1px solid rgba(255, 165, 2, 0.3);
+			cursor: help;
+		}
+
+		.badge-closed {
+			background: rgba(255, 255, 255, 0.08);
+			color: var(--text-muted);
+			border: 1px solid rgba(255, 255, 255, 0.2);
+		}
+
 		@keyframes pulse {
 			0%, 100% { opacity: 1; }
 			50% { opacity: 0.7; }
@@ -1797,8 +1883,114 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			gap: 0.5rem;
 			align-items: center;
 		}
-		.history-list {
-			max-height: 400px;
+		.kanban {
+			display: grid;
+			grid-template-columns: repeat(7, minmax(170px, 1fr));
+			gap: 0.75rem;
+			overflow-x: auto;
+			padding-bottom: 0.5rem;
+		}
+		.kanban-column {
+			background: var(--bg-card);
+			border: 1px solid var(--border-color);
+			border-radius: 8px;
+			padding: 0.6rem;
+			min-height: 160px;
+		}
+		.kanban-column.drag-over { border-color: var(--accent-primary); }
+		.kanban-column h3 {
+			font-size: 0.75rem;
+			color: var(--text-secondary);
+			text-transform: uppercase;
+			letter-spacing: 0.5px;
+			margin-bottom: 0.5rem;
+			display: flex;
+			justify-content: space-between;
+		}
+		.kanban-card {
+			background: var(--bg-secondary);
+			border: 1px solid var(--border-color);
+			border-radius: 6px;
+			padding: 0.5rem 0.6rem;
+			margin-bottom: 0.5rem;
+			cursor: grab;
+			font-size: 0.8rem;
+		}
+		.kanban-card:hover { border-color: var(--accent-primary); }
+		.kanban-card-company { color: var(--text-secondary); font-size: 0.7rem; text-transform: uppercase; }
+		.kanban-card-title { color: var(--text-primary); margin: 0.15rem 0; }
+		.kanban-card-meta { color: var(--text-muted); font-size: 0.7rem; }
+		.kanban-card-meta.due { color: var(--warning); }
+		.application-form { max-width: 640px; max-height: 90vh; overflow-y: auto; }
+		.application-form .form-row { display: flex; gap: 0.5rem; }
+		.application-form .form-row > * { flex: 1; min-width: 0; }
+		.application-form select, .application-form textarea {
+			width: 100%;
+			padding: 0.6rem 0.8rem;
+			background: var(--bg-card);
+			border: 1px solid var(--border-color);
+			border-radius: 8px;
+			color: var(--text-primary);
+			font-size: 0.9rem;
+			font-family: 'Outfit', sans-serif;
+		}
+		.application-form h3 { font-size: 0.9rem; color: var(--text-secondary); margin: 1rem 0 0.5rem; }
+		.stage-history { font-size: 0.8rem; color: var(--text-secondary); list-style: none; }
+		.stage-history li { padding: 0.2rem 0; }
+		.offer-compare { max-width: 960px; max-height: 90vh; overflow: auto; }
+		.offer-table { width: 100%; border-collapse: collapse; font-size: 0.85rem; }
+		.offer-table th, .offer-table td {
+			padding: 0.45rem 0.6rem;
+			border-bottom: 1px solid var(--border-color);
+			text-align: right;
+			white-space: nowrap;
+		}
+		.offer-table th:first-child, .offer-table td:first-child { text-align: left; color: var(--text-secondary); }
+		.offer-table thead th { color: var(--text-primary); }
+		.offer-table tr.offer-total td { color: var(--accent-primary); font-weight: 600; }
+		.offer-table .best { color: var(--accent-primary); }
+		.offer-table .above { color: var(--accent-primary); }
+		.offer-table .below { color: #ff4757; }
+		.analytics-grid {
+			display: grid;
+			grid-template-columns: repeat(auto-fit, minmax(420px, 1fr));
+			gap: 1rem;
+		}
+		.analytics-card {
+			background: var(--bg-card);
+			border: 1px solid var(--border-color);
+			border-radius: 8px;
+			padding: 1rem;
+			min-width: 0;
+		}
+		.analytics-card-header {
+			display: flex;
+			justify-content: space-between;
+			align-items: center;
+			margin-bottom: 0.5rem;
+		}
+		.analytics-card h3 { font-size: 0.95rem; color: var(--text-primary); font-weight: 500; }
+		.analytics-card select {
+			padding: 0.25rem 0.5rem;
+			background: var(--bg-secondary);
+			border: 1px solid var(--border-color);
+			border-radius: 6px;
+			color: var(--text-primary);
+			font-size: 0.8rem;
+		}
+		.chart { width: 100%; height: auto; display: block; }
+		.chart text { fill: var(--text-muted); font-size: 10px; font-family: 'JetBrains Mono', monospace; }
+		.chart .grid { stroke: var(--border-color); stroke-width: 1; }
+		.chart-legend { display: flex; flex-wrap: wrap; gap: 0.25rem 0.75rem; margin-top: 0.5rem; font-size: 0.75rem; color: var(--text-secondary); }
+		.chart-legend span::before { content: ''; display: inline-block; width: 10px; height: 10px; border-radius: 2px; margin-right: 0.3rem; background: var(--swatch); }
+		.company-bars { display: flex; flex-direction: column; gap: 0.4rem; }
+		.company-bar { display: grid; grid-template-columns: 9rem 1fr; gap: 0.5rem; align-items: center; font-size: 0.8rem; }
+		.company-bar-name { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; color: var(--text-primary); }
+		.company-bar-track { position: relative; height: 1.3rem; background: var(--bg-secondary); border-radius: 4px; }
+		.company-bar-fill { position: absolute; top: 0; left: 0; bottom: 0; background: var(--accent-glow); border-right: 2px solid var(--accent-primary); border-radius: 4px; }
+		.company-bar-label { position: relative; padding: 0 0.4rem; line-height: 1.3rem; color: var(--text-secondary); white-space: nowrap; }
+		.history-list {
+			max-height: 400px;
 			overflow-y: auto;
 		}
 		.history-entry {
@@ -1922,6 +2114,10 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			color: var(--warning);
 			background: rgba(255, 165, 2, 0.1);
 		}
+		.save-btn.hide-btn:hover {
+			border-color: var(--text-secondary);
+			color: var(--text-secondary);
+		}
 		.job-actions {
 			display: flex;
 			gap: 0.6rem;
@@ -2042,10 +2238,19 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 						<option value="">All Sources</option>
 					</select>
 				</div>
+				<div class="filter-group">
+					<label>Status</label>
+					<select id="filter-status" onchange="userApplyFilters()">
+						<option value="">Open Jobs</option>
+						<option value="closed">Recently Closed</option>
+						<option value="all">Open &amp; Closed</option>
+					</select>
+				</div>
 				<div class="filter-group">
 					<label>Sort By</label>
 					<select id="filter-sort" onchange="userApplyFilters()">
 						<option value="newest">Newest First</option>
+						<option value="score">Best Match</option>
 						<option value="salary_high">Highest Salary</option>
 						<option value="salary_low">Lowest Salary</option>
 						<option value="company">Company A-Z</option>
@@ -2065,6 +2270,13 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					<span>Show Excluded</span>
 				</div>
 			</div>
+			<div class="filter-group">
+				<label>&nbsp;</label>
+				<div class="checkbox-group">
+					<input type="checkbox" id="filter-show-hidden" onchange="userApplyFilters()">
+					<span>Show Hidden</span>
+				</div>
+			</div>
 			<div class="filter-group">
 				<label>&nbsp;</label>
 				<div class="checkbox-group">
@@ -2079,13 +2291,18 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					<span>🎯 Exact Title Match</span>
 				</div>
 			</div>
+			<div class="filter-group">
+				<label>Min Score</label>
+				<input type="number" id="filter-min-score" step="5" placeholder="Any" oninput="userApplyFilters()" style="width:100%">
+			</div>
 			<div class="filter-group search-group" style="min-width:220px">
 				<label>Exclude Title Words</label>
 				<input type="text" id="filter-exclude-words" placeholder="e.g. account, senior, manager" oninput="userApplyFilters()" style="width:100%">
 			</div>
 			<div id="search-container" class="filter-group search-group hidden">
 				<label>Search (Title / Keywords)</label>
-				<input type="text" id="search-input" placeholder="Search by title, company, or location..." oninput="userApplyFilters()">
+				<input type="text" id="search-input" placeholder='e.g. title:"red team" -manager salary>=200k' title='Terms match whole words. Use "phrases", AND, OR, NOT (or -term), parentheses, title:, company:, location: and salary>=200k' oninput="userApplyFilters()">
+				<div id="search-error" style="display:none;color:var(--danger);font-size:0.75rem;margin-top:0.25rem"></div>
 			</div>
 		</div>
 	</div>
@@ -2114,9 +2331,11 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					<span id="refresh-btn-text">Refresh Jobs</span>
 				</button>
 				<button id="saved-jobs-btn" class="btn-login hidden" onclick="toggleSavedJobsPanel()">&#9733; Saved</button>
+				<button id="applications-btn" class="btn-login hidden" onclick="toggleApplicationsPanel()">&#128203; Applications</button>
 				<button id="clear-results-btn" class="btn-login hidden" onclick="clearResultsForUser()" style="border-color:var(--warning);color:var(--warning)">&#10005; Clear Jobs</button>
 				<button id="restore-results-btn" class="btn-login hidden" onclick="restoreResults()" style="border-color:var(--accent-secondary);color:var(--accent-secondary)">&#8634; Restore Jobs</button>
 				<button id="history-btn" class="btn-login hidden" onclick="toggleHistoryPanel()">&#128337; History</button>
+				<button id="analytics-btn" class="btn-login hidden" onclick="toggleAnalyticsPanel()">&#128200; Analytics</button>
 				<button id="alerts-btn" class="btn-login hidden" onclick="toggleAlertsPanel()">&#128276; Alerts</button>
 				<button id="admin-panel-btn" class="btn-login hidden" onclick="toggleAdminPanel()">&#9881; Admin</button>
 				<button id="admin-history-btn" class="btn-login hidden" onclick="toggleAdminHistoryPanel()">&#128337; All History</button>
@@ -2160,6 +2379,89 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			</div>
 		</div>
 
+		<div id="applications-panel" class="history-panel hidden">
+			<div class="history-header">
+				<h2 onclick="toggleApplicationsPanel()">&#128203; Applications</h2>
+				<div class="history-header-actions">
+					<span class="auth-hint">Drag a card to change its stage; click it for details</span>
+					<button class="btn-reset" onclick="openOfferComparison()">Compare offers</button>
+					<button class="btn-reset" onclick="toggleApplicationsPanel()">Close</button>
+				</div>
+			</div>
+			<div id="applications-board" class="kanban"></div>
+		</div>
+
+		<div id="application-modal" class="modal hidden" onclick="if(event.target===this)closeApplication()">
+			<div class="modal-content application-form">
+				<h2 id="application-title"></h2>
+				<div class="form-row">
+					<div class="form-group">
+						<label>Stage</label>
+						<select id="app-stage"></select>
+					</div>
+					<div class="form-group">
+						<label>Stage note</label>
+						<input type="text" id="app-stage-note" placeholder="Recorded with a stage change">
+					</div>
+				</div>
+				<div class="form-row">
+					<div class="form-group">
+						<label>Follow up on</label>
+						<input type="date" id="app-follow-up">
+					</div>
+					<div class="form-group">
+						<label>Follow-up note</label>
+						<input type="text" id="app-follow-up-note" placeholder="e.g. Ping recruiter about onsite">
+					</div>
+				</div>
+				<h3>Contacts</h3>
+				<div id="app-contacts"></div>
+				<button class="btn-sm" onclick="addContactRow()">+ Add contact</button>
+				<h3>Interviews</h3>
+				<div id="app-interviews"></div>
+				<button class="btn-sm" onclick="addInterviewRow()">+ Add interview</button>
+				<h3>Offer</h3>
+				<div class="form-row">
+					<div class="form-group"><label>Base</label><input type="number" id="app-offer-base" min="0" placeholder="180000"></div>
+					<div class="form-group"><label>Annual bonus</label><input type="number" id="app-offer-bonus" min="0"></div>
+					<div class="form-group"><label>Equity (grant)</label><input type="number" id="app-offer-equity" min="0"></div>
+				</div>
+				<div class="form-row">
+					<div class="form-group"><label>Vesting (% per year)</label><input type="text" id="app-offer-vesting" placeholder="25/25/25/25"></div>
+					<div class="form-group"><label>Cliff (months)</label><input type="number" id="app-offer-cliff" min="0" placeholder="12"></div>
+					<div class="form-group"><label>Sign-on</label><input type="number" id="app-offer-sign-on" min="0"></div>
+					<div class="form-group"><label>Sign-on over (years)</label><input type="number" id="app-offer-sign-on-years" min="1" placeholder="1"></div>
+				</div>
+				<div class="form-row">
+					<div class="form-group"><label>Deadline</label><input type="date" id="app-offer-deadline"></div>
+					<div class="form-group"><label>Offer notes</label><input type="text" id="app-offer-notes"></div>
+				</div>
+				<div class="form-group">
+					<label>Notes</label>
+					<textarea id="app-note" rows="3"></textarea>
+				</div>
+				<h3>History</h3>
+				<ul id="app-history" class="stage-history"></ul>
+				<div id="app-error" class="form-error hidden"></div>
+				<div style="display:flex;gap:0.5rem;justify-content:flex-end;margin-top:1rem">
+					<button class="btn-sm btn-danger" onclick="removeApplication()" style="margin-right:auto">Remove</button>
+					<button class="btn-reset" onclick="closeApplication()">Cancel</button>
+					<button class="refresh-btn" onclick="saveApplication()">Save</button>
+				</div>
+			</div>
+		</div>
+
+		<div id="offer-compare-modal" class="modal hidden" onclick="if(event.target===this)closeOfferComparison()">
+			<div class="modal-content offer-compare">
+				<h2>&#128176; Offer Comparison</h2>
+				<div id="offer-compare-table"></div>
+				<p class="auth-hint" style="margin-top:0.75rem">Annual figures average the years until the grant has vested. Range placement is where the base falls in the posted range; vs Levels.fyi compares annual total comp to the company median.</p>
+				<div style="display:flex;justify-content:flex-end;margin-top:1rem">
+					<button class="btn-reset" onclick="closeOfferComparison()">Close</button>
+				</div>
+			</div>
+		</div>
+
 		<div id="history-panel" class="history-panel hidden">
 			<div class="history-header">
 				<h2 onclick="toggleHistoryPanel()">&#128337; Search History</h2>
@@ -2185,6 +2487,43 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			</div>
 		</div>
 
+		<div id="analytics-panel" class="history-panel hidden">
+			<div class="history-header">
+				<h2 onclick="toggleAnalyticsPanel()">&#128200; Analytics</h2>
+				<div class="history-header-actions">
+					<select id="analytics-interval" onchange="loadAnalytics()" style="padding:0.3rem 0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.8rem">
+						<option value="month">Monthly</option>
+						<option value="week">Weekly</option>
+					</select>
+					<button class="btn-reset" onclick="toggleAnalyticsPanel()">Close</button>
+				</div>
+			</div>
+			<div class="analytics-grid">
+				<div class="analytics-card">
+					<div class="analytics-card-header">
+						<h3>Median Posted Salary</h3>
+						<select id="analytics-salary-by" onchange="loadSalaryChart()">
+							<option value="category">By Category</option>
+							<option value="level">By Level</option>
+						</select>
+					</div>
+					<div id="analytics-salaries"></div>
+				</div>
+				<div class="analytics-card">
+					<div class="analytics-card-header"><h3>Open Roles at Top Companies</h3></div>
+					<div id="analytics-open-roles"></div>
+				</div>
+				<div class="analytics-card">
+					<div class="analytics-card-header"><h3>New vs Closed per Scan</h3></div>
+					<div id="analytics-scans"></div>
+				</div>
+				<div class="analytics-card">
+					<div class="analytics-card-header"><h3>Top Hiring Companies (90 days)</h3></div>
+					<div id="analytics-top-companies"></div>
+				</div>
+			</div>
+		</div>
+
 		<main class="jobs-grid" id="jobs-container">
 			<div class="empty-state">
 				<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="1">
@@ -2217,9 +2556,95 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 
 			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
 
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Email Setup</h3>
+				<p id="email-unavailable" class="hidden" style="color:var(--text-secondary);font-size:0.85rem">Email alerts aren't set up on this server. Ask an admin to configure SMTP.</p>
+				<div id="email-setup">
+					<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Receive alerts as an email digest. We'll send a code to confirm the address.</p>
+					<div style="display:flex;gap:0.5rem;align-items:center;margin-bottom:0.75rem;flex-wrap:wrap">
+						<input type="email" id="email-address" placeholder="you@example.com" style="flex:1;min-width:200px;padding:0.5rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+						<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="email-enabled" checked> Send alerts</label>
+					</div>
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<button onclick="saveEmailConfig()" class="refresh-btn" style="font-size:0.85rem">Save</button>
+						<button onclick="sendEmailCode()" class="refresh-btn" style="font-size:0.85rem;background:var(--bg-card);border:1px solid var(--accent-primary)">Send Code</button>
+						<input type="text" id="email-code" inputmode="numeric" maxlength="6" placeholder="Code" class="hidden" style="width:90px;padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'JetBrains Mono',monospace">
+						<button id="email-verify-btn" onclick="verifyEmailCode()" class="refresh-btn hidden" style="font-size:0.85rem">Verify</button>
+						<span id="email-status" style="font-size:0.8rem;color:var(--text-secondary)"></span>
+					</div>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Slack, Discord &amp; Webhooks</h3>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Post alerts to a Slack or Discord channel through an incoming webhook, or to your own endpoint as signed JSON. Send a test message to start receiving alerts.</p>
+
+				<div id="webhooks-list" style="margin-bottom:1rem"></div>
+				<div id="webhook-secret-note" class="hidden" style="margin-bottom:1rem;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--warning);border-radius:6px;font-size:0.8rem;color:var(--text-secondary)"></div>
+
+				<div style="background:var(--bg-card);border:1px solid var(--border-color);border-radius:8px;padding:1rem">
+					<h4 style="color:var(--text-primary);margin-bottom:0.75rem">Add Webhook</h4>
+					<div style="display:flex;flex-direction:column;gap:0.5rem">
+						<div style="display:flex;gap:0.5rem;flex-wrap:wrap">
+							<select id="wh-type" onchange="onWebhookTypeChange()" style="min-width:140px;padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+								<option value="slack">Slack</option>
+								<option value="discord">Discord</option>
+								<option value="webhook">Generic JSON</option>
+							</select>
+							<input type="text" id="wh-name" placeholder="Name (e.g. #job-alerts)" maxlength="60" style="flex:1;min-width:160px;padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+						</div>
+						<input type="password" id="wh-url" placeholder="https://hooks.slack.com/services/..." style="padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+						<input type="text" id="wh-secret" class="hidden" placeholder="Signing secret (leave empty to generate one)" style="padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+					</div>
+					<button onclick="addWebhook()" class="refresh-btn" style="font-size:0.85rem;margin-top:0.75rem">Add Webhook</button>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Delivery</h3>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Choose when alerts reach you. Alerts held for a digest or for quiet hours are kept until they are sent, even across restarts.</p>
+				<div style="display:flex;flex-direction:column;gap:0.5rem">
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<select id="delivery-mode" onchange="onDeliveryModeChange()" style="min-width:160px;padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+							<option value="">Send immediately</option>
+							<option value="hourly">Hourly digest</option>
+							<option value="daily">Daily digest</option>
+						</select>
+						<span id="delivery-daily" class="hidden" style="font-size:0.85rem;color:var(--text-secondary)">at
+							<select id="delivery-hour" style="padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif"></select>
+						</span>
+					</div>
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="delivery-quiet" onchange="onDeliveryQuietChange()"> Quiet hours from</label>
+						<input type="time" id="delivery-quiet-start" value="22:00" style="padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+						<span style="font-size:0.85rem;color:var(--text-secondary)">to</span>
+						<input type="time" id="delivery-quiet-end" value="07:00" style="padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+					</div>
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<span style="font-size:0.85rem;color:var(--text-secondary)">Timezone</span>
+						<input type="text" id="delivery-timezone" placeholder="e.g. America/Chicago" style="flex:1;min-width:160px;padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+					</div>
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<span style="font-size:0.85rem;color:var(--text-secondary)">List at most</span>
+						<input type="number" id="delivery-max-jobs" min="0" max="200" placeholder="all" style="width:80px;padding:0.5rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+						<span style="font-size:0.85rem;color:var(--text-secondary)">jobs per alert (the rest are linked in History)</span>
+					</div>
+					<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+						<button onclick="saveDelivery()" class="refresh-btn" style="font-size:0.85rem">Save</button>
+						<span id="delivery-status" style="font-size:0.8rem;color:var(--text-secondary)"></span>
+					</div>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
 			<div style="padding:1rem">
 				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Scan Schedules</h3>
-				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Schedule automatic scans. Results are sent to your Telegram.</p>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Schedule automatic scans. Jobs a schedule hasn't sent you before are sent to all of your alert channels.</p>
 
 				<div id="schedules-list" style="margin-bottom:1rem"></div>
 
@@ -2240,6 +2665,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 								<option value="weekdays">Weekdays</option>
 								<option value="weekly">Weekly (Mon)</option>
 								<option value="custom">Custom Days</option>
+								<option value="cron">Cron Expression</option>
 							</select>
 							<div id="sched-custom-days" style="display:none;gap:0.25rem;flex-wrap:wrap">
 								<label style="font-size:0.75rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" value="0" class="sched-day-cb"> Sun</label>
@@ -2250,16 +2676,93 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 								<label style="font-size:0.75rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" value="5" class="sched-day-cb"> Fri</label>
 								<label style="font-size:0.75rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" value="6" class="sched-day-cb"> Sat</label>
 							</div>
-							<span style="font-size:0.85rem;color:var(--text-secondary)">at</span>
-							<input type="time" id="sched-time" value="09:00" style="padding:0.5rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+							<span id="sched-at" style="display:flex;gap:0.5rem;align-items:center">
+								<span style="font-size:0.85rem;color:var(--text-secondary)">at</span>
+								<input type="time" id="sched-time" value="09:00" style="padding:0.5rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+							</span>
+							<input type="text" id="sched-cron" placeholder="minute hour day month weekday, e.g. 30 8 * * 1-5" style="flex:1;min-width:200px;padding:0.5rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:monospace;display:none">
+						</div>
+						<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+							<span style="font-size:0.85rem;color:var(--text-secondary)">Timezone</span>
+							<input type="text" id="sched-timezone" placeholder="e.g. America/Chicago" style="flex:1;min-width:160px;padding:0.5rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
 						</div>
-						<div style="display:flex;gap:0.5rem;align-items:center">
-							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="sched-notify-empty"> Notify even if no results</label>
+						<div style="display:flex;gap:0.5rem 1rem;align-items:center;flex-wrap:wrap">
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="sched-notify-empty"> Notify even if no new jobs</label>
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="sched-notify-salary"> Also send seen jobs whose salary changed</label>
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" id="sched-notify-seen"> Send every job found, not only new ones</label>
 						</div>
 						<button onclick="addSchedule()" class="refresh-btn" style="font-size:0.85rem;align-self:flex-start">Add Schedule</button>
 					</div>
 				</div>
 			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Calendar Feed</h3>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Subscribe to this link in your calendar app to see interviews, follow-ups, offer deadlines and scan schedules. Anyone with the link can read it; reset it if it leaks.</p>
+				<p id="calendar-note" class="hidden" style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Your calendar feed is on. The link is only shown when it is created; reset it to get a new one.</p>
+				<input type="text" id="calendar-url" readonly class="hidden" onclick="this.select()" style="width:100%;margin-bottom:0.75rem;padding:0.5rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.8rem;font-family:'JetBrains Mono',monospace">
+				<div style="display:flex;gap:0.5rem;align-items:center;flex-wrap:wrap">
+					<button id="calendar-enable" onclick="createCalendarFeed()" class="refresh-btn" style="font-size:0.85rem">Create Link</button>
+					<button id="calendar-copy" onclick="copyCalendarFeed()" class="refresh-btn hidden" style="font-size:0.85rem">Copy</button>
+					<button id="calendar-reset" onclick="createCalendarFeed()" class="refresh-btn hidden" style="font-size:0.85rem;background:var(--bg-card);border:1px solid var(--accent-primary)">Reset Link</button>
+					<button id="calendar-revoke" onclick="revokeCalendarFeed()" class="btn-sm btn-danger hidden">Turn Off</button>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">API Tokens</h3>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Personal access tokens let scripts call the API as you, with <code>Authorization: Bearer &lt;token&gt;</code>. Give each only the scopes it needs.</p>
+
+				<div id="api-tokens-list" style="margin-bottom:1rem"></div>
+				<div id="api-token-note" class="hidden" style="margin-bottom:1rem;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--warning);border-radius:6px;font-size:0.8rem;color:var(--text-secondary)"></div>
+
+				<div style="background:var(--bg-card);border:1px solid var(--border-color);border-radius:8px;padding:1rem">
+					<h4 style="color:var(--text-primary);margin-bottom:0.75rem">Create Token</h4>
+					<div style="display:flex;flex-direction:column;gap:0.5rem">
+						<div style="display:flex;gap:0.5rem;flex-wrap:wrap">
+							<input type="text" id="api-token-name" placeholder="Name (e.g. CI job export)" maxlength="60" style="flex:1;min-width:160px;padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+							<select id="api-token-expiry" style="min-width:120px;padding:0.5rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+								<option value="7">7 days</option>
+								<option value="30" selected>30 days</option>
+								<option value="90">90 days</option>
+								<option value="365">1 year</option>
+								<option value="0">Never expires</option>
+							</select>
+						</div>
+						<div style="display:flex;gap:0.5rem 1rem;align-items:center;flex-wrap:wrap">
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" class="api-token-scope" value="jobs:read" checked> Read jobs</label>
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" class="api-token-scope" value="search"> Run searches</label>
+							<label style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" class="api-token-scope" value="saved:write"> Manage saved jobs &amp; alerts</label>
+							<label id="api-token-admin-scope" class="hidden" style="font-size:0.8rem;color:var(--text-secondary);cursor:pointer"><input type="checkbox" class="api-token-scope" value="admin"> Admin</label>
+						</div>
+					</div>
+					<button onclick="createAPIToken()" class="refresh-btn" style="font-size:0.85rem;margin-top:0.75rem">Create Token</button>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Change Password</h3>
+				<div style="display:flex;gap:0.5rem;flex-wrap:wrap;align-items:center">
+					<input type="password" id="password-current" placeholder="Current password" autocomplete="current-password" style="flex:1;min-width:160px;padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+					<input type="password" id="password-new" placeholder="New password (8+ chars)" autocomplete="new-password" style="flex:1;min-width:160px;padding:0.5rem 0.75rem;background:var(--bg-primary);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem;font-family:'Outfit',sans-serif">
+					<button onclick="changePassword()" class="refresh-btn" style="font-size:0.85rem">Change</button>
+				</div>
+			</div>
+
+			<hr style="border-color:var(--border-color);margin:0.5rem 1rem">
+
+			<div style="padding:1rem">
+				<h3 style="color:var(--accent-primary);margin-bottom:0.75rem">Sessions</h3>
+				<p style="color:var(--text-secondary);font-size:0.85rem;margin-bottom:0.75rem">Where you're logged in. Log out any session you don't recognise.</p>
+				<div id="sessions-list" style="margin-bottom:0.75rem"></div>
+				<button onclick="revokeOtherSessions()" class="btn-sm btn-danger">Log Out Other Sessions</button>
+			</div>
 		</div>
 
 		<div id="admin-panel" class="admin-panel hidden">
@@ -2304,6 +2807,8 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 	<script>
 		const allJobs = {{ALL_JOBS}};
 		const appConfig = {{APP_CONFIG}};
+		// Starting filters from the user's profile, or null
+		const dashboardDefaults = {{DASHBOARD_DEFAULTS}};
 
 		function initFilters() {
 			const categorySelect = document.getElementById('filter-category');
@@ -2353,11 +2858,20 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				sourceSelect.appendChild(opt);
 			});
 
-			document.getElementById('stat-total').textContent = allJobs.length;
+			document.getElementById('stat-total').textContent = allJobs.filter(function(j) { return !isClosed(j.job); }).length;
 			
 			// Set default filters: sort by highest salary and show only jobs with salary
 			document.getElementById('filter-sort').value = 'salary_high';
 			document.getElementById('filter-salary-only').checked = true;
+			document.getElementById('filter-min-score').value = defaultMinScore();
+
+			if (dashboardDefaults) setFilterControls(dashboardDefaults);
+		}
+
+		// defaultMinScore is scoring.min_score from config.yaml, blank if unset
+		function defaultMinScore() {
+			var min = appConfig.Scoring && appConfig.Scoring.MinScore;
+			return min ? String(min) : '';
 		}
 
 	function applyFilters() {
@@ -2368,25 +2882,36 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		var sortBy = document.getElementById('filter-sort').value;
 		var remoteOnly = document.getElementById('filter-remote').checked;
 		var showExcluded = document.getElementById('filter-show-excluded').checked;
+		var showHidden = document.getElementById('filter-show-hidden').checked;
 		var salaryOnly = document.getElementById('filter-salary-only').checked;
 		var searchInput = document.getElementById('search-input');
 		var searchQuery = searchInput ? searchInput.value.trim() : '';
 		var excludeWordsRaw = (document.getElementById('filter-exclude-words').value || '').trim();
 		var excludeWords = excludeWordsRaw ? excludeWordsRaw.split(',').map(function(w) { return w.trim().toLowerCase(); }).filter(function(w) { return w.length > 0; }) : [];
 		var exactMatch = document.getElementById('filter-exact-match').checked;
+		var minScore = parseFloat(document.getElementById('filter-min-score').value);
+		var status = document.getElementById('filter-status').value;
 
 		var baseJobs = viewingSearchResults ? currentSearchResults : allJobs;
 
+		// The search box is evaluated server-side; re-render once it answers
+		if (!searchQuery) document.getElementById('search-error').style.display = 'none';
+		if (searchQuery && !ensureQueryMatch(searchQuery, baseJobs)) return;
+
 		var filtered = baseJobs.filter(function(j) {
 			if (!viewingSearchResults) {
 				if (!showExcluded && j.tags.is_excluded) return false;
 				if (category && j.tags.categories.indexOf(category) === -1) return false;
 				if (cert && j.tags.certifications.indexOf(cert) === -1) return false;
+				if (!isNaN(minScore) && j.score < minScore) return false;
+				if (status === '' && isClosed(j.job)) return false;
+				if (status === 'closed' && !isClosed(j.job)) return false;
 			}
 			if (exactMatch && viewingSearchResults && currentSearchQuery) {
 				var titleLower = (j.job.title || '').toLowerCase();
 				if (titleLower.indexOf(currentSearchQuery.toLowerCase()) === -1) return false;
 			}
+			if (!showHidden && hiddenJobIDs[j.job.id]) return false;
 			if (level && j.tags.level !== level) return false;
 			if (source && j.job.source !== source) return false;
 			if (remoteOnly && !j.tags.is_remote) return false;
@@ -2397,13 +2922,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					if (titleLower.indexOf(excludeWords[i]) !== -1) return false;
 				}
 			}
-			if (searchQuery) {
-				var q = searchQuery.toLowerCase();
-				var inTitle = (j.job.title || '').toLowerCase().indexOf(q) !== -1;
-				var inCompany = (j.job.company || '').toLowerCase().indexOf(q) !== -1;
-				var inLocation = (j.job.location || '').toLowerCase().indexOf(q) !== -1;
-				if (!inTitle && !inCompany && !inLocation) return false;
-			}
+			if (searchQuery && queryMatch.ids && !queryMatch.ids[j.job.id]) return false;
 			return true;
 		});
 
@@ -2415,7 +2934,11 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					return extractSalary(a) - extractSalary(b);
 				case 'company':
 					return a.job.company.localeCompare(b.job.company);
+				case 'score':
+					return (b.score - a.score) || (new Date(b.job.first_seen) - new Date(a.job.first_seen));
 				default:
+					// Recently closed jobs are listed by when they closed
+					if (status === 'closed') return new Date(b.job.closed_at) - new Date(a.job.closed_at);
 					return new Date(b.job.first_seen) - new Date(a.job.first_seen);
 			}
 		});
@@ -2424,6 +2947,41 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		document.getElementById('stat-showing').textContent = filtered.length;
 	}
 
+	// Result of the last /api/jobs/match call for the search box
+	var queryMatch = { query: '', base: null, ids: null };
+	var queryMatchTimer = null;
+
+	// ensureQueryMatch returns true when queryMatch holds the results for this
+	// query and job list; otherwise it schedules a request and returns false
+	function ensureQueryMatch(searchQuery, baseJobs) {
+		if (queryMatch.query === searchQuery && queryMatch.base === baseJobs) return true;
+		clearTimeout(queryMatchTimer);
+		queryMatchTimer = setTimeout(function() {
+			fetch('/api/jobs/match', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ query: searchQuery, search_id: viewingSearchResults ? (currentSearchId || '') : '' })
+			})
+			.then(function(r) { return r.json(); })
+			.then(function(data) {
+				var errorEl = document.getElementById('search-error');
+				var ids = null;
+				if (data.error) {
+					errorEl.textContent = data.error;
+					errorEl.style.display = 'block';
+				} else {
+					errorEl.style.display = 'none';
+					ids = {};
+					(data.ids || []).forEach(function(id) { ids[id] = true; });
+				}
+				queryMatch = { query: searchQuery, base: baseJobs, ids: ids };
+				applyFilters();
+			})
+			.catch(function() {});
+		}, 250);
+		return false;
+	}
+
 	function userApplyFilters() {
 		applyFilters();
 		if (!viewingSearchResults) {
@@ -2442,6 +3000,21 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		return !!(job.job.level_salary || job.job.salary_range);
 	}
 
+	function isClosed(job) {
+		return job.status === 'closed';
+	}
+
+	// daysOpen is how long a job has been listed; for a closed job, its time
+	// to close
+	function daysOpen(job) {
+		var days = (new Date(job.last_seen) - new Date(job.first_seen)) / (24 * 60 * 60 * 1000);
+		return days > 0 ? Math.round(days) : 0;
+	}
+
+	function formatDays(days) {
+		return days === 1 ? '1 day' : days + ' days';
+	}
+
 		function renderJobs(jobs) {
 			var container = document.getElementById('jobs-container');
 			
@@ -2454,6 +3027,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		}
 
 		var savedJobIDs = {};
+		var hiddenJobIDs = {};
 
 		function renderJobCard(taggedJob) {
 			var job = taggedJob.job;
@@ -2461,6 +3035,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			
 			var isNew = (Date.now() - new Date(job.first_seen).getTime()) < 24 * 60 * 60 * 1000;
 			var isSaved = !!savedJobIDs[job.id];
+			var isHidden = !!hiddenJobIDs[job.id];
 			
 			var tagsHTML = '';
 			tags.categories.forEach(function(cat) {
@@ -2486,6 +3061,18 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				tagsHTML += '<span class="badge badge-excluded">' + escapeHtml(tags.exclude_reason) + '</span>';
 			}
 
+			if (job.status === 'missing') {
+				var misses = job.misses || 1;
+				tagsHTML += '<span class="badge badge-missing" title="Not found in the last ' + (misses === 1 ? 'scrape' : misses + ' scrapes') + '; closed if it stays missing">Missing</span>';
+			} else if (isClosed(job)) {
+				tagsHTML += '<span class="badge badge-closed">Closed ' + (job.closed_at ? new Date(job.closed_at).toLocaleDateString() : '') + '</span>';
+			}
+
+			if (typeof taggedJob.score === 'number') {
+				var minScore = appConfig.Scoring && appConfig.Scoring.MinScore;
+				tagsHTML += '<span class="badge badge-score' + (minScore && taggedJob.score < minScore ? ' low' : '') + '" title="' + escapeHtml(scoreBreakdownText(taggedJob)) + '">Score ' + taggedJob.score + '</span>';
+			}
+
 			var salaryHTML = '';
 			if (job.level_salary || job.salary_range) {
 				salaryHTML = '<div class="salary-info">';
@@ -2499,8 +3086,9 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				salaryHTML += '</div>';
 			}
 
-			var cardClass = 'job-card' + (tags.is_excluded ? ' excluded' : '');
-			var newBadge = isNew ? '<span class="badge badge-new">NEW</span>' : '';
+			var cardClass = 'job-card' + (tags.is_excluded ? ' excluded' : '') + (isClosed(job) ? ' closed' : '') + (isHidden ? ' hidden-job' : '');
+			var newBadge = isNew && !isClosed(job) ? '<span class="badge badge-new">NEW</span>' : '';
+			var openLabel = isClosed(job) ? 'Closed after ' + formatDays(daysOpen(job)) : 'Listed ' + formatDays(daysOpen(job));
 
 			var salaryStr = job.level_salary || job.salary_range || '';
 			var saveDataAttr = 'data-jobid="' + escapeHtml(job.id) + '" '
@@ -2515,12 +3103,15 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				? '<button class="save-btn' + (isSaved ? ' saved' : '') + '" id="save-btn-' + escapeHtml(job.id) + '" onclick="toggleSaveJob(this)" ' + saveDataAttr + '>'
 					+ (isSaved ? '&#9733; Saved' : '&#9734; Save')
 					+ '</button>'
+					+ '<button class="save-btn hide-btn" data-jobid="' + escapeHtml(job.id) + '" onclick="toggleHideJob(this)" title="Hidden jobs are left out of your alerts">'
+					+ (isHidden ? 'Unhide' : 'Hide')
+					+ '</button>'
 				: '';
 
 			return '<article class="' + cardClass + '">' +
 				'<div class="job-header">' +
 					'<div class="company-info">' +
-						'<h2 class="company-name">' + escapeHtml(job.company) + '</h2>' +
+						'<h2 class="company-name">' + companyLink(taggedJob.company_id, job.company) + '</h2>' +
 						newBadge +
 					'</div>' +
 				'</div>' +
@@ -2530,6 +3121,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				'<div class="job-meta">' +
 					'<span><svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M21 10c0 7-9 13-9 13s-9-6-9-13a9 9 0 0 1 18 0z"/><circle cx="12" cy="10" r="3"/></svg> ' + escapeHtml(job.location) + '</span>' +
 					'<span><svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><path d="M10 13a5 5 0 0 0 7.54.54l3-3a5 5 0 0 0-7.07-7.07l-1.72 1.71"/><path d="M14 11a5 5 0 0 0-7.54-.54l-3 3a5 5 0 0 0 7.07 7.07l1.71-1.71"/></svg> ' + job.source + '</span>' +
+					(job.first_seen ? '<span><svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2"><circle cx="12" cy="12" r="10"/><polyline points="12 6 12 12 16 14"/></svg> ' + openLabel + '</span>' : '') +
 				'</div>' +
 				'<div class="job-actions">' +
 					'<a href="' + job.url + '" target="_blank" rel="noopener noreferrer" class="apply-btn">' +
@@ -2541,6 +3133,15 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			'</article>';
 		}
 
+		// scoreBreakdownText explains a job's score, one rule per line
+		function scoreBreakdownText(taggedJob) {
+			var items = taggedJob.score_breakdown || [];
+			if (items.length === 0) return 'No scoring rules matched';
+			return items.map(function(item) {
+				return (item.points >= 0 ? '+' : '') + item.points + '  ' + item.reason;
+			}).join('\n');
+		}
+
 		function escapeHtml(text) {
 			if (!text) return '';
 			var div = document.createElement('div');
@@ -2553,12 +3154,15 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		document.getElementById('filter-level').value = '';
 		document.getElementById('filter-cert').value = '';
 		document.getElementById('filter-source').value = '';
+		document.getElementById('filter-status').value = '';
 		document.getElementById('filter-sort').value = 'newest';
 		document.getElementById('filter-remote').checked = false;
 		document.getElementById('filter-show-excluded').checked = false;
+		document.getElementById('filter-show-hidden').checked = false;
 		document.getElementById('filter-salary-only').checked = false;
 		document.getElementById('filter-exact-match').checked = false;
 		document.getElementById('filter-exclude-words').value = '';
+		document.getElementById('filter-min-score').value = defaultMinScore();
 		var searchInput = document.getElementById('search-input');
 		if (searchInput) searchInput.value = '';
 		applyFilters();
@@ -2616,9 +3220,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 						btn.disabled = true;
 						btn.classList.add('refreshing');
 						btnText.textContent = 'Refreshing...';
-						if (!refreshPollingInterval) {
-							refreshPollingInterval = setInterval(pollRefreshStatus, 5000);
-						}
+						watchRefresh();
 					} else {
 						btn.classList.remove('refreshing');
 						if (data.can_refresh) {
@@ -2639,6 +3241,56 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				});
 		}
 		
+		var refreshEvents = null;
+
+		// watchRefresh follows a refresh over Server-Sent Events, falling back
+		// to polling the status where the browser or a proxy can't stream
+		function watchRefresh() {
+			if (refreshEvents || refreshPollingInterval) return;
+			if (!window.EventSource) {
+				refreshPollingInterval = setInterval(pollRefreshStatus, 5000);
+				return;
+			}
+			var btnText = document.getElementById('refresh-btn-text');
+			var passes = 0;
+			refreshEvents = new EventSource('/api/refresh/events');
+			refreshEvents.addEventListener('pass', function(e) {
+				var d = JSON.parse(e.data);
+				passes = d.passes;
+				btnText.textContent = 'Refreshing... (pass ' + d.pass + '/' + d.passes + ')';
+			});
+			refreshEvents.addEventListener('page', function(e) {
+				var d = JSON.parse(e.data);
+				btnText.textContent = 'Refreshing... (pass ' + d.pass + '/' + passes + ', ' + d.source + ' page ' + d.pages + ')';
+			});
+			refreshEvents.addEventListener('enrich', function(e) {
+				var d = JSON.parse(e.data);
+				btnText.textContent = 'Refreshing... (pass ' + d.pass + '/' + passes + ', salaries ' + d.done + '/' + d.total + ')';
+			});
+			refreshEvents.addEventListener('source_failed', function(e) {
+				var d = JSON.parse(e.data);
+				showToast('warning', 'Source ' + (d.timed_out ? 'Timed Out' : 'Failed'), d.source + ': ' + d.error, 5000);
+			});
+			refreshEvents.addEventListener('done', function(e) {
+				var d = JSON.parse(e.data);
+				refreshEvents.close();
+				refreshEvents = null;
+				if (d.error) {
+					showToast('error', 'Refresh Failed', d.error);
+					checkRefreshStatus();
+					return;
+				}
+				showToast('success', 'Refresh Complete', 'Found ' + d['new'] + ' new jobs. Reloading page...', 3000);
+				setTimeout(function() { location.reload(); }, 2000);
+			});
+			refreshEvents.onerror = function() {
+				if (refreshEvents.readyState === EventSource.CLOSED) {
+					refreshEvents = null;
+					refreshPollingInterval = setInterval(pollRefreshStatus, 5000);
+				}
+			};
+		}
+
 		function pollRefreshStatus() {
 			fetch('/api/refresh/status', { credentials: 'same-origin' })
 				.then(function(res) { return res.json(); })
@@ -2677,11 +3329,11 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					} else if (status === 409) {
 						btnText.textContent = 'Refreshing...';
 						showToast('info', 'In Progress', data.message, 4000);
-						refreshPollingInterval = setInterval(pollRefreshStatus, 5000);
+						watchRefresh();
 					} else if (data.success) {
 						btnText.textContent = 'Refreshing...';
 						showToast('info', 'Refresh Started', data.message, 0);
-						refreshPollingInterval = setInterval(pollRefreshStatus, 5000);
+						watchRefresh();
 					} else {
 						btn.classList.remove('refreshing');
 						btn.disabled = false;
@@ -2706,6 +3358,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		var resultsCleared = false;
 		var lastPartialCount = 0;
 		var searchPollingInterval = null;
+		var currentSearchId = null;
 
 		function checkAuth() {
 			fetch('/api/auth/check', { credentials: 'same-origin' })
@@ -2731,7 +3384,9 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			var adminPanelBtn = document.getElementById('admin-panel-btn');
 			var adminHistoryBtn = document.getElementById('admin-history-btn');
 			var historyBtn = document.getElementById('history-btn');
+			var analyticsBtn = document.getElementById('analytics-btn');
 			var savedJobsBtn = document.getElementById('saved-jobs-btn');
+			var applicationsBtn = document.getElementById('applications-btn');
 			var alertsBtn = document.getElementById('alerts-btn');
 			var userInfo = document.getElementById('user-info');
 			var userDisplay = document.getElementById('user-display');
@@ -2743,14 +3398,18 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				searchContainer.classList.remove('hidden');
 				customSearchPanel.classList.remove('hidden');
 				historyBtn.classList.remove('hidden');
+				analyticsBtn.classList.remove('hidden');
 				savedJobsBtn.classList.remove('hidden');
+				applicationsBtn.classList.remove('hidden');
 				alertsBtn.classList.remove('hidden');
 				if (!resultsCleared) clearResultsBtn.classList.remove('hidden');
 				userInfo.style.display = '';
 				userDisplay.textContent = currentUser + ' (' + currentRole + ')';
 				anonActions.style.display = 'none';
 				loadSavedJobIDs();
+				loadHiddenJobIDs();
 				checkActiveSearch();
+				openLinkedHistory();
 
 				if (currentRole === 'admin') {
 					refreshBtn.classList.remove('hidden');
@@ -2769,7 +3428,9 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				adminPanelBtn.classList.add('hidden');
 				adminHistoryBtn.classList.add('hidden');
 				historyBtn.classList.add('hidden');
+				analyticsBtn.classList.add('hidden');
 				savedJobsBtn.classList.add('hidden');
+				applicationsBtn.classList.add('hidden');
 				alertsBtn.classList.add('hidden');
 				clearResultsBtn.classList.add('hidden');
 				restoreResultsBtn.classList.add('hidden');
@@ -2791,6 +3452,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				.then(function(res) { return res.json(); })
 				.then(function(data) {
 					if (data.is_searching) {
+						currentSearchId = data.id || null;
 						var btn = document.getElementById('custom-search-btn');
 						var btnText = document.getElementById('custom-search-btn-text');
 						var cancelBtn = document.getElementById('cancel-search-btn');
@@ -2819,9 +3481,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 							toggleSearchFilters(true);
 						}
 
-						if (!searchPollingInterval) {
-							searchPollingInterval = setInterval(pollSearchStatus, 4000);
-						}
+						watchSearch(data.id, data.query);
 					} else if (data.results && data.results.length > 0) {
 						showSearchResults(data.results, data.query);
 						var statusMsg = document.getElementById('search-status-msg');
@@ -2870,9 +3530,8 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			.then(function(res) { return res.json().then(function(d) { return { status: res.status, data: d }; }); })
 			.then(function(result) {
 				if (result.data.success) {
-					if (!searchPollingInterval) {
-						searchPollingInterval = setInterval(pollSearchStatus, 4000);
-					}
+					currentSearchId = result.data.id || null;
+					watchSearch(currentSearchId, query);
 				} else {
 					statusMsg.textContent = result.data.error || result.data.message || 'Search failed';
 					resetSearchUI();
@@ -2885,12 +3544,16 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		}
 
 		function cancelSearch() {
-			fetch('/api/search/cancel', { method: 'POST', credentials: 'same-origin' })
+			var req = currentSearchId
+				? fetch('/api/search/' + encodeURIComponent(currentSearchId), { method: 'DELETE', credentials: 'same-origin' })
+				: fetch('/api/search/cancel', { method: 'POST', credentials: 'same-origin' });
+			req
 				.then(function(res) { return res.json(); })
 				.then(function(data) {
 					if (data.success) {
 						showToast('info', 'Cancelled', data.message);
 					}
+					stopSearchEvents();
 					if (searchPollingInterval) {
 						clearInterval(searchPollingInterval);
 						searchPollingInterval = null;
@@ -2903,6 +3566,75 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				});
 		}
 
+		var searchEvents = null;
+
+		// watchSearch follows a search over Server-Sent Events, adding each
+		// source's jobs as it finishes. Where the browser or a proxy can't
+		// stream it falls back to polling the status.
+		function watchSearch(id, query) {
+			stopSearchEvents();
+			if (!window.EventSource || !id) {
+				if (!searchPollingInterval) {
+					searchPollingInterval = setInterval(pollSearchStatus, 4000);
+				}
+				return;
+			}
+			var statusMsg = document.getElementById('search-status-msg');
+			var found = [];
+			var bySource = {};
+			var stream = new EventSource('/api/search/' + encodeURIComponent(id) + '/events');
+			searchEvents = stream;
+			stream.addEventListener('source_started', function(e) {
+				var d = JSON.parse(e.data);
+				statusMsg.textContent = 'Searching ' + d.source + ' for: ' + query + '... Found ' + found.length + ' jobs so far.';
+			});
+			stream.addEventListener('page', function(e) {
+				var d = JSON.parse(e.data);
+				statusMsg.textContent = 'Searching for: ' + query + '... ' + d.source + ' page ' + d.pages + ' (' + d.found + ' jobs). Found ' + found.length + ' jobs so far.';
+			});
+			stream.addEventListener('enrich', function(e) {
+				var d = JSON.parse(e.data);
+				statusMsg.textContent = 'Looking up salaries for ' + d.source + ' jobs (' + d.done + '/' + d.total + ' companies)...';
+			});
+			stream.addEventListener('jobs', function(e) {
+				var d = JSON.parse(e.data);
+				// A replay after a reconnect can send a source's jobs again
+				bySource[d.source || ''] = d.jobs || [];
+				found = [];
+				Object.keys(bySource).forEach(function(source) {
+					found = found.concat(bySource[source]);
+				});
+				lastPartialCount = found.length;
+				if (found.length > 0) {
+					showSearchResults(found.slice(), query);
+				}
+				statusMsg.textContent = 'Found ' + found.length + ' jobs so far for "' + query + '". Still searching other sources...';
+			});
+			stream.addEventListener('source_failed', function(e) {
+				var d = JSON.parse(e.data);
+				statusMsg.textContent = d.source + (d.timed_out ? ' timed out' : ' failed') + '. Found ' + found.length + ' jobs so far for "' + query + '".';
+			});
+			stream.addEventListener('done', function() {
+				stopSearchEvents();
+				pollSearchStatus();
+			});
+			stream.onerror = function() {
+				if (stream.readyState === EventSource.CLOSED && searchEvents === stream) {
+					stopSearchEvents();
+					if (!searchPollingInterval) {
+						searchPollingInterval = setInterval(pollSearchStatus, 4000);
+					}
+				}
+			};
+		}
+
+		function stopSearchEvents() {
+			if (searchEvents) {
+				searchEvents.close();
+				searchEvents = null;
+			}
+		}
+
 		function resetSearchUI() {
 			var btn = document.getElementById('custom-search-btn');
 			var btnText = document.getElementById('custom-search-btn-text');
@@ -3033,22 +3765,56 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				.catch(function() {});
 		}
 
-		function toggleSaveJob(btn) {
-			var jobId = btn.getAttribute('data-jobid');
-			var isSaved = btn.classList.contains('saved');
-
-			if (isSaved) {
-				fetch('/api/saved', {
-					method: 'DELETE',
-					credentials: 'same-origin',
-					headers: { 'Content-Type': 'application/json' },
-					body: JSON.stringify({ job_id: jobId })
-				})
+		function loadHiddenJobIDs() {
+			fetch('/api/hidden', { credentials: 'same-origin' })
 				.then(function(res) { return res.json(); })
-				.then(function(data) {
-					if (data.success) {
-						delete savedJobIDs[jobId];
-						btn.classList.remove('saved');
+				.then(function(ids) {
+					hiddenJobIDs = {};
+					(ids || []).forEach(function(id) { hiddenJobIDs[id] = true; });
+					applyFilters();
+				})
+				.catch(function() {});
+		}
+
+		function toggleHideJob(btn) {
+			var jobId = btn.getAttribute('data-jobid');
+			var hide = !hiddenJobIDs[jobId];
+			fetch('/api/hidden', {
+				method: hide ? 'POST' : 'DELETE',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ job_id: jobId })
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (!data.success) return;
+				if (hide) {
+					hiddenJobIDs[jobId] = true;
+					showToast('info', 'Hidden', 'Job hidden. Tick Show Hidden to see it again.');
+				} else {
+					delete hiddenJobIDs[jobId];
+					showToast('info', 'Unhidden', 'Job is back in your list and alerts');
+				}
+				applyFilters();
+			});
+		}
+
+		function toggleSaveJob(btn) {
+			var jobId = btn.getAttribute('data-jobid');
+			var isSaved = btn.classList.contains('saved');
+
+			if (isSaved) {
+				fetch('/api/saved', {
+					method: 'DELETE',
+					credentials: 'same-origin',
+					headers: { 'Content-Type': 'application/json' },
+					body: JSON.stringify({ job_id: jobId })
+				})
+				.then(function(res) { return res.json(); })
+				.then(function(data) {
+					if (data.success) {
+						delete savedJobIDs[jobId];
+						btn.classList.remove('saved');
 						btn.innerHTML = '&#9734; Save';
 						showToast('info', 'Removed', 'Job removed from saved list');
 					}
@@ -3158,6 +3924,360 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			});
 		}
 
+		// --- Applications ---
+
+		var applicationStageNames = {
+			interested: 'Interested',
+			applied: 'Applied',
+			recruiter_screen: 'Recruiter Screen',
+			onsite: 'Onsite',
+			offer: 'Offer',
+			rejected: 'Rejected',
+			withdrawn: 'Withdrawn'
+		};
+		var applications = [];
+		var applicationStages = [];
+		var editingApplication = null;
+
+		function toggleApplicationsPanel() {
+			var panel = document.getElementById('applications-panel');
+			if (panel.classList.contains('hidden')) {
+				panel.classList.remove('hidden');
+				loadApplications();
+			} else {
+				panel.classList.add('hidden');
+			}
+		}
+
+		function loadApplications() {
+			fetch('/api/applications', { credentials: 'same-origin' })
+				.then(function(res) { return res.json(); })
+				.then(function(data) {
+					applications = data.applications || [];
+					applicationStages = data.stages || [];
+					renderApplicationsBoard();
+				})
+				.catch(function() {
+					document.getElementById('applications-board').innerHTML = '<div class="history-empty">Failed to load applications</div>';
+				});
+		}
+
+		function followUpIsDue(app) {
+			if (!app.follow_up) return false;
+			return app.follow_up <= new Date().toLocaleDateString('en-CA');
+		}
+
+		function renderApplicationsBoard() {
+			var board = document.getElementById('applications-board');
+			if (!applications.length) {
+				board.innerHTML = '<div class="history-empty" style="grid-column:1/-1">No applications yet. Save a job with the &#9734; Save button to start tracking it.</div>';
+				return;
+			}
+			board.innerHTML = applicationStages.map(function(stage) {
+				var cards = applications.filter(function(app) { return app.stage === stage; });
+				return '<div class="kanban-column" data-stage="' + stage + '" ondragover="event.preventDefault();this.classList.add(\'drag-over\')" ondragleave="this.classList.remove(\'drag-over\')" ondrop="dropApplication(event, this)">' +
+					'<h3><span>' + (applicationStageNames[stage] || stage) + '</span><span>' + cards.length + '</span></h3>' +
+					cards.map(renderApplicationCard).join('') +
+					'</div>';
+			}).join('');
+		}
+
+		function renderApplicationCard(app) {
+			var meta = [];
+			if (app.follow_up) {
+				meta.push('<div class="kanban-card-meta' + (followUpIsDue(app) ? ' due' : '') + '">&#9200; Follow up ' + escapeHtml(app.follow_up) + '</div>');
+			}
+			var nextInterview = nextApplicationInterview(app);
+			if (nextInterview) {
+				meta.push('<div class="kanban-card-meta">&#128197; ' + escapeHtml(nextInterview.kind || 'Interview') + ' ' + new Date(nextInterview.at).toLocaleString([], { month: 'short', day: 'numeric', hour: 'numeric', minute: '2-digit' }) + '</div>');
+			}
+			if (app.offer && app.offer.base) {
+				meta.push('<div class="kanban-card-meta">&#128176; ' + formatSalaryK(app.offer.base) + ' base</div>');
+			}
+			return '<div class="kanban-card" draggable="true" data-jobid="' + escapeHtml(app.job_id) + '" ' +
+				'ondragstart="event.dataTransfer.setData(\'text/plain\', this.getAttribute(\'data-jobid\'))" ' +
+				'onclick="openApplication(this.getAttribute(\'data-jobid\'))">' +
+				'<div class="kanban-card-company">' + escapeHtml(app.company) + '</div>' +
+				'<div class="kanban-card-title">' + escapeHtml(app.title) + '</div>' +
+				meta.join('') +
+				'</div>';
+		}
+
+		function nextApplicationInterview(app) {
+			var now = new Date();
+			return (app.interviews || []).find(function(iv) { return new Date(iv.at) >= now; });
+		}
+
+		function dropApplication(event, column) {
+			event.preventDefault();
+			column.classList.remove('drag-over');
+			var jobId = event.dataTransfer.getData('text/plain');
+			var stage = column.getAttribute('data-stage');
+			var app = applications.find(function(a) { return a.job_id === jobId; });
+			if (!app || app.stage === stage) return;
+			patchApplication(jobId, { stage: stage }).then(function(updated) {
+				if (updated) {
+					showToast('success', 'Moved', escapeHtml(app.title) + ' &rarr; ' + (applicationStageNames[stage] || stage));
+					loadApplications();
+				}
+			});
+		}
+
+		function patchApplication(jobId, update) {
+			return fetch('/api/applications/' + encodeURIComponent(jobId), {
+				method: 'PATCH',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(update)
+			})
+			.then(function(res) {
+				return res.json().then(function(data) {
+					if (!res.ok) throw new Error(data.error || 'Failed to update application');
+					return data;
+				});
+			})
+			.catch(function(err) {
+				if (editingApplication) {
+					var errorEl = document.getElementById('app-error');
+					errorEl.textContent = err.message;
+					errorEl.classList.remove('hidden');
+				} else {
+					showToast('error', 'Error', escapeHtml(err.message));
+				}
+				return null;
+			});
+		}
+
+		function openApplication(jobId) {
+			var app = applications.find(function(a) { return a.job_id === jobId; });
+			if (!app) return;
+			editingApplication = app;
+			document.getElementById('application-title').textContent = app.title + ' at ' + app.company;
+			document.getElementById('app-stage').innerHTML = applicationStages.map(function(stage) {
+				return '<option value="' + stage + '"' + (stage === app.stage ? ' selected' : '') + '>' + (applicationStageNames[stage] || stage) + '</option>';
+			}).join('');
+			document.getElementById('app-stage-note').value = '';
+			document.getElementById('app-follow-up').value = app.follow_up || '';
+			document.getElementById('app-follow-up-note').value = app.follow_up_note || '';
+			var offer = app.offer || {};
+			document.getElementById('app-offer-base').value = offer.base || '';
+			document.getElementById('app-offer-bonus').value = offer.bonus || '';
+			document.getElementById('app-offer-equity').value = offer.equity || '';
+			document.getElementById('app-offer-vesting').value = (offer.vesting || []).join('/');
+			document.getElementById('app-offer-cliff').value = offer.cliff_months || '';
+			document.getElementById('app-offer-sign-on').value = offer.sign_on || '';
+			document.getElementById('app-offer-sign-on-years').value = offer.sign_on_years || '';
+			document.getElementById('app-offer-deadline').value = offer.deadline || '';
+			document.getElementById('app-offer-notes').value = offer.notes || '';
+			document.getElementById('app-note').value = app.note || '';
+			document.getElementById('app-contacts').innerHTML = '';
+			(app.contacts || []).forEach(addContactRow);
+			document.getElementById('app-interviews').innerHTML = '';
+			(app.interviews || []).forEach(addInterviewRow);
+			document.getElementById('app-history').innerHTML = (app.stage_history || []).slice().reverse().map(function(change) {
+				return '<li>' + new Date(change.at).toLocaleDateString() + ': ' +
+					(change.from ? (applicationStageNames[change.from] || change.from) + ' &rarr; ' : '') +
+					(applicationStageNames[change.to] || change.to) +
+					(change.note ? ' &middot; ' + escapeHtml(change.note) : '') + '</li>';
+			}).join('') || '<li>Saved ' + new Date(app.saved_at).toLocaleDateString() + '</li>';
+			document.getElementById('app-error').classList.add('hidden');
+			document.getElementById('application-modal').classList.remove('hidden');
+		}
+
+		function closeApplication() {
+			editingApplication = null;
+			document.getElementById('application-modal').classList.add('hidden');
+		}
+
+		function addContactRow(contact) {
+			contact = contact || {};
+			var row = document.createElement('div');
+			row.className = 'form-row contact-row';
+			row.style.marginBottom = '0.5rem';
+			row.innerHTML =
+				'<input type="text" class="contact-name" placeholder="Name">' +
+				'<input type="text" class="contact-role" placeholder="Role">' +
+				'<input type="email" class="contact-email" placeholder="Email">' +
+				'<button class="btn-sm btn-danger" style="flex:0" onclick="this.parentElement.remove()">&times;</button>';
+			row.querySelectorAll('input').forEach(function(input) {
+				input.style.cssText = 'padding:0.4rem 0.6rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem';
+			});
+			row.querySelector('.contact-name').value = contact.name || '';
+			row.querySelector('.contact-role').value = contact.role || '';
+			row.querySelector('.contact-email').value = contact.email || '';
+			row.setAttribute('data-notes', contact.notes || '');
+			document.getElementById('app-contacts').appendChild(row);
+		}
+
+		function addInterviewRow(interview) {
+			interview = interview || {};
+			var row = document.createElement('div');
+			row.className = 'form-row interview-row';
+			row.style.marginBottom = '0.5rem';
+			row.innerHTML =
+				'<input type="datetime-local" class="interview-at">' +
+				'<input type="number" class="interview-minutes" min="0" placeholder="Minutes">' +
+				'<input type="text" class="interview-kind" placeholder="Kind (e.g. Technical)">' +
+				'<input type="text" class="interview-location" placeholder="Location or link">' +
+				'<button class="btn-sm btn-danger" style="flex:0" onclick="this.parentElement.remove()">&times;</button>';
+			row.querySelectorAll('input').forEach(function(input) {
+				input.style.cssText = 'padding:0.4rem 0.6rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;color:var(--text-primary);font-size:0.85rem';
+			});
+			row.querySelector('.interview-at').value = interview.at || '';
+			row.querySelector('.interview-minutes').value = interview.minutes || '';
+			row.querySelector('.interview-kind').value = interview.kind || '';
+			row.querySelector('.interview-location').value = interview.location || '';
+			document.getElementById('app-interviews').appendChild(row);
+		}
+
+		function saveApplication() {
+			if (!editingApplication) return;
+			var contacts = [];
+			document.querySelectorAll('#app-contacts .contact-row').forEach(function(row) {
+				var contact = {
+					name: row.querySelector('.contact-name').value.trim(),
+					role: row.querySelector('.contact-role').value.trim(),
+					email: row.querySelector('.contact-email').value.trim(),
+					notes: row.getAttribute('data-notes') || ''
+				};
+				if (contact.name || contact.email) contacts.push(contact);
+			});
+			var interviews = [];
+			document.querySelectorAll('#app-interviews .interview-row').forEach(function(row) {
+				var at = row.querySelector('.interview-at').value;
+				if (!at) return;
+				interviews.push({
+					at: at,
+					minutes: parseInt(row.querySelector('.interview-minutes').value, 10) || 0,
+					kind: row.querySelector('.interview-kind').value.trim(),
+					location: row.querySelector('.interview-location').value.trim()
+				});
+			});
+			var update = {
+				stage: document.getElementById('app-stage').value,
+				stage_note: document.getElementById('app-stage-note').value,
+				follow_up: document.getElementById('app-follow-up').value,
+				follow_up_note: document.getElementById('app-follow-up-note').value,
+				note: document.getElementById('app-note').value,
+				contacts: contacts,
+				interviews: interviews
+			};
+			var base = parseFloat(document.getElementById('app-offer-base').value) || 0;
+			var bonus = parseFloat(document.getElementById('app-offer-bonus').value) || 0;
+			var equity = parseFloat(document.getElementById('app-offer-equity').value) || 0;
+			var vesting = document.getElementById('app-offer-vesting').value.split(/[\/,\s]+/).filter(function(v) { return v; }).map(parseFloat);
+			var cliff = parseInt(document.getElementById('app-offer-cliff').value, 10) || 0;
+			var signOn = parseFloat(document.getElementById('app-offer-sign-on').value) || 0;
+			var signOnYears = parseInt(document.getElementById('app-offer-sign-on-years').value, 10) || 0;
+			var deadline = document.getElementById('app-offer-deadline').value;
+			var offerNotes = document.getElementById('app-offer-notes').value.trim();
+			if (base || bonus || equity || signOn || deadline || offerNotes) {
+				update.offer = {
+					base: base, bonus: bonus, equity: equity, vesting: vesting, cliff_months: cliff,
+					sign_on: signOn, sign_on_years: signOnYears, deadline: deadline, notes: offerNotes
+				};
+			} else {
+				update.clear_offer = true;
+			}
+
+			patchApplication(editingApplication.job_id, update).then(function(updated) {
+				if (updated) {
+					closeApplication();
+					showToast('success', 'Saved', 'Application updated');
+					loadApplications();
+				}
+			});
+		}
+
+		function removeApplication() {
+			if (!editingApplication || !confirm('Remove this job and its application details?')) return;
+			var jobId = editingApplication.job_id;
+			fetch('/api/applications/' + encodeURIComponent(jobId), { method: 'DELETE', credentials: 'same-origin' })
+				.then(function(res) {
+					if (!res.ok) throw new Error();
+					delete savedJobIDs[jobId];
+					var cardBtn = document.getElementById('save-btn-' + jobId);
+					if (cardBtn) {
+						cardBtn.classList.remove('saved');
+						cardBtn.innerHTML = '&#9734; Save';
+					}
+					closeApplication();
+					loadApplications();
+				})
+				.catch(function() { showToast('error', 'Error', 'Failed to remove application'); });
+		}
+
+		function openOfferComparison() {
+			var table = document.getElementById('offer-compare-table');
+			table.innerHTML = '<div class="history-empty">Comparing offers...</div>';
+			document.getElementById('offer-compare-modal').classList.remove('hidden');
+			fetch('/api/offers/compare', { credentials: 'same-origin' })
+				.then(function(res) {
+					return res.json().then(function(data) {
+						if (!res.ok) throw new Error(data.error || 'Failed to compare offers');
+						return data;
+					});
+				})
+				.then(function(data) { renderOfferComparison(data.offers || []); })
+				.catch(function(err) {
+					table.innerHTML = '<div class="history-empty">' + escapeHtml(err.message) + '</div>';
+				});
+		}
+
+		function closeOfferComparison() {
+			document.getElementById('offer-compare-modal').classList.add('hidden');
+		}
+
+		function formatPercent(v) {
+			return (v > 0 ? '+' : '') + Math.round(v) + '%';
+		}
+
+		// renderOfferComparison lays the offers out side by side, one column
+		// per offer, highlighting the highest annual total
+		function renderOfferComparison(offers) {
+			var table = document.getElementById('offer-compare-table');
+			if (offers.length === 0) {
+				table.innerHTML = '<div class="history-empty">No offers recorded yet. Add one from an application\'s details.</div>';
+				return;
+			}
+			var rows = [
+				['Base', function(o) { return formatSalaryK(o.annual.base); }],
+				['Bonus', function(o) { return formatSalaryK(o.annual.bonus); }],
+				['Equity / yr', function(o) { return formatSalaryK(o.annual.equity); }],
+				['Sign-on / yr', function(o) { return formatSalaryK(o.annual.sign_on); }],
+				['Annual total', function(o) { return formatSalaryK(o.annual.total); }, 'offer-total'],
+				['First year', function(o) { return formatSalaryK(o.first_year.total); }],
+				['Years to vest', function(o) { return o.years.length; }],
+				['Posted range', function(o) {
+					return o.posted_max ? formatSalaryK(o.posted_min) + ' - ' + formatSalaryK(o.posted_max) : '&mdash;';
+				}],
+				['Range placement', function(o) {
+					if (o.posted_percentile === undefined) return '&mdash;';
+					var p = o.posted_percentile;
+					if (p < 0) return '<span class="below">below range</span>';
+					if (p > 100) return '<span class="above">above range</span>';
+					return Math.round(p) + 'th pct';
+				}],
+				['Levels.fyi median', function(o) { return o.levels_median ? formatSalaryK(o.levels_median) : '&mdash;'; }],
+				['vs Levels.fyi', function(o) {
+					if (o.vs_levels === undefined) return '&mdash;';
+					return '<span class="' + (o.vs_levels >= 0 ? 'above' : 'below') + '">' + formatPercent(o.vs_levels) + '</span>';
+				}]
+			];
+			var html = '<table class="offer-table"><thead><tr><th></th>';
+			offers.forEach(function(o) {
+				html += '<th class="' + (o.rank === 1 ? 'best' : '') + '">' + escapeHtml(o.company) +
+					(o.title ? '<br><span class="kanban-card-meta">' + escapeHtml(o.title) + '</span>' : '') + '</th>';
+			});
+			html += '</tr></thead><tbody>';
+			rows.forEach(function(row) {
+				html += '<tr class="' + (row[2] || '') + '"><td>' + row[0] + '</td>';
+				offers.forEach(function(o) { html += '<td>' + row[1](o) + '</td>'; });
+				html += '</tr>';
+			});
+			table.innerHTML = html + '</tbody></table>';
+		}
+
 		// --- Admin Panel ---
 
 		function toggleAdminPanel() {
@@ -3230,15 +4350,18 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		function renderUsers(users) {
 			var c = document.getElementById('users-list');
 			if (!users.length) { c.innerHTML = '<p class="auth-hint">No users</p>'; return; }
-			var h = '<table class="admin-table"><thead><tr><th>Username</th><th>Role</th><th>Created</th><th>By</th><th></th></tr></thead><tbody>';
+			var h = '<table class="admin-table"><thead><tr><th>Username</th><th>Role</th><th>Created</th><th>By</th><th>Sessions</th><th></th></tr></thead><tbody>';
 			users.forEach(function(u) {
 				var rc = u.role === 'admin' ? 'badge-role-admin' : 'badge-role-user';
 				var del = u.username === currentUser ? '' : '<button class="btn-sm btn-danger" onclick="deleteUser(\'' + escapeHtml(u.username) + '\')">Del</button>';
+				var out = u.username === currentUser || !u.sessions ? '' : '<button class="btn-sm btn-danger" onclick="logoutUser(\'' + escapeHtml(u.username) + '\')">Log Out</button> ';
+				var reset = u.username === currentUser ? '' : '<button class="btn-sm btn-danger" onclick="resetUserPassword(\'' + escapeHtml(u.username) + '\')">Reset PW</button> ';
 				h += '<tr><td>' + escapeHtml(u.username) + '</td>'
 					+ '<td><span class="' + rc + '">' + u.role + '</span></td>'
 					+ '<td>' + new Date(u.created_at).toLocaleDateString() + '</td>'
 					+ '<td>' + escapeHtml(u.created_by) + '</td>'
-					+ '<td>' + del + '</td></tr>';
+					+ '<td>' + (u.sessions || 0) + '</td>'
+					+ '<td>' + reset + out + del + '</td></tr>';
 			});
 			c.innerHTML = h + '</tbody></table>';
 		}
@@ -3250,6 +4373,26 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			}).then(function(res) { return res.json(); }).then(function() { showToast('success', 'Deleted', 'Token removed'); loadAdminData(); });
 		}
 
+		function resetUserPassword(username) {
+			if (!confirm('Create a password reset token for "' + username + '"? Their current password keeps working until they use it.')) return;
+			fetch('/api/admin/users/reset', { method: 'POST', credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ username: username })
+			}).then(function(res) { return res.json(); }).then(function(data) {
+				if (data.error) { showToast('error', 'Error', data.error); return; }
+				prompt('Reset token for ' + username + ', valid for 24 hours. They can use it from "Forgot your password?" on the login page:', data.token);
+			});
+		}
+
+		function logoutUser(username) {
+			if (!confirm('Log "' + username + '" out everywhere?')) return;
+			fetch('/api/admin/sessions', { method: 'DELETE', credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' }, body: JSON.stringify({ username: username })
+			}).then(function(res) { return res.json(); }).then(function(data) {
+				if (data.error) { showToast('error', 'Error', data.error); }
+				else { showToast('success', 'Logged Out', username + ' has been logged out'); loadAdminData(); }
+			});
+		}
+
 		function deleteUser(username) {
 			if (!confirm('Delete user "' + username + '"?')) return;
 			fetch('/api/admin/users', { method: 'DELETE', credentials: 'same-origin',
@@ -3271,10 +4414,12 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				level: document.getElementById('filter-level').value,
 				certification: document.getElementById('filter-cert').value,
 				sort: document.getElementById('filter-sort').value,
+				status: document.getElementById('filter-status').value,
 				remote_only: document.getElementById('filter-remote').checked,
 				show_excluded: document.getElementById('filter-show-excluded').checked,
 				salary_only: document.getElementById('filter-salary-only').checked,
 				exclude_words: document.getElementById('filter-exclude-words').value.trim(),
+				min_score: parseFloat(document.getElementById('filter-min-score').value) || 0,
 				search_text: document.getElementById('search-input') ? document.getElementById('search-input').value.trim() : ''
 			};
 
@@ -3328,6 +4473,198 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			}
 		}
 
+		function toggleAnalyticsPanel() {
+			var panel = document.getElementById('analytics-panel');
+			if (panel.classList.contains('hidden')) {
+				panel.classList.remove('hidden');
+				loadAnalytics();
+			} else {
+				panel.classList.add('hidden');
+			}
+		}
+
+		// Charts are drawn as inline SVG so the dashboard needs nothing from a CDN
+		var chartColors = ['#00ff88', '#4dabf7', '#ffa502', '#ff6b9d', '#b197fc', '#20c997'];
+		var chartClosedColor = '#ff4757';
+
+		function loadAnalytics() {
+			var interval = document.getElementById('analytics-interval').value;
+			loadSalaryChart();
+			fetchAnalytics('/api/analytics/open-roles?interval=' + interval, 'analytics-open-roles', function(data) {
+				return renderLineChart(data.series || [], function(v) { return String(Math.round(v)); }, 'open');
+			});
+			fetchAnalytics('/api/analytics/scans', 'analytics-scans', function(data) {
+				return renderScanChart(data.scans || []);
+			});
+			fetchAnalytics('/api/analytics/top-companies', 'analytics-top-companies', function(data) {
+				return renderTopCompanies(data.companies || []);
+			});
+		}
+
+		function loadSalaryChart() {
+			var interval = document.getElementById('analytics-interval').value;
+			var by = document.getElementById('analytics-salary-by').value;
+			fetchAnalytics('/api/analytics/salaries?by=' + by + '&interval=' + interval, 'analytics-salaries', function(data) {
+				// "All jobs" plus the five groups with the most salaries posted
+				var series = data.series || [];
+				var all = series.filter(function(s) { return s.key === 'all'; });
+				var groups = series.filter(function(s) { return s.key !== 'all'; });
+				var total = function(s) { return s.points.reduce(function(n, p) { return n + p.count; }, 0); };
+				groups.sort(function(a, b) { return total(b) - total(a); });
+				return renderLineChart(all.concat(groups.slice(0, 5)), formatSalaryK, 'salaries');
+			});
+		}
+
+		function fetchAnalytics(url, containerId, render) {
+			var container = document.getElementById(containerId);
+			container.innerHTML = '<div class="history-empty">Loading...</div>';
+			fetch(url, { credentials: 'same-origin' })
+				.then(function(res) {
+					if (!res.ok) throw new Error(res.statusText);
+					return res.json();
+				})
+				.then(function(data) { container.innerHTML = render(data); })
+				.catch(function() {
+					container.innerHTML = '<div class="history-empty">Failed to load</div>';
+				});
+		}
+
+		function companyLink(id, name) {
+			if (!id) return escapeHtml(name);
+			return '<a href="/company/' + encodeURIComponent(id) + '">' + escapeHtml(name) + '</a>';
+		}
+
+		function formatSalaryK(v) {
+			return '$' + Math.round(v / 1000) + 'k';
+		}
+
+		function chartNoData() {
+			return '<div class="history-empty">Not enough data yet. Trends appear as scans accumulate.</div>';
+		}
+
+		// chartFrame draws the y-axis grid and labels for values up to max and
+		// returns the SVG along with the plot area
+		function chartFrame(max, fmt) {
+			var f = { width: 640, height: 220, left: 52, right: 12, top: 10, bottom: 26 };
+			f.plotW = f.width - f.left - f.right;
+			f.plotH = f.height - f.top - f.bottom;
+			f.y = function(v) { return f.top + f.plotH - (max > 0 ? v / max * f.plotH : 0); };
+			var svg = '<svg class="chart" viewBox="0 0 ' + f.width + ' ' + f.height + '" role="img">';
+			for (var i = 0; i <= 4; i++) {
+				var v = max * i / 4;
+				var y = f.y(v).toFixed(1);
+				svg += '<line class="grid" x1="' + f.left + '" x2="' + (f.width - f.right) + '" y1="' + y + '" y2="' + y + '"/>';
+				svg += '<text x="' + (f.left - 6) + '" y="' + y + '" dy="3" text-anchor="end">' + escapeHtml(fmt(v)) + '</text>';
+			}
+			f.svg = svg;
+			return f;
+		}
+
+		function chartXLabels(f, labels, xAt) {
+			var step = Math.max(1, Math.ceil(labels.length / 8));
+			var svg = '';
+			labels.forEach(function(label, i) {
+				if (i % step === 0) {
+					svg += '<text x="' + xAt(i).toFixed(1) + '" y="' + (f.height - 8) + '" text-anchor="middle">' + escapeHtml(label) + '</text>';
+				}
+			});
+			return svg;
+		}
+
+		function chartLegend(items) {
+			return '<div class="chart-legend">' + items.map(function(item) {
+				return '<span style="--swatch:' + item.color + '">' + escapeHtml(item.label) + '</span>';
+			}).join('') + '</div>';
+		}
+
+		function renderLineChart(series, fmt, unit) {
+			var periods = [];
+			series.forEach(function(s) {
+				s.points.forEach(function(p) {
+					if (periods.indexOf(p.period) === -1) periods.push(p.period);
+				});
+			});
+			periods.sort();
+			if (!periods.length) return chartNoData();
+
+			var max = 0;
+			series.forEach(function(s) {
+				s.points.forEach(function(p) { max = Math.max(max, p.value); });
+			});
+			var f = chartFrame(max * 1.1, fmt);
+			var x = function(i) {
+				return f.left + (periods.length === 1 ? f.plotW / 2 : i * f.plotW / (periods.length - 1));
+			};
+
+			var svg = f.svg;
+			series.forEach(function(s, si) {
+				var color = chartColors[si % chartColors.length];
+				var coords = s.points.map(function(p) {
+					return { x: x(periods.indexOf(p.period)), y: f.y(p.value), p: p };
+				});
+				if (coords.length > 1) {
+					svg += '<polyline fill="none" stroke="' + color + '" stroke-width="2" points="' +
+						coords.map(function(c) { return c.x.toFixed(1) + ',' + c.y.toFixed(1); }).join(' ') + '"/>';
+				}
+				coords.forEach(function(c) {
+					var tip = (s.label || s.key) + ' ' + c.p.period + ': ' + fmt(c.p.value) +
+						(unit === 'salaries' ? ' (' + c.p.count + ' posted)' : '');
+					svg += '<circle cx="' + c.x.toFixed(1) + '" cy="' + c.y.toFixed(1) + '" r="3" fill="' + color + '"><title>' + escapeHtml(tip) + '</title></circle>';
+				});
+			});
+			svg += chartXLabels(f, periods, x) + '</svg>';
+
+			return svg + chartLegend(series.map(function(s, si) {
+				return { label: s.label || s.key, color: chartColors[si % chartColors.length] };
+			}));
+		}
+
+		function renderScanChart(scans) {
+			if (!scans.length) return chartNoData();
+
+			var max = 0;
+			scans.forEach(function(s) { max = Math.max(max, s.new, s.closed); });
+			var f = chartFrame(Math.max(max * 1.1, 1), function(v) { return String(Math.round(v)); });
+			var slot = f.plotW / scans.length;
+			var barW = Math.max(1, Math.min(14, slot / 2 - 2));
+			var x = function(i) { return f.left + slot * (i + 0.5); };
+
+			var svg = f.svg;
+			var labels = scans.map(function(s) {
+				return new Date(s.at).toLocaleDateString(undefined, { month: 'short', day: 'numeric' });
+			});
+			scans.forEach(function(s, i) {
+				var tip = new Date(s.at).toLocaleString() + ': ' + s.new + ' new, ' + s.closed + ' closed' +
+					(s.reopened ? ', ' + s.reopened + ' reopened' : '') + ', ' + s.open + ' open';
+				[[s.new, chartColors[0], -barW], [s.closed, chartClosedColor, 0]].forEach(function(bar) {
+					var y = f.y(bar[0]);
+					svg += '<rect x="' + (x(i) + bar[2]).toFixed(1) + '" y="' + y.toFixed(1) + '" width="' + barW.toFixed(1) +
+						'" height="' + (f.top + f.plotH - y).toFixed(1) + '" fill="' + bar[1] + '"><title>' + escapeHtml(tip) + '</title></rect>';
+				});
+			});
+			svg += chartXLabels(f, labels, x) + '</svg>';
+
+			return svg + chartLegend([
+				{ label: 'New', color: chartColors[0] },
+				{ label: 'Closed', color: chartClosedColor }
+			]);
+		}
+
+		function renderTopCompanies(companies) {
+			if (!companies.length) return chartNoData();
+
+			var max = Math.max.apply(null, companies.map(function(c) { return c.open; })) || 1;
+			return '<div class="company-bars">' + companies.map(function(c) {
+				var label = c.open + ' open &middot; +' + c.opened + ' / &minus;' + c.closed +
+					(c.median_salary ? ' &middot; median ' + formatSalaryK(c.median_salary) : '');
+				return '<div class="company-bar">' +
+					'<span class="company-bar-name" title="' + escapeHtml(c.company) + '">' + companyLink(c.id, c.company) + '</span>' +
+					'<div class="company-bar-track"><div class="company-bar-fill" style="width:' + (c.open / max * 100).toFixed(1) + '%"></div>' +
+					'<div class="company-bar-label">' + label + '</div></div>' +
+					'</div>';
+			}).join('') + '</div>';
+		}
+
 		function loadHistory() {
 			fetch('/api/history', { credentials: 'same-origin' })
 				.then(function(res) { return res.json(); })
@@ -3383,12 +4720,15 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		}
 
 		function renderHistoryEntry(entry, showUsername) {
-			var isCustomSearch = entry.type === 'custom_search';
+			var isAlert = entry.type === 'alert';
+			var isCustomSearch = entry.type === 'custom_search' || isAlert;
 			var iconClass = isCustomSearch ? 'search-type' : 'filter-type';
-			var iconChar = isCustomSearch ? '&#128269;' : '&#9881;';
+			var iconChar = isAlert ? '&#128276;' : (isCustomSearch ? '&#128269;' : '&#9881;');
 
 			var summary = '';
-			if (isCustomSearch) {
+			if (isAlert) {
+				summary = 'Alert: <strong>' + escapeHtml(entry.query) + '</strong>';
+			} else if (isCustomSearch) {
 				summary = 'Searched: <strong>' + escapeHtml(entry.query) + '</strong>';
 			} else {
 				var parts = [];
@@ -3411,6 +4751,9 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				if (f.remote_only) parts.push('Remote');
 				if (f.salary_only) parts.push('With Salary');
 				if (f.show_excluded) parts.push('Inc. Excluded');
+				if (f.min_score) parts.push('Score ≥ ' + f.min_score);
+				if (f.status === 'closed') parts.push('Recently Closed');
+				if (f.status === 'all') parts.push('Open & Closed');
 				summary = parts.length > 0 ? 'Filtered: ' + parts.join(', ') : 'Applied filters';
 			}
 
@@ -3448,21 +4791,27 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		}
 
 		function replayFilter(filtersJson) {
-			var f = JSON.parse(filtersJson);
+			setFilterControls(JSON.parse(filtersJson));
+			if (viewingSearchResults) closeSearchResults();
+			applyFilters();
+			document.getElementById('history-panel').classList.add('hidden');
+			showToast('success', 'Filters Applied', 'Replayed your saved search filters');
+		}
+
+		// setFilterControls sets the filter inputs from a saved filter set
+		function setFilterControls(f) {
 			document.getElementById('filter-category').value = f.category || '';
 			document.getElementById('filter-level').value = f.level || '';
 			document.getElementById('filter-cert').value = f.certification || '';
 			document.getElementById('filter-sort').value = f.sort || 'newest';
+			document.getElementById('filter-status').value = f.status || '';
 			document.getElementById('filter-remote').checked = !!f.remote_only;
 			document.getElementById('filter-show-excluded').checked = !!f.show_excluded;
 			document.getElementById('filter-salary-only').checked = !!f.salary_only;
 			document.getElementById('filter-exclude-words').value = f.exclude_words || '';
+			document.getElementById('filter-min-score').value = f.min_score ? String(f.min_score) : defaultMinScore();
 			var searchInput = document.getElementById('search-input');
 			if (searchInput) searchInput.value = f.search_text || '';
-			if (viewingSearchResults) closeSearchResults();
-			applyFilters();
-			document.getElementById('history-panel').classList.add('hidden');
-			showToast('success', 'Filters Applied', 'Replayed your saved search filters');
 		}
 
 		function clearHistory() {
@@ -3483,8 +4832,16 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			});
 		}
 
+		// openLinkedHistory shows the results an alert linked to with ?history=
+		function openLinkedHistory() {
+			var entryId = new URLSearchParams(location.search).get('history');
+			if (!entryId) return;
+			window.history.replaceState(null, '', location.pathname);
+			loadHistoryResults(entryId, '');
+		}
+
 		function loadHistoryResults(entryId, query) {
-			showToast('info', 'Loading', 'Loading results for "' + query + '"...');
+			showToast('info', 'Loading', query ? 'Loading results for "' + escapeHtml(query) + '"...' : 'Loading results...');
 			fetch('/api/history/results/' + entryId, { credentials: 'same-origin' })
 				.then(function(res) {
 					if (!res.ok) throw new Error('not found');
@@ -3492,6 +4849,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 				})
 				.then(function(data) {
 					var results = data.results || [];
+					query = query || (data.entry && data.entry.query) || '';
 					if (results.length === 0) {
 						showToast('warning', 'No Results', 'No stored results found for this search');
 						return;
@@ -3500,8 +4858,9 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					var statusMsg = document.getElementById('search-status-msg');
 					statusMsg.style.display = 'block';
 					statusMsg.textContent = 'Showing ' + results.length + ' saved results for "' + query + '" (from history)';
+					currentSearchId = entryId;
 					showSearchResults(results, query);
-					showToast('success', 'Loaded', 'Showing ' + results.length + ' results for "' + query + '"');
+					showToast('success', 'Loaded', 'Showing ' + results.length + ' results for "' + escapeHtml(query) + '"');
 				})
 				.catch(function() {
 					showToast('error', 'Error', 'Failed to load results. They may have expired.');
@@ -3515,9 +4874,238 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			panel.classList.toggle('hidden');
 			if (!panel.classList.contains('hidden')) {
 				loadAlertsConfig();
+				loadCalendarFeed();
+				loadAPITokens();
+				loadSessions();
 			}
 		}
 
+		function loadSessions() {
+			fetch('/api/sessions', { credentials: 'same-origin' })
+				.then(function(res) { return res.json(); })
+				.then(function(data) { renderSessions(data.sessions || []); })
+				.catch(function() {});
+		}
+
+		function renderSessions(sessions) {
+			var container = document.getElementById('sessions-list');
+			if (!sessions.length) {
+				container.innerHTML = '<p style="color:var(--text-secondary);font-size:0.85rem;font-style:italic">No sessions.</p>';
+				return;
+			}
+			var html = '';
+			sessions.forEach(function(sess) {
+				html += '<div style="display:flex;justify-content:space-between;align-items:center;gap:0.5rem;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;margin-bottom:0.5rem">';
+				html += '<div style="flex:1;min-width:0">';
+				html += '<div style="font-weight:600;font-size:0.9rem;color:var(--text-primary)">' + escapeHtml(sess.ip || 'Unknown address') + (sess.current ? ' <span style="font-size:0.75rem;font-weight:400;color:var(--accent-primary)">This session</span>' : '') + '</div>';
+				html += '<div style="font-size:0.75rem;color:var(--text-secondary);overflow:hidden;text-overflow:ellipsis;white-space:nowrap">' + escapeHtml(sess.user_agent || 'Unknown browser') + '</div>';
+				html += '<div style="font-size:0.75rem;color:var(--text-secondary)">Logged in ' + new Date(sess.created_at).toLocaleString() + ' · Last seen ' + new Date(sess.last_seen).toLocaleString() + '</div>';
+				html += '</div>';
+				if (!sess.current) {
+					html += '<button onclick="revokeSession(\'' + sess.id + '\')" class="btn-login" style="padding:0.2rem 0.5rem;font-size:0.75rem;border-color:var(--error);color:var(--error)">Log Out</button>';
+				}
+				html += '</div>';
+			});
+			container.innerHTML = html;
+		}
+
+		function sessionsRequest(body, done) {
+			fetch('/api/sessions', {
+				method: 'DELETE',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(body)
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (data.success) {
+					showToast('success', 'Logged Out', done);
+					loadSessions();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to log out session'));
+				}
+			});
+		}
+
+		function changePassword() {
+			var current = document.getElementById('password-current');
+			var next = document.getElementById('password-new');
+			if (next.value.length < 8) {
+				showToast('warning', 'Too Short', 'Password must be at least 8 characters');
+				return;
+			}
+			fetch('/api/auth/password', {
+				method: 'POST',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ current_password: current.value, new_password: next.value })
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (data.success) {
+					current.value = '';
+					next.value = '';
+					showToast('success', 'Password Changed', data.message);
+					loadSessions();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to change password'));
+				}
+			})
+			.catch(function() { showToast('error', 'Error', 'Failed to change password'); });
+		}
+
+		function revokeSession(id) {
+			sessionsRequest({ id: id }, 'Session logged out');
+		}
+
+		function revokeOtherSessions() {
+			if (!confirm('Log out everywhere except this browser?')) return;
+			sessionsRequest({ others: true }, 'Other sessions logged out');
+		}
+
+		var apiTokenScopeLabels = { 'jobs:read': 'Read jobs', 'search': 'Run searches', 'saved:write': 'Manage saved jobs & alerts', 'admin': 'Admin' };
+
+		function loadAPITokens() {
+			document.getElementById('api-token-admin-scope').classList.toggle('hidden', currentRole !== 'admin');
+			fetch('/api/tokens', { credentials: 'same-origin' })
+				.then(function(res) { return res.json(); })
+				.then(function(data) { renderAPITokens(data.tokens || []); })
+				.catch(function() {});
+		}
+
+		function renderAPITokens(tokens) {
+			var container = document.getElementById('api-tokens-list');
+			if (!tokens.length) {
+				container.innerHTML = '<p style="color:var(--text-secondary);font-size:0.85rem;font-style:italic">No tokens yet. Create one below.</p>';
+				return;
+			}
+			var html = '';
+			tokens.forEach(function(t) {
+				var expired = t.expires_at && new Date(t.expires_at) < new Date();
+				var scopes = (t.scopes || []).map(function(s) { return apiTokenScopeLabels[s] || s; }).join(', ');
+				var details = 'Created ' + new Date(t.created_at).toLocaleDateString();
+				details += t.expires_at ? (expired ? ' · Expired ' : ' · Expires ') + new Date(t.expires_at).toLocaleDateString() : ' · Never expires';
+				details += t.last_used ? ' · Last used ' + new Date(t.last_used).toLocaleString() : ' · Never used';
+				html += '<div style="display:flex;justify-content:space-between;align-items:center;gap:0.5rem;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;margin-bottom:0.5rem">';
+				html += '<div style="flex:1;min-width:0">';
+				html += '<div style="font-weight:600;font-size:0.9rem;color:var(--text-primary)">' + (expired ? '🔴' : '🟢') + ' ' + escapeHtml(t.name) + ' <span style="font-size:0.75rem;font-weight:400;color:var(--text-secondary)">' + escapeHtml(scopes) + '</span></div>';
+				html += '<div style="font-size:0.75rem;color:var(--text-secondary)">' + details + '</div>';
+				html += '</div>';
+				html += '<button onclick="revokeAPIToken(\'' + t.id + '\')" class="btn-login" style="padding:0.2rem 0.5rem;font-size:0.75rem;border-color:var(--error);color:var(--error)">Revoke</button>';
+				html += '</div>';
+			});
+			container.innerHTML = html;
+		}
+
+		function createAPIToken() {
+			var name = document.getElementById('api-token-name').value.trim();
+			if (!name) {
+				showToast('warning', 'Missing Info', 'Name the token');
+				return;
+			}
+			var scopes = [];
+			document.querySelectorAll('.api-token-scope').forEach(function(cb) {
+				if (cb.checked) scopes.push(cb.value);
+			});
+			if (!scopes.length) {
+				showToast('warning', 'Missing Info', 'Choose at least one scope');
+				return;
+			}
+			fetch('/api/tokens', {
+				method: 'POST',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ name: name, scopes: scopes, expires_in_days: parseInt(document.getElementById('api-token-expiry').value, 10) })
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (!data.success) {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to create token'));
+					return;
+				}
+				document.getElementById('api-token-name').value = '';
+				var note = document.getElementById('api-token-note');
+				note.innerHTML = 'Token <strong>' + escapeHtml(data.info.name) + '</strong>: <code style="user-select:all;color:var(--text-primary);word-break:break-all">' + escapeHtml(data.token) + '</code><br>Copy it now; it won\'t be shown again.';
+				note.classList.remove('hidden');
+				showToast('success', 'Created', 'API token created');
+				loadAPITokens();
+			})
+			.catch(function() { showToast('error', 'Error', 'Failed to create token'); });
+		}
+
+		function revokeAPIToken(id) {
+			if (!confirm('Revoke this token? Scripts using it will stop working.')) return;
+			fetch('/api/tokens', {
+				method: 'DELETE',
+				credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ id: id })
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (data.success) {
+					showToast('success', 'Revoked', 'API token revoked');
+					loadAPITokens();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to revoke token'));
+				}
+			});
+		}
+
+		var calendarFeedEnabled = false;
+
+		function renderCalendarFeed(data) {
+			calendarFeedEnabled = data.enabled;
+			var input = document.getElementById('calendar-url');
+			input.value = data.url ? location.origin + data.url : '';
+			input.classList.toggle('hidden', !data.url);
+			document.getElementById('calendar-copy').classList.toggle('hidden', !data.url);
+			document.getElementById('calendar-note').classList.toggle('hidden', !data.enabled || !!data.url);
+			document.getElementById('calendar-enable').classList.toggle('hidden', data.enabled);
+			['calendar-reset', 'calendar-revoke'].forEach(function(id) {
+				document.getElementById(id).classList.toggle('hidden', !data.enabled);
+			});
+		}
+
+		function calendarFeedRequest(method) {
+			return fetch('/api/calendar', { method: method, credentials: 'same-origin' })
+				.then(function(res) {
+					if (!res.ok) throw new Error();
+					return res.json();
+				})
+				.then(renderCalendarFeed);
+		}
+
+		function loadCalendarFeed() {
+			calendarFeedRequest('GET').catch(function() {});
+		}
+
+		function createCalendarFeed() {
+			var resetting = calendarFeedEnabled;
+			if (resetting && !confirm('Reset the link? Calendars subscribed to the old one will stop updating.')) return;
+			calendarFeedRequest('POST')
+				.then(function() { showToast('success', 'Calendar', resetting ? 'New link created' : 'Calendar link created'); })
+				.catch(function() { showToast('error', 'Error', 'Failed to create calendar link'); });
+		}
+
+		function revokeCalendarFeed() {
+			if (!confirm('Turn off the calendar feed? Subscribed calendars will stop updating.')) return;
+			calendarFeedRequest('DELETE')
+				.then(function() { showToast('success', 'Calendar', 'Calendar feed turned off'); })
+				.catch(function() { showToast('error', 'Error', 'Failed to turn off calendar feed'); });
+		}
+
+		function copyCalendarFeed() {
+			var input = document.getElementById('calendar-url');
+			input.select();
+			if (navigator.clipboard) {
+				navigator.clipboard.writeText(input.value);
+			} else {
+				document.execCommand('copy');
+			}
+			showToast('success', 'Copied', 'Calendar link copied');
+		}
+
 		function loadAlertsConfig() {
 			fetch('/api/alerts/config', { credentials: 'same-origin' })
 				.then(function(res) { return res.json(); })
@@ -3539,11 +5127,272 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					if (tg.has_chatid) {
 						document.getElementById('tg-chat-id').placeholder = '••••••• (saved — enter new to change)';
 					}
+					renderEmailConfig(data.email || {});
+					renderWebhooks(data.webhooks || []);
+					renderDelivery(data.delivery || {}, data.queued || 0);
 					renderSchedules(data.schedules || []);
 				})
 				.catch(function() {});
 		}
 
+		function renderDelivery(d, queued) {
+			var hour = document.getElementById('delivery-hour');
+			if (!hour.options.length) {
+				for (var h = 0; h < 24; h++) {
+					var opt = document.createElement('option');
+					opt.value = h;
+					opt.textContent = (h < 10 ? '0' : '') + h + ':00';
+					hour.appendChild(opt);
+				}
+			}
+			document.getElementById('delivery-mode').value = d.mode || '';
+			hour.value = d.mode === 'daily' ? d.daily_hour : 9;
+			document.getElementById('delivery-quiet').checked = !!d.quiet_start;
+			if (d.quiet_start) {
+				document.getElementById('delivery-quiet-start').value = d.quiet_start;
+				document.getElementById('delivery-quiet-end').value = d.quiet_end;
+			}
+			document.getElementById('delivery-timezone').value = d.timezone || Intl.DateTimeFormat().resolvedOptions().timeZone || '';
+			document.getElementById('delivery-max-jobs').value = d.max_jobs || '';
+			document.getElementById('delivery-status').textContent = queued > 0 ? queued + ' alert(s) waiting to be sent' : '';
+			onDeliveryModeChange();
+			onDeliveryQuietChange();
+		}
+
+		function onDeliveryModeChange() {
+			var daily = document.getElementById('delivery-mode').value === 'daily';
+			document.getElementById('delivery-daily').classList.toggle('hidden', !daily);
+		}
+
+		function onDeliveryQuietChange() {
+			var quiet = document.getElementById('delivery-quiet').checked;
+			document.getElementById('delivery-quiet-start').disabled = !quiet;
+			document.getElementById('delivery-quiet-end').disabled = !quiet;
+		}
+
+		function saveDelivery() {
+			var quiet = document.getElementById('delivery-quiet').checked;
+			var body = {
+				mode: document.getElementById('delivery-mode').value,
+				daily_hour: parseInt(document.getElementById('delivery-hour').value) || 0,
+				timezone: document.getElementById('delivery-timezone').value.trim(),
+				quiet_start: quiet ? document.getElementById('delivery-quiet-start').value : '',
+				quiet_end: quiet ? document.getElementById('delivery-quiet-end').value : '',
+				max_jobs: parseInt(document.getElementById('delivery-max-jobs').value) || 0
+			};
+			fetch('/api/alerts/delivery', {
+				method: 'POST', credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(body)
+			})
+			.then(function(res) { return res.json(); })
+			.then(function(data) {
+				if (data.success) {
+					showToast('success', 'Saved', 'Delivery preferences saved');
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to save'));
+				}
+			});
+		}
+
+		function renderEmailConfig(email) {
+			document.getElementById('email-unavailable').classList.toggle('hidden', !!email.available);
+			document.getElementById('email-setup').classList.toggle('hidden', !email.available);
+			document.getElementById('email-address').value = email.address || '';
+			document.getElementById('email-enabled').checked = !email.address || !!email.enabled;
+			document.getElementById('email-code').classList.toggle('hidden', !email.code_pending);
+			document.getElementById('email-verify-btn').classList.toggle('hidden', !email.code_pending);
+			var status = document.getElementById('email-status');
+			if (email.verified && email.enabled) {
+				status.textContent = '✅ Verified & Active';
+				status.style.color = 'var(--accent-primary)';
+			} else if (email.verified) {
+				status.textContent = 'Verified, alerts off';
+				status.style.color = 'var(--text-secondary)';
+			} else if (email.address) {
+				status.textContent = email.code_pending ? 'Enter the code we emailed you' : '⚠️ Not verified — send a code';
+				status.style.color = 'var(--warning)';
+			} else {
+				status.textContent = '';
+			}
+		}
+
+		function emailRequest(url, body) {
+			return fetch(url, {
+				method: 'POST', credentials: 'same-origin',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(body)
+			}).then(function(res) { return res.json(); });
+		}
+
+		function saveEmailConfig() {
+			emailRequest('/api/alerts/email', {
+				address: document.getElementById('email-address').value.trim(),
+				enabled: document.getElementById('email-enabled').checked
+			}).then(function(data) {
+				if (data.success) {
+					showToast('success', 'Saved', 'Email settings saved');
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to save'));
+				}
+			});
+		}
+
+		function sendEmailCode() {
+			var status = document.getElementById('email-status');
+			status.textContent = 'Sending code...';
+			status.style.color = 'var(--text-secondary)';
+			// Save the address first, in case it was just typed in
+			emailRequest('/api/alerts/email', {
+				address: document.getElementById('email-address').value.trim(),
+				enabled: document.getElementById('email-enabled').checked
+			}).then(function(data) {
+				if (!data.success) return data;
+				return emailRequest('/api/alerts/email/verify', {});
+			}).then(function(data) {
+				if (data.success) {
+					showToast('success', 'Code Sent', data.message);
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to send code'));
+					status.textContent = '❌ ' + (data.error || 'Failed to send code');
+					status.style.color = 'var(--error)';
+				}
+			});
+		}
+
+		function verifyEmailCode() {
+			var code = document.getElementById('email-code').value.trim();
+			if (!code) return;
+			emailRequest('/api/alerts/email/verify', { code: code }).then(function(data) {
+				if (data.success) {
+					showToast('success', 'Verified', data.message);
+					document.getElementById('email-code').value = '';
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Verification failed'));
+				}
+			});
+		}
+
+		var webhookLabels = { slack: 'Slack', discord: 'Discord', webhook: 'Webhook' };
+		var webhookPlaceholders = {
+			slack: 'https://hooks.slack.com/services/...',
+			discord: 'https://discord.com/api/webhooks/...',
+			webhook: 'https://example.com/salarysleuth'
+		};
+
+		function renderWebhooks(webhooks) {
+			var container = document.getElementById('webhooks-list');
+			if (!webhooks.length) {
+				container.innerHTML = '<p style="color:var(--text-secondary);font-size:0.85rem;font-style:italic">No webhooks yet. Add one below.</p>';
+				return;
+			}
+			var html = '';
+			webhooks.forEach(function(wh) {
+				var status;
+				if (!wh.verified) {
+					status = '<span style="color:var(--warning)">⚠️ Not verified — send a test message</span>';
+				} else if (wh.enabled) {
+					status = '<span style="color:var(--accent-primary)">✅ Verified &amp; Active</span>';
+				} else {
+					status = 'Verified, alerts off';
+				}
+				html += '<div style="display:flex;justify-content:space-between;align-items:center;gap:0.5rem;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;margin-bottom:0.5rem">';
+				html += '<div style="flex:1;min-width:0">';
+				html += '<div style="font-weight:600;font-size:0.9rem;color:var(--text-primary)">' + (wh.enabled ? '🟢' : '🔴') + ' ' + escapeHtml(wh.name) + ' <span style="font-size:0.75rem;font-weight:400;color:var(--text-secondary)">' + webhookLabels[wh.type] + '</span></div>';
+				html += '<div style="font-size:0.75rem;color:var(--text-secondary);font-family:\'JetBrains Mono\',monospace;overflow:hidden;text-overflow:ellipsis;white-space:nowrap">' + escapeHtml(wh.url) + '</div>';
+				html += '<div style="font-size:0.75rem;color:var(--text-secondary)">' + status + '</div>';
+				html += '</div>';
+				html += '<div style="display:flex;gap:0.4rem">';
+				html += '<button onclick="testWebhook(\'' + wh.id + '\')" class="btn-login" style="padding:0.2rem 0.5rem;font-size:0.75rem">Test</button>';
+				html += '<button onclick="toggleWebhook(\'' + wh.id + '\',' + !wh.enabled + ')" class="btn-login" style="padding:0.2rem 0.5rem;font-size:0.75rem">' + (wh.enabled ? 'Disable' : 'Enable') + '</button>';
+				html += '<button onclick="deleteWebhook(\'' + wh.id + '\')" class="btn-login" style="padding:0.2rem 0.5rem;font-size:0.75rem;border-color:var(--error);color:var(--error)">Delete</button>';
+				html += '</div>';
+				html += '</div>';
+			});
+			container.innerHTML = html;
+		}
+
+		function onWebhookTypeChange() {
+			var type = document.getElementById('wh-type').value;
+			document.getElementById('wh-url').placeholder = webhookPlaceholders[type];
+			document.getElementById('wh-secret').classList.toggle('hidden', type !== 'webhook');
+		}
+
+		function webhookRequest(method, url, body) {
+			var opts = { method: method, credentials: 'same-origin' };
+			if (body) {
+				opts.headers = { 'Content-Type': 'application/json' };
+				opts.body = JSON.stringify(body);
+			}
+			return fetch(url, opts).then(function(res) { return res.json(); });
+		}
+
+		function addWebhook() {
+			var type = document.getElementById('wh-type').value;
+			var url = document.getElementById('wh-url').value.trim();
+			if (!url) {
+				showToast('warning', 'Missing Info', 'Enter the webhook URL');
+				return;
+			}
+			var body = { type: type, name: document.getElementById('wh-name').value.trim(), url: url };
+			if (type === 'webhook') body.secret = document.getElementById('wh-secret').value.trim();
+			webhookRequest('POST', '/api/alerts/webhooks', body).then(function(data) {
+				if (!data.success) {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to add webhook'));
+					return;
+				}
+				['wh-name', 'wh-url', 'wh-secret'].forEach(function(id) { document.getElementById(id).value = ''; });
+				var note = document.getElementById('webhook-secret-note');
+				if (data.secret) {
+					note.innerHTML = 'Signing secret for <strong>' + escapeHtml(data.webhook.name) + '</strong>: <code style="user-select:all;color:var(--text-primary)">' + escapeHtml(data.secret) + '</code><br>Copy it now; it won\'t be shown again.';
+					note.classList.remove('hidden');
+				} else {
+					note.classList.add('hidden');
+				}
+				showToast('success', 'Saved', 'Webhook added. Send a test message to verify.');
+				loadAlertsConfig();
+			});
+		}
+
+		function testWebhook(id) {
+			showToast('info', 'Sending', 'Sending test message...');
+			webhookRequest('POST', '/api/alerts/webhooks/test', { id: id }).then(function(data) {
+				if (data.success) {
+					showToast('success', 'Test Sent', escapeHtml(data.message));
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Test Failed', escapeHtml(data.error || 'Test failed'));
+				}
+			});
+		}
+
+		function toggleWebhook(id, enabled) {
+			webhookRequest('POST', '/api/alerts/webhooks', { id: id, enabled: enabled }).then(function(data) {
+				if (data.success) {
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to save'));
+				}
+			});
+		}
+
+		function deleteWebhook(id) {
+			if (!confirm('Delete this webhook?')) return;
+			webhookRequest('DELETE', '/api/alerts/webhooks?id=' + encodeURIComponent(id)).then(function(data) {
+				if (data.success) {
+					document.getElementById('webhook-secret-note').classList.add('hidden');
+					showToast('success', 'Deleted', 'Webhook removed');
+					loadAlertsConfig();
+				} else {
+					showToast('error', 'Error', escapeHtml(data.error || 'Failed to delete'));
+				}
+			});
+		}
+
 		function saveTelegramConfig() {
 			var token = document.getElementById('tg-bot-token').value.trim();
 			var chatId = document.getElementById('tg-chat-id').value.trim();
@@ -3600,6 +5449,8 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		function onSchedFreqChange() {
 			var freq = document.getElementById('sched-frequency').value;
 			document.getElementById('sched-custom-days').style.display = freq === 'custom' ? 'flex' : 'none';
+			document.getElementById('sched-at').style.display = freq === 'cron' ? 'none' : 'flex';
+			document.getElementById('sched-cron').style.display = freq === 'cron' ? '' : 'none';
 		}
 
 		function addSchedule() {
@@ -3609,27 +5460,37 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 			var freq = document.getElementById('sched-frequency').value;
 			var timeParts = document.getElementById('sched-time').value.split(':');
 			var notifyEmpty = document.getElementById('sched-notify-empty').checked;
+			var notifySalary = document.getElementById('sched-notify-salary').checked;
+			var notifySeen = document.getElementById('sched-notify-seen').checked;
 
 			if (!name) { showToast('warning', 'Missing', 'Enter a schedule name'); return; }
 			if (type === 'custom' && !query) { showToast('warning', 'Missing', 'Enter a search query'); return; }
 
-			var days = [];
-			if (freq === 'custom') {
-				var cbs = document.querySelectorAll('.sched-day-cb:checked');
-				cbs.forEach(function(cb) { days.push(parseInt(cb.value)); });
-				if (days.length === 0) { showToast('warning', 'Missing', 'Select at least one day'); return; }
+			// The presets are cron expressions too: minute hour * * weekdays
+			var cron = document.getElementById('sched-cron').value.trim();
+			if (freq === 'cron') {
+				if (!cron) { showToast('warning', 'Missing', 'Enter a cron expression'); return; }
+			} else {
+				var weekdays = { daily: '*', weekdays: '1-5', weekly: '1' }[freq];
+				if (freq === 'custom') {
+					var days = [];
+					document.querySelectorAll('.sched-day-cb:checked').forEach(function(cb) { days.push(cb.value); });
+					if (days.length === 0) { showToast('warning', 'Missing', 'Select at least one day'); return; }
+					weekdays = days.join(',');
+				}
+				cron = (parseInt(timeParts[1]) || 0) + ' ' + (parseInt(timeParts[0]) || 0) + ' * * ' + weekdays;
 			}
 
 			var body = {
 				name: name,
 				type: type,
 				query: type === 'custom' ? query : '',
-				schedule: freq,
-				days: days,
-				hour: parseInt(timeParts[0]) || 9,
-				minute: parseInt(timeParts[1]) || 0,
+				cron: cron,
+				timezone: document.getElementById('sched-timezone').value.trim(),
 				enabled: true,
-				notify_empty: notifyEmpty
+				notify_empty: notifyEmpty,
+				notify_salary_change: notifySalary,
+				notify_seen: notifySeen
 			};
 
 			fetch('/api/alerts/schedules', {
@@ -3643,6 +5504,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 					showToast('success', 'Added', 'Schedule created');
 					document.getElementById('sched-name').value = '';
 					document.getElementById('sched-query').value = '';
+					document.getElementById('sched-cron').value = '';
 					renderSchedules(data.schedules || []);
 				} else {
 					showToast('error', 'Error', data.error || 'Failed to create');
@@ -3652,32 +5514,33 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 
 		function renderSchedules(schedules) {
 			var container = document.getElementById('schedules-list');
+			var tz = document.getElementById('sched-timezone');
+			if (!tz.value) tz.value = Intl.DateTimeFormat().resolvedOptions().timeZone || '';
 			if (!schedules || schedules.length === 0) {
 				container.innerHTML = '<p style="color:var(--text-secondary);font-size:0.85rem;font-style:italic">No schedules yet. Add one below.</p>';
 				return;
 			}
-			var dayNames = ['Sun','Mon','Tue','Wed','Thu','Fri','Sat'];
 			var html = '';
 			schedules.forEach(function(s) {
-				var timeStr = (s.hour < 10 ? '0' : '') + s.hour + ':' + (s.minute < 10 ? '0' : '') + s.minute;
-				var freqStr = s.schedule;
-				if (s.schedule === 'custom' && s.days) {
-					freqStr = s.days.map(function(d) { return dayNames[d]; }).join(', ');
-				} else if (s.schedule === 'weekly') {
-					freqStr = 'Mondays';
-				}
+				var whenStr = '<code>' + escapeHtml(s.cron) + '</code>' + (s.timezone ? ' (' + escapeHtml(s.timezone) + ')' : '');
 				var typeLabel = s.type === 'default' ? '🛡️ OffSec' : '🔍 ' + escapeHtml(s.query);
+				var sendLabel = s.notify_seen ? 'every job' : (s.notify_salary_change ? 'new jobs and salary changes' : 'new jobs');
 				var statusIcon = s.enabled ? '🟢' : '🔴';
 				var lastInfo = '';
 				if (s.last_run) {
 					var ago = formatHistoryTime(s.last_run);
 					lastInfo = '<span style="font-size:0.75rem;color:var(--text-secondary)">Last: ' + ago + (s.last_result ? ' — ' + escapeHtml(s.last_result) : '') + '</span>';
 				}
+				var nextInfo = '';
+				if (s.enabled && s.next_run) {
+					nextInfo = '<span style="font-size:0.75rem;color:var(--text-secondary)">Next: ' + escapeHtml(new Date(s.next_run).toLocaleString()) + '</span>';
+				}
 
 				html += '<div style="display:flex;justify-content:space-between;align-items:center;padding:0.6rem 0.75rem;background:var(--bg-card);border:1px solid var(--border-color);border-radius:6px;margin-bottom:0.5rem">';
 				html += '<div style="flex:1">';
 				html += '<div style="font-weight:600;font-size:0.9rem;color:var(--text-primary)">' + statusIcon + ' ' + escapeHtml(s.name) + '</div>';
-				html += '<div style="font-size:0.8rem;color:var(--text-secondary)">' + typeLabel + ' · ' + freqStr + ' at ' + timeStr + '</div>';
+				html += '<div style="font-size:0.8rem;color:var(--text-secondary)">' + typeLabel + ' · ' + whenStr + ' · ' + sendLabel + '</div>';
+				if (nextInfo) html += '<div>' + nextInfo + '</div>';
 				if (lastInfo) html += '<div>' + lastInfo + '</div>';
 				html += '</div>';
 				html += '<div style="display:flex;gap:0.4rem">';
@@ -3731,7 +5594,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 		checkAuth();
 
 		setTimeout(function() {
-			if (!searchPollingInterval && !viewingSearchResults) {
+			if (!searchPollingInterval && !searchEvents && !viewingSearchResults) {
 				location.reload();
 			}
 		}, 5 * 60 * 1000);
@@ -3743,6 +5606,7 @@ func generateInteractiveHTML(jobs []TaggedJob, lastUpdated time.Time, cfg *AppCo
 	html = strings.Replace(html, "{{LAST_UPDATED}}", lastUpdatedStr, 1)
 	html = strings.Replace(html, "{{ALL_JOBS}}", jobsStr, 1)
 	html = strings.Replace(html, "{{APP_CONFIG}}", configStr, 1)
+	html = strings.Replace(html, "{{DASHBOARD_DEFAULTS}}", string(dashboardJSON), 1)
 	
 	return html
 }
@@ -0,0 +1,644 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook channel types
+const (
+	WebhookSlack   = "slack"
+	WebhookDiscord = "discord"
+	WebhookGeneric = "webhook"
+)
+
+// maxWebhooks is the most webhook channels a user can add
+const maxWebhooks = 10
+
+var errWebhookNotFound = errors.New("webhook not found")
+
+// WebhookChannel is one of a user's Slack, Discord or generic JSON webhooks.
+// Like Telegram, a channel only gets alerts once a test message has been
+// delivered to it.
+type WebhookChannel struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Secret signs generic webhook payloads (X-SalarySleuth-Signature)
+	Secret    string `json:"secret,omitempty"`
+	Enabled   bool   `json:"enabled"`
+	Verified  bool   `json:"verified"`
+	CreatedAt string `json:"created_at"`
+}
+
+// active reports whether alerts should be posted to the channel
+func (c WebhookChannel) active() bool {
+	return c.Enabled && c.Verified && c.URL != ""
+}
+
+func webhookLabel(kind string) string {
+	switch kind {
+	case WebhookSlack:
+		return "Slack"
+	case WebhookDiscord:
+		return "Discord"
+	}
+	return "Webhook"
+}
+
+// validateWebhookURL checks the URL is one the channel type can post to.
+// Slack and Discord URLs must be their incoming webhook endpoints.
+func validateWebhookURL(kind, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid URL")
+	}
+	switch kind {
+	case WebhookSlack:
+		if u.Scheme != "https" || u.Host != "hooks.slack.com" {
+			return fmt.Errorf("Slack webhook URLs start with https://hooks.slack.com/")
+		}
+	case WebhookDiscord:
+		host := strings.TrimPrefix(strings.TrimPrefix(u.Host, "ptb."), "canary.")
+		if u.Scheme != "https" || (host != "discord.com" && host != "discordapp.com") || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+			return fmt.Errorf("Discord webhook URLs start with https://discord.com/api/webhooks/")
+		}
+	case WebhookGeneric:
+		if u.Scheme != "https" && u.Scheme != "http" {
+			return fmt.Errorf("webhook URLs must be http or https")
+		}
+		// notifyClient refuses these anyway, and hostnames that resolve to
+		// them, but saying so here is clearer than a failed test message
+		host := u.Hostname()
+		if ip, err := netip.ParseAddr(host); (err == nil && isInternalAddr(ip)) || strings.EqualFold(host, "localhost") {
+			return fmt.Errorf("webhook URLs must point at a public address")
+		}
+	default:
+		return fmt.Errorf("unknown webhook type %q", kind)
+	}
+	return nil
+}
+
+// maskWebhookURL hides the token part of a webhook URL, which is all it
+// takes to post to the channel
+func maskWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "•••••••"
+	}
+	tail := raw
+	if len(tail) > 4 {
+		tail = tail[len(tail)-4:]
+	}
+	return u.Scheme + "://" + u.Host + "/•••" + tail
+}
+
+// webhookRetry is each webhook type's retry policy. Slack and Discord rate
+// limit per webhook and say when to try again; generic receivers get longer
+// to recover.
+var webhookRetry = map[string]retryPolicy{
+	WebhookSlack:   {Attempts: 4, Backoff: time.Second, MaxBackoff: 30 * time.Second},
+	WebhookDiscord: {Attempts: 4, Backoff: time.Second, MaxBackoff: 30 * time.Second},
+	WebhookGeneric: {Attempts: 5, Backoff: 2 * time.Second, MaxBackoff: time.Minute},
+}
+
+// webhookNotifier posts digests to a webhook channel
+type webhookNotifier struct {
+	ch WebhookChannel
+}
+
+func (n webhookNotifier) Channel() string {
+	return fmt.Sprintf("%s (%s)", webhookLabel(n.ch.Type), n.ch.Name)
+}
+
+func (n webhookNotifier) Notify(d Digest) error {
+	return n.deliver(d, webhookRetry[n.ch.Type])
+}
+
+// deliver renders the digest for the channel and posts each message,
+// retrying each by the policy
+func (n webhookNotifier) deliver(d Digest, policy retryPolicy) error {
+	var payloads []interface{}
+	switch n.ch.Type {
+	case WebhookSlack:
+		payloads = renderSlackDigest(d)
+	case WebhookDiscord:
+		payloads = renderDiscordDigest(d)
+	case WebhookGeneric:
+		payloads = []interface{}{renderGenericDigest(d)}
+	default:
+		return fmt.Errorf("unknown webhook type %q", n.ch.Type)
+	}
+
+	event := genericEvent(d)
+	delivery := make([]byte, 12)
+	rand.Read(delivery)
+	for i, payload := range payloads {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			// Slack and Discord allow about one message a second
+			time.Sleep(time.Second)
+		}
+		err = withRetry(policy, func() error {
+			req, err := http.NewRequest(http.MethodPost, n.ch.URL, bytes.NewReader(body))
+			if err != nil {
+				return &permanentError{err}
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "SalarySleuth-Webhook/1.0")
+			if n.ch.Type == WebhookGeneric {
+				ts := strconv.FormatInt(time.Now().Unix(), 10)
+				req.Header.Set("X-SalarySleuth-Event", event)
+				req.Header.Set("X-SalarySleuth-Delivery", hex.EncodeToString(delivery))
+				req.Header.Set("X-SalarySleuth-Timestamp", ts)
+				req.Header.Set("X-SalarySleuth-Signature", "sha256="+signWebhook(n.ch.Secret, ts, body))
+			}
+			resp, err := notifyClient.Do(req)
+			if errors.Is(err, errInternalAddress) {
+				return &permanentError{err}
+			} else if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				return newStatusError(strings.ToLower(webhookLabel(n.ch.Type))+" webhook", resp)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signWebhook is the HMAC-SHA256 of "<timestamp>.<body>", hex encoded.
+// Receivers recompute it with their copy of the secret and should reject
+// old timestamps to stop replays.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func genericEvent(d Digest) string {
+	switch {
+	case d.Test:
+		return "test"
+	case len(d.Jobs) == 0:
+		return "scan.empty"
+	}
+	return "jobs.found"
+}
+
+// renderGenericDigest is the JSON body posted to generic webhooks
+func renderGenericDigest(d Digest) interface{} {
+	jobs := d.Jobs
+	if jobs == nil {
+		jobs = []DigestJob{}
+	}
+	return map[string]interface{}{
+		"event":    genericEvent(d),
+		"scan":     d.ScanName,
+		"count":    len(jobs),
+		"omitted":  d.Omitted,
+		"more":     d.More,
+		"more_url": d.MoreURL,
+		"jobs":     jobs,
+		"sent_at":  d.Time.UTC().Format(time.RFC3339),
+	}
+}
+
+// slackBatchSize keeps messages well under Slack's 50 block limit
+const slackBatchSize = 20
+
+// slackEscape escapes text for Slack mrkdwn
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// slackLink is a mrkdwn link; the URL can't contain the link delimiters
+func slackLink(target, text string) string {
+	target = strings.NewReplacer("|", "%7C", ">", "%3E", "<", "%3C").Replace(target)
+	return "<" + target + "|" + slackEscape(text) + ">"
+}
+
+func slackText(kind, text string) map[string]interface{} {
+	return map[string]interface{}{"type": kind, "text": text}
+}
+
+// renderSlackDigest formats a digest as Block Kit messages of up to
+// slackBatchSize jobs each
+func renderSlackDigest(d Digest) []interface{} {
+	footerText := "📅 " + d.Date()
+	if note := d.OmittedNote(); note != "" {
+		footerText = "🙈 " + note + "  ·  " + footerText
+	}
+	if note := d.MoreNote(); note != "" {
+		note = slackEscape(note)
+		if d.MoreURL != "" {
+			note += " " + slackLink(d.MoreURL, "See them all")
+		}
+		footerText = "📋 " + note + "  ·  " + footerText
+	}
+	footer := map[string]interface{}{
+		"type":     "context",
+		"elements": []interface{}{slackText("mrkdwn", footerText)},
+	}
+	header := map[string]interface{}{
+		"type": "header",
+		"text": slackText("plain_text", "🎯 SalarySleuth Alert"),
+	}
+
+	if d.Test || len(d.Jobs) == 0 {
+		summary := fmt.Sprintf("Schedule: *%s*\nNo new jobs found this scan.", slackEscape(d.ScanName))
+		fallback := "SalarySleuth: no new jobs for " + d.ScanName
+		if d.Test {
+			header["text"] = slackText("plain_text", "🧪 SalarySleuth Test")
+			summary = "Slack integration is working! You will receive job alerts here."
+			fallback = "SalarySleuth test message"
+		}
+		return []interface{}{map[string]interface{}{
+			"text": fallback,
+			"blocks": []interface{}{
+				header,
+				map[string]interface{}{"type": "section", "text": slackText("mrkdwn", summary)},
+				footer,
+			},
+		}}
+	}
+
+	var messages []interface{}
+	for i := 0; i < len(d.Jobs); i += slackBatchSize {
+		end := i + slackBatchSize
+		if end > len(d.Jobs) {
+			end = len(d.Jobs)
+		}
+		var blocks []interface{}
+		if i == 0 {
+			blocks = append(blocks,
+				header,
+				map[string]interface{}{"type": "section", "text": slackText("mrkdwn",
+					fmt.Sprintf("Schedule: *%s*\nFound *%d* new job(s)", slackEscape(d.ScanName), d.Total()))},
+				map[string]interface{}{"type": "divider"},
+			)
+		}
+		for j, job := range d.Jobs[i:end] {
+			text := "*" + slackLink(job.URL, fmt.Sprintf("%d. %s", i+j+1, job.Title)) + "*"
+			if label := job.ChangeLabel(); label != "" {
+				text += "  _🔁 " + label + "_"
+			}
+			text += "\n🏢 " + slackEscape(job.Company)
+			if job.Location != "" {
+				text += "  📍 " + slackEscape(job.Location)
+			}
+			var salary []string
+			if job.SalaryRange != "" {
+				salary = append(salary, "Posted: "+slackEscape(job.SalaryRange))
+			}
+			if job.LevelSalary != "" {
+				salary = append(salary, "Levels.fyi: "+slackEscape(job.LevelSalary))
+			}
+			if len(salary) > 0 {
+				text += "\n💰 " + strings.Join(salary, " · ")
+			}
+			blocks = append(blocks, map[string]interface{}{"type": "section", "text": slackText("mrkdwn", text)})
+		}
+		if end == len(d.Jobs) {
+			blocks = append(blocks, footer)
+		}
+		messages = append(messages, map[string]interface{}{
+			"text":   fmt.Sprintf("SalarySleuth: %d new job(s) for %s", d.Total(), d.ScanName),
+			"blocks": blocks,
+		})
+	}
+	return messages
+}
+
+// discordBatchSize is Discord's limit of embeds per message
+const discordBatchSize = 10
+
+// discordColor is the dashboard's accent green
+const discordColor = 0x00ff88
+
+// discordEscape escapes Discord markdown
+func discordEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "*", "\\*", "_", "\\_", "~", "\\~", "`", "\\`", "|", "\\|", ">", "\\>").Replace(s)
+}
+
+// truncateRunes shortens s to at most n runes, for Discord's field limits
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// renderDiscordDigest formats a digest as messages with an embed per job,
+// up to discordBatchSize each
+func renderDiscordDigest(d Digest) []interface{} {
+	footer := "📅 " + d.Date()
+	if note := d.OmittedNote(); note != "" {
+		footer = "🙈 " + note + "\n" + footer
+	}
+	if note := d.MoreNote(); note != "" {
+		footer = "📋 " + note + "\n" + footer
+	}
+	if d.Test {
+		return []interface{}{map[string]interface{}{
+			"username": "SalarySleuth",
+			"content":  "🧪 **SalarySleuth Test**\nDiscord integration is working! You will receive job alerts here.",
+		}}
+	}
+	if len(d.Jobs) == 0 {
+		return []interface{}{map[string]interface{}{
+			"username": "SalarySleuth",
+			"content": fmt.Sprintf("📋 **SalarySleuth Schedule**\n**%s**\nNo new jobs found this scan.\n%s",
+				discordEscape(d.ScanName), footer),
+		}}
+	}
+
+	var messages []interface{}
+	for i := 0; i < len(d.Jobs); i += discordBatchSize {
+		end := i + discordBatchSize
+		if end > len(d.Jobs) {
+			end = len(d.Jobs)
+		}
+		var embeds []interface{}
+		for j, job := range d.Jobs[i:end] {
+			desc := "🏢 " + discordEscape(job.Company)
+			if label := job.ChangeLabel(); label != "" {
+				desc = "🔁 *" + label + "*\n" + desc
+			}
+			if job.Location != "" {
+				desc += "\n📍 " + discordEscape(job.Location)
+			}
+			var fields []interface{}
+			if job.SalaryRange != "" {
+				fields = append(fields, map[string]interface{}{"name": "Posted", "value": truncateRunes(job.SalaryRange, 1024), "inline": true})
+			}
+			if job.LevelSalary != "" {
+				fields = append(fields, map[string]interface{}{"name": "Levels.fyi", "value": truncateRunes(job.LevelSalary, 1024), "inline": true})
+			}
+			embed := map[string]interface{}{
+				"title":       truncateRunes(fmt.Sprintf("%d. %s", i+j+1, job.Title), 256),
+				"description": truncateRunes(desc, 4096),
+				"color":       discordColor,
+			}
+			if strings.HasPrefix(job.URL, "http") {
+				embed["url"] = job.URL
+			}
+			if len(fields) > 0 {
+				embed["fields"] = fields
+			}
+			if i+j == len(d.Jobs)-1 {
+				embed["footer"] = map[string]interface{}{"text": footer}
+			}
+			embeds = append(embeds, embed)
+		}
+		msg := map[string]interface{}{
+			"username": "SalarySleuth",
+			"embeds":   embeds,
+		}
+		if i == 0 {
+			content := fmt.Sprintf("🎯 **SalarySleuth Alert**\nSchedule: **%s**\nFound **%d** new job(s)!",
+				discordEscape(d.ScanName), d.Total())
+			if d.MoreURL != "" {
+				content += fmt.Sprintf("\n[See all %d jobs](%s)", d.Total(), d.MoreURL)
+			}
+			msg["content"] = content
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// webhookView is a channel as shown to its owner, without its secrets
+func webhookView(ch WebhookChannel) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         ch.ID,
+		"type":       ch.Type,
+		"name":       ch.Name,
+		"url":        maskWebhookURL(ch.URL),
+		"has_secret": ch.Secret != "",
+		"enabled":    ch.Enabled,
+		"verified":   ch.Verified,
+		"created_at": ch.CreatedAt,
+	}
+}
+
+func webhookViews(list []WebhookChannel) []map[string]interface{} {
+	views := make([]map[string]interface{}, 0, len(list))
+	for _, ch := range list {
+		views = append(views, webhookView(ch))
+	}
+	return views
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handleWebhooks lists (GET), adds or updates (POST) and removes (DELETE
+// ?id=) the user's webhook channels. A new generic webhook without a secret
+// gets a generated one, returned only in that response. Changing a channel's
+// URL or secret requires testing it again.
+func handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	username := r.Header.Get("X-Auth-User")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"webhooks": webhookViews(loadUserAlerts(username).Webhooks)})
+
+	case http.MethodPost:
+		var req struct {
+			ID      string `json:"id"`
+			Type    string `json:"type"`
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Secret  string `json:"secret"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			jsonError(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		req.URL = strings.TrimSpace(req.URL)
+		req.Secret = strings.TrimSpace(req.Secret)
+
+		var saved WebhookChannel
+		var generated string
+		err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+			var ch *WebhookChannel
+			if req.ID == "" {
+				if len(cfg.Webhooks) >= maxWebhooks {
+					return fmt.Errorf("Maximum %d webhooks allowed", maxWebhooks)
+				}
+				if req.URL == "" {
+					return fmt.Errorf("Webhook URL is required")
+				}
+				cfg.Webhooks = append(cfg.Webhooks, WebhookChannel{
+					ID:        generateEntryID(),
+					Type:      req.Type,
+					Enabled:   true,
+					CreatedAt: time.Now().Format(time.RFC3339),
+				})
+				ch = &cfg.Webhooks[len(cfg.Webhooks)-1]
+			} else {
+				for i := range cfg.Webhooks {
+					if cfg.Webhooks[i].ID == req.ID {
+						ch = &cfg.Webhooks[i]
+					}
+				}
+				if ch == nil {
+					return errWebhookNotFound
+				}
+			}
+
+			if req.URL != "" && req.URL != ch.URL {
+				if err := validateWebhookURL(ch.Type, req.URL); err != nil {
+					return err
+				}
+				ch.URL = req.URL
+				ch.Verified = false
+			}
+			if req.Name != "" {
+				ch.Name = truncateRunes(req.Name, 60)
+			}
+			if ch.Name == "" {
+				ch.Name = webhookLabel(ch.Type)
+			}
+			if ch.Type == WebhookGeneric {
+				if req.Secret != "" && req.Secret != ch.Secret {
+					ch.Secret = req.Secret
+					ch.Verified = false
+				}
+				if ch.Secret == "" {
+					generated = newWebhookSecret()
+					ch.Secret = generated
+				}
+			}
+			if req.Enabled != nil {
+				ch.Enabled = *req.Enabled
+			}
+			saved = *ch
+			return nil
+		})
+		if errors.Is(err, errWebhookNotFound) {
+			jsonError(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			jsonError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := map[string]interface{}{"success": true, "webhook": webhookView(saved)}
+		if generated != "" {
+			resp["secret"] = generated
+		}
+		json.NewEncoder(w).Encode(resp)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		err := updateUserAlerts(username, func(cfg *UserAlertConfig) error {
+			for i, ch := range cfg.Webhooks {
+				if ch.ID == id {
+					cfg.Webhooks = append(cfg.Webhooks[:i], cfg.Webhooks[i+1:]...)
+					return nil
+				}
+			}
+			return errWebhookNotFound
+		})
+		if errors.Is(err, errWebhookNotFound) {
+			jsonError(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			jsonError(w, "Failed to save", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTestWebhook posts a test message to one of the user's webhooks and,
+// when it is delivered, verifies and enables the channel
+func handleTestWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		jsonError(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	username := r.Header.Get("X-Auth-User")
+
+	var ch *WebhookChannel
+	cfg := loadUserAlerts(username)
+	for i := range cfg.Webhooks {
+		if cfg.Webhooks[i].ID == req.ID {
+			ch = &cfg.Webhooks[i]
+		}
+	}
+	if ch == nil {
+		jsonError(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	n := webhookNotifier{*ch}
+	if err := n.deliver(testDigest(), retryPolicy{Attempts: 1}); err != nil {
+		jsonError(w, fmt.Sprintf("Test failed: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	tested := *ch
+	err := updateUserAlerts(username, func(stored *UserAlertConfig) error {
+		for i := range stored.Webhooks {
+			// Only verify the settings that were tested
+			if c := &stored.Webhooks[i]; c.ID == tested.ID && c.URL == tested.URL && c.Secret == tested.Secret {
+				c.Verified = true
+				c.Enabled = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to save verified webhook for %s: %v", username, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Test message sent! Check %s.", tested.Name),
+	})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://203.0.113.7:8080/hook", true},
+		{"ftp://example.com/hook", false},
+		{"http://localhost:8080/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://[::1]/hook", false},
+		{"http://[::ffff:192.168.1.1]/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://0.1.2.3/hook", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://100.127.255.254/hook", false},
+		{"http://100.128.0.1/hook", true},
+		{"http://224.0.0.251/hook", false},
+		{"http://255.255.255.255/hook", false},
+		{"http://[ff02::1]/hook", false},
+		{"http://[ff0e::1]/hook", false},
+		{"http://[fd00::1]/hook", false},
+		{"http://[64:ff9b::a00:5]/hook", false},
+		{"http://[64:ff9b::7f00:1]/hook", false},
+		{"http://[2606:4700:4700::1111]/hook", true},
+	}
+	for _, tt := range tests {
+		if err := validateWebhookURL(WebhookGeneric, tt.url); (err == nil) != tt.ok {
+			t.Errorf("validateWebhookURL(%q) = %v, want ok %v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+func TestNotifyClientRefusesInternal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, err := notifyClient.Get(srv.URL)
+	if !errors.Is(err, errInternalAddress) {
+		t.Errorf("request to %s: err = %v, want errInternalAddress", srv.URL, err)
+	}
+}
+
+func TestNotifyClientRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.RedirectHandler("http://127.0.0.1/", http.StatusFound))
+	defer srv.Close()
+	// a plain transport, as the test server is itself on loopback
+	client := *notifyClient
+	client.Transport = nil
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want the unfollowed %d", resp.StatusCode, http.StatusFound)
+	}
+}
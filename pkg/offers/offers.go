@@ -0,0 +1,279 @@
+// Package offers annualises job offers so they can be compared side by side:
+// base, bonus, equity vesting per year (with a cliff), sign-on bonuses spread
+// over the years they are earned, and total compensation. Each offer is placed
+// against the posted salary range of the job and the company's Levels.fyi
+// median. It is shared by the salarysleuth compare command and the jobtracker
+// offer comparison.
+package offers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/fr4nk3nst1ner/salarysleuth/pkg/query"
+)
+
+// DefaultVesting is the common four-year schedule, in percent per year
+var DefaultVesting = []float64{25, 25, 25, 25}
+
+// MaxYears bounds the vesting schedule and the sign-on amortisation, so
+// terms can't ask Years for an absurd number of years
+const MaxYears = 10
+
+// Terms are the compensation terms of an offer in dollars
+type Terms struct {
+	Base float64 `json:"base" yaml:"base"`
+	// Bonus is the annual target bonus. BonusPercent, a percentage of base,
+	// is used when Bonus is 0.
+	Bonus        float64 `json:"bonus,omitempty" yaml:"bonus"`
+	BonusPercent float64 `json:"bonus_percent,omitempty" yaml:"bonus_percent"`
+	// Equity is the value of the whole grant, vested by Vesting (percent per
+	// year, DefaultVesting when empty). Nothing vests before CliffMonths;
+	// what would have vested by then vests at the cliff.
+	Equity      float64   `json:"equity,omitempty" yaml:"equity"`
+	Vesting     []float64 `json:"vesting,omitempty" yaml:"vesting"`
+	CliffMonths int       `json:"cliff_months,omitempty" yaml:"cliff_months"`
+	// SignOn is amortised evenly over SignOnYears (1 when 0), usually the
+	// clawback period
+	SignOn      float64 `json:"sign_on,omitempty" yaml:"sign_on"`
+	SignOnYears int     `json:"sign_on_years,omitempty" yaml:"sign_on_years"`
+}
+
+// Validate reports terms that can't be annualised
+func (t Terms) Validate() error {
+	for _, v := range []float64{t.Base, t.Bonus, t.BonusPercent, t.Equity, t.SignOn} {
+		if v < 0 {
+			return fmt.Errorf("amounts can't be negative")
+		}
+	}
+	if t.CliffMonths < 0 || t.SignOnYears < 0 {
+		return fmt.Errorf("cliff_months and sign_on_years can't be negative")
+	}
+	if len(t.Vesting) > MaxYears {
+		return fmt.Errorf("vesting can cover at most %d years", MaxYears)
+	}
+	if t.SignOnYears > MaxYears {
+		return fmt.Errorf("sign_on_years can be at most %d", MaxYears)
+	}
+	vestingYears := len(t.Vesting)
+	if vestingYears == 0 {
+		vestingYears = len(DefaultVesting)
+	}
+	if t.CliffMonths > vestingYears*12 {
+		return fmt.Errorf("cliff_months can't be longer than the %d-year vesting schedule", vestingYears)
+	}
+	if len(t.Vesting) > 0 {
+		total := 0.0
+		for _, pct := range t.Vesting {
+			if pct < 0 {
+				return fmt.Errorf("vesting percentages can't be negative")
+			}
+			total += pct
+		}
+		if math.Abs(total-100) > 0.5 {
+			return fmt.Errorf("vesting adds up to %g%%, not 100%%", total)
+		}
+	}
+	return nil
+}
+
+func (t Terms) bonus() float64 {
+	if t.Bonus > 0 {
+		return t.Bonus
+	}
+	return t.Base * t.BonusPercent / 100
+}
+
+// Year is the compensation earned in one year of an offer
+type Year struct {
+	Base   float64 `json:"base"`
+	Bonus  float64 `json:"bonus"`
+	Equity float64 `json:"equity"`
+	SignOn float64 `json:"sign_on"`
+	Total  float64 `json:"total"`
+}
+
+// Years breaks the terms down by year, to the dollar, until the grant has
+// fully vested and the sign-on is earned. Base and bonus are assumed flat.
+// The terms must be valid.
+func (t Terms) Years() []Year {
+	vesting := t.Vesting
+	if len(vesting) == 0 {
+		vesting = DefaultVesting
+	}
+	signOnYears := t.SignOnYears
+	if signOnYears == 0 {
+		signOnYears = 1
+	}
+
+	// Vest each year's share evenly by month, holding back what falls
+	// before the cliff
+	months := len(vesting) * 12
+	if t.CliffMonths > months {
+		months = t.CliffMonths
+	}
+	vested := make([]float64, months)
+	for m := 0; m < len(vesting)*12; m++ {
+		amount := t.Equity * vesting[m/12] / 100 / 12
+		if m+1 < t.CliffMonths {
+			vested[t.CliffMonths-1] += amount
+		} else {
+			vested[m] += amount
+		}
+	}
+
+	n := (months + 11) / 12
+	if signOnYears > n {
+		n = signOnYears
+	}
+	years := make([]Year, n)
+	for i := range years {
+		y := Year{Base: t.Base, Bonus: t.bonus()}
+		for m := i * 12; m < (i+1)*12 && m < months; m++ {
+			y.Equity += vested[m]
+		}
+		y.Equity = math.Round(y.Equity)
+		if i < signOnYears {
+			y.SignOn = math.Round(t.SignOn / float64(signOnYears))
+		}
+		y.Total = y.Base + y.Bonus + y.Equity + y.SignOn
+		years[i] = y
+	}
+	return years
+}
+
+// Annual averages the years of the terms, to the dollar
+func (t Terms) Annual() Year {
+	years := t.Years()
+	var avg Year
+	for _, y := range years {
+		avg.Base += y.Base
+		avg.Bonus += y.Bonus
+		avg.Equity += y.Equity
+		avg.SignOn += y.SignOn
+		avg.Total += y.Total
+	}
+	n := float64(len(years))
+	return Year{
+		Base:   math.Round(avg.Base / n),
+		Bonus:  math.Round(avg.Bonus / n),
+		Equity: math.Round(avg.Equity / n),
+		SignOn: math.Round(avg.SignOn / n),
+		Total:  math.Round(avg.Total / n),
+	}
+}
+
+// Offer is an offer to compare along with what it is benchmarked against.
+// PostedRange ("$180,000 - $240,000") and LevelsMedian ("$250k") are parsed
+// as written in job postings and Levels.fyi.
+type Offer struct {
+	Name         string `json:"name,omitempty" yaml:"name"`
+	Company      string `json:"company" yaml:"company"`
+	Title        string `json:"title,omitempty" yaml:"title"`
+	Terms        `yaml:",inline"`
+	PostedRange  string `json:"posted_range,omitempty" yaml:"posted_range"`
+	LevelsMedian string `json:"levels_median,omitempty" yaml:"levels_median"`
+}
+
+// Label names the offer in comparisons
+func (o Offer) Label() string {
+	if o.Name != "" {
+		return o.Name
+	}
+	if o.Title != "" {
+		return o.Company + " - " + o.Title
+	}
+	return o.Company
+}
+
+// Comparison is an offer annualised and placed against its benchmarks
+type Comparison struct {
+	Name      string `json:"name"`
+	Company   string `json:"company"`
+	Title     string `json:"title,omitempty"`
+	Years     []Year `json:"years"`
+	FirstYear Year   `json:"first_year"`
+	Annual    Year   `json:"annual"`
+	// PostedMin and PostedMax are the posted range, and PostedPercentile
+	// where the base falls in it (0 at the bottom, 100 at the top, outside
+	// 0-100 when the base is outside the range)
+	PostedMin        float64  `json:"posted_min,omitempty"`
+	PostedMax        float64  `json:"posted_max,omitempty"`
+	PostedPercentile *float64 `json:"posted_percentile,omitempty"`
+	// VsLevels is how far the annual total is above (or below, when
+	// negative) the Levels.fyi median, in percent
+	LevelsMedian float64  `json:"levels_median,omitempty"`
+	VsLevels     *float64 `json:"vs_levels,omitempty"`
+	// Rank orders the offers by annual total, 1 being the highest
+	Rank int `json:"rank"`
+}
+
+// Compare annualises the offers and ranks them by annual total
+// compensation. The comparisons are in the order of the offers.
+func Compare(list []Offer) ([]Comparison, error) {
+	result := make([]Comparison, len(list))
+	for i, o := range list {
+		if err := o.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.Label(), err)
+		}
+		years := o.Years()
+		c := Comparison{
+			Name:      o.Label(),
+			Company:   o.Company,
+			Title:     o.Title,
+			Years:     years,
+			FirstYear: years[0],
+			Annual:    o.Annual(),
+		}
+		if min, max, ok := ParseRange(o.PostedRange); ok {
+			c.PostedMin, c.PostedMax = min, max
+			if max > min {
+				pct := (o.Base - min) / (max - min) * 100
+				c.PostedPercentile = &pct
+			}
+		}
+		if median, ok := query.ParseAmount(o.LevelsMedian); ok && median > 0 {
+			c.LevelsMedian = median
+			vs := (c.Annual.Total - median) / median * 100
+			c.VsLevels = &vs
+		}
+		result[i] = c
+	}
+
+	for i := range result {
+		result[i].Rank = 1
+		for j := range result {
+			if result[j].Annual.Total > result[i].Annual.Total {
+				result[i].Rank++
+			}
+		}
+	}
+	return result, nil
+}
+
+// ParseRange parses a salary range such as "$180,000 - $240,000" or
+// "150k-200k". A single amount is a range of one value.
+func ParseRange(s string) (min, max float64, ok bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, false
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '–' || r == '—' })
+	if len(parts) == 0 || len(parts) > 2 {
+		return 0, 0, false
+	}
+	if min, ok = query.ParseAmount(parts[0]); !ok {
+		return 0, 0, false
+	}
+	max = min
+	if len(parts) == 2 {
+		if max, ok = query.ParseAmount(parts[1]); !ok {
+			return 0, 0, false
+		}
+		if max < min {
+			min, max = max, min
+		}
+	}
+	return min, max, true
+}
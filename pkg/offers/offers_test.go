@@ -0,0 +1,160 @@
+package offers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestYears(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms Terms
+		want  []Year
+	}{
+		{
+			name:  "default vesting",
+			terms: Terms{Base: 200000, BonusPercent: 10, Equity: 400000},
+			want: []Year{
+				{Base: 200000, Bonus: 20000, Equity: 100000, Total: 320000},
+				{Base: 200000, Bonus: 20000, Equity: 100000, Total: 320000},
+				{Base: 200000, Bonus: 20000, Equity: 100000, Total: 320000},
+				{Base: 200000, Bonus: 20000, Equity: 100000, Total: 320000},
+			},
+		},
+		{
+			name:  "bonus overrides bonus percent",
+			terms: Terms{Base: 100000, Bonus: 5000, BonusPercent: 50, Vesting: []float64{100}},
+			want:  []Year{{Base: 100000, Bonus: 5000, Total: 105000}},
+		},
+		{
+			name:  "one-year cliff vests the first year at the cliff",
+			terms: Terms{Base: 100000, Equity: 120000, Vesting: []float64{50, 50}, CliffMonths: 12},
+			want: []Year{
+				{Base: 100000, Equity: 60000, Total: 160000},
+				{Base: 100000, Equity: 60000, Total: 160000},
+			},
+		},
+		{
+			name:  "cliff past the first year moves equity into the second",
+			terms: Terms{Base: 100000, Equity: 120000, Vesting: []float64{50, 50}, CliffMonths: 18},
+			want: []Year{
+				{Base: 100000, Total: 100000},
+				{Base: 100000, Equity: 120000, Total: 220000},
+			},
+		},
+		{
+			name:  "sign-on amortised over its years",
+			terms: Terms{Base: 100000, SignOn: 30000, SignOnYears: 2, Vesting: []float64{100}},
+			want: []Year{
+				{Base: 100000, SignOn: 15000, Total: 115000},
+				{Base: 100000, SignOn: 15000, Total: 115000},
+			},
+		},
+		{
+			name:  "sign-on outlasting vesting adds years",
+			terms: Terms{Base: 100000, SignOn: 30000, SignOnYears: 3, Equity: 12000, Vesting: []float64{100}},
+			want: []Year{
+				{Base: 100000, Equity: 12000, SignOn: 10000, Total: 122000},
+				{Base: 100000, SignOn: 10000, Total: 110000},
+				{Base: 100000, SignOn: 10000, Total: 110000},
+			},
+		},
+		{
+			name:  "sign-on defaults to the first year",
+			terms: Terms{Base: 100000, SignOn: 25000, Vesting: []float64{100}},
+			want:  []Year{{Base: 100000, SignOn: 25000, Total: 125000}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.terms.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if got := tt.terms.Years(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Years() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnual(t *testing.T) {
+	terms := Terms{Base: 100000, Equity: 120000, Vesting: []float64{50, 50}, CliffMonths: 18, SignOn: 20000}
+	want := Year{Base: 100000, Equity: 60000, SignOn: 10000, Total: 170000}
+	if got := terms.Annual(); got != want {
+		t.Errorf("Annual() = %+v, want %+v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		terms Terms
+		ok    bool
+	}{
+		{"zero terms", Terms{}, true},
+		{"negative base", Terms{Base: -1}, false},
+		{"negative cliff", Terms{CliffMonths: -1}, false},
+		{"cliff at end of default vesting", Terms{CliffMonths: 48}, true},
+		{"cliff past default vesting", Terms{CliffMonths: 49}, false},
+		{"cliff past custom vesting", Terms{Vesting: []float64{100}, CliffMonths: 13}, false},
+		{"huge cliff", Terms{CliffMonths: 1000000000}, false},
+		{"sign-on years at the cap", Terms{SignOnYears: MaxYears}, true},
+		{"sign-on years over the cap", Terms{SignOnYears: MaxYears + 1}, false},
+		{"vesting over the cap", Terms{Vesting: make([]float64, MaxYears+1)}, false},
+		{"vesting not 100%", Terms{Vesting: []float64{25, 25, 25}}, false},
+		{"negative vesting", Terms{Vesting: []float64{150, -50}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.terms.Validate(); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate() = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	list := []Offer{
+		{Company: "Acme", Terms: Terms{Base: 150000}, PostedRange: "$100,000 - $200,000", LevelsMedian: "$200k"},
+		{Company: "Globex", Title: "Red Teamer", Terms: Terms{Base: 180000}},
+	}
+	got, err := Compare(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Rank != 2 || got[1].Rank != 1 {
+		t.Errorf("ranks = %d, %d, want 2, 1", got[0].Rank, got[1].Rank)
+	}
+	if got[0].PostedPercentile == nil || *got[0].PostedPercentile != 50 {
+		t.Errorf("posted percentile = %v, want 50", got[0].PostedPercentile)
+	}
+	if got[0].VsLevels == nil || *got[0].VsLevels != -25 {
+		t.Errorf("vs Levels.fyi = %v, want -25", got[0].VsLevels)
+	}
+	if got[1].Name != "Globex - Red Teamer" || got[1].PostedPercentile != nil || got[1].VsLevels != nil {
+		t.Errorf("unbenchmarked offer = %+v", got[1])
+	}
+
+	if _, err := Compare([]Offer{{Company: "Acme", Terms: Terms{CliffMonths: 1000000000}}}); err == nil {
+		t.Error("Compare accepted an out-of-range cliff")
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		min, max float64
+		ok       bool
+	}{
+		{"$180,000 - $240,000", 180000, 240000, true},
+		{"150k-200k", 150000, 200000, true},
+		{"200k – 150k", 150000, 200000, true},
+		{"$175,000", 175000, 175000, true},
+		{"", 0, 0, false},
+		{"competitive", 0, 0, false},
+	}
+	for _, tt := range tests {
+		min, max, ok := ParseRange(tt.input)
+		if min != tt.min || max != tt.max || ok != tt.ok {
+			t.Errorf("ParseRange(%q) = %v, %v, %v, want %v, %v, %v", tt.input, min, max, ok, tt.min, tt.max, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokTerm
+	tokSalary
+	tokField
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+	term  termNode   // tokTerm
+	cmp   salaryNode // tokSalary
+	field int        // tokField
+}
+
+// salaryOps are the comparison operators accepted after "salary", longest
+// first so that ">=" is not read as ">"
+var salaryOps = []string{">=", "<=", ">", "<", "="}
+
+// Parse parses a boolean search expression.
+//
+// Supported syntax:
+//
+//	oscp                      a single word (case-insensitive, whole words only)
+//	"red team"                a quoted phrase
+//	secur*, *security         wildcards relax the word boundary on that side
+//	oscp AND "red team"       both must match (AND is implied between terms)
+//	pentest OR "pen test"     either may match
+//	NOT manager, -manager     the term must not match
+//	(oscp OR osce) -manager   parentheses group sub-expressions
+//	title:"red team"          scope a term or group to title, company,
+//	                          location or description
+//	salary>=200k              compare the salary (>=, <=, >, <, =); jobs
+//	                          without a known salary never match
+//
+// Operators must be written in upper case so that ordinary words like "or"
+// in a title search are still treated as terms.
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, fmt.Errorf("unexpected ')' at position %d", tok.pos+1)
+		}
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.value, tok.pos+1)
+	}
+
+	return &Query{raw: input, root: root}, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed. It is
+// intended for queries built from constants.
+func MustParse(input string) *Query {
+	q, err := Parse(input)
+	if err != nil {
+		panic(fmt.Sprintf("query: Parse(%q): %v", input, err))
+	}
+	return q
+}
+
+// RegexPrefix marks a keyword passed to Keywords as a regular expression
+const RegexPrefix = "regex:"
+
+// Keywords returns a query matching any of the keywords as a phrase, with the
+// same word-boundary and wildcard rules as quoted phrases in Parse. Keywords
+// starting with "regex:" are case-insensitive regular expressions matched
+// against the lowercased, single-spaced field text. With no fields the
+// keywords match any text field. It is how keyword lists in config files are
+// turned into queries.
+func Keywords(keywords []string, fields ...string) (*Query, error) {
+	var root node
+	quoted := make([]string, 0, len(keywords))
+	for _, kw := range keywords {
+		var term node
+		if strings.HasPrefix(kw, RegexPrefix) {
+			pattern := strings.TrimPrefix(kw, RegexPrefix)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %v", pattern, err)
+			}
+			term = regexNode{re: regexp.MustCompile("(?i)" + pattern)}
+		} else {
+			t, err := newTerm(kw)
+			if err != nil {
+				return nil, fmt.Errorf("keyword %q: %v", kw, err)
+			}
+			term = t
+		}
+		quoted = append(quoted, strconv.Quote(kw))
+		if root == nil {
+			root = term
+		} else {
+			root = orNode{left: root, right: term}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("no keywords")
+	}
+
+	raw := strings.Join(quoted, " OR ")
+	if len(fields) > 0 {
+		scope := make([]int, 0, len(fields))
+		for _, f := range fields {
+			idx := fieldIndex(strings.ToLower(f))
+			if idx < 0 {
+				return nil, fmt.Errorf("unknown field %q", f)
+			}
+			scope = append(scope, idx)
+		}
+		root = fieldNode{fields: scope, child: root}
+		raw = strings.Join(fields, ",") + ":(" + raw + ")"
+	}
+
+	return &Query{raw: raw, root: root}, nil
+}
+
+// ParseAmount parses a dollar amount such as "200k", "$185,000" or "1.2m".
+// Text after the number (a range, "/year") is ignored.
+func ParseAmount(s string) (float64, bool) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	s = strings.TrimPrefix(s, "$")
+
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == ',' || s[end] == '.') {
+		end++
+	}
+	value, err := strconv.ParseFloat(strings.ReplaceAll(s[:end], ",", ""), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	suffix := strings.TrimSpace(s[end:])
+	if strings.HasPrefix(suffix, "k") {
+		value *= 1000
+	} else if strings.HasPrefix(suffix, "m") {
+		value *= 1000000
+	}
+	return value, true
+}
+
+// newTerm builds a term from a word or phrase, handling * wildcards
+func newTerm(text string) (termNode, error) {
+	t := termNode{value: normalize(text)}
+	if strings.HasPrefix(t.value, "*") {
+		t.anyStart = true
+		t.value = strings.TrimLeft(t.value, "*")
+	}
+	if strings.HasSuffix(t.value, "*") {
+		t.anyEnd = true
+		t.value = strings.TrimRight(t.value, "*")
+	}
+	t.value = strings.TrimSpace(t.value)
+	if t.value == "" {
+		if strings.Contains(text, "*") {
+			return t, fmt.Errorf("wildcard without a term")
+		}
+		return t, fmt.Errorf("empty term")
+	}
+	return t, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, value: ")", pos: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated quote at position %d", i+1)
+			}
+			phrase := string(runes[i+1 : end])
+			if strings.TrimSpace(phrase) == "" {
+				return nil, fmt.Errorf("empty phrase at position %d", i+1)
+			}
+			term, err := newTerm(phrase)
+			if err != nil {
+				return nil, fmt.Errorf("%v at position %d", err, i+1)
+			}
+			tokens = append(tokens, token{kind: tokTerm, value: phrase, pos: i, term: term})
+			i = end + 1
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != '-' && runes[i+1] != ')':
+			// "-term" and -"phrase" are shorthand for NOT
+			tokens = append(tokens, token{kind: tokNot, value: "-", pos: i})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' && runes[i] != '"' {
+				i++
+			}
+			wordTokens, err := lexWord(string(runes[start:i]), start)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, wordTokens...)
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, pos: len(runes)}), nil
+}
+
+// lexWord classifies a bare word as an operator, a salary comparison, a
+// field prefix (optionally followed by its term) or a plain term
+func lexWord(word string, pos int) ([]token, error) {
+	switch word {
+	case "AND":
+		return []token{{kind: tokAnd, value: word, pos: pos}}, nil
+	case "OR":
+		return []token{{kind: tokOr, value: word, pos: pos}}, nil
+	case "NOT":
+		return []token{{kind: tokNot, value: word, pos: pos}}, nil
+	}
+
+	lower := strings.ToLower(word)
+	if strings.HasPrefix(lower, "salary") && len(lower) > len("salary") {
+		rest := lower[len("salary"):]
+		if rest[0] == ':' {
+			return nil, fmt.Errorf("salary only supports comparisons such as salary>=200k (position %d)", pos+1)
+		}
+		for _, op := range salaryOps {
+			if strings.HasPrefix(rest, op) {
+				amount, ok := ParseAmount(rest[len(op):])
+				if !ok {
+					return nil, fmt.Errorf("invalid salary amount %q at position %d", rest[len(op):], pos+1)
+				}
+				return []token{{kind: tokSalary, value: word, pos: pos, cmp: salaryNode{op: op, amount: amount}}}, nil
+			}
+		}
+	}
+
+	// field:term, or field: followed by a phrase or group. Words with an
+	// unknown prefix (URLs, "c++:") are ordinary terms.
+	if idx := strings.IndexByte(word, ':'); idx > 0 {
+		if field := fieldIndex(strings.ToLower(word[:idx])); field >= 0 {
+			tokens := []token{{kind: tokField, value: word[:idx+1], pos: pos, field: field}}
+			if rest := word[idx+1:]; rest != "" {
+				term, err := newTerm(rest)
+				if err != nil {
+					return nil, fmt.Errorf("%v at position %d", err, pos+idx+2)
+				}
+				tokens = append(tokens, token{kind: tokTerm, value: rest, pos: pos + idx + 1, term: term})
+			}
+			return tokens, nil
+		}
+	}
+
+	term, err := newTerm(word)
+	if err != nil {
+		return nil, fmt.Errorf("%v at position %d", err, pos+1)
+	}
+	return []token{{kind: tokTerm, value: word, pos: pos, term: term}}, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr handles the lowest-precedence operator: a OR b OR c
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseAnd handles explicit AND as well as implicit AND between adjacent terms
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokTerm, tokSalary, tokField, tokNot, tokLParen:
+			// implicit AND
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+}
+
+// parseUnary handles NOT and field prefixes, which both apply to the
+// expression that follows them
+func (p *parser) parseUnary() (node, error) {
+	switch tok := p.peek(); tok.kind {
+	case tokNot:
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	case tokField:
+		p.next()
+		if p.peek().kind == tokEOF {
+			return nil, fmt.Errorf("expected a term after %q at position %d", tok.value, tok.pos+1)
+		}
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return fieldNode{fields: []int{tok.field}, child: child}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokTerm:
+		return tok.term, nil
+	case tokSalary:
+		return tok.cmp, nil
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, fmt.Errorf("missing ')' for '(' at position %d", tok.pos+1)
+		}
+		p.next()
+		return inner, nil
+	case tokEOF:
+		return nil, fmt.Errorf("expected a term at end of query")
+	default:
+		return nil, fmt.Errorf("expected a term but found %q at position %d", tok.value, tok.pos+1)
+	}
+}
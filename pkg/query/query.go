@@ -0,0 +1,409 @@
+// Package query implements the boolean search syntax shared by the
+// salarysleuth CLI, the jobtracker config rules and the jobtracker web search:
+// terms, "quoted phrases", AND, OR, NOT, parentheses, field scoping such as
+// title:oscp, and salary comparisons such as salary>=200k. Adjacent terms are
+// implicitly ANDed, so `offensive security` is the same as
+// `offensive AND security`.
+//
+// Terms match whole words: "ii" matches "Engineer II" but not "Engineering".
+// A leading or trailing * relaxes the boundary on that side, so secur*
+// matches "Security" and *security matches "Cybersecurity".
+package query
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Field names accepted in field-scoped terms (title:oscp)
+const (
+	FieldTitle       = "title"
+	FieldCompany     = "company"
+	FieldLocation    = "location"
+	FieldDescription = "description"
+)
+
+// fieldNames is indexed by the field positions used in document.text
+var fieldNames = []string{FieldTitle, FieldCompany, FieldLocation, FieldDescription}
+
+func fieldIndex(name string) int {
+	for i, f := range fieldNames {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Document is a job posting as seen by a query. Unscoped terms search every
+// text field; scoped terms only search the named one.
+type Document struct {
+	Title       string
+	Company     string
+	Location    string
+	Description string
+	// Salary is the annual salary in dollars used by salary comparisons. It
+	// is 0 when unknown, in which case no comparison matches.
+	Salary float64
+}
+
+// document is a Document with its text fields normalized for matching
+type document struct {
+	text   [4]string
+	salary float64
+}
+
+func newDocument(d Document) *document {
+	return &document{
+		text:   [4]string{normalize(d.Title), normalize(d.Company), normalize(d.Location), normalize(d.Description)},
+		salary: d.Salary,
+	}
+}
+
+// Query is a parsed search expression
+type Query struct {
+	raw  string
+	root node
+}
+
+// node is a single element of the parsed expression tree
+type node interface {
+	// match reports whether the node matches the document. scope lists the
+	// fields terms may match in; nil means any text field.
+	match(d *document, scope []int) bool
+	// terms appends the positive (non-negated) terms under this node
+	terms(dst []termNode, negated bool) []termNode
+}
+
+type termNode struct {
+	value    string // normalized
+	anyStart bool   // leading *: may start mid-word
+	anyEnd   bool   // trailing *: may end mid-word
+}
+
+type fieldNode struct {
+	fields []int
+	child  node
+}
+
+type salaryNode struct {
+	op     string // one of >=, <=, >, <, =
+	amount float64
+}
+
+// regexNode matches a regular expression, only created by Keywords
+type regexNode struct {
+	re *regexp.Regexp
+}
+
+type andNode struct {
+	left, right node
+}
+
+type orNode struct {
+	left, right node
+}
+
+type notNode struct {
+	child node
+}
+
+func (n termNode) match(d *document, scope []int) bool {
+	if scope == nil {
+		for _, text := range d.text {
+			if n.find(text, 0) >= 0 {
+				return true
+			}
+		}
+		return false
+	}
+	for _, f := range scope {
+		if n.find(d.text[f], 0) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (n termNode) terms(dst []termNode, negated bool) []termNode {
+	if negated {
+		return dst
+	}
+	return append(dst, n)
+}
+
+// find returns the byte offset of the first occurrence of the term in the
+// lowercased text at or after from that respects word boundaries, or -1
+func (n termNode) find(text string, from int) int {
+	for from <= len(text) {
+		idx := strings.Index(text[from:], n.value)
+		if idx < 0 {
+			return -1
+		}
+		start := from + idx
+		if n.bounded(text, start, start+len(n.value)) {
+			return start
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		from = start + size
+	}
+	return -1
+}
+
+// bounded reports whether text[start:end] is not part of a longer word. Terms
+// that begin or end with punctuation ("sr.", "c++") only need a boundary on
+// the side that is a word character.
+func (n termNode) bounded(text string, start, end int) bool {
+	if !n.anyStart {
+		first, _ := utf8.DecodeRuneInString(n.value)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		if start > 0 && isWordRune(first) && isWordRune(before) {
+			return false
+		}
+	}
+	if !n.anyEnd {
+		last, _ := utf8.DecodeLastRuneInString(n.value)
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if end < len(text) && isWordRune(last) && isWordRune(after) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func (n fieldNode) match(d *document, scope []int) bool {
+	return n.child.match(d, n.fields)
+}
+
+func (n fieldNode) terms(dst []termNode, negated bool) []termNode {
+	return n.child.terms(dst, negated)
+}
+
+func (n salaryNode) match(d *document, scope []int) bool {
+	if d.salary <= 0 {
+		return false
+	}
+	switch n.op {
+	case ">=":
+		return d.salary >= n.amount
+	case "<=":
+		return d.salary <= n.amount
+	case ">":
+		return d.salary > n.amount
+	case "<":
+		return d.salary < n.amount
+	default:
+		return d.salary == n.amount
+	}
+}
+
+func (n salaryNode) terms(dst []termNode, negated bool) []termNode {
+	return dst
+}
+
+func (n regexNode) match(d *document, scope []int) bool {
+	if scope == nil {
+		for _, text := range d.text {
+			if n.re.MatchString(text) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, f := range scope {
+		if n.re.MatchString(d.text[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n regexNode) terms(dst []termNode, negated bool) []termNode {
+	return dst
+}
+
+func (n andNode) match(d *document, scope []int) bool {
+	return n.left.match(d, scope) && n.right.match(d, scope)
+}
+
+func (n andNode) terms(dst []termNode, negated bool) []termNode {
+	return n.right.terms(n.left.terms(dst, negated), negated)
+}
+
+func (n orNode) match(d *document, scope []int) bool {
+	return n.left.match(d, scope) || n.right.match(d, scope)
+}
+
+func (n orNode) terms(dst []termNode, negated bool) []termNode {
+	return n.right.terms(n.left.terms(dst, negated), negated)
+}
+
+func (n notNode) match(d *document, scope []int) bool {
+	return !n.child.match(d, scope)
+}
+
+func (n notNode) terms(dst []termNode, negated bool) []termNode {
+	return n.child.terms(dst, !negated)
+}
+
+// String returns the query as it was originally written
+func (q *Query) String() string {
+	return q.raw
+}
+
+// Match reports whether text satisfies the query, treating text as a
+// posting's description. Matching is case-insensitive and treats any run of
+// whitespace as a single space.
+func (q *Query) Match(text string) bool {
+	return q.MatchDocument(Document{Description: text})
+}
+
+// MatchDocument reports whether the document satisfies the query
+func (q *Query) MatchDocument(d Document) bool {
+	return q.root.match(newDocument(d), nil)
+}
+
+// positiveTerms returns the distinct terms that can contribute to a match,
+// longest first
+func (q *Query) positiveTerms() []termNode {
+	all := q.root.terms(nil, false)
+	seen := make(map[termNode]bool, len(all))
+	var terms []termNode
+	for _, t := range all {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, t)
+		}
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i].value) > len(terms[j].value) })
+	return terms
+}
+
+// Terms returns the distinct positive terms and phrases in the query, longest
+// first. Negated terms are omitted since they never appear in a match.
+func (q *Query) Terms() []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, t := range q.positiveTerms() {
+		if !seen[t.value] {
+			seen[t.value] = true
+			terms = append(terms, t.value)
+		}
+	}
+	return terms
+}
+
+// Span marks the byte range [Start, End) of a term occurrence in a text
+type Span struct {
+	Start int
+	End   int
+}
+
+// Spans returns the non-overlapping occurrences of the query's positive terms
+// in text, ordered by position. Phrases are only found where their words are
+// separated by single spaces; use Snippet for text with arbitrary whitespace.
+func (q *Query) Spans(text string) []Span {
+	lower, offsets := foldCase(text)
+
+	var spans []Span
+	for _, term := range q.positiveTerms() {
+		for from := 0; from <= len(lower); {
+			start := term.find(lower, from)
+			if start < 0 {
+				break
+			}
+			end := start + len(term.value)
+			if !overlaps(spans, offsets[start], offsets[end]) {
+				spans = append(spans, Span{Start: offsets[start], End: offsets[end]})
+			}
+			from = end
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	return spans
+}
+
+// Snippet returns a window of text around the first match, extended by
+// roughly radius bytes on each side, along with the spans that fall inside
+// the window (relative to the returned string). It returns "" if no term
+// occurs in text.
+func (q *Query) Snippet(text string, radius int) (string, []Span) {
+	text = strings.Join(strings.Fields(text), " ")
+	spans := q.Spans(text)
+	if len(spans) == 0 {
+		return "", nil
+	}
+
+	start := spans[0].Start - radius
+	end := spans[0].End + radius
+	prefix, suffix := "...", "..."
+	if start <= 0 {
+		start, prefix = 0, ""
+	} else if i := strings.IndexByte(text[start:spans[0].Start], ' '); i >= 0 {
+		start += i + 1
+	} else {
+		for !utf8.RuneStart(text[start]) {
+			start++
+		}
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	} else if i := strings.LastIndexByte(text[spans[0].End:end], ' '); i >= 0 {
+		end = spans[0].End + i
+	} else {
+		for !utf8.RuneStart(text[end]) {
+			end--
+		}
+	}
+
+	var inside []Span
+	for _, s := range spans {
+		if s.Start >= start && s.End <= end {
+			inside = append(inside, Span{
+				Start: s.Start - start + len(prefix),
+				End:   s.End - start + len(prefix),
+			})
+		}
+	}
+	return prefix + text[start:end] + suffix, inside
+}
+
+// foldCase lowercases text the way strings.ToLower does, and returns the
+// offset in text of each byte of the result (plus len(text) at the end), since
+// lowercasing can change a rune's length ("İ" becomes "i")
+func foldCase(text string) (string, []int) {
+	var b strings.Builder
+	b.Grow(len(text))
+	offsets := make([]int, 0, len(text)+1)
+	for i, r := range text {
+		n := b.Len()
+		b.WriteRune(unicode.ToLower(r))
+		for ; n < b.Len(); n++ {
+			offsets = append(offsets, i)
+		}
+	}
+	return b.String(), append(offsets, len(text))
+}
+
+// normalize lowercases text and collapses whitespace so phrases match across
+// line breaks and repeated spaces
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func overlaps(spans []Span, start, end int) bool {
+	for _, s := range spans {
+		if start < s.End && end > s.Start {
+			return true
+		}
+	}
+	return false
+}
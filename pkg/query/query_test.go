@@ -0,0 +1,238 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query"},
+		{`"red team`, "unterminated quote at position 1"},
+		{`""`, "empty phrase at position 1"},
+		{"(oscp OR osce", "missing ')' for '(' at position 1"},
+		{"oscp)", "unexpected ')' at position 5"},
+		{"oscp AND", "expected a term at end of query"},
+		{"OR oscp", `expected a term but found "OR" at position 1`},
+		{"title:", `expected a term after "title:" at position 1`},
+		{"*", "wildcard without a term at position 1"},
+		{"salary>=lots", `invalid salary amount "lots" at position 1`},
+		{"salary:200k", "salary only supports comparisons such as salary>=200k (position 1)"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error %q", tt.input, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %q, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestMatchDocument(t *testing.T) {
+	doc := Document{
+		Title:       "Senior Offensive Security Engineer II",
+		Company:     "Acme Cybersecurity",
+		Location:    "Remote, US",
+		Description: "Lead red\nteam  engagements. OSCP preferred; C++ a plus.",
+		Salary:      210000,
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		// terms are case-insensitive whole words
+		{"offensive", true},
+		{"OFFENSIVE", true},
+		{"ii", true},
+		{"engine", false},
+		{"c++", true},
+
+		// phrases match across any whitespace
+		{`"red team"`, true},
+		{`"team red"`, false},
+
+		// implicit and explicit AND, OR, NOT
+		{"offensive security", true},
+		{"offensive manager", false},
+		{"offensive AND manager", false},
+		{"manager OR oscp", true},
+		{"NOT manager", true},
+		{"-manager", true},
+		{"-oscp", false},
+		{`-"red team"`, false},
+		{"or", false}, // lowercase operators are terms
+
+		// AND binds tighter than OR
+		{"manager AND director OR oscp", true},
+		{"manager AND (director OR oscp)", false},
+		{"(manager OR offensive) AND oscp", true},
+
+		// field scoping
+		{"title:offensive", true},
+		{"company:offensive", false},
+		{"company:acme", true},
+		{"location:remote", true},
+		{`title:"security engineer"`, true},
+		{"title:(oscp OR engineer)", true},
+		{"title:(oscp OR manager)", false},
+		{"-title:manager", true},
+		{"description:oscp title:senior", true},
+		{"https://example.com", false}, // unknown prefix is a plain term
+
+		// salary comparisons
+		{"salary>=200k", true},
+		{"salary>=$210,000", true},
+		{"salary>210k", false},
+		{"salary<=0.25m", true},
+		{"salary<200k", false},
+		{"salary=210000", true},
+
+		// wildcards relax the boundary on their side
+		{"secur*", true},
+		{"*security", true},
+		{"cyber", false},
+		{"cyber*", true},
+		{"*curity*", true},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.query, err)
+			continue
+		}
+		if got := q.MatchDocument(doc); got != tt.want {
+			t.Errorf("%q matched = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSalaryUnknown(t *testing.T) {
+	for _, input := range []string{"salary>=1", "salary<1000000", "salary=0"} {
+		q := MustParse(input)
+		if q.MatchDocument(Document{Title: "Engineer"}) {
+			t.Errorf("%q matched a job without a salary", input)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		keywords []string
+		fields   []string
+		doc      Document
+		want     bool
+	}{
+		{[]string{"red team"}, nil, Document{Description: "Red  Team lead"}, true},
+		{[]string{"red team"}, []string{FieldTitle}, Document{Description: "red team"}, false},
+		{[]string{"intern"}, []string{FieldTitle}, Document{Title: "International Sales"}, false},
+		{[]string{"manager", "intern*"}, []string{FieldTitle}, Document{Title: "Internship"}, true},
+		{[]string{`regex:sr\.? (pen)?tester`}, nil, Document{Title: "Sr. Pentester"}, true},
+		{[]string{`regex:^staff`}, []string{FieldTitle}, Document{Title: "Principal Staff Engineer"}, false},
+	}
+	for _, tt := range tests {
+		q, err := Keywords(tt.keywords, tt.fields...)
+		if err != nil {
+			t.Errorf("Keywords(%q): %v", tt.keywords, err)
+			continue
+		}
+		if got := q.MatchDocument(tt.doc); got != tt.want {
+			t.Errorf("Keywords(%q, %q) matched %+v = %v, want %v", tt.keywords, tt.fields, tt.doc, got, tt.want)
+		}
+	}
+
+	if _, err := Keywords([]string{"regex:("}); err == nil {
+		t.Error("Keywords accepted an invalid regex")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+		ok    bool
+	}{
+		{"200k", 200000, true},
+		{"$185,000", 185000, true},
+		{"1.2m", 1200000, true},
+		{"$150K - $200K", 150000, true},
+		{"120000/year", 120000, true},
+		{"lots", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAmount(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseAmount(%q) = %v, %v, want %v, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	q := MustParse(`oscp "red team" -manager title:(oscp OR lead) salary>=200k`)
+	want := []string{"red team", "oscp", "lead"}
+	if got := q.Terms(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Terms() = %q, want %q", got, want)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	tests := []struct {
+		query string
+		text  string
+		want  []string
+	}{
+		{"oscp", "OSCP or OSCE, oscp", []string{"OSCP", "oscp"}},
+		{`"red team" red`, "Red team and red cell", []string{"Red team", "red"}},
+		{"-manager lead", "Lead, not manager", []string{"Lead"}},
+		{"secur*", "Security", []string{"Secur"}},
+		// lowercasing changes the byte length of İ
+		{"security", "İstanbul İİ Security team", []string{"Security"}},
+		{"istanbul", "İstanbul", []string{"İstanbul"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range MustParse(tt.query).Spans(tt.text) {
+			got = append(got, tt.text[s.Start:s.End])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q spans in %q = %q, want %q", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		query  string
+		text   string
+		radius int
+		want   string
+		marked []string
+	}{
+		{"oscp", "no match here", 10, "", nil},
+		{"oscp", "Requires\nOSCP  certification", 100, "Requires OSCP certification", []string{"OSCP"}},
+		{"oscp", "one two three four OSCP five six seven eight", 6, "...four OSCP five...", []string{"OSCP"}},
+		// without a space in the window it is cut at rune boundaries
+		{"oscp", "ééééé/OSCP/ééééé", 3, "...é/OSCP/é...", []string{"OSCP"}},
+		{"oscp", "ééééé/OSCP/ééééé", 4, "...é/OSCP/é...", []string{"OSCP"}},
+	}
+	for _, tt := range tests {
+		got, spans := MustParse(tt.query).Snippet(tt.text, tt.radius)
+		if got != tt.want {
+			t.Errorf("%q snippet of %q = %q, want %q", tt.query, tt.text, got, tt.want)
+			continue
+		}
+		var marked []string
+		for _, s := range spans {
+			marked = append(marked, got[s.Start:s.End])
+		}
+		if !reflect.DeepEqual(marked, tt.marked) {
+			t.Errorf("%q snippet of %q marks %q, want %q", tt.query, tt.text, marked, tt.marked)
+		}
+	}
+}
@@ -19,8 +19,8 @@ func main() {
 	// Get the project root directory (parent of the current file)
 	projectRoot := filepath.Dir(filename)
 
-	// Build the path to the main.go file
-	mainPath := filepath.Join(projectRoot, "cmd", "salarysleuth", "main.go")
+	// Build the path to the main package
+	mainPath := filepath.Join(projectRoot, "cmd", "salarysleuth")
 
 	// Create the command to run the main program
 	cmd := exec.Command("go", "run", mainPath)